@@ -17,6 +17,7 @@ limitations under the License.
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -58,12 +59,664 @@ type AuthenticationSpec struct {
 	// LDAP contains the configuration needed to setup LDAP authentication.
 	// +optional
 	LDAP *AuthenticationLDAP `json:"ldap,omitempty"`
+
+	// ManagerClient contains the configuration of the static client that Dex registers for the Manager.
+	// +optional
+	ManagerClient *ManagerClient `json:"managerClient,omitempty"`
+
+	// AllowIssuerAliasing allows identity providers to specify an IssuerAlias, for when Dex federates an upstream issuer
+	// through a broker that reports a different issuer than the one it is reached at.
+	// Default: false
+	// +optional
+	AllowIssuerAliasing bool `json:"allowIssuerAliasing,omitempty"`
+
+	// StaticClients is a list of additional OAuth2 clients that Dex registers, for example for CLI tools that
+	// authenticate users against Dex.
+	// +optional
+	StaticClients []StaticClient `json:"staticClients,omitempty"`
+
+	// DexDeployment configures the Dex deployment.
+	// +optional
+	DexDeployment *DexDeployment `json:"dexDeployment,omitempty"`
+
+	// KubectlConfig renders a ConfigMap with a kubeconfig user that logs in to Dex with the oidc-login plugin of
+	// kubectl, so that the issuer, client ID and CA of Dex do not have to be looked up by hand.
+	// +optional
+	KubectlConfig *KubectlConfig `json:"kubectlConfig,omitempty"`
+
+	// DiscoveryNamespaces are the namespaces into which the operator copies the <dex name>-oidc-info ConfigMap, e.g.
+	// tigera-dex-oidc-info. It holds the issuer, URLs, client ID, claims and CA of Dex for workloads that validate
+	// the tokens of Dex, and is updated whenever they change.
+	// +optional
+	DiscoveryNamespaces []string `json:"discoveryNamespaces,omitempty"`
+}
+
+// KubectlConfig configures the ConfigMap with the kubeconfig user for kubectl. The ConfigMap has the keys issuer,
+// client-id, ca.crt and user.yaml, which holds the users stanza of a kubeconfig. It is updated when the issuer or the
+// certificate of Dex changes.
+type KubectlConfig struct {
+	// ClientID is the ID of the public static client that kubectl logs in as. The redirect URIs of the client must
+	// include the local addresses that the oidc-login plugin listens on, like http://localhost:8000.
+	// +kubebuilder:validation:MinLength=1
+	ClientID string `json:"clientID"`
+
+	// Namespace is the namespace of the ConfigMap, like kube-public to make it readable for all users.
+	// Default: the namespace of Dex
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Scopes are the scopes that kubectl requests. Default: the scopes that the Manager requests
+	// +optional
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// DexDeployment is the configuration of the Dex deployment.
+type DexDeployment struct {
+	// LivenessProbe selects the endpoint that the liveness probe of Dex queries. The OIDC discovery endpoint also fails
+	// on TLS and discovery problems, while the healthz endpoint only reports whether Dex itself is healthy. When Healthz
+	// is selected, the discovery endpoint is used for the readiness probe instead.
+	// Default: Discovery
+	// +optional
+	// +kubebuilder:validation:Enum=Discovery;Healthz
+	LivenessProbe *DexProbeEndpoint `json:"livenessProbe,omitempty"`
+
+	// Namespace is the namespace in which Dex is installed. It must exist before Dex can be installed. When it is
+	// changed, the objects in the previous namespace are removed.
+	// Default: tigera-dex
+	// +optional
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Namespace string `json:"namespace,omitempty"`
+
+	// TerminationMessagePolicy is set on the Dex container and its init containers. With FallbackToLogsOnError, the
+	// tail of the container log is used as termination message when Dex exits with an error before writing one.
+	// Default: FallbackToLogsOnError
+	// +optional
+	// +kubebuilder:validation:Enum=File;FallbackToLogsOnError
+	TerminationMessagePolicy *corev1.TerminationMessagePolicy `json:"terminationMessagePolicy,omitempty"`
+
+	// InitContainerResources are the resources of the init container that requests the certificate of Dex when
+	// certificate management is enabled.
+	// +optional
+	InitContainerResources *corev1.ResourceRequirements `json:"initContainerResources,omitempty"`
+
+	// InitContainerSecurityContext replaces the security context of the init container that requests the certificate
+	// of Dex when certificate management is enabled.
+	// Default: a security context that runs as non-root, without privileges or privilege escalation.
+	// +optional
+	InitContainerSecurityContext *corev1.SecurityContext `json:"initContainerSecurityContext,omitempty"`
+
+	// DNSPolicy is the DNS policy of the Dex pod, for example to resolve the identity provider through a custom
+	// resolver. The None policy requires a DNSConfig.
+	// Default: ClusterFirst
+	// +optional
+	// +kubebuilder:validation:Enum=ClusterFirstWithHostNet;ClusterFirst;Default;None
+	DNSPolicy *corev1.DNSPolicy `json:"dnsPolicy,omitempty"`
+
+	// StorageClusterRole is the name of a pre-provisioned ClusterRole that grants access to the dex.coreos.com
+	// resources in which Dex stores its state. When set, Dex is installed without creating a ClusterRole or
+	// ClusterRoleBinding: the ClusterRole is bound in the Dex namespace with a RoleBinding, and the Dex
+	// CustomResourceDefinitions must already exist, since Dex is not allowed to create them.
+	// +optional
+	StorageClusterRole string `json:"storageClusterRole,omitempty"`
+
+	// PodLabels are added to the labels of the Dex pod template, for example for service mesh injection or log
+	// collection. They are not added to the selector of the deployment and may not replace the k8s-app label.
+	// +optional
+	PodLabels map[string]string `json:"podLabels,omitempty"`
+
+	// PodAnnotations are added to the annotations of the Dex pod template, for example to control service mesh sidecar
+	// injection. The annotations that the operator sets to restart Dex on configuration changes take precedence.
+	// +optional
+	PodAnnotations map[string]string `json:"podAnnotations,omitempty"`
+
+	// ServiceAccountLabels are added to the labels of the service account of Dex. They may not replace the
+	// app.kubernetes.io labels that the operator sets on the objects of Dex, and are removed when they are no longer
+	// configured.
+	// +optional
+	ServiceAccountLabels map[string]string `json:"serviceAccountLabels,omitempty"`
+
+	// ServiceAccountAnnotations are added to the annotations of the service account of Dex, for example for workload
+	// identity. The annotations that others add to the service account are kept, but the configured annotations are
+	// removed when they are no longer configured. Their keys are listed in the operator.tigera.io/managed-annotations
+	// annotation.
+	// +optional
+	ServiceAccountAnnotations map[string]string `json:"serviceAccountAnnotations,omitempty"`
+
+	// CoLocateWithManager makes the scheduler prefer the nodes that run the Manager for the Dex pod, which shortens the
+	// path of the OIDC callbacks between them. The preference is soft, so Dex is still scheduled when it cannot be met.
+	// +optional
+	CoLocateWithManager bool `json:"coLocateWithManager,omitempty"`
+
+	// AvoidSpotNodes makes the scheduler prefer the nodes that are not spot or preemptible instances, which the cloud
+	// provider may reclaim at any time and interrupt the logins in progress. The preference is soft, so Dex is still
+	// scheduled on a spot node when no other node fits.
+	// +optional
+	AvoidSpotNodes *DexSpotNodes `json:"avoidSpotNodes,omitempty"`
+
+	// Affinity is the affinity of the Dex pod. It replaces the affinity that the operator generates for
+	// CoLocateWithManager and AvoidSpotNodes. The nodes that the pod is scheduled on must satisfy both its required node
+	// affinity and the ControlPlaneNodeSelector of the Installation, so the required node affinity may not exclude the
+	// nodes that the ControlPlaneNodeSelector selects.
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// DNSConfig is the DNS configuration of the Dex pod. It is merged with the configuration generated from DNSPolicy.
+	// +optional
+	DNSConfig *corev1.PodDNSConfig `json:"dnsConfig,omitempty"`
+
+	// ClusterRBAC controls whether the operator creates the ClusterRole and ClusterRoleBinding of Dex. Disable it when
+	// the permissions of Dex are granted by an external RBAC manager. A ClusterRole and ClusterRoleBinding that the
+	// operator created before are left in place. Has no effect when StorageClusterRole is set.
+	// Default: Enabled
+	// +optional
+	// +kubebuilder:validation:Enum=Enabled;Disabled
+	ClusterRBAC *DexRBACType `json:"clusterRBAC,omitempty"`
+
+	// CSRClusterRoleBinding controls whether the operator creates the ClusterRoleBinding that allows Dex to request its
+	// certificate when certificate management is enabled. Disable it when the binding is granted by an external RBAC
+	// manager.
+	// Default: Enabled
+	// +optional
+	// +kubebuilder:validation:Enum=Enabled;Disabled
+	CSRClusterRoleBinding *DexRBACType `json:"csrClusterRoleBinding,omitempty"`
+
+	// CSRTimeoutSeconds is how long the init container that requests the certificate of Dex when certificate
+	// management is enabled waits for the certificate to be issued. The init container fails when it times out, and is
+	// restarted, so a signer that is slow to issue certificates needs a longer timeout. The timeout is passed in the
+	// TIMEOUT environment variable of the init container, which only key-cert-provisioner images that read it honor.
+	// Default: the timeout of the key-cert-provisioner image
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	CSRTimeoutSeconds *int32 `json:"csrTimeoutSeconds,omitempty"`
+
+	// TLSTermination selects where the TLS connections to Dex are terminated. With Upstream, for example when a service
+	// mesh terminates TLS, Dex serves plain HTTP on its port and the probes and the in-cluster clients of Dex use HTTP.
+	// Default: Dex
+	// +optional
+	// +kubebuilder:validation:Enum=Dex;Upstream
+	TLSTermination *DexTLSTermination `json:"tlsTermination,omitempty"`
+
+	// PreStopSleepSeconds is how long Dex keeps serving after its pod is asked to stop, so that load balancers stop
+	// sending new connections to the pod before Dex stops accepting them. Set to 0 to stop Dex immediately. Must be less
+	// than TerminationGracePeriodSeconds.
+	// Default: 5
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	PreStopSleepSeconds *int32 `json:"preStopSleepSeconds,omitempty"`
+
+	// TerminationGracePeriodSeconds is how long the Dex pod may take to stop, including the PreStopSleepSeconds.
+	// Default: 30
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
+
+	// ResponseHeaders are added to the HTTP responses of Dex. Dex only supports the Content-Security-Policy,
+	// Strict-Transport-Security, X-Content-Type-Options, X-Frame-Options and X-XSS-Protection headers.
+	// +optional
+	ResponseHeaders map[string]string `json:"responseHeaders,omitempty"`
+
+	// CertificateIPAddresses are added as IP SANs to the certificate that the operator creates or requests for Dex, in
+	// addition to the ClusterIP of the Dex service. Both IPv4 and IPv6 addresses are supported.
+	// +optional
+	CertificateIPAddresses []string `json:"certificateIPAddresses,omitempty"`
+
+	// WellKnownPathPrefix is the path below which the probes query the OIDC discovery endpoint, for when a reverse
+	// proxy serves the .well-known path of Dex elsewhere. It must start with a slash.
+	// Default: the issuer path, for example /dex
+	// +optional
+	WellKnownPathPrefix string `json:"wellKnownPathPrefix,omitempty"`
+
+	// ImageOverrides replace the registry and image path of the Installation for the images of Dex, keyed by the name
+	// of the image: dex, or key-cert-provisioner for the init container that requests the certificate of Dex. An image
+	// that the ImageSet pins by digest keeps its digest and is pulled from the overridden location.
+	// +optional
+	ImageOverrides map[string]DexImageOverride `json:"imageOverrides,omitempty"`
+
+	// ShareProcessNamespace makes the containers of the Dex pod share a process namespace, so that an ephemeral
+	// debug container can inspect the Dex process. Dex then no longer runs as PID 1 of its container.
+	// Default: false
+	// +optional
+	ShareProcessNamespace *bool `json:"shareProcessNamespace,omitempty"`
+
+	// ImageDigests controls whether the images of Dex must be pinned by the digests of an ImageSet, for example in
+	// air-gapped environments whose registries only serve images by digest. With Required, Dex is not installed while
+	// there is no ImageSet for the release of the operator or the ImageSet has no digest for an image of Dex.
+	// Default: Optional
+	// +optional
+	// +kubebuilder:validation:Enum=Optional;Required
+	ImageDigests *DexImageDigests `json:"imageDigests,omitempty"`
+
+	// DisableServiceMeshInjection annotates the Dex pod so that Istio and Linkerd do not inject their sidecar into it,
+	// which would otherwise intercept the TLS connections to Dex. PodAnnotations take precedence over these annotations.
+	// +optional
+	DisableServiceMeshInjection bool `json:"disableServiceMeshInjection,omitempty"`
+
+	// SplitConnectorConfig renders the connector of Dex into a ConfigMap of its own, named after the Dex instance with
+	// the suffix -connectors, so that changing the connector leaves the ConfigMap of the base config untouched. Since Dex
+	// reads a single config file, an init container that runs the Dex image merges both into the config that Dex reads.
+	// +optional
+	SplitConnectorConfig bool `json:"splitConnectorConfig,omitempty"`
+
+	// ProgressDeadlineSeconds is how long a rollout of Dex may make no progress before the deployment reports it as
+	// failed. Must exceed the time that the probes of Dex allow for its startup.
+	// Default: 600
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+
+	// MinReadySeconds is how long a new Dex pod must be ready before the deployment counts it as available, for
+	// load balancers whose health checks lag the readiness of the pod. Dex is deployed with the Recreate strategy, which
+	// removes the old pods before the new ones start, so it delays the availability that the rollout reports, but does
+	// not keep the old pods serving meanwhile. Must be less than the ProgressDeadlineSeconds.
+	// Default: 0
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MinReadySeconds *int32 `json:"minReadySeconds,omitempty"`
+
+	// StorageRules selects the rules of the ClusterRole of Dex for the dex.coreos.com resources in which Dex stores its
+	// state. Resources grants the verbs that Dex needs on the resources of its storage. Wildcard grants all verbs on all
+	// dex.coreos.com resources, for versions of Dex that store their state in other resources.
+	// Default: Resources
+	// +optional
+	// +kubebuilder:validation:Enum=Resources;Wildcard
+	StorageRules *DexStorageRules `json:"storageRules,omitempty"`
+
+	// StorageRBACScope selects where Dex is granted access to the dex.coreos.com resources in which it stores its
+	// state. Cluster grants it with the ClusterRole of Dex. Namespace grants it with a Role and RoleBinding in the
+	// namespace of Dex, in which the kubernetes storage keeps its state, and leaves only the permission to create the
+	// CustomResourceDefinitions of the storage in the ClusterRole. No ClusterRole is needed then when the operator
+	// applies the CustomResourceDefinitions. Has no effect when StorageClusterRole is set.
+	// Default: Cluster
+	// +optional
+	// +kubebuilder:validation:Enum=Cluster;Namespace
+	StorageRBACScope *DexStorageRBACScope `json:"storageRBACScope,omitempty"`
+
+	// StorageCRDs selects who creates the CustomResourceDefinitions of the kubernetes storage of Dex. With Dex, Dex
+	// creates them when it starts and its ClusterRole allows it to create CustomResourceDefinitions. With Operator, the
+	// operator applies the CustomResourceDefinitions for the version of Dex that it installs and Dex is not allowed to
+	// create them. The CustomResourceDefinitions that the operator applies are kept when the Authentication is deleted,
+	// so that the state of Dex is not lost, unless DeleteStorageCRDs is set.
+	// Default: Dex
+	// +optional
+	// +kubebuilder:validation:Enum=Dex;Operator
+	StorageCRDs *DexStorageCRDs `json:"storageCRDs,omitempty"`
+
+	// DeleteStorageCRDs deletes the CustomResourceDefinitions that the operator applies for the storage of Dex, and the
+	// state that Dex stored in them, when the Authentication is deleted. It has no effect unless StorageCRDs is Operator.
+	// +optional
+	DeleteStorageCRDs bool `json:"deleteStorageCRDs,omitempty"`
+
+	// PodSecurityStandard selects the Pod Security Standard that the security contexts of the Dex pod and its
+	// containers satisfy. Restricted runs Dex as a non-root user and group with the seccomp profile of the container
+	// runtime and without capabilities, so that Dex is admitted to namespaces that enforce the restricted standard.
+	// InitContainerSecurityContext still replaces the security context of the init containers.
+	// Default: Baseline
+	// +optional
+	// +kubebuilder:validation:Enum=Baseline;Restricted
+	PodSecurityStandard *DexPodSecurityStandard `json:"podSecurityStandard,omitempty"`
+
+	// SessionAffinity is the session affinity of the Dex service. With ClientIP, the connections of a client are sent
+	// to the same Dex replica, which keeps an authentication flow on one replica when the storage of Dex is not
+	// immediately consistent.
+	// Default: None
+	// +optional
+	// +kubebuilder:validation:Enum=None;ClientIP
+	SessionAffinity *corev1.ServiceAffinity `json:"sessionAffinity,omitempty"`
+
+	// SessionAffinityTimeoutSeconds is how long the connections of a client stick to a Dex replica. It can only be set
+	// when the SessionAffinity is ClientIP.
+	// Default: 10800
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=86400
+	SessionAffinityTimeoutSeconds *int32 `json:"sessionAffinityTimeoutSeconds,omitempty"`
+
+	// PublishNotReadyAddresses publishes the addresses of the Dex pods in the endpoints of the Dex service before they
+	// are ready, for the service meshes and clients that discover the Dex replicas through DNS or the endpoints of the
+	// service themselves. The connections to the service may then reach a Dex replica that is not ready.
+	// Default: false
+	// +optional
+	PublishNotReadyAddresses bool `json:"publishNotReadyAddresses,omitempty"`
+
+	// RunAs overrides the user and groups that the Dex pod and its containers run as, for example to stay within the
+	// range of IDs that a pod security policy allows in the Dex namespace. InitContainerSecurityContext still replaces
+	// the security context of the init containers.
+	// +optional
+	RunAs *DexRunAs `json:"runAs,omitempty"`
+
+	// ExternalHost is the host name of a Dex that runs outside of this cluster, like a Dex that serves several clusters.
+	// When set, the operator does not deploy Dex, but points the Dex service to this host with an ExternalName service,
+	// so that the manager reaches the external Dex at the usual service name. The Dex TLS secret then holds the
+	// certificate of the external Dex, which the manager trusts.
+	// +optional
+	ExternalHost string `json:"externalHost,omitempty"`
+
+	// ReadinessGates are extra conditions that must be true before the Dex pod is ready, like the condition that a
+	// load balancer controller sets once the pod is healthy in the target group of a cloud load balancer. The condition
+	// types must be qualified names.
+	// +optional
+	ReadinessGates []corev1.PodReadinessGate `json:"readinessGates,omitempty"`
+
+	// MetricsService enables the telemetry endpoint of Dex on port 5558 and renders a separate metrics service for it,
+	// so that the metrics of Dex are scraped without going through the TLS of the Dex service.
+	// +optional
+	MetricsService bool `json:"metricsService,omitempty"`
+
+	// IdentityProviderSecretsStore mounts the credentials of the identity provider from a volume of the secrets-store
+	// CSI driver, instead of copying the secret of the identity provider from the operator namespace. The volume must
+	// have a file for every field of that secret, named after the field, like clientID and clientSecret for OIDC.
+	// Dex does not start while a file is missing, which is reported as a degraded condition. The service account of
+	// the Google connector is not supported with a secrets store.
+	// +optional
+	IdentityProviderSecretsStore *DexSecretsStore `json:"identityProviderSecretsStore,omitempty"`
+
+	// CopySecretMetadata keeps the labels and annotations of the secrets that the operator copies from the operator
+	// namespace into the namespace of Dex and of the components that use it, so that the tools that select the secrets
+	// by their labels or annotations find the copies too. The type of the secrets is always kept.
+	// +optional
+	CopySecretMetadata bool `json:"copySecretMetadata,omitempty"`
+
+	// IdentityProviderCAConfigMaps are the names of ConfigMaps in the operator namespace with CA certificates that Dex
+	// trusts for the identity provider, like the CA of an intermediate or of a proxy in front of the identity provider.
+	// Their certificates are merged with the rootCA of the identity provider secret into a CA bundle, the
+	// <dex name>-idp-ca-bundle secret, which Dex mounts as the root CA of the identity provider. The bundle is updated,
+	// and Dex restarted, when a certificate in one of the ConfigMaps changes. With an IdentityProviderSecretsStore, the
+	// bundle replaces the rootCA of the secrets store.
+	// +optional
+	IdentityProviderCAConfigMaps []string `json:"identityProviderCAConfigMaps,omitempty"`
+
+	// ServiceServingCertificate lets the service CA of OpenShift issue the certificate of Dex through the serving-cert
+	// annotation of the Dex service, instead of the operator or the certificate management of the Installation. The
+	// CA is injected into the <dex name>-service-ca ConfigMap in the namespace of Dex, and the components that connect
+	// to Dex trust it. It is ignored outside of OpenShift.
+	// +optional
+	ServiceServingCertificate bool `json:"serviceServingCertificate,omitempty"`
+
+	// PrometheusRule renders a PrometheusRule with alerts for the availability of Dex, which the Prometheus operator
+	// in the cluster loads, and a ServiceMonitor that makes the Prometheus scrape the metrics service of Dex, which the
+	// alerts are based on. It requires MetricsService, and is removed when it is unset.
+	// +optional
+	PrometheusRule *DexPrometheusRule `json:"prometheusRule,omitempty"`
+}
+
+// DexImageDigests controls whether the images of Dex must be pinned by digest.
+// One of: Optional, Required
+type DexImageDigests string
+
+const (
+	// The images are pinned by digest when there is an ImageSet, and referenced by tag otherwise.
+	DexImageDigestsOptional DexImageDigests = "Optional"
+	// The images must be pinned by the digests of an ImageSet.
+	DexImageDigestsRequired DexImageDigests = "Required"
+)
+
+// DexStorageRules selects the rules that grant Dex access to its storage.
+// One of: Resources, Wildcard
+type DexStorageRules string
+
+const (
+	// The rules grant the verbs that Dex needs on the resources of its storage.
+	DexStorageRulesResources DexStorageRules = "Resources"
+	// The rules grant all verbs on all dex.coreos.com resources.
+	DexStorageRulesWildcard DexStorageRules = "Wildcard"
+)
+
+// DexStorageRBACScope selects where Dex is granted access to its storage.
+// One of: Cluster, Namespace
+type DexStorageRBACScope string
+
+const (
+	// Dex is granted access to its storage in all namespaces by its ClusterRole.
+	DexStorageRBACScopeCluster DexStorageRBACScope = "Cluster"
+	// Dex is granted access to its storage in its own namespace by a Role.
+	DexStorageRBACScopeNamespace DexStorageRBACScope = "Namespace"
+)
+
+// DexStorageCRDs selects who creates the CustomResourceDefinitions of the storage of Dex.
+// One of: Dex, Operator
+type DexStorageCRDs string
+
+const (
+	// Dex creates the CustomResourceDefinitions of its storage.
+	DexStorageCRDsDex DexStorageCRDs = "Dex"
+	// The operator applies the CustomResourceDefinitions of the storage of Dex.
+	DexStorageCRDsOperator DexStorageCRDs = "Operator"
+)
+
+// DexPodSecurityStandard is a Pod Security Standard that the security contexts of Dex satisfy.
+// One of: Baseline, Restricted
+type DexPodSecurityStandard string
+
+const (
+	DexPodSecurityStandardBaseline   DexPodSecurityStandard = "Baseline"
+	DexPodSecurityStandardRestricted DexPodSecurityStandard = "Restricted"
+)
+
+// DexImageOverride is the location from which an image of Dex is pulled. Fields that are not set are taken from the
+// Installation.
+type DexImageOverride struct {
+	// Registry is the Docker registry from which the image is pulled, for example quay.io/.
+	// +optional
+	Registry string `json:"registry,omitempty"`
+
+	// ImagePath is the path part of the image, which replaces the default path of the image.
+	// +optional
+	ImagePath string `json:"imagePath,omitempty"`
+}
+
+// DexRunAs is the user and groups that the Dex pod and its containers run as.
+type DexRunAs struct {
+	// RunAsUser is the user that the containers of Dex run as.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	RunAsUser *int64 `json:"runAsUser,omitempty"`
+
+	// RunAsGroup is the group that the containers of Dex run as.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	RunAsGroup *int64 `json:"runAsGroup,omitempty"`
+
+	// FSGroup is the group that owns the volumes of the Dex pod.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	FSGroup *int64 `json:"fsGroup,omitempty"`
+
+	// PlatformAssigned leaves the user and groups unset, so that the platform assigns them, like the security context
+	// constraints of OpenShift assign a user from the range of the namespace. The other fields may then not be set.
+	// +optional
+	PlatformAssigned bool `json:"platformAssigned,omitempty"`
+}
+
+// DexSecretsStore is a volume of the secrets-store CSI driver from which Dex reads credentials.
+type DexSecretsStore struct {
+	// SecretProviderClass is the name of the SecretProviderClass in the namespace of Dex that provides the files of
+	// the volume.
+	// +kubebuilder:validation:MinLength=1
+	SecretProviderClass string `json:"secretProviderClass"`
+
+	// NodePublishSecretName is the name of a secret in the namespace of Dex with the credentials that the CSI driver
+	// uses to access the secrets store, if the provider requires them.
+	// +optional
+	NodePublishSecretName string `json:"nodePublishSecretName,omitempty"`
+}
+
+// DexSpotNodes is the node label of spot or preemptible instances, like cloud.google.com/gke-spot: "true" or
+// eks.amazonaws.com/capacityType: SPOT.
+type DexSpotNodes struct {
+	// Key is the key of the node label.
+	// +kubebuilder:validation:MinLength=1
+	Key string `json:"key"`
+
+	// Value is the value of the node label on spot nodes. Without a value, the nodes that have the label are avoided
+	// whatever its value.
+	// +optional
+	Value string `json:"value,omitempty"`
+
+	// Weight is the weight of the preference against the other scheduling preferences of the Dex pod.
+	// Default: 100
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	Weight *int32 `json:"weight,omitempty"`
+}
+
+// DexPrometheusRule configures the PrometheusRule with the alerts for Dex. It alerts when no Dex pod is available, when
+// many logins fail, when the identity provider fails the callbacks of Dex, and when the certificate of Dex expires soon.
+type DexPrometheusRule struct {
+	// Namespace is the namespace of the PrometheusRule and the ServiceMonitor.
+	// Default: tigera-prometheus
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Labels replace the labels with which the rule selector and the service monitor selector of the Prometheus select
+	// the PrometheusRule and the ServiceMonitor.
+	// Default: prometheus: calico-node-prometheus and role: tigera-prometheus-rules for the PrometheusRule, and
+	// team: network-operators for the ServiceMonitor
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// CertificateExpiryDays is the number of days before the certificate of Dex expires from which on its expiry is
+	// alerted. Only the certificate that the operator creates or is given is known, not those that are requested
+	// through certificate management or issued by OpenShift.
+	// Default: 30
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	CertificateExpiryDays *int32 `json:"certificateExpiryDays,omitempty"`
+}
+
+// DexTLSTermination is where the TLS connections to Dex are terminated.
+// One of: Dex, Upstream
+type DexTLSTermination string
+
+const (
+	// Dex serves HTTPS with its own certificate.
+	DexTLSTerminationDex DexTLSTermination = "Dex"
+	// TLS is terminated before Dex, which serves HTTP.
+	DexTLSTerminationUpstream DexTLSTermination = "Upstream"
+)
+
+// DexRBACType controls whether the operator creates RBAC objects for Dex.
+// One of: Enabled, Disabled
+type DexRBACType string
+
+const (
+	DexRBACEnabled  DexRBACType = "Enabled"
+	DexRBACDisabled DexRBACType = "Disabled"
+)
+
+// DexProbeEndpoint is an endpoint of Dex that a probe can query.
+// One of: Discovery, Healthz.
+type DexProbeEndpoint string
+
+const (
+	// The OIDC discovery endpoint.
+	DexProbeEndpointDiscovery DexProbeEndpoint = "Discovery"
+	// The healthz endpoint.
+	DexProbeEndpointHealthz DexProbeEndpoint = "Healthz"
+)
+
+// ManagerClient is the configuration of the static client that Dex registers for the Manager.
+type ManagerClient struct {
+	// GrantTypes is the list of OAuth2 grant types that the client is allowed to use.
+	// Default: [AuthorizationCode, RefreshToken]
+	// +optional
+	GrantTypes []GrantType `json:"grantTypes,omitempty"`
+
+	// Name is the human readable name of the client that Dex shows to users, for example on the approval screen.
+	// Default: Calico Enterprise Manager
+	// +optional
+	Name string `json:"name,omitempty"`
+}
+
+// StaticClient is the configuration of an additional OAuth2 client that Dex registers.
+type StaticClient struct {
+	// ID is the client ID that the client uses to identify itself to Dex.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	ID string `json:"id"`
+
+	// Name is the human readable name of the client that Dex shows to users.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// RedirectURIs is the list of URIs that Dex may redirect to after a user is authenticated.
+	// +optional
+	RedirectURIs []string `json:"redirectURIs,omitempty"`
+
+	// Public marks a client that cannot keep a secret, such as a CLI or a single page application. A public client
+	// uses PKCE instead of a client secret and must not specify a SecretName.
+	// +optional
+	Public bool `json:"public,omitempty"`
+
+	// SecretName is the name of a secret in the tigera-operator namespace that contains the clientSecret of the client.
+	// Required for clients that are not public.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// GenerateSecret makes the operator generate a random clientSecret for the client when the secret named by
+	// SecretName does not exist. The generated secret is created in the tigera-operator namespace, where the
+	// client can read it, and in the namespace of Dex. A secret that exists is never regenerated.
+	// +optional
+	GenerateSecret bool `json:"generateSecret,omitempty"`
+
+	// TrustedPeers is the list of IDs of the clients that may request tokens on behalf of this client, for token
+	// exchange between clients. Each ID must be the ID of another static client or tigera-manager.
+	// +optional
+	TrustedPeers []string `json:"trustedPeers,omitempty"`
+}
+
+// GrantType is an OAuth2 grant type that a client is allowed to use when requesting tokens from Dex.
+// One of: AuthorizationCode, RefreshToken, DeviceCode.
+// +kubebuilder:validation:Enum=AuthorizationCode;RefreshToken;DeviceCode
+type GrantType string
+
+const (
+	// The client may exchange an authorization code for tokens.
+	GrantTypeAuthorizationCode GrantType = "AuthorizationCode"
+	// The client may use a refresh token to obtain new tokens.
+	GrantTypeRefreshToken GrantType = "RefreshToken"
+	// The client may use the device authorization flow.
+	GrantTypeDeviceCode GrantType = "DeviceCode"
+)
+
+// Value returns the grant type as it is defined by the OAuth2 specifications, or an empty string if the grant type is
+// unknown.
+func (g GrantType) Value() string {
+	switch g {
+	case GrantTypeAuthorizationCode:
+		return "authorization_code"
+	case GrantTypeRefreshToken:
+		return "refresh_token"
+	case GrantTypeDeviceCode:
+		return "urn:ietf:params:oauth:grant-type:device_code"
+	default:
+		return ""
+	}
 }
 
 // AuthenticationStatus defines the observed state of Authentication
 type AuthenticationStatus struct {
 	// State provides user-readable status.
 	State string `json:"state,omitempty"`
+
+	// DexNamespace is the namespace in which Dex was last installed.
+	// +optional
+	DexNamespace string `json:"dexNamespace,omitempty"`
+
+	// IssuerURL is the issuer of the tokens of the Dex that was last rendered.
+	// +optional
+	IssuerURL string `json:"issuerURL,omitempty"`
+
+	// ConnectorType is the type of the connector of the Dex that was last rendered. May be oidc, google, openshift or
+	// ldap.
+	// +optional
+	ConnectorType string `json:"connectorType,omitempty"`
+
+	// Conditions represents the latest observed set of conditions of Dex. Dex may be one or more of Available,
+	// Progressing, or Degraded.
+	// +optional
+	Conditions []TigeraStatusCondition `json:"conditions,omitempty"`
 }
 
 // AuthenticationOIDC is the configuration needed to setup OIDC.
@@ -72,7 +725,14 @@ type AuthenticationOIDC struct {
 	// +required
 	IssuerURL string `json:"issuerURL"`
 
-	// UsernameClaim specifies which claim to use from the OIDC provider as the username.
+	// IssuerAlias is the issuer that the OIDC provider reports in its discovery document and tokens, if it differs
+	// from IssuerURL. Requires Authentication.Spec.AllowIssuerAliasing.
+	// +optional
+	IssuerAlias string `json:"issuerAlias,omitempty"`
+
+	// UsernameClaim specifies which claim to use from the OIDC provider as the username. It has to be one of the
+	// claims that Dex includes in the tokens it issues, since the username is read from those tokens, which are email,
+	// name, preferred_username and sub. Default: email
 	// +required
 	UsernameClaim string `json:"usernameClaim"`
 
@@ -81,6 +741,13 @@ type AuthenticationOIDC struct {
 	// +optional
 	RequestedScopes []string `json:"requestedScopes,omitempty"`
 
+	// ConnectorScopes is a list of scopes that Dex requests from the OIDC provider, for example to obtain groups or
+	// custom claims. Unlike RequestedScopes, these are not requested by the Manager. Must include "openid". If not
+	// provided, RequestedScopes is used when it is set, and otherwise the following scopes are requested:
+	// ["openid", "email", "profile"].
+	// +optional
+	ConnectorScopes []string `json:"connectorScopes,omitempty"`
+
 	// Deprecated. Please use Authentication.Spec.UsernamePrefix instead.
 	// +optional
 	UsernamePrefix string `json:"usernamePrefix,omitempty"`
@@ -107,6 +774,24 @@ type AuthenticationOIDC struct {
 	// Default: "Consent"
 	// +optional
 	PromptTypes []PromptType `json:"promptTypes,omitempty"`
+
+	// GetUserInfo makes Dex query the userinfo endpoint of the identity provider for additional claims, for providers
+	// that do not include all claims in the ID token.
+	// Default: false
+	// +optional
+	GetUserInfo *bool `json:"getUserInfo,omitempty"`
+
+	// BasicAuthUnsupported makes Dex send the client credentials in the body of token requests instead of using HTTP
+	// Basic authentication, for providers that do not support the latter at their token endpoint.
+	// Default: false
+	// +optional
+	BasicAuthUnsupported *bool `json:"basicAuthUnsupported,omitempty"`
+
+	// OverrideClaimMapping makes the claims that are mapped with GroupsClaim take precedence over the standard claims
+	// of the same name when the identity provider sends both. Requires a GroupsClaim other than "groups".
+	// Default: false
+	// +optional
+	OverrideClaimMapping *bool `json:"overrideClaimMapping,omitempty"`
 }
 
 // PromptType is a value that specifies whether the identity provider prompts the end user for re-authentication and
@@ -126,6 +811,23 @@ const (
 	PromptTypeSelectAccount PromptType = "SelectAccount"
 )
 
+// Value returns the prompt type as it is defined by the OpenID Connect specifications, or an empty string if the prompt
+// type is unknown.
+func (p PromptType) Value() string {
+	switch p {
+	case PromptTypeNone:
+		return "none"
+	case PromptTypeLogin:
+		return "login"
+	case PromptTypeConsent:
+		return "consent"
+	case PromptTypeSelectAccount:
+		return "select_account"
+	default:
+		return ""
+	}
+}
+
 // AuthenticationOpenshift is the configuration needed to setup Openshift.
 type AuthenticationOpenshift struct {
 	// IssuerURL is the URL to the Openshift OAuth provider. Ex.: https://api.my-ocp-domain.com:6443
@@ -206,6 +908,10 @@ type UserMatch struct {
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:path=authentications,scope=Cluster
+// +kubebuilder:printcolumn:name="Available",type="string",JSONPath=".status.conditions[?(@.type=='Available')].status",description="Whether Dex is running and stable."
+// +kubebuilder:printcolumn:name="Progressing",type="string",JSONPath=".status.conditions[?(@.type=='Progressing')].status",description="Whether Dex is processing changes."
+// +kubebuilder:printcolumn:name="Degraded",type="string",JSONPath=".status.conditions[?(@.type=='Degraded')].status",description="Whether Dex is degraded."
+// +kubebuilder:printcolumn:name="Connector",type="string",JSONPath=".status.connectorType",description="The connector of Dex to the identity provider."
 
 // Authentication is the Schema for the authentications API
 type Authentication struct {
@@ -57,6 +57,24 @@ type InstallationSpec struct {
 	// +optional
 	ImagePath string `json:"imagePath,omitempty"`
 
+	// ImagePathFormat selects how the path of an image is composed. With Flatten, the path of the image is flattened
+	// into a single level below the ImagePath, for registries that mirror all images at one level: the segments of
+	// the image are joined by dashes, so tigera/dex is pulled as `<registry>/<imagePath>/tigera-dex:<image-tag>`.
+	// The format is applied to the images of all components.
+	// Default: Nested
+	// +optional
+	// +kubebuilder:validation:Enum=Nested;Flatten
+	ImagePathFormat ImagePathFormat `json:"imagePathFormat,omitempty"`
+
+	// ImageVariant selects the builds of the images that are pulled. With FIPS, the FIPS-validated UBI-based builds
+	// are pulled, whose tags have a -fips suffix. The variant is applied to the images of all components. Only the
+	// images of Dex and of the init container that requests certificates are published in the FIPS variant, and a
+	// component whose image is not published in the variant is degraded with an error that names the image.
+	// Default: Standard
+	// +optional
+	// +kubebuilder:validation:Enum=Standard;FIPS
+	ImageVariant ImageVariant `json:"imageVariant,omitempty"`
+
 	// ImagePullSecrets is an array of references to container registry pull secrets to use. These are
 	// applied to all images to be pulled.
 	// +optional
@@ -118,6 +136,11 @@ type InstallationSpec struct {
 	// +optional
 	ComponentResources []ComponentResource `json:"componentResources,omitempty"`
 
+	// ComponentMetadata are labels and annotations that the operator adds to every object that it renders, and to the
+	// pod templates of its workloads, for example to classify the data of the cluster for backup policies.
+	// +optional
+	ComponentMetadata *ComponentMetadata `json:"componentMetadata,omitempty"`
+
 	// CertificateManagement configures pods to submit a CertificateSigningRequest to the certificates.k8s.io/v1beta1 API in order
 	// to obtain TLS certificates. This feature requires that you bring your own CSR signing and approval process, otherwise
 	// pods will be stuck during initialization.
@@ -153,6 +176,19 @@ const (
 	ComponentNameKubeControllers ComponentName = "KubeControllers"
 )
 
+// ComponentMetadata are the labels and annotations that the operator adds to the objects that it renders. They do
+// not replace the labels and annotations that the operator sets itself.
+type ComponentMetadata struct {
+	// Labels are added to the labels of the objects and of their pod templates.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are added to the annotations of the objects and of their pod templates. The annotations that are
+	// removed from here are removed from the objects.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
 // The ComponentResource struct associates a ResourceRequirements with a component by name
 type ComponentResource struct {
 	// ComponentName is an enum which identifies the component
@@ -185,6 +221,29 @@ var (
 	TigeraSecureEnterprise ProductVariant = "TigeraSecureEnterprise"
 )
 
+// ImagePathFormat selects how the path of an image is composed.
+//
+// One of: Nested, Flatten
+type ImagePathFormat string
+
+const (
+	// The ImagePath replaces the first segment of the path of the image.
+	ImagePathFormatNested ImagePathFormat = "Nested"
+	// The segments of the path of the image are joined into a single level below the ImagePath.
+	ImagePathFormatFlatten ImagePathFormat = "Flatten"
+)
+
+// ImageVariant selects the builds of the images that are pulled.
+//
+// One of: Standard, FIPS
+type ImageVariant string
+
+const (
+	ImageVariantStandard ImageVariant = "Standard"
+	// The FIPS-validated UBI-based builds.
+	ImageVariantFIPS ImageVariant = "FIPS"
+)
+
 // ContainerIPForwardingType specifies whether the CNI config for container ip forwarding is enabled.
 type ContainerIPForwardingType string
 
@@ -284,7 +284,7 @@ func (in *Authentication) DeepCopyInto(out *Authentication) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Authentication.
@@ -375,6 +375,11 @@ func (in *AuthenticationOIDC) DeepCopyInto(out *AuthenticationOIDC) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ConnectorScopes != nil {
+		in, out := &in.ConnectorScopes, &out.ConnectorScopes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.EmailVerification != nil {
 		in, out := &in.EmailVerification, &out.EmailVerification
 		*out = new(EmailVerificationType)
@@ -385,6 +390,21 @@ func (in *AuthenticationOIDC) DeepCopyInto(out *AuthenticationOIDC) {
 		*out = make([]PromptType, len(*in))
 		copy(*out, *in)
 	}
+	if in.GetUserInfo != nil {
+		in, out := &in.GetUserInfo, &out.GetUserInfo
+		*out = new(bool)
+		**out = **in
+	}
+	if in.BasicAuthUnsupported != nil {
+		in, out := &in.BasicAuthUnsupported, &out.BasicAuthUnsupported
+		*out = new(bool)
+		**out = **in
+	}
+	if in.OverrideClaimMapping != nil {
+		in, out := &in.OverrideClaimMapping, &out.OverrideClaimMapping
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuthenticationOIDC.
@@ -430,6 +450,33 @@ func (in *AuthenticationSpec) DeepCopyInto(out *AuthenticationSpec) {
 		*out = new(AuthenticationLDAP)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ManagerClient != nil {
+		in, out := &in.ManagerClient, &out.ManagerClient
+		*out = new(ManagerClient)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DexDeployment != nil {
+		in, out := &in.DexDeployment, &out.DexDeployment
+		*out = new(DexDeployment)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StaticClients != nil {
+		in, out := &in.StaticClients, &out.StaticClients
+		*out = make([]StaticClient, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.KubectlConfig != nil {
+		in, out := &in.KubectlConfig, &out.KubectlConfig
+		*out = new(KubectlConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DiscoveryNamespaces != nil {
+		in, out := &in.DiscoveryNamespaces, &out.DiscoveryNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuthenticationSpec.
@@ -445,6 +492,13 @@ func (in *AuthenticationSpec) DeepCopy() *AuthenticationSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AuthenticationStatus) DeepCopyInto(out *AuthenticationStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]TigeraStatusCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuthenticationStatus.
@@ -648,6 +702,35 @@ func (in *ComplianceStatus) DeepCopy() *ComplianceStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentMetadata) DeepCopyInto(out *ComponentMetadata) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentMetadata.
+func (in *ComponentMetadata) DeepCopy() *ComponentMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ComponentResource) DeepCopyInto(out *ComponentResource) {
 	*out = *in
@@ -668,6 +751,320 @@ func (in *ComponentResource) DeepCopy() *ComponentResource {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexDeployment) DeepCopyInto(out *DexDeployment) {
+	*out = *in
+	if in.LivenessProbe != nil {
+		in, out := &in.LivenessProbe, &out.LivenessProbe
+		*out = new(DexProbeEndpoint)
+		**out = **in
+	}
+	if in.TerminationMessagePolicy != nil {
+		in, out := &in.TerminationMessagePolicy, &out.TerminationMessagePolicy
+		*out = new(corev1.TerminationMessagePolicy)
+		**out = **in
+	}
+	if in.PodLabels != nil {
+		in, out := &in.PodLabels, &out.PodLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PodAnnotations != nil {
+		in, out := &in.PodAnnotations, &out.PodAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ServiceAccountLabels != nil {
+		in, out := &in.ServiceAccountLabels, &out.ServiceAccountLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ServiceAccountAnnotations != nil {
+		in, out := &in.ServiceAccountAnnotations, &out.ServiceAccountAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.InitContainerResources != nil {
+		in, out := &in.InitContainerResources, &out.InitContainerResources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.InitContainerSecurityContext != nil {
+		in, out := &in.InitContainerSecurityContext, &out.InitContainerSecurityContext
+		*out = new(corev1.SecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DNSPolicy != nil {
+		in, out := &in.DNSPolicy, &out.DNSPolicy
+		*out = new(corev1.DNSPolicy)
+		**out = **in
+	}
+	if in.AvoidSpotNodes != nil {
+		in, out := &in.AvoidSpotNodes, &out.AvoidSpotNodes
+		*out = new(DexSpotNodes)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(corev1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DNSConfig != nil {
+		in, out := &in.DNSConfig, &out.DNSConfig
+		*out = new(corev1.PodDNSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClusterRBAC != nil {
+		in, out := &in.ClusterRBAC, &out.ClusterRBAC
+		*out = new(DexRBACType)
+		**out = **in
+	}
+	if in.CSRClusterRoleBinding != nil {
+		in, out := &in.CSRClusterRoleBinding, &out.CSRClusterRoleBinding
+		*out = new(DexRBACType)
+		**out = **in
+	}
+	if in.CSRTimeoutSeconds != nil {
+		in, out := &in.CSRTimeoutSeconds, &out.CSRTimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TLSTermination != nil {
+		in, out := &in.TLSTermination, &out.TLSTermination
+		*out = new(DexTLSTermination)
+		**out = **in
+	}
+	if in.PreStopSleepSeconds != nil {
+		in, out := &in.PreStopSleepSeconds, &out.PreStopSleepSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TerminationGracePeriodSeconds != nil {
+		in, out := &in.TerminationGracePeriodSeconds, &out.TerminationGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.ResponseHeaders != nil {
+		in, out := &in.ResponseHeaders, &out.ResponseHeaders
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.CertificateIPAddresses != nil {
+		in, out := &in.CertificateIPAddresses, &out.CertificateIPAddresses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ImageOverrides != nil {
+		in, out := &in.ImageOverrides, &out.ImageOverrides
+		*out = make(map[string]DexImageOverride, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ShareProcessNamespace != nil {
+		in, out := &in.ShareProcessNamespace, &out.ShareProcessNamespace
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ImageDigests != nil {
+		in, out := &in.ImageDigests, &out.ImageDigests
+		*out = new(DexImageDigests)
+		**out = **in
+	}
+	if in.ProgressDeadlineSeconds != nil {
+		in, out := &in.ProgressDeadlineSeconds, &out.ProgressDeadlineSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MinReadySeconds != nil {
+		in, out := &in.MinReadySeconds, &out.MinReadySeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.StorageRules != nil {
+		in, out := &in.StorageRules, &out.StorageRules
+		*out = new(DexStorageRules)
+		**out = **in
+	}
+	if in.StorageRBACScope != nil {
+		in, out := &in.StorageRBACScope, &out.StorageRBACScope
+		*out = new(DexStorageRBACScope)
+		**out = **in
+	}
+	if in.StorageCRDs != nil {
+		in, out := &in.StorageCRDs, &out.StorageCRDs
+		*out = new(DexStorageCRDs)
+		**out = **in
+	}
+	if in.PodSecurityStandard != nil {
+		in, out := &in.PodSecurityStandard, &out.PodSecurityStandard
+		*out = new(DexPodSecurityStandard)
+		**out = **in
+	}
+	if in.SessionAffinity != nil {
+		in, out := &in.SessionAffinity, &out.SessionAffinity
+		*out = new(corev1.ServiceAffinity)
+		**out = **in
+	}
+	if in.SessionAffinityTimeoutSeconds != nil {
+		in, out := &in.SessionAffinityTimeoutSeconds, &out.SessionAffinityTimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RunAs != nil {
+		in, out := &in.RunAs, &out.RunAs
+		*out = new(DexRunAs)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReadinessGates != nil {
+		in, out := &in.ReadinessGates, &out.ReadinessGates
+		*out = make([]corev1.PodReadinessGate, len(*in))
+		copy(*out, *in)
+	}
+	if in.IdentityProviderSecretsStore != nil {
+		in, out := &in.IdentityProviderSecretsStore, &out.IdentityProviderSecretsStore
+		*out = new(DexSecretsStore)
+		**out = **in
+	}
+	if in.IdentityProviderCAConfigMaps != nil {
+		in, out := &in.IdentityProviderCAConfigMaps, &out.IdentityProviderCAConfigMaps
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PrometheusRule != nil {
+		in, out := &in.PrometheusRule, &out.PrometheusRule
+		*out = new(DexPrometheusRule)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexDeployment.
+func (in *DexDeployment) DeepCopy() *DexDeployment {
+	if in == nil {
+		return nil
+	}
+	out := new(DexDeployment)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexImageOverride) DeepCopyInto(out *DexImageOverride) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexImageOverride.
+func (in *DexImageOverride) DeepCopy() *DexImageOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(DexImageOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexPrometheusRule) DeepCopyInto(out *DexPrometheusRule) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.CertificateExpiryDays != nil {
+		in, out := &in.CertificateExpiryDays, &out.CertificateExpiryDays
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexPrometheusRule.
+func (in *DexPrometheusRule) DeepCopy() *DexPrometheusRule {
+	if in == nil {
+		return nil
+	}
+	out := new(DexPrometheusRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexRunAs) DeepCopyInto(out *DexRunAs) {
+	*out = *in
+	if in.RunAsUser != nil {
+		in, out := &in.RunAsUser, &out.RunAsUser
+		*out = new(int64)
+		**out = **in
+	}
+	if in.RunAsGroup != nil {
+		in, out := &in.RunAsGroup, &out.RunAsGroup
+		*out = new(int64)
+		**out = **in
+	}
+	if in.FSGroup != nil {
+		in, out := &in.FSGroup, &out.FSGroup
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexRunAs.
+func (in *DexRunAs) DeepCopy() *DexRunAs {
+	if in == nil {
+		return nil
+	}
+	out := new(DexRunAs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexSecretsStore) DeepCopyInto(out *DexSecretsStore) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexSecretsStore.
+func (in *DexSecretsStore) DeepCopy() *DexSecretsStore {
+	if in == nil {
+		return nil
+	}
+	out := new(DexSecretsStore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DexSpotNodes) DeepCopyInto(out *DexSpotNodes) {
+	*out = *in
+	if in.Weight != nil {
+		in, out := &in.Weight, &out.Weight
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DexSpotNodes.
+func (in *DexSpotNodes) DeepCopy() *DexSpotNodes {
+	if in == nil {
+		return nil
+	}
+	out := new(DexSpotNodes)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EksCloudwatchLogsSpec) DeepCopyInto(out *EksCloudwatchLogsSpec) {
 	*out = *in
@@ -965,6 +1362,11 @@ func (in *InstallationSpec) DeepCopyInto(out *InstallationSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ComponentMetadata != nil {
+		in, out := &in.ComponentMetadata, &out.ComponentMetadata
+		*out = new(ComponentMetadata)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.CertificateManagement != nil {
 		in, out := &in.CertificateManagement, &out.CertificateManagement
 		*out = new(CertificateManagement)
@@ -1091,6 +1493,26 @@ func (in *IntrusionDetectionStatus) DeepCopy() *IntrusionDetectionStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubectlConfig) DeepCopyInto(out *KubectlConfig) {
+	*out = *in
+	if in.Scopes != nil {
+		in, out := &in.Scopes, &out.Scopes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubectlConfig.
+func (in *KubectlConfig) DeepCopy() *KubectlConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(KubectlConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LogCollector) DeepCopyInto(out *LogCollector) {
 	*out = *in
@@ -1501,6 +1923,26 @@ func (in *Manager) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagerClient) DeepCopyInto(out *ManagerClient) {
+	*out = *in
+	if in.GrantTypes != nil {
+		in, out := &in.GrantTypes, &out.GrantTypes
+		*out = make([]GrantType, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagerClient.
+func (in *ManagerClient) DeepCopy() *ManagerClient {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagerClient)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ManagerList) DeepCopyInto(out *ManagerList) {
 	*out = *in
@@ -1747,6 +2189,31 @@ func (in *SplunkStoreSpec) DeepCopy() *SplunkStoreSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StaticClient) DeepCopyInto(out *StaticClient) {
+	*out = *in
+	if in.RedirectURIs != nil {
+		in, out := &in.RedirectURIs, &out.RedirectURIs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TrustedPeers != nil {
+		in, out := &in.TrustedPeers, &out.TrustedPeers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StaticClient.
+func (in *StaticClient) DeepCopy() *StaticClient {
+	if in == nil {
+		return nil
+	}
+	out := new(StaticClient)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SyslogStoreSpec) DeepCopyInto(out *SyslogStoreSpec) {
 	*out = *in
@@ -33,6 +33,7 @@ require (
 	google.golang.org/protobuf v1.25.0 // indirect
 	gopkg.in/yaml.v2 v2.3.0
 	k8s.io/klog/v2 v2.3.0 // indirect
+	sigs.k8s.io/yaml v1.2.0
 )
 
 replace (
@@ -205,7 +205,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	clusterDomain, err := dns.GetClusterDomain(dns.DefaultResolveConfPath)
+	clusterDomain, err := dns.DefaultClusterDomainDetector.Detect()
 	if err != nil {
 		clusterDomain = dns.DefaultClusterDomain
 		log.Error(err, fmt.Sprintf("Couldn't find the cluster domain from the resolv.conf, defaulting to %s", clusterDomain))
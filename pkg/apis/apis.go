@@ -24,6 +24,7 @@ import (
 	tigera "github.com/tigera/api/pkg/apis/projectcalico/v3"
 	crdv1 "github.com/tigera/operator/pkg/apis/crd.projectcalico.org/v1"
 	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apiextensions "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	aggregator "k8s.io/kube-aggregator/pkg/client/clientset_generated/clientset/scheme"
 )
@@ -41,6 +42,7 @@ func init() {
 	AddToSchemes = append(AddToSchemes, configv1.Install)
 	AddToSchemes = append(AddToSchemes, aggregator.AddToScheme)
 	AddToSchemes = append(AddToSchemes, apiextensions.AddToScheme)
+	AddToSchemes = append(AddToSchemes, apiextensionsv1.AddToScheme)
 	AddToSchemes = append(AddToSchemes, tigera.AddToScheme)
 	AddToSchemes = append(AddToSchemes, ocsv1.AddToScheme)
 	AddToSchemes = append(AddToSchemes, esv1.SchemeBuilder.AddToScheme)
@@ -15,10 +15,15 @@
 package components
 
 import (
+	"errors"
+	"fmt"
+
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 
 	op "github.com/tigera/operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var _ = Describe("test GetReference", func() {
@@ -119,3 +124,157 @@ var _ = Describe("test GetReference", func() {
 		})
 	})
 })
+
+var _ = Describe("test digests", func() {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	DescribeTable("should validate the format of digests", func(digest string, valid bool) {
+		if valid {
+			Expect(ValidateDigest(digest)).To(Succeed())
+		} else {
+			Expect(ValidateDigest(digest)).To(MatchError(ContainSubstring("is not a sha256 digest")))
+		}
+	},
+		Entry("sha256", digest, true),
+		Entry("short", "sha256:0123456789abcdef", false),
+		Entry("uppercase", "sha256:0123456789ABCDEF0123456789abcdef0123456789abcdef0123456789abcdef", false),
+		Entry("no algorithm", "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", false),
+		Entry("other algorithm", "sha512:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", false),
+		Entry("tag", "v1.0.0", false),
+	)
+
+	It("should accept valid digests for the images of the components", func() {
+		is := &op.ImageSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "enterprise-test"},
+			Spec: op.ImageSetSpec{Images: []op.Image{
+				{Image: ComponentDex.Image, Digest: digest},
+				{Image: ComponentCSRInitContainer.Image, Digest: digest},
+				{Image: ComponentCalicoTypha.Image, Digest: "sha256:xxxxxxxxx"},
+			}},
+		}
+		Expect(ValidateImageSetDigests(is, ComponentDex.Image, ComponentCSRInitContainer.Image)).To(Succeed())
+		Expect(ValidateImageSetDigests(nil, ComponentDex.Image)).To(Succeed())
+	})
+
+	It("should report invalid digests and digests for the wrong image", func() {
+		is := &op.ImageSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "enterprise-test"},
+			Spec: op.ImageSetSpec{Images: []op.Image{
+				{Image: ComponentDex.Image, Digest: "sha256:dexhash"},
+				{Image: "key-cert-provisioner", Digest: digest},
+			}},
+		}
+		Expect(ValidateImageSetDigests(is, ComponentDex.Image, ComponentCSRInitContainer.Image)).To(MatchError(
+			`ImageSet enterprise-test: the digest of image tigera/dex is invalid: "sha256:dexhash" is not a sha256 digest of the form sha256:<64 hex characters>; ` +
+				"image key-cert-provisioner has a digest, but the image of the component is tigera/key-cert-provisioner"))
+	})
+})
+
+var _ = Describe("test image errors", func() {
+	It("should join the messages of the errors and unwrap to each of them", func() {
+		cause := fmt.Errorf("ImageSet did not contain image %s", ComponentDex.Image)
+		errs := ImageErrors{
+			{Component: ComponentDex.Image, Reference: "quay.io/tigera/dex", Err: cause},
+			{Component: ComponentCSRInitContainer.Image, Reference: "quay.io/tigera/key-cert-provisioner", Err: fmt.Errorf("invalid")},
+		}
+		err := fmt.Errorf("wrapped: %w", errs)
+		Expect(err).To(MatchError("wrapped: ImageSet did not contain image tigera/dex,invalid"))
+
+		var imageErrs ImageErrors
+		Expect(errors.As(err, &imageErrs)).To(BeTrue())
+		Expect(imageErrs).To(HaveLen(2))
+		Expect(errors.Is(imageErrs[0], cause)).To(BeTrue())
+	})
+
+	It("should return the repository of the image without version", func() {
+		Expect(GetRepository(ComponentDex, "", "")).To(Equal(TigeraRegistry + ComponentDex.Image))
+		Expect(GetRepository(ComponentCSRInitContainer, "", "")).To(Equal(InitRegistry + ComponentCSRInitContainer.Image))
+		Expect(GetRepository(ComponentDex, "quay.io/", "mirror")).To(Equal("quay.io/mirror/dex"))
+	})
+})
+
+var _ = Describe("test flattened image paths", func() {
+	DescribeTable("should flatten the image below the image path", func(image, imagepath, expected string) {
+		Expect(FlattenImagePath(image, imagepath)).To(Equal(expected))
+	},
+		Entry("no image path", "tigera/dex", "", "tigera-dex"),
+		Entry("UseDefault", "tigera/dex", UseDefault, "tigera-dex"),
+		Entry("image path", "tigera/dex", "mirror", "mirror/tigera-dex"),
+		Entry("nested image path", "tigera/key-cert-provisioner", "mirror/tigera/", "mirror/tigera/tigera-key-cert-provisioner"),
+		Entry("nested upstream path", "eck/eck-operator", "mirror", "mirror/eck-eck-operator"),
+		Entry("single segment", "key-cert-provisioner", "mirror", "mirror/key-cert-provisioner"),
+	)
+
+	It("should build flattened references", func() {
+		Expect(GetReference(ComponentDex, "registry.corp/", "mirror", nil, WithFlattenedImagePath())).To(
+			Equal("registry.corp/mirror/tigera-dex:" + ComponentDex.Version))
+		Expect(GetReference(ComponentCSRInitContainer, "registry.corp/", "mirror", nil, WithFlattenedImagePath())).To(
+			Equal("registry.corp/mirror/tigera-key-cert-provisioner:" + ComponentCSRInitContainer.Version))
+
+		is := &op.ImageSet{Spec: op.ImageSetSpec{Images: []op.Image{{Image: ComponentDex.Image, Digest: "sha256:dexhash"}}}}
+		Expect(GetReference(ComponentDex, "registry.corp/", "mirror", is, WithFlattenedImagePath())).To(
+			Equal("registry.corp/mirror/tigera-dex@sha256:dexhash"))
+	})
+
+	It("should flatten the images of all components when the Installation selects it", func() {
+		installation := &op.InstallationSpec{ImagePathFormat: op.ImagePathFormatFlatten}
+		Expect(GetReference(ComponentTigeraNode, "registry.corp/", "mirror", nil, ReferenceOptionsFor(installation)...)).To(
+			Equal("registry.corp/mirror/tigera-cnx-node:" + ComponentTigeraNode.Version))
+		Expect(GetReference(ComponentCSRInitContainer, "registry.corp/", "mirror", nil, ReferenceOptionsFor(installation)...)).To(
+			Equal("registry.corp/mirror/tigera-key-cert-provisioner:" + ComponentCSRInitContainer.Version))
+
+		installation.ImagePathFormat = op.ImagePathFormatNested
+		Expect(GetReference(ComponentTigeraNode, "registry.corp/", "mirror", nil, ReferenceOptionsFor(installation)...)).To(
+			Equal("registry.corp/mirror/cnx-node:" + ComponentTigeraNode.Version))
+	})
+
+	It("should not map different images to the same flattened image", func() {
+		flattened := map[string]string{}
+		for _, c := range append(append([]component{}, CalicoComponents...), EnterpriseComponents...) {
+			f := FlattenImagePath(c.Image, "mirror")
+			if other, ok := flattened[f]; ok {
+				Expect(other).To(Equal(c.Image), "%s and %s are both flattened to %s", other, c.Image, f)
+			}
+			flattened[f] = c.Image
+		}
+		Expect(flattened).To(HaveKeyWithValue("mirror/tigera-cni", "tigera/cni"))
+		Expect(flattened).To(HaveKeyWithValue("mirror/calico-cni", "calico/cni"))
+	})
+})
+
+var _ = Describe("test image variants", func() {
+	It("should select the tag of the variant", func() {
+		Expect(GetReference(ComponentDex, "registry.corp/", "", nil, WithImageVariant(op.ImageVariantFIPS))).To(
+			Equal("registry.corp/tigera/dex:" + ComponentDex.Version + "-fips"))
+		Expect(GetReference(ComponentDex, "registry.corp/", "mirror", nil, WithImageVariant(op.ImageVariantFIPS), WithFlattenedImagePath())).To(
+			Equal("registry.corp/mirror/tigera-dex:" + ComponentDex.Version + "-fips"))
+		Expect(GetReference(ComponentDex, "registry.corp/", "", nil, WithImageVariant(op.ImageVariantStandard))).To(
+			Equal("registry.corp/tigera/dex:" + ComponentDex.Version))
+		Expect(GetReference(ComponentDex, "registry.corp/", "", nil, WithImageVariant(""))).To(
+			Equal("registry.corp/tigera/dex:" + ComponentDex.Version))
+	})
+
+	It("should pin the variant by the digest of the ImageSet", func() {
+		is := &op.ImageSet{Spec: op.ImageSetSpec{Images: []op.Image{{Image: ComponentDex.Image, Digest: "sha256:dexhash"}}}}
+		Expect(GetReference(ComponentDex, "registry.corp/", "", is, WithImageVariant(op.ImageVariantFIPS))).To(
+			Equal("registry.corp/tigera/dex@sha256:dexhash"))
+	})
+
+	It("should select the FIPS variant of the CSR init container", func() {
+		Expect(GetReference(ComponentCSRInitContainer, "registry.corp/", "", nil, WithImageVariant(op.ImageVariantFIPS))).To(
+			Equal("registry.corp/tigera/key-cert-provisioner:" + ComponentCSRInitContainer.Version + "-fips"))
+	})
+
+	It("should report images that are not published in the variant", func() {
+		_, err := GetReference(ComponentTigeraNode, "registry.corp/", "", nil, WithImageVariant(op.ImageVariantFIPS))
+		Expect(err).To(MatchError("image tigera/cnx-node is not published in the FIPS variant"))
+	})
+
+	It("should apply the variant of the Installation", func() {
+		installation := &op.InstallationSpec{ImageVariant: op.ImageVariantFIPS}
+		Expect(GetReference(ComponentDex, "", "", nil, ReferenceOptionsFor(installation)...)).To(HaveSuffix("-fips"))
+		_, err := GetReference(ComponentTigeraNode, "", "", nil, ReferenceOptionsFor(installation)...)
+		Expect(err).To(HaveOccurred())
+		Expect(ReferenceOptionsFor(nil)).To(BeEmpty())
+	})
+})
@@ -15,6 +15,8 @@ package components
 
 import (
 	"fmt"
+	"path"
+	"regexp"
 	"strings"
 
 	operator "github.com/tigera/operator/api/v1"
@@ -28,8 +30,152 @@ type component struct {
 
 const UseDefault = "UseDefault"
 
+var digestRegexp = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// ValidateDigest returns an error if the digest is not a sha256 digest, which is sha256: followed by 64 lowercase
+// hexadecimal characters.
+func ValidateDigest(digest string) error {
+	if !digestRegexp.MatchString(digest) {
+		return fmt.Errorf("%q is not a sha256 digest of the form sha256:<64 hex characters>", digest)
+	}
+	return nil
+}
+
+// ValidateImageSetDigests checks the given component images, such as "tigera/dex", in the ImageSet. Their digests must
+// be valid, and images that have the name of one of them but another path, such as "dex" or "calico/dex" for
+// "tigera/dex", are reported as digests for the wrong image.
+func ValidateImageSetDigests(is *operator.ImageSet, images ...string) error {
+	if is == nil {
+		return nil
+	}
+	var problems []string
+	for _, image := range images {
+		for _, img := range is.Spec.Images {
+			if img.Image == image {
+				if err := ValidateDigest(img.Digest); err != nil {
+					problems = append(problems, fmt.Sprintf("the digest of image %s is invalid: %v", img.Image, err))
+				}
+			} else if path.Base(img.Image) == path.Base(image) {
+				problems = append(problems, fmt.Sprintf("image %s has a digest, but the image of the component is %s", img.Image, image))
+			}
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("ImageSet %s: %s", is.Name, strings.Join(problems, "; "))
+}
+
+// ImageError is returned when the image of a component cannot be resolved.
+type ImageError struct {
+	// Component is the image of the component, such as tigera/dex.
+	Component string
+	// Reference is the requested reference of the image without tag or digest, such as quay.io/tigera/dex.
+	Reference string
+	Err       error
+}
+
+func (e *ImageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ImageError) Unwrap() error {
+	return e.Err
+}
+
+// ImageErrors are the errors of the images of one or more components that could not be resolved. Its message joins the
+// messages of the errors.
+type ImageErrors []*ImageError
+
+func (e ImageErrors) Error() string {
+	var msgs []string
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, ",")
+}
+
+// ReferenceOption customizes the references that GetReference and GetRepository build.
+type ReferenceOption func(*referenceOptions)
+
+type referenceOptions struct {
+	flatten bool
+	variant operator.ImageVariant
+}
+
+// variantTagSuffixes are the suffixes of the tags of the images that are published in variants other than the standard
+// one, by image and variant.
+var variantTagSuffixes = map[string]map[operator.ImageVariant]string{
+	ComponentDex.Image:              {operator.ImageVariantFIPS: "-fips"},
+	ComponentCSRInitContainer.Image: {operator.ImageVariantFIPS: "-fips"},
+}
+
+// WithFlattenedImagePath joins the segments of the image of the component by dashes, below the image path if one is
+// given, for registries that mirror all images at one level. For example, tigera/dex becomes <imagepath>/tigera-dex.
+func WithFlattenedImagePath() ReferenceOption {
+	return func(o *referenceOptions) {
+		o.flatten = true
+	}
+}
+
+// WithImageVariant selects the variant of the image of the component. GetReference returns an error when the image is
+// not published in the variant.
+func WithImageVariant(variant operator.ImageVariant) ReferenceOption {
+	return func(o *referenceOptions) {
+		o.variant = variant
+	}
+}
+
+// ReferenceOptionsFor returns the options for the references of the images of all components that the Installation
+// selects: its image path format and its image variant. An image that is not published in the variant of the
+// Installation is reported by the component that needs it.
+func ReferenceOptionsFor(installation *operator.InstallationSpec) []ReferenceOption {
+	if installation == nil {
+		return nil
+	}
+	opts := []ReferenceOption{WithImageVariant(installation.ImageVariant)}
+	if installation.ImagePathFormat == operator.ImagePathFormatFlatten {
+		opts = append(opts, WithFlattenedImagePath())
+	}
+	return opts
+}
+
 // GetReference returns the fully qualified image to use, including registry and version.
-func GetReference(c component, registry, imagepath string, is *operator.ImageSet) (string, error) {
+func GetReference(c component, registry, imagepath string, is *operator.ImageSet, opts ...ReferenceOption) (string, error) {
+	o := referenceOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var suffix string
+	if o.variant != "" && o.variant != operator.ImageVariantStandard {
+		var ok bool
+		if suffix, ok = variantTagSuffixes[c.Image][o.variant]; !ok {
+			return "", fmt.Errorf("image %s is not published in the %s variant", c.Image, o.variant)
+		}
+	}
+
+	image := GetRepository(c, registry, imagepath, opts...)
+
+	if is == nil {
+		return fmt.Sprintf("%s:%s%s", image, c.Version, suffix), nil
+	}
+
+	for _, img := range is.Spec.Images {
+		if img.Image == c.Image {
+			return fmt.Sprintf("%s@%s", image, img.Digest), nil
+		}
+	}
+
+	return "", fmt.Errorf("ImageSet did not contain image %s", c.Image)
+}
+
+// GetRepository returns the image of the component with its registry and image path, but without version or digest.
+func GetRepository(c component, registry, imagepath string, opts ...ReferenceOption) string {
+	o := referenceOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	// If a user did not supply a registry, use the default registry
 	// based on component
 	if registry == "" || registry == UseDefault {
@@ -52,21 +198,24 @@ func GetReference(c component, registry, imagepath string, is *operator.ImageSet
 	}
 
 	image := c.Image
-	if imagepath != "" && imagepath != UseDefault {
+	if o.flatten {
+		image = FlattenImagePath(image, imagepath)
+	} else if imagepath != "" && imagepath != UseDefault {
 		image = ReplaceImagePath(image, imagepath)
 	}
 
-	if is == nil {
-		return fmt.Sprintf("%s%s:%s", registry, image, c.Version), nil
-	}
+	return registry + image
+}
 
-	for _, img := range is.Spec.Images {
-		if img.Image == c.Image {
-			return fmt.Sprintf("%s%s@%s", registry, image, img.Digest), nil
-		}
+// FlattenImagePath joins the segments of the image by dashes, below the image path unless it is empty or UseDefault.
+// Since every segment is kept, images that differ only in their first segment, such as tigera/cni and calico/cni,
+// remain distinct.
+func FlattenImagePath(image, imagepath string) string {
+	image = strings.ReplaceAll(image, "/", "-")
+	if imagepath == "" || imagepath == UseDefault {
+		return image
 	}
-
-	return "", fmt.Errorf("ImageSet did not contain image %s", c.Image)
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(imagepath, "/"), image)
 }
 
 func ReplaceImagePath(image, imagepath string) string {
@@ -16,23 +16,36 @@ package authentication
 
 import (
 	"context"
+	goerrors "errors"
 	"fmt"
+	"net"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/go-ldap/ldap"
+	"github.com/go-logr/logr"
 
 	oprv1 "github.com/tigera/operator/api/v1"
+	"github.com/tigera/operator/pkg/components"
 	"github.com/tigera/operator/pkg/controller/installation"
 	"github.com/tigera/operator/pkg/controller/options"
 	"github.com/tigera/operator/pkg/controller/status"
 	"github.com/tigera/operator/pkg/controller/utils"
 	"github.com/tigera/operator/pkg/controller/utils/imageset"
+	"github.com/tigera/operator/pkg/dns"
 	"github.com/tigera/operator/pkg/render"
 	rmeta "github.com/tigera/operator/pkg/render/common/meta"
+	"github.com/tigera/operator/pkg/render/common/secret"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -44,12 +57,24 @@ import (
 
 var log = logf.Log.WithName("controller_authentication")
 
+// secretProviderClassGVK is the kind of the objects that provide the files of the volumes of the secrets-store CSI
+// driver.
+var secretProviderClassGVK = schema.GroupVersionKind{Group: "secrets-store.csi.x-k8s.io", Version: "v1", Kind: "SecretProviderClass"}
+
 const (
 	controllerName = "authentication-controller"
 
 	defaultNameAttribute string = "uid"
 )
 
+// The reasons of the warning events that are emitted on the Authentication when Dex cannot be rendered.
+const (
+	eventImageResolutionFailed  = "ImageResolutionFailed"
+	eventSecretValidationFailed = "SecretValidationFailed"
+	eventInvalidConfiguration   = "InvalidConfiguration"
+	eventConfigMarshalFailed    = "ConfigMarshalFailed"
+)
+
 // Add creates a new authentication Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager, opts options.AddOptions) error {
@@ -68,6 +93,8 @@ func newReconciler(mgr manager.Manager, opts options.AddOptions) *ReconcileAuthe
 		provider:      opts.DetectedProvider,
 		status:        status.New(mgr.GetClient(), "authentication", opts.KubernetesVersion),
 		clusterDomain: opts.ClusterDomain,
+		events:        utils.NewFailureEvents(mgr.GetEventRecorderFor(controllerName), utils.DefaultFailureEventInterval),
+		watches:       newWatchedObjects(),
 	}
 	r.status.Run()
 	return r
@@ -94,14 +121,10 @@ func add(mgr manager.Manager, r *ReconcileAuthentication) error {
 		return fmt.Errorf("%s failed to watch resource: %w", controllerName, err)
 	}
 
-	for _, namespace := range []string{rmeta.OperatorNamespace(), render.DexNamespace} {
-		for _, secretName := range []string{
-			render.DexTLSSecretName, render.DexCertSecretName, render.OIDCSecretName, render.OpenshiftSecretName, render.DexObjectName,
-		} {
-			if err = utils.AddSecretsWatch(c, secretName, namespace); err != nil {
-				return fmt.Errorf("%s failed to watch the secret '%s' in '%s' namespace: %w", controllerName, secretName, namespace, err)
-			}
-		}
+	// The secrets, the service and the service CA ConfigMap of Dex are in the namespaces and have the names that the
+	// Authentication configures, so the reconciler keeps track of them.
+	if err = r.watches.addWatches(c); err != nil {
+		return fmt.Errorf("%s %w", controllerName, err)
 	}
 
 	if err = imageset.AddImageSetWatch(c); err != nil {
@@ -121,6 +144,8 @@ type ReconcileAuthentication struct {
 	provider      oprv1.Provider
 	status        status.StatusManager
 	clusterDomain string
+	events        *utils.FailureEvents
+	watches       *watchedObjects
 }
 
 // Reconciles the cluster state with the Authentication object that is found in the cluster.
@@ -132,7 +157,7 @@ func (r *ReconcileAuthentication) Reconcile(ctx context.Context, request reconci
 	reqLogger.Info("Reconciling ", "controller", controllerName)
 
 	// Fetch the Authentication spec. If present, we deploy dex in the cluster.
-	authentication, err := utils.GetAuthentication(ctx, r.client)
+	authentication, ignored, err := utils.GetAuthentications(ctx, r.client)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			r.status.OnCRNotFound()
@@ -142,19 +167,55 @@ func (r *ReconcileAuthentication) Reconcile(ctx context.Context, request reconci
 	}
 	r.status.OnCRFound()
 	reqLogger.V(2).Info("Loaded config", "config", authentication)
+
+	// Only one Authentication is supported. Dex is not rendered from the others, which report that they are ignored.
+	// Failing to report that does not keep Dex from being reconciled, the request is retried afterwards.
+	var ignoredErr error
+	for i := range ignored {
+		if err := r.reportIgnored(ctx, &ignored[i], authentication.Name); err != nil {
+			log.Error(err, "Failed to update the status of an ignored Authentication", "name", ignored[i].Name)
+			ignoredErr = err
+		}
+	}
+	previousStatus := authentication.Status.DeepCopy()
+
+	// Reconcile with a status manager that records why Dex is degraded, so that the status of the Authentication
+	// reports it as well.
+	recorder := &degradedRecorder{StatusManager: r.status}
+	reconciler := *r
+	reconciler.status = recorder
+	result, err := reconciler.reconcile(ctx, authentication, reqLogger)
+
+	updateConditions(&authentication.Status, recorder, r.status.IsAvailable(), r.status.IsDegraded(), secretsStore(authentication) != nil)
+	if !reflect.DeepEqual(previousStatus, &authentication.Status) {
+		if statusErr := r.client.Status().Update(ctx, authentication); statusErr != nil {
+			log.Error(statusErr, "Failed to update the status of the Authentication")
+			if err == nil {
+				return reconcile.Result{}, statusErr
+			}
+		}
+	}
+	if err == nil && ignoredErr != nil {
+		return reconcile.Result{}, ignoredErr
+	}
+	return result, err
+}
+
+// reconcile renders Dex for the given Authentication. The status of the Authentication is updated in memory only.
+func (r *ReconcileAuthentication) reconcile(ctx context.Context, authentication *oprv1.Authentication, reqLogger logr.Logger) (reconcile.Result, error) {
 	preDefaultPatchFrom := client.MergeFrom(authentication.DeepCopy())
 
 	// Set defaults for backwards compatibility.
 	updateAuthenticationWithDefaults(authentication)
 
 	// Validate the configuration
-	if err := validateAuthentication(authentication); err != nil {
+	if err := render.ValidateAuthentication(authentication).ToAggregate(); err != nil {
 		r.status.SetDegraded("Invalid Authentication provided", err.Error())
 		return reconcile.Result{}, err
 	}
 
 	// Write the authentication back to the datastore, so the controllers depending on this can reconcile.
-	if err = r.client.Patch(ctx, authentication, preDefaultPatchFrom); err != nil {
+	if err := r.client.Patch(ctx, authentication, preDefaultPatchFrom); err != nil {
 		log.Error(err, "Failed to write defaults")
 		r.status.SetDegraded("Failed to write defaults", err.Error())
 		return reconcile.Result{}, err
@@ -178,15 +239,17 @@ func (r *ReconcileAuthentication) Reconcile(ctx context.Context, request reconci
 		return reconcile.Result{}, nil
 	}
 
-	// Make sure the tigera-dex namespace exists, before rendering any objects there.
-	if err := r.client.Get(ctx, client.ObjectKey{Name: render.DexObjectName}, &corev1.Namespace{}); err != nil {
+	// Make sure the Dex namespace exists, before rendering any objects there.
+	dexNamespace := render.DexNamespaceFor(authentication)
+	r.watches.setFor(authentication)
+	if err := r.client.Get(ctx, client.ObjectKey{Name: dexNamespace}, &corev1.Namespace{}); err != nil {
 		if errors.IsNotFound(err) {
-			log.Error(err, "Waiting for namespace tigera-dex to be created")
-			r.status.SetDegraded("Waiting for namespace tigera-dex to be created", err.Error())
+			log.Error(err, fmt.Sprintf("Waiting for namespace %s to be created", dexNamespace))
+			r.status.SetDegraded(fmt.Sprintf("Waiting for namespace %s to be created", dexNamespace), err.Error())
 			return reconcile.Result{RequeueAfter: 10 * time.Second}, nil
 		} else {
-			log.Error(err, "Error querying tigera-dex namespace")
-			r.status.SetDegraded("Error querying tigera-dex namespace", err.Error())
+			log.Error(err, fmt.Sprintf("Error querying %s namespace", dexNamespace))
+			r.status.SetDegraded(fmt.Sprintf("Error querying %s namespace", dexNamespace), err.Error())
 			return reconcile.Result{}, err
 		}
 	}
@@ -203,27 +266,68 @@ func (r *ReconcileAuthentication) Reconcile(ctx context.Context, request reconci
 		return reconcile.Result{}, err
 	}
 
+	// The ClusterIP of the Dex service, once it is allocated, and the configured IP addresses are the IP SANs of the
+	// Dex certificate.
+	dexService := &corev1.Service{}
+	if err := r.client.Get(ctx, types.NamespacedName{Name: render.DexObjectName, Namespace: dexNamespace}, dexService); err != nil && !errors.IsNotFound(err) {
+		log.Error(err, fmt.Sprintf("Failed to read %s/%s service", dexNamespace, render.DexObjectName))
+		r.status.SetDegraded(fmt.Sprintf("Failed to read %s/%s service", dexNamespace, render.DexObjectName), err.Error())
+		return reconcile.Result{}, err
+	}
+	var certIPAddresses []string
+	if authentication.Spec.DexDeployment != nil {
+		certIPAddresses = authentication.Spec.DexDeployment.CertificateIPAddresses
+	}
+	ipAddresses := render.DexCertIPAddresses(dexService.Spec.ClusterIP, certIPAddresses)
+
+	// The certificate of Dex is created for the cluster domain that the Dex config uses, which is detected when none is
+	// configured, so that its names match the issuer and the service URLs that are rendered.
+	clusterDomain := dns.DefaultClusterDomainDetector.ClusterDomain(r.clusterDomain)
+
 	// Secret used for TLS between dex and other components.
 	var tlsSecret *corev1.Secret
 	if install.CertificateManagement == nil {
 		tlsSecret = &corev1.Secret{}
 		if err := r.client.Get(ctx, types.NamespacedName{Name: render.DexTLSSecretName, Namespace: rmeta.OperatorNamespace()}, tlsSecret); err != nil {
 			if errors.IsNotFound(err) {
-				tlsSecret = render.CreateDexTLSSecret(fmt.Sprintf(render.DexCNPattern, r.clusterDomain))
+				tlsSecret = nil
 			} else {
 				log.Error(err, "Failed to read tigera-operator/tigera-dex-tls secret")
 				r.status.SetDegraded("Failed to read tigera-operator/tigera-dex-tls secret", err.Error())
 				return reconcile.Result{}, err
 			}
 		}
+		if tlsSecret == nil || isSelfSignedForOtherNamespace(tlsSecret, dexNamespace, clusterDomain) ||
+			isSelfSignedForOtherIPAddresses(tlsSecret, dexNamespace, clusterDomain, ipAddresses) {
+			tlsSecret = render.CreateDexTLSSecret(render.DexCommonName(dexNamespace, clusterDomain), render.DexCertSANs(dexNamespace, clusterDomain, nil), ipAddresses...)
+		}
 	}
 
-	// Dex will be configured with the contents of this secret, such as clientID and clientSecret.
-	idpSecret, err := getIdpSecret(ctx, r.client, authentication)
-	if err != nil {
-		log.Error(err, "Invalid or missing identity provider secret")
-		r.status.SetDegraded("Invalid or missing identity provider secret", err.Error())
-		return reconcile.Result{}, err
+	// Dex will be configured with the contents of this secret, such as clientID and clientSecret, unless they are
+	// mounted from a secrets store.
+	var idpSecret *corev1.Secret
+	if store := secretsStore(authentication); store != nil {
+		spc := &unstructured.Unstructured{}
+		spc.SetGroupVersionKind(secretProviderClassGVK)
+		if err := r.client.Get(ctx, types.NamespacedName{Name: store.SecretProviderClass, Namespace: dexNamespace}, spc); err != nil {
+			if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				msg := fmt.Sprintf("Waiting for SecretProviderClass %s/%s to be created", dexNamespace, store.SecretProviderClass)
+				log.Info(msg, "reason", err.Error())
+				r.status.SetDegraded(msg, err.Error())
+				return reconcile.Result{RequeueAfter: 10 * time.Second}, nil
+			}
+			log.Error(err, fmt.Sprintf("Failed to read SecretProviderClass %s/%s", dexNamespace, store.SecretProviderClass))
+			r.status.SetDegraded(fmt.Sprintf("Failed to read SecretProviderClass %s/%s", dexNamespace, store.SecretProviderClass), err.Error())
+			return reconcile.Result{}, err
+		}
+	} else {
+		idpSecret, err = getIdpSecret(ctx, r.client, authentication)
+		if err != nil {
+			log.Error(err, "Invalid or missing identity provider secret")
+			r.status.SetDegraded("Invalid or missing identity provider secret", err.Error())
+			r.warn(authentication, eventSecretValidationFailed, err.Error())
+			return reconcile.Result{}, err
+		}
 	}
 
 	dexSecret := &corev1.Secret{}
@@ -237,6 +341,10 @@ func (r *ReconcileAuthentication) Reconcile(ctx context.Context, request reconci
 			return reconcile.Result{}, err
 		}
 	}
+	if rotated := render.RotateDexClientSecret(authentication, dexSecret); rotated != dexSecret {
+		log.Info("Rotating the client secret of Dex")
+		dexSecret = rotated
+	}
 
 	pullSecrets, err := utils.GetNetworkingPullSecrets(install, r.client)
 	if err != nil {
@@ -245,36 +353,211 @@ func (r *ReconcileAuthentication) Reconcile(ctx context.Context, request reconci
 		return reconcile.Result{}, err
 	}
 
+	// Static clients that are not public read their client secret from a secret in the operator namespace.
+	var staticClientSecrets []*corev1.Secret
+	for _, c := range authentication.Spec.StaticClients {
+		if c.Public {
+			continue
+		}
+		staticClientSecret := &corev1.Secret{}
+		if err := r.client.Get(ctx, types.NamespacedName{Name: c.SecretName, Namespace: rmeta.OperatorNamespace()}, staticClientSecret); err != nil {
+			if errors.IsNotFound(err) && c.GenerateSecret {
+				// The generated secret is rendered in the operator namespace, so that later reconciles keep it.
+				log.Info("Generating the client secret of a static client", "id", c.ID, "secret", c.SecretName)
+				staticClientSecrets = append(staticClientSecrets, render.CreateStaticClientSecret(c.SecretName))
+				continue
+			} else if errors.IsNotFound(err) {
+				// Dex is not ready without this secret, which is reported below.
+				continue
+			}
+			log.Error(err, fmt.Sprintf("Failed to read %s/%s secret", rmeta.OperatorNamespace(), c.SecretName))
+			r.status.SetDegraded(fmt.Sprintf("Failed to read %s/%s secret", rmeta.OperatorNamespace(), c.SecretName), err.Error())
+			return reconcile.Result{}, err
+		}
+		staticClientSecrets = append(staticClientSecrets, staticClientSecret)
+	}
+
+	// The secrets that were copied into the Dex namespace before, so that the copies that are no longer needed are removed.
+	secretCopies := &corev1.SecretList{}
+	if err := r.client.List(ctx, secretCopies, client.InNamespace(dexNamespace), client.HasLabels{secret.CopiedFromLabel}); err != nil {
+		log.Error(err, fmt.Sprintf("Failed to list the secrets copied into the %s namespace", dexNamespace))
+		r.status.SetDegraded(fmt.Sprintf("Failed to list the secrets copied into the %s namespace", dexNamespace), err.Error())
+		return reconcile.Result{}, err
+	}
+
+	// The ConfigMaps with a kubeconfig user for kubectl that were rendered before, so that the ones that are no longer
+	// configured are removed.
+	kubectlConfigMaps := &corev1.ConfigMapList{}
+	if err := r.client.List(ctx, kubectlConfigMaps, client.HasLabels{render.DexKubectlConfigLabel}); err != nil {
+		log.Error(err, "Failed to list the kubectl ConfigMaps of Dex")
+		r.status.SetDegraded("Failed to list the kubectl ConfigMaps of Dex", err.Error())
+		return reconcile.Result{}, err
+	}
+
+	// The same for the ConfigMaps with the discovery details of Dex, which are removed from the namespaces that are no
+	// longer configured.
+	discoveryConfigMaps := &corev1.ConfigMapList{}
+	if err := r.client.List(ctx, discoveryConfigMaps, client.HasLabels{render.DexDiscoveryLabel}); err != nil {
+		log.Error(err, "Failed to list the discovery ConfigMaps of Dex")
+		r.status.SetDegraded("Failed to list the discovery ConfigMaps of Dex", err.Error())
+		return reconcile.Result{}, err
+	}
+
+	// The PrometheusRules with alerts for Dex that were rendered before, so that the ones that are no longer configured
+	// are removed. Without the Prometheus operator, there are none.
+	prometheusRules := &unstructured.UnstructuredList{}
+	prometheusRules.SetGroupVersionKind(render.PrometheusRuleGVK.GroupVersion().WithKind(render.PrometheusRuleGVK.Kind + "List"))
+	if err := r.client.List(ctx, prometheusRules, client.HasLabels{render.DexPrometheusRuleLabel}); err != nil && !meta.IsNoMatchError(err) {
+		log.Error(err, "Failed to list the PrometheusRules of Dex")
+		r.status.SetDegraded("Failed to list the PrometheusRules of Dex", err.Error())
+		return reconcile.Result{}, err
+	}
+
+	// With a serving certificate of OpenShift, the clients of Dex trust the CA that OpenShift injected, once it did.
+	var serviceCA []byte
+	servingCertificate := r.provider == oprv1.ProviderOpenShift && authentication.Spec.DexDeployment != nil &&
+		authentication.Spec.DexDeployment.ServiceServingCertificate && authentication.Spec.DexDeployment.ExternalHost == ""
+	if servingCertificate {
+		caConfigMap := &corev1.ConfigMap{}
+		key := types.NamespacedName{Name: render.DexServiceCAName(render.DexObjectName), Namespace: dexNamespace}
+		if err := r.client.Get(ctx, key, caConfigMap); err != nil && !errors.IsNotFound(err) {
+			log.Error(err, fmt.Sprintf("Failed to read the %s ConfigMap", key))
+			r.status.SetDegraded(fmt.Sprintf("Failed to read the %s ConfigMap", key), err.Error())
+			return reconcile.Result{}, err
+		}
+		serviceCA = []byte(caConfigMap.Data[rmeta.OpenShiftServiceCAKey])
+	}
+
+	// The CA certificates of the configured ConfigMaps are merged with the root CA of the identity provider into one
+	// bundle that Dex trusts.
+	var idpCABundle *corev1.Secret
+	if authentication.Spec.DexDeployment != nil && len(authentication.Spec.DexDeployment.IdentityProviderCAConfigMaps) != 0 {
+		var sources []client.Object
+		if idpSecret != nil && idpSecret.Data[render.RootCASecretField] != nil {
+			sources = append(sources, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: idpSecret.Name, Namespace: idpSecret.Namespace},
+				Data:       map[string][]byte{render.RootCASecretField: idpSecret.Data[render.RootCASecretField]},
+			})
+		}
+		for _, name := range authentication.Spec.DexDeployment.IdentityProviderCAConfigMaps {
+			cm := &corev1.ConfigMap{}
+			if err := r.client.Get(ctx, types.NamespacedName{Name: name, Namespace: rmeta.OperatorNamespace()}, cm); err != nil {
+				log.Error(err, fmt.Sprintf("Failed to read the %s/%s ConfigMap", rmeta.OperatorNamespace(), name))
+				r.status.SetDegraded(fmt.Sprintf("Failed to read the %s/%s ConfigMap", rmeta.OperatorNamespace(), name), err.Error())
+				return reconcile.Result{}, err
+			}
+			sources = append(sources, cm)
+		}
+		idpCABundle, err = secret.MergeCABundles(render.DexIdpCABundleName(render.DexObjectName), rmeta.OperatorNamespace(), sources...)
+		if err != nil {
+			log.Error(err, "Invalid CA certificates for the identity provider")
+			r.status.SetDegraded("Invalid CA certificates for the identity provider", err.Error())
+			return reconcile.Result{}, err
+		}
+	}
+
 	// DexConfig adds convenience methods around dex related objects in k8s and can be used to configure Dex.
-	dexCfg := render.NewDexConfig(install.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, r.clusterDomain)
+	dexCfg := render.NewDexConfig(install.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, staticClientSecrets, clusterDomain,
+		render.WithSecretCopies(secretCopies.Items), render.WithKubectlConfigMaps(kubectlConfigMaps.Items),
+		render.WithDiscoveryConfigMapCopies(discoveryConfigMaps.Items), render.WithServiceClusterIP(dexService.Spec.ClusterIP),
+		render.WithServiceCA(serviceCA), render.WithPrometheusRules(prometheusRules.Items), render.WithIdentityProviderCABundle(idpCABundle))
+
+	// Fail fast when a secret that Dex would read does not exist, rather than deploying a Dex that cannot authenticate.
+	r.watches.addSecrets(dexCfg.SecretReferences())
+	missing, invalid, err := checkSecretReferences(ctx, r.client, dexCfg.SecretReferences())
+	if err != nil {
+		log.Error(err, "Failed to read the secrets referenced by Dex")
+		r.status.SetDegraded("Failed to read the secrets referenced by Dex", err.Error())
+		return reconcile.Result{}, err
+	} else if len(invalid) != 0 {
+		err = invalid.ToAggregate()
+		log.Error(err, "Invalid Authentication provided")
+		r.status.SetDegraded("Invalid Authentication provided", err.Error())
+		r.warn(authentication, eventSecretValidationFailed, fmt.Sprintf("%v; add the keys to the referenced secrets", err))
+		return reconcile.Result{}, err
+	} else if len(missing) != 0 {
+		reason := strings.Join(missing, "; ")
+		log.Info("Waiting for the secrets referenced by Dex", "reason", reason)
+		r.status.SetDegraded("Waiting for the secrets referenced by Dex", reason)
+		return reconcile.Result{RequeueAfter: 10 * time.Second}, nil
+	}
 
 	// Create a component handler to manage the rendered component.
 	hlr := utils.NewComponentHandler(log, r.client, r.scheme, authentication)
 
 	// Render the desired objects from the CRD and create or update them.
 	reqLogger.V(3).Info("rendering components")
-	component := render.Dex(
-		pullSecrets,
-		r.provider == oprv1.ProviderOpenShift,
-		install,
-		dexCfg,
-		r.clusterDomain,
-	)
+	component := render.Dex(render.DexConfiguration{
+		PullSecrets:  pullSecrets,
+		Openshift:    r.provider == oprv1.ProviderOpenShift,
+		Installation: install,
+		DexConfig:    dexCfg,
+	})
+
+	// Dex only runs on Linux nodes, so it is not applied when the Installation runs the control plane on other nodes.
+	component = render.WithOSFilter(component, utils.AvailableOSTypes(install))
+
+	if !component.Ready() {
+		reason := render.NotReadyReason(component)
+		log.Info("Waiting for Dex prerequisites", "reason", reason)
+		r.status.SetDegraded("Waiting for Dex prerequisites", reason)
+		return reconcile.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
+	if err = component.Validate(); err != nil {
+		log.Error(err, "Invalid Dex configuration")
+		r.status.SetDegraded("Invalid Dex configuration", err.Error())
+		if marshalErr := (*render.MarshalError)(nil); goerrors.As(err, &marshalErr) {
+			r.warn(authentication, eventConfigMarshalFailed, err.Error())
+		} else {
+			r.warn(authentication, eventInvalidConfiguration, err.Error())
+		}
+		return reconcile.Result{}, err
+	}
 
-	if err = imageset.ApplyImageSet(ctx, r.client, variant, component); err != nil {
+	// Check that the ImageSet has all images of Dex before any object is applied, and report all missing images at once.
+	imageSet, err := imageset.GetImageSet(ctx, r.client, variant)
+	if err != nil {
 		log.Error(err, "Error with images from ImageSet")
 		r.status.SetDegraded("Error with images from ImageSet", err.Error())
+		r.warn(authentication, eventImageResolutionFailed, err.Error())
+		return reconcile.Result{}, err
+	}
+	extraImages, err := imageset.ValidateImageSetCompleteness(imageSet, component)
+	if err != nil {
+		log.Error(err, "Incomplete ImageSet")
+		r.status.SetDegraded("Incomplete ImageSet", err.Error())
+		r.warn(authentication, eventImageResolutionFailed, fmt.Sprintf("%v; add the images to the ImageSet", err))
+		return reconcile.Result{}, err
+	} else if len(extraImages) != 0 {
+		reqLogger.Info("The ImageSet has images that Dex does not require", "images", extraImages)
+	}
+
+	if err = imageset.ApplyImageSet(ctx, r.client, variant, component); err != nil {
+		log.Error(err, "Error with images from ImageSet")
+		r.status.SetDegraded("Error with images from ImageSet", imageSetErrorMessage(err))
+		r.warn(authentication, eventImageResolutionFailed, imageSetErrorMessage(err))
 		return reconcile.Result{}, err
 	}
 
-	if err := hlr.CreateOrUpdateOrDelete(context.Background(), component, r.status); err != nil {
+	if err := hlr.CreateOrUpdateOrDelete(context.Background(), render.WithInstallationMetadata(component, install), r.status); err != nil {
 		log.Error(err, "Error creating / updating resource")
 		r.status.SetDegraded("Error creating / updating resource", err.Error())
 		return reconcile.Result{}, err
 	}
 
+	// The ConfigMap for the CA of the serving certificate was just created, OpenShift injects the CA into it shortly.
+	if servingCertificate && len(serviceCA) == 0 {
+		msg := fmt.Sprintf("Waiting for OpenShift to inject the service CA into %s/%s", dexNamespace, render.DexServiceCAName(render.DexObjectName))
+		log.Info(msg)
+		r.status.SetDegraded(msg, "")
+		return reconcile.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
 	// Clear the degraded bit if we've reached this far.
 	r.status.ClearDegraded()
+	authentication.Status.IssuerURL = dexCfg.Issuer()
+	authentication.Status.ConnectorType = dexCfg.ConnectorType()
 
 	if !r.status.IsAvailable() {
 		// Schedule a kick to check again in the near future.
@@ -283,46 +566,198 @@ func (r *ReconcileAuthentication) Reconcile(ctx context.Context, request reconci
 
 	// Everything is available - update the CRD status.
 	authentication.Status.State = oprv1.TigeraStatusReady
-	if err = r.client.Status().Update(ctx, authentication); err != nil {
-		return reconcile.Result{}, err
-	}
+	authentication.Status.DexNamespace = dexNamespace
 	return reconcile.Result{}, nil
 }
 
-func getIdpSecret(ctx context.Context, client client.Client, authentication *oprv1.Authentication) (*corev1.Secret, error) {
-	var secretName string
-	var requiredFields []string
-	if authentication.Spec.OIDC != nil {
-		secretName = render.OIDCSecretName
-		requiredFields = append(requiredFields, render.ClientIDSecretField, render.ClientSecretSecretField)
-	} else if authentication.Spec.Openshift != nil {
-		secretName = render.OpenshiftSecretName
-		requiredFields = append(requiredFields, render.ClientIDSecretField, render.ClientSecretSecretField, render.RootCASecretField)
-	} else if authentication.Spec.LDAP != nil {
-		secretName = render.LDAPSecretName
-		requiredFields = append(requiredFields, render.BindDNSecretField, render.BindPWSecretField, render.RootCASecretField)
+// warn emits a warning event on the Authentication for a failure to render Dex that persists until the user fixes it.
+func (r *ReconcileAuthentication) warn(authentication *oprv1.Authentication, reason, detail string) {
+	r.events.Warn(authentication, reason, fmt.Sprintf("component %s: %s", render.DexObjectName, detail))
+}
+
+// degradedRecorder is a status manager that records whether it was set degraded and why.
+type degradedRecorder struct {
+	status.StatusManager
+	degraded bool
+	reason   string
+	msg      string
+}
+
+func (d *degradedRecorder) SetDegraded(reason, msg string) {
+	d.degraded, d.reason, d.msg = true, reason, msg
+	d.StatusManager.SetDegraded(reason, msg)
+}
+
+func (d *degradedRecorder) ClearDegraded() {
+	d.degraded, d.reason, d.msg = false, "", ""
+	d.StatusManager.ClearDegraded()
+}
+
+// reportIgnored sets the status of an Authentication that is ignored, since the Authentication with the given name is
+// used instead.
+func (r *ReconcileAuthentication) reportIgnored(ctx context.Context, authentication *oprv1.Authentication, used string) error {
+	previousStatus := authentication.Status.DeepCopy()
+	authentication.Status = oprv1.AuthenticationStatus{Conditions: authentication.Status.Conditions}
+	setConditions(&authentication.Status,
+		oprv1.TigeraStatusCondition{Type: oprv1.ComponentAvailable, Status: oprv1.ConditionFalse},
+		oprv1.TigeraStatusCondition{Type: oprv1.ComponentProgressing, Status: oprv1.ConditionFalse},
+		oprv1.TigeraStatusCondition{
+			Type:    oprv1.ComponentDegraded,
+			Status:  oprv1.ConditionTrue,
+			Reason:  "Ignored",
+			Message: fmt.Sprintf("Only one Authentication is supported, Authentication %s is used instead", used),
+		},
+	)
+	if reflect.DeepEqual(previousStatus, &authentication.Status) {
+		return nil
+	}
+	return r.client.Status().Update(ctx, authentication)
+}
+
+// updateConditions sets the Available, Progressing and Degraded conditions of the Authentication. Dex is available
+// when its deployment is, and progressing when it is neither available nor degraded.
+func updateConditions(s *oprv1.AuthenticationStatus, recorder *degradedRecorder, available, degraded, secretsStore bool) {
+	conditions := []oprv1.TigeraStatusCondition{
+		{Type: oprv1.ComponentAvailable, Status: oprv1.ConditionFalse},
+		{Type: oprv1.ComponentProgressing, Status: oprv1.ConditionFalse},
+		{Type: oprv1.ComponentDegraded, Status: oprv1.ConditionFalse},
+	}
+	switch {
+	case recorder.degraded:
+		conditions[2] = oprv1.TigeraStatusCondition{Type: oprv1.ComponentDegraded, Status: oprv1.ConditionTrue, Reason: recorder.reason, Message: recorder.msg}
+	case degraded:
+		// The status manager reports failing pods. When the credentials are mounted from a secrets store, a missing file
+		// is the likely cause, since Dex does not start without it.
+		msg := "See the TigeraStatus of authentication for the failing pods"
+		if secretsStore {
+			msg = "Check that the secrets store volume of Dex has a file for every credential of the identity provider"
+		}
+		conditions[2] = oprv1.TigeraStatusCondition{Type: oprv1.ComponentDegraded, Status: oprv1.ConditionTrue, Reason: "Dex pods are failing", Message: msg}
+	case !available:
+		conditions[1] = oprv1.TigeraStatusCondition{Type: oprv1.ComponentProgressing, Status: oprv1.ConditionTrue, Reason: "Dex is not available yet"}
 	}
+	if available {
+		conditions[0] = oprv1.TigeraStatusCondition{Type: oprv1.ComponentAvailable, Status: oprv1.ConditionTrue, Reason: "All objects available"}
+	}
+	setConditions(s, conditions...)
+}
+
+// setConditions sets the given conditions of the Authentication, and updates their transition times if their status
+// changed.
+func setConditions(s *oprv1.AuthenticationStatus, conditions ...oprv1.TigeraStatusCondition) {
+	for _, condition := range conditions {
+		found := false
+		for i, c := range s.Conditions {
+			if c.Type == condition.Type {
+				// If the status has changed, update the transition time.
+				condition.LastTransitionTime = c.LastTransitionTime
+				if c.Status != condition.Status {
+					condition.LastTransitionTime = metav1.NewTime(time.Now())
+				}
+				s.Conditions[i] = condition
+				found = true
+			}
+		}
+		if !found {
+			condition.LastTransitionTime = metav1.NewTime(time.Now())
+			s.Conditions = append(s.Conditions, condition)
+		}
+	}
+}
+
+// checkSecretReferences returns a problem for every referenced secret that does not exist yet, and an error for every
+// field of the Authentication that references an existing secret which lacks the referenced key.
+func checkSecretReferences(ctx context.Context, cli client.Client, refs []render.DexSecretReference) ([]string, field.ErrorList, error) {
+	var missing []string
+	var invalid field.ErrorList
+	secrets := map[types.NamespacedName]*corev1.Secret{}
+	for _, ref := range refs {
+		key := types.NamespacedName{Name: ref.Name, Namespace: ref.Namespace}
+		s, ok := secrets[key]
+		if !ok {
+			s = &corev1.Secret{}
+			if err := cli.Get(ctx, key, s); err != nil {
+				if !errors.IsNotFound(err) {
+					return nil, nil, err
+				}
+				s = nil
+				missing = append(missing, fmt.Sprintf("secret %s is missing", key))
+			}
+			secrets[key] = s
+		}
+		if s != nil && len(s.Data[ref.Key]) == 0 {
+			invalid = append(invalid, field.Invalid(field.NewPath(ref.Field), ref.Name, fmt.Sprintf("secret %s has no %s", key, ref.Key)))
+		}
+	}
+	return missing, invalid, nil
+}
+
+// isSelfSignedForOtherNamespace returns true if the secret holds a self-signed certificate that the operator created for
+// Dex in another namespace. Its names no longer match the Dex service, so it has to be replaced.
+func isSelfSignedForOtherNamespace(secret *corev1.Secret, namespace, clusterDomain string) bool {
+	issuer, err := utils.GetCertificateIssuer(secret.Data[corev1.TLSCertKey])
+	if err != nil || issuer == render.DexCommonName(namespace, clusterDomain) {
+		return false
+	}
+	return strings.HasPrefix(issuer, render.DexObjectName+".") && strings.HasSuffix(issuer, ".svc."+clusterDomain)
+}
+
+// isSelfSignedForOtherIPAddresses returns true if the secret has a certificate that the operator created for Dex in the
+// given namespace, which is not valid for exactly the given IP addresses, for example since the ClusterIP of the Dex
+// service changed.
+func isSelfSignedForOtherIPAddresses(secret *corev1.Secret, namespace, clusterDomain string, ipAddresses []net.IP) bool {
+	issuer, err := utils.GetCertificateIssuer(secret.Data[corev1.TLSCertKey])
+	if err != nil || issuer != render.DexCommonName(namespace, clusterDomain) {
+		return false
+	}
+	certIPAddresses, err := utils.GetCertificateIPAddresses(secret.Data[corev1.TLSCertKey])
+	if err != nil {
+		return false
+	}
+	if len(certIPAddresses) != len(ipAddresses) {
+		return true
+	}
+	for i := range ipAddresses {
+		if !certIPAddresses[i].Equal(ipAddresses[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func getIdpSecret(ctx context.Context, client client.Client, authentication *oprv1.Authentication) (*corev1.Secret, error) {
+	secretName := render.IdpSecretName(authentication)
+	fld := render.IdpFieldPath(authentication)
 
 	secret := &corev1.Secret{}
 	if err := client.Get(ctx, types.NamespacedName{Name: secretName, Namespace: rmeta.OperatorNamespace()}, secret); err != nil {
-		return nil, fmt.Errorf("missing secret %s/%s: %w", rmeta.OperatorNamespace(), secretName, err)
+		return nil, fmt.Errorf("%s: missing secret %s/%s: %w", fld, rmeta.OperatorNamespace(), secretName, err)
 	}
 
-	for _, field := range requiredFields {
-		data := secret.Data[field]
+	for _, key := range render.RequiredIdpSecretFields(authentication) {
+		data := secret.Data[key]
 		if len(data) == 0 {
-			return nil, fmt.Errorf("%s is a required field for secret %s/%s", field, secret.Namespace, secret.Name)
+			return nil, fmt.Errorf("%s: %s is a required field for secret %s/%s", fld, key, secret.Namespace, secret.Name)
 		}
 
-		if field == render.BindDNSecretField {
+		if key == render.BindDNSecretField {
 			if _, err := ldap.ParseDN(string(data)); err != nil {
-				return nil, fmt.Errorf("secret %s/%s field %s: should have be a valid LDAP DN", rmeta.OperatorNamespace(), secretName, field)
+				return nil, fmt.Errorf("%s: secret %s/%s field %s: should have be a valid LDAP DN", fld, rmeta.OperatorNamespace(), secretName, key)
 			}
 		}
 	}
 	return secret, nil
 }
 
+// secretsStore returns the secrets store that the credentials of the identity provider are mounted from, or nil if they
+// are read from the secret of the identity provider.
+func secretsStore(authentication *oprv1.Authentication) *oprv1.DexSecretsStore {
+	if authentication.Spec.DexDeployment == nil || render.IdpFieldPath(authentication) == "" {
+		return nil
+	}
+	return authentication.Spec.DexDeployment.IdentityProviderSecretsStore
+}
+
 // updateAuthenticationWithDefaults sets values for backwards compatibility.
 func updateAuthenticationWithDefaults(authentication *oprv1.Authentication) {
 	if authentication.Spec.OIDC != nil {
@@ -338,76 +773,23 @@ func updateAuthenticationWithDefaults(authentication *oprv1.Authentication) {
 		}
 	}
 	ldap := authentication.Spec.LDAP
-	if ldap != nil {
+	if ldap != nil && ldap.UserSearch != nil {
 		if ldap.UserSearch.NameAttribute == "" {
 			ldap.UserSearch.NameAttribute = defaultNameAttribute
 		}
 	}
 }
 
-// validateAuthentication makes sure that the authentication spec is ready for use.
-func validateAuthentication(authentication *oprv1.Authentication) error {
-	oidc := authentication.Spec.OIDC
-	ldp := authentication.Spec.LDAP
-	// We support using only one connector at once.
-	var numConnectors int8 = 0
-	if oidc != nil {
-		numConnectors++
-	}
-	if ldp != nil {
-		numConnectors++
-	}
-	if authentication.Spec.Openshift != nil {
-		numConnectors++
-	}
-
-	if numConnectors == 0 {
-		return fmt.Errorf("no identity provider connector was specified, please add a connector to the Authentication spec")
-	} else if numConnectors > 1 {
-		return fmt.Errorf("multiple identity provider connectors were specified, but only 1 is allowed in the Authentication spec")
-	}
-
-	// If the user has specified the deprecated and the new prefix field, but with different values, we cannot proceed.
-	if oidc != nil {
-		if authentication.Spec.OIDC.UsernamePrefix != "" && authentication.Spec.UsernamePrefix != "" && authentication.Spec.OIDC.UsernamePrefix != authentication.Spec.UsernamePrefix {
-			return fmt.Errorf("you set username prefix twice, but with different values, please remove Authentication.Spec.OIDC.UsernamePrefix")
-		}
-
-		if authentication.Spec.OIDC.GroupsPrefix != "" && authentication.Spec.GroupsPrefix != "" && authentication.Spec.OIDC.GroupsPrefix != authentication.Spec.GroupsPrefix {
-			return fmt.Errorf("you set groups prefix twice, but with different values, please remove Authentication.Spec.OIDC.GroupsPrefix")
-		}
-
-		promptTypes := authentication.Spec.OIDC.PromptTypes
-		if promptTypes != nil && len(authentication.Spec.OIDC.PromptTypes) > 1 {
-			for _, pt := range promptTypes {
-				if pt == oprv1.PromptTypeNone {
-					return fmt.Errorf("you cannot combine PromptType None with other prompt types, please modify Authentication.Spec.OIDC.PromptType")
-				}
-			}
-		}
-
+// imageSetErrorMessage returns the message of an error of ApplyImageSet. When the error names the images that could not
+// be resolved, the message lists each image with the reference that was requested for it.
+func imageSetErrorMessage(err error) string {
+	var imageErrs components.ImageErrors
+	if !goerrors.As(err, &imageErrs) {
+		return err.Error()
 	}
-
-	if ldp != nil {
-		if _, err := ldap.ParseDN(ldp.UserSearch.BaseDN); err != nil {
-			return fmt.Errorf("invalid dn for LDAP user search: %w", err)
-		}
-		if ldp.GroupSearch != nil {
-			if _, err := ldap.ParseDN(ldp.GroupSearch.BaseDN); err != nil {
-				return fmt.Errorf("invalid dn for LDAP group search: %w", err)
-			}
-			if ldp.GroupSearch.Filter != "" {
-				if _, err := ldap.CompileFilter(ldp.GroupSearch.Filter); err != nil {
-					return fmt.Errorf("invalid filter for LDAP group search: %w", err)
-				}
-			}
-		}
-		if ldp.UserSearch.Filter != "" {
-			if _, err := ldap.CompileFilter(ldp.UserSearch.Filter); err != nil {
-				return fmt.Errorf("invalid filter for LDAP user search: %w", err)
-			}
-		}
+	var msgs []string
+	for _, e := range imageErrs {
+		msgs = append(msgs, fmt.Sprintf("image %s (%s): %v", e.Component, e.Reference, e.Err))
 	}
-
-	return nil
+	return strings.Join(msgs, "; ")
 }
@@ -17,6 +17,7 @@ package authentication
 import (
 	"context"
 	"fmt"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
@@ -31,13 +32,20 @@ import (
 	"github.com/tigera/operator/pkg/controller/utils"
 	"github.com/tigera/operator/pkg/render"
 	rmeta "github.com/tigera/operator/pkg/render/common/meta"
+	"github.com/tigera/operator/pkg/render/common/secret"
+	"github.com/tigera/operator/pkg/tls"
 	"github.com/tigera/operator/test"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -60,6 +68,9 @@ var _ = Describe("authentication controller tests", func() {
 		Expect(apis.AddToScheme(scheme)).ShouldNot(HaveOccurred())
 		Expect(appsv1.SchemeBuilder.AddToScheme(scheme)).ShouldNot(HaveOccurred())
 		Expect(rbacv1.SchemeBuilder.AddToScheme(scheme)).ShouldNot(HaveOccurred())
+		// The Prometheus operator is installed, but the operator has no types for its resources.
+		scheme.AddKnownTypeWithName(render.PrometheusRuleGVK, &unstructured.Unstructured{})
+		scheme.AddKnownTypeWithName(render.PrometheusRuleGVK.GroupVersion().WithKind("PrometheusRuleList"), &unstructured.UnstructuredList{})
 
 		ctx = context.Background()
 		cli = fake.NewFakeClientWithScheme(scheme)
@@ -71,6 +82,7 @@ var _ = Describe("authentication controller tests", func() {
 		mockStatus.On("AddStatefulSets", mock.Anything).Return()
 		mockStatus.On("AddCronJobs", mock.Anything)
 		mockStatus.On("IsAvailable").Return(true)
+		mockStatus.On("IsDegraded").Return(false)
 		mockStatus.On("OnCRFound").Return()
 		mockStatus.On("ClearDegraded")
 		mockStatus.On("SetDegraded", mock.Anything, mock.Anything).Return()
@@ -122,7 +134,7 @@ var _ = Describe("authentication controller tests", func() {
 			Expect(cli.Create(ctx, auth)).ToNot(HaveOccurred())
 
 			// Reconcile
-			r := &ReconcileAuthentication{cli, scheme, operatorv1.ProviderNone, mockStatus, ""}
+			r := &ReconcileAuthentication{cli, scheme, operatorv1.ProviderNone, mockStatus, "", nil, nil}
 			_, err := r.Reconcile(ctx, reconcile.Request{})
 			Expect(err).ShouldNot(HaveOccurred())
 			authentication, err := utils.GetAuthentication(ctx, cli)
@@ -195,11 +207,12 @@ var _ = Describe("authentication controller tests", func() {
 					components.ComponentDex.Version)))
 		})
 		It("should use images from imageset", func() {
+			dexDigest := "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
 			Expect(cli.Create(ctx, &operatorv1.ImageSet{
 				ObjectMeta: metav1.ObjectMeta{Name: "enterprise-" + components.EnterpriseRelease},
 				Spec: operatorv1.ImageSetSpec{
 					Images: []operatorv1.Image{
-						{Image: "tigera/dex", Digest: "sha256:dexhash"},
+						{Image: "tigera/dex", Digest: dexDigest},
 					},
 				},
 			})).ToNot(HaveOccurred())
@@ -227,7 +240,732 @@ var _ = Describe("authentication controller tests", func() {
 			Expect(apiserver.Image).To(Equal(
 				fmt.Sprintf("some.registry.org/%s@%s",
 					components.ComponentDex.Image,
-					"sha256:dexhash")))
+					dexDigest)))
+		})
+
+		It("should report the images that are missing from the imageset before rendering", func() {
+			Expect(cli.Create(ctx, &operatorv1.ImageSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "enterprise-" + components.EnterpriseRelease},
+				Spec: operatorv1.ImageSetSpec{
+					Images: []operatorv1.Image{
+						{Image: "tigera/key-cert-provisioner", Digest: "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"},
+					},
+				},
+			})).ToNot(HaveOccurred())
+
+			r := ReconcileAuthentication{
+				client:   cli,
+				scheme:   scheme,
+				provider: operatorv1.ProviderNone,
+				status:   mockStatus,
+			}
+			_, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).To(HaveOccurred())
+			mockStatus.AssertCalled(GinkgoT(), "SetDegraded", "Incomplete ImageSet",
+				"ImageSet enterprise-"+components.EnterpriseRelease+": missing images: tigera/dex")
+			Expect(test.GetResource(cli, &appsv1.Deployment{
+				TypeMeta:   metav1.TypeMeta{Kind: "Deployment", APIVersion: "v1"},
+				ObjectMeta: metav1.ObjectMeta{Name: render.DexObjectName, Namespace: render.DexNamespace},
+			})).NotTo(BeNil())
+		})
+
+		It("should emit an event on the Authentication for the images that are missing from the imageset", func() {
+			Expect(cli.Create(ctx, &operatorv1.ImageSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "enterprise-" + components.EnterpriseRelease},
+				Spec: operatorv1.ImageSetSpec{
+					Images: []operatorv1.Image{
+						{Image: "tigera/key-cert-provisioner", Digest: "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"},
+					},
+				},
+			})).ToNot(HaveOccurred())
+
+			recorder := record.NewFakeRecorder(10)
+			r := ReconcileAuthentication{
+				client:   cli,
+				scheme:   scheme,
+				provider: operatorv1.ProviderNone,
+				status:   mockStatus,
+				events:   utils.NewFailureEvents(recorder, utils.DefaultFailureEventInterval),
+			}
+			_, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).To(HaveOccurred())
+			Expect(recorder.Events).To(Receive(Equal("Warning ImageResolutionFailed component tigera-dex: ImageSet enterprise-" +
+				components.EnterpriseRelease + ": missing images: tigera/dex; add the images to the ImageSet")))
+
+			// The failure persists, but is not emitted again within the interval.
+			_, err = r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).To(HaveOccurred())
+			Expect(recorder.Events).NotTo(Receive())
+		})
+
+		It("should report the images that cannot be pinned without an imageset", func() {
+			authentication := &operatorv1.Authentication{}
+			Expect(cli.Get(ctx, client.ObjectKey{Name: "tigera-secure"}, authentication)).To(Succeed())
+			required := operatorv1.DexImageDigestsRequired
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{ImageDigests: &required}
+			Expect(cli.Update(ctx, authentication)).To(Succeed())
+
+			r := ReconcileAuthentication{
+				client:   cli,
+				scheme:   scheme,
+				provider: operatorv1.ProviderNone,
+				status:   mockStatus,
+			}
+			_, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).To(HaveOccurred())
+			mockStatus.AssertCalled(GinkgoT(), "SetDegraded", "Error with images from ImageSet",
+				"image tigera/dex (some.registry.org/tigera/dex): the image tigera/dex must be pinned by digest, but there is no ImageSet for the release "+components.EnterpriseRelease)
+		})
+	})
+
+	Context("dex prerequisites", func() {
+		BeforeEach(func() {
+			Expect(cli.Create(ctx, &operatorv1.Installation{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "default",
+				},
+				Status: operatorv1.InstallationStatus{
+					Variant:  operatorv1.TigeraSecureEnterprise,
+					Computed: &operatorv1.InstallationSpec{},
+				},
+				Spec: operatorv1.InstallationSpec{
+					Variant: operatorv1.TigeraSecureEnterprise,
+				},
+			})).ToNot(HaveOccurred())
+			Expect(cli.Create(ctx, idpSecret)).ToNot(HaveOccurred())
+			Expect(cli.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tigera-dex"}})).ToNot(HaveOccurred())
+			auth.Spec.OIDC = &operatorv1.AuthenticationOIDC{IssuerURL: "https://example.com", UsernameClaim: "email"}
+			auth.Spec.StaticClients = []operatorv1.StaticClient{{ID: "tigera-cli", SecretName: "tigera-cli-secret"}}
+			Expect(cli.Create(ctx, auth)).ToNot(HaveOccurred())
+		})
+
+		It("should wait for a missing static client secret before deploying dex", func() {
+			r := &ReconcileAuthentication{cli, scheme, operatorv1.ProviderNone, mockStatus, "", nil, nil}
+			result, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(result.RequeueAfter).NotTo(BeZero())
+			mockStatus.AssertCalled(GinkgoT(), "SetDegraded", "Waiting for the secrets referenced by Dex", "secret tigera-operator/tigera-cli-secret is missing")
+
+			d := appsv1.Deployment{
+				TypeMeta:   metav1.TypeMeta{Kind: "Deployment", APIVersion: "v1"},
+				ObjectMeta: metav1.ObjectMeta{Name: render.DexObjectName, Namespace: render.DexNamespace},
+			}
+			Expect(test.GetResource(cli, &d)).NotTo(BeNil())
+
+			Expect(cli.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "tigera-cli-secret", Namespace: rmeta.OperatorNamespace()},
+				Data:       map[string][]byte{render.ClientSecretSecretField: []byte("cli-secret")},
+			})).ToNot(HaveOccurred())
+			_, err = r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(test.GetResource(cli, &d)).To(BeNil())
+		})
+
+		It("should generate a missing static client secret once and keep it", func() {
+			Expect(cli.Get(ctx, client.ObjectKey{Name: auth.Name}, auth)).To(Succeed())
+			auth.Spec.StaticClients[0].GenerateSecret = true
+			Expect(cli.Update(ctx, auth)).To(Succeed())
+
+			r := &ReconcileAuthentication{cli, scheme, operatorv1.ProviderNone, mockStatus, "", nil, nil}
+			_, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			generated := &corev1.Secret{}
+			Expect(cli.Get(ctx, client.ObjectKey{Name: "tigera-cli-secret", Namespace: rmeta.OperatorNamespace()}, generated)).To(Succeed())
+			Expect(generated.Data[render.ClientSecretSecretField]).NotTo(BeEmpty())
+			copied := &corev1.Secret{}
+			Expect(cli.Get(ctx, client.ObjectKey{Name: "tigera-cli-secret", Namespace: render.DexNamespace}, copied)).To(Succeed())
+			Expect(copied.Data).To(Equal(generated.Data))
+
+			// The secret is reused rather than regenerated.
+			_, err = r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+			reused := &corev1.Secret{}
+			Expect(cli.Get(ctx, client.ObjectKey{Name: "tigera-cli-secret", Namespace: rmeta.OperatorNamespace()}, reused)).To(Succeed())
+			Expect(reused.Data).To(Equal(generated.Data))
+		})
+
+		It("should not generate a static client secret that exists", func() {
+			Expect(cli.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "tigera-cli-secret", Namespace: rmeta.OperatorNamespace()},
+				Data:       map[string][]byte{render.ClientSecretSecretField: []byte("cli-secret")},
+			})).ToNot(HaveOccurred())
+			Expect(cli.Get(ctx, client.ObjectKey{Name: auth.Name}, auth)).To(Succeed())
+			auth.Spec.StaticClients[0].GenerateSecret = true
+			Expect(cli.Update(ctx, auth)).To(Succeed())
+
+			r := &ReconcileAuthentication{cli, scheme, operatorv1.ProviderNone, mockStatus, "", nil, nil}
+			_, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+			copied := &corev1.Secret{}
+			Expect(cli.Get(ctx, client.ObjectKey{Name: "tigera-cli-secret", Namespace: render.DexNamespace}, copied)).To(Succeed())
+			Expect(copied.Data).To(HaveKeyWithValue(render.ClientSecretSecretField, []byte("cli-secret")))
+		})
+
+		It("should not deploy dex when the installation runs the control plane on windows nodes", func() {
+			Expect(cli.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "tigera-cli-secret", Namespace: rmeta.OperatorNamespace()},
+				Data:       map[string][]byte{render.ClientSecretSecretField: []byte("cli-secret")},
+			})).ToNot(HaveOccurred())
+			installation := &operatorv1.Installation{}
+			Expect(cli.Get(ctx, utils.DefaultInstanceKey, installation)).To(Succeed())
+			installation.Spec.ControlPlaneNodeSelector = map[string]string{"kubernetes.io/os": "windows"}
+			Expect(cli.Update(ctx, installation)).To(Succeed())
+
+			r := &ReconcileAuthentication{cli, scheme, operatorv1.ProviderNone, mockStatus, "", nil, nil}
+			result, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(result.RequeueAfter).NotTo(BeZero())
+			mockStatus.AssertCalled(GinkgoT(), "SetDegraded", "Waiting for Dex prerequisites",
+				"the component runs on linux nodes, but the cluster only has windows nodes")
+
+			d := appsv1.Deployment{
+				TypeMeta:   metav1.TypeMeta{Kind: "Deployment", APIVersion: "v1"},
+				ObjectMeta: metav1.ObjectMeta{Name: render.DexObjectName, Namespace: render.DexNamespace},
+			}
+			Expect(test.GetResource(cli, &d)).NotTo(BeNil())
+		})
+
+		It("should render the kubeconfig user for kubectl and remove it when it is no longer configured", func() {
+			Expect(cli.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "tigera-cli-secret", Namespace: rmeta.OperatorNamespace()},
+				Data:       map[string][]byte{render.ClientSecretSecretField: []byte("cli-secret")},
+			})).ToNot(HaveOccurred())
+			Expect(cli.Get(ctx, client.ObjectKey{Name: auth.Name}, auth)).To(Succeed())
+			auth.Spec.StaticClients = append(auth.Spec.StaticClients, operatorv1.StaticClient{ID: "kubectl", Public: true, RedirectURIs: []string{"http://localhost:8000"}})
+			auth.Spec.KubectlConfig = &operatorv1.KubectlConfig{ClientID: "kubectl", Namespace: "kube-public"}
+			Expect(cli.Update(ctx, auth)).To(Succeed())
+
+			r := &ReconcileAuthentication{cli, scheme, operatorv1.ProviderNone, mockStatus, "", nil, nil}
+			_, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+			key := client.ObjectKey{Name: "tigera-dex-kubectl", Namespace: "kube-public"}
+			cm := &corev1.ConfigMap{}
+			Expect(cli.Get(ctx, key, cm)).To(Succeed())
+			Expect(cm.Data).To(HaveKeyWithValue("issuer", "https://example.com/dex"))
+			Expect(cm.Data).To(HaveKeyWithValue("client-id", "kubectl"))
+
+			Expect(cli.Get(ctx, client.ObjectKey{Name: auth.Name}, auth)).To(Succeed())
+			auth.Spec.KubectlConfig = nil
+			Expect(cli.Update(ctx, auth)).To(Succeed())
+			_, err = r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(errors.IsNotFound(cli.Get(ctx, key, cm))).To(BeTrue())
+		})
+
+		It("should let the clients of dex trust the service CA of openshift once it is injected", func() {
+			Expect(cli.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "tigera-cli-secret", Namespace: rmeta.OperatorNamespace()},
+				Data:       map[string][]byte{render.ClientSecretSecretField: []byte("cli-secret")},
+			})).ToNot(HaveOccurred())
+			Expect(cli.Get(ctx, client.ObjectKey{Name: auth.Name}, auth)).To(Succeed())
+			auth.Spec.DexDeployment = &operatorv1.DexDeployment{ServiceServingCertificate: true}
+			Expect(cli.Update(ctx, auth)).To(Succeed())
+
+			r := &ReconcileAuthentication{cli, scheme, operatorv1.ProviderOpenShift, mockStatus, "", nil, nil}
+			result, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(result.RequeueAfter).NotTo(BeZero())
+			svc := &corev1.Service{}
+			Expect(cli.Get(ctx, client.ObjectKey{Name: render.DexObjectName, Namespace: render.DexNamespace}, svc)).To(Succeed())
+			Expect(svc.Annotations).To(HaveKeyWithValue(rmeta.OpenShiftServingCertAnnotation, "tigera-dex-serving-cert"))
+
+			// OpenShift injects the CA, which the clients of dex then trust.
+			cm := &corev1.ConfigMap{}
+			key := client.ObjectKey{Name: "tigera-dex-service-ca", Namespace: render.DexNamespace}
+			Expect(cli.Get(ctx, key, cm)).To(Succeed())
+			cm.Data = map[string]string{rmeta.OpenShiftServiceCAKey: "service-ca"}
+			Expect(cli.Update(ctx, cm)).To(Succeed())
+			result, err = r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeZero())
+			certSecret := &corev1.Secret{}
+			Expect(cli.Get(ctx, client.ObjectKey{Name: render.DexCertSecretName, Namespace: rmeta.OperatorNamespace()}, certSecret)).To(Succeed())
+			Expect(certSecret.Data).To(HaveKeyWithValue(corev1.TLSCertKey, []byte("service-ca")))
+			Expect(cli.Get(ctx, key, cm)).To(Succeed())
+			Expect(cm.Data).To(HaveKeyWithValue(rmeta.OpenShiftServiceCAKey, "service-ca"))
+		})
+
+		It("should let dex trust the CA certificates of the configured ConfigMaps for the identity provider", func() {
+			Expect(cli.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "tigera-cli-secret", Namespace: rmeta.OperatorNamespace()},
+				Data:       map[string][]byte{render.ClientSecretSecretField: []byte("cli-secret")},
+			})).ToNot(HaveOccurred())
+			Expect(cli.Get(ctx, client.ObjectKey{Name: auth.Name}, auth)).To(Succeed())
+			auth.Spec.DexDeployment = &operatorv1.DexDeployment{IdentityProviderCAConfigMaps: []string{"idp-ca"}}
+			Expect(cli.Update(ctx, auth)).To(Succeed())
+
+			// Dex is not rendered while the ConfigMap is missing.
+			r := &ReconcileAuthentication{cli, scheme, operatorv1.ProviderNone, mockStatus, "", nil, nil}
+			_, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).To(HaveOccurred())
+
+			ca, err := tls.MakeCA("idp")
+			Expect(err).ShouldNot(HaveOccurred())
+			cert, _, err := ca.Config.GetPEMBytes()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(cli.Create(ctx, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "idp-ca", Namespace: rmeta.OperatorNamespace()},
+				Data:       map[string]string{"ca.crt": string(cert)},
+			})).To(Succeed())
+			_, err = r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			bundle := &corev1.Secret{}
+			Expect(cli.Get(ctx, client.ObjectKey{Name: "tigera-dex-idp-ca-bundle", Namespace: render.DexNamespace}, bundle)).To(Succeed())
+			Expect(bundle.Data).To(HaveKeyWithValue(secret.CABundleKey, cert))
+			d := &appsv1.Deployment{}
+			Expect(cli.Get(ctx, client.ObjectKey{Name: render.DexObjectName, Namespace: render.DexNamespace}, d)).To(Succeed())
+			var items []corev1.KeyToPath
+			for _, v := range d.Spec.Template.Spec.Volumes {
+				if v.Secret != nil && v.Secret.SecretName == "tigera-dex-idp-ca-bundle" {
+					items = v.Secret.Items
+				}
+			}
+			Expect(items).To(Equal([]corev1.KeyToPath{{Key: secret.CABundleKey, Path: "idp.pem"}}))
+		})
+
+		It("should render the PrometheusRule of dex and remove it when it is no longer configured", func() {
+			Expect(cli.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "tigera-cli-secret", Namespace: rmeta.OperatorNamespace()},
+				Data:       map[string][]byte{render.ClientSecretSecretField: []byte("cli-secret")},
+			})).ToNot(HaveOccurred())
+			Expect(cli.Get(ctx, client.ObjectKey{Name: auth.Name}, auth)).To(Succeed())
+			auth.Spec.DexDeployment = &operatorv1.DexDeployment{MetricsService: true, PrometheusRule: &operatorv1.DexPrometheusRule{}}
+			Expect(cli.Update(ctx, auth)).To(Succeed())
+
+			r := &ReconcileAuthentication{cli, scheme, operatorv1.ProviderNone, mockStatus, "", nil, nil}
+			_, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+			rule := &unstructured.Unstructured{}
+			rule.SetGroupVersionKind(render.PrometheusRuleGVK)
+			key := client.ObjectKey{Name: render.DexObjectName, Namespace: "tigera-prometheus"}
+			Expect(cli.Get(ctx, key, rule)).To(Succeed())
+			Expect(rule.GetLabels()).To(HaveKeyWithValue(render.DexPrometheusRuleLabel, render.DexObjectName))
+
+			// The rule is updated on the next reconcile, and removed when the metrics of dex are turned off.
+			_, err = r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(cli.Get(ctx, client.ObjectKey{Name: auth.Name}, auth)).To(Succeed())
+			auth.Spec.DexDeployment = nil
+			Expect(cli.Update(ctx, auth)).To(Succeed())
+			_, err = r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(errors.IsNotFound(cli.Get(ctx, key, rule))).To(BeTrue())
+		})
+
+		It("should keep the discovery details of dex in sync in the discovery namespaces", func() {
+			Expect(cli.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "tigera-cli-secret", Namespace: rmeta.OperatorNamespace()},
+				Data:       map[string][]byte{render.ClientSecretSecretField: []byte("cli-secret")},
+			})).ToNot(HaveOccurred())
+			Expect(cli.Get(ctx, client.ObjectKey{Name: auth.Name}, auth)).To(Succeed())
+			auth.Spec.DiscoveryNamespaces = []string{"monitoring"}
+			Expect(cli.Update(ctx, auth)).To(Succeed())
+
+			r := &ReconcileAuthentication{cli, scheme, operatorv1.ProviderNone, mockStatus, "", nil, nil}
+			_, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+			key := client.ObjectKey{Name: "tigera-dex-oidc-info", Namespace: "monitoring"}
+			cm := &corev1.ConfigMap{}
+			Expect(cli.Get(ctx, key, cm)).To(Succeed())
+			Expect(cm.Data).To(HaveKeyWithValue("issuer", "https://example.com/dex"))
+			Expect(cm.Data).To(HaveKeyWithValue("client-id", render.DexClientId))
+
+			// A new manager domain is published on the next reconcile.
+			Expect(cli.Get(ctx, client.ObjectKey{Name: auth.Name}, auth)).To(Succeed())
+			auth.Spec.ManagerDomain = "https://other.example.com"
+			Expect(cli.Update(ctx, auth)).To(Succeed())
+			_, err = r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(cli.Get(ctx, key, cm)).To(Succeed())
+			Expect(cm.Data).To(HaveKeyWithValue("issuer", "https://other.example.com/dex"))
+
+			Expect(cli.Get(ctx, client.ObjectKey{Name: auth.Name}, auth)).To(Succeed())
+			auth.Spec.DiscoveryNamespaces = nil
+			Expect(cli.Update(ctx, auth)).To(Succeed())
+			_, err = r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(errors.IsNotFound(cli.Get(ctx, key, cm))).To(BeTrue())
+		})
+
+		It("should report the status of dex in the Authentication", func() {
+			Expect(cli.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "tigera-cli-secret", Namespace: rmeta.OperatorNamespace()},
+				Data:       map[string][]byte{render.ClientSecretSecretField: []byte("cli-secret")},
+			})).ToNot(HaveOccurred())
+			r := &ReconcileAuthentication{cli, scheme, operatorv1.ProviderNone, mockStatus, "", nil, nil}
+			_, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Expect(cli.Get(ctx, client.ObjectKey{Name: auth.Name}, auth)).To(Succeed())
+			Expect(auth.Status.IssuerURL).To(Equal("https://example.com/dex"))
+			Expect(auth.Status.ConnectorType).To(Equal("oidc"))
+			Expect(conditionStatuses(auth)).To(Equal(map[operatorv1.StatusConditionType]operatorv1.ConditionStatus{
+				operatorv1.ComponentAvailable:   operatorv1.ConditionTrue,
+				operatorv1.ComponentProgressing: operatorv1.ConditionFalse,
+				operatorv1.ComponentDegraded:    operatorv1.ConditionFalse,
+			}))
+			since := auth.Status.Conditions[0].LastTransitionTime
+
+			// The conditions only transition when their status changes.
+			_, err = r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(cli.Get(ctx, client.ObjectKey{Name: auth.Name}, auth)).To(Succeed())
+			Expect(auth.Status.Conditions[0].LastTransitionTime).To(Equal(since))
+		})
+
+		It("should report why dex is degraded in the Authentication", func() {
+			r := &ReconcileAuthentication{cli, scheme, operatorv1.ProviderNone, mockStatus, "", nil, nil}
+			_, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Expect(cli.Get(ctx, client.ObjectKey{Name: auth.Name}, auth)).To(Succeed())
+			Expect(conditionStatuses(auth)).To(HaveKeyWithValue(operatorv1.ComponentDegraded, operatorv1.ConditionTrue))
+			Expect(conditionStatuses(auth)).To(HaveKeyWithValue(operatorv1.ComponentProgressing, operatorv1.ConditionFalse))
+			for _, c := range auth.Status.Conditions {
+				if c.Type == operatorv1.ComponentDegraded {
+					Expect(c.Reason).To(Equal("Waiting for the secrets referenced by Dex"))
+					Expect(c.Message).To(Equal("secret tigera-operator/tigera-cli-secret is missing"))
+				}
+			}
+		})
+
+		It("should mount the credentials of the identity provider from a secrets store once it exists", func() {
+			Expect(cli.Get(ctx, client.ObjectKey{Name: auth.Name}, auth)).To(Succeed())
+			auth.Spec.StaticClients = nil
+			auth.Spec.DexDeployment = &operatorv1.DexDeployment{
+				IdentityProviderSecretsStore: &operatorv1.DexSecretsStore{SecretProviderClass: "oidc-credentials"},
+			}
+			Expect(cli.Update(ctx, auth)).To(Succeed())
+			Expect(cli.Delete(ctx, idpSecret)).To(Succeed())
+
+			r := &ReconcileAuthentication{cli, scheme, operatorv1.ProviderNone, mockStatus, "", nil, nil}
+			result, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(result.RequeueAfter).NotTo(BeZero())
+			mockStatus.AssertCalled(GinkgoT(), "SetDegraded", "Waiting for SecretProviderClass tigera-dex/oidc-credentials to be created", mock.Anything)
+
+			spc := &unstructured.Unstructured{}
+			spc.SetGroupVersionKind(schema.GroupVersionKind{Group: "secrets-store.csi.x-k8s.io", Version: "v1", Kind: "SecretProviderClass"})
+			spc.SetName("oidc-credentials")
+			spc.SetNamespace(render.DexNamespace)
+			Expect(cli.Create(ctx, spc)).To(Succeed())
+			_, err = r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			d := &appsv1.Deployment{}
+			Expect(cli.Get(ctx, client.ObjectKey{Name: render.DexObjectName, Namespace: render.DexNamespace}, d)).To(Succeed())
+			var volumes []string
+			for _, v := range d.Spec.Template.Spec.Volumes {
+				if v.CSI != nil {
+					volumes = append(volumes, v.CSI.VolumeAttributes["secretProviderClass"])
+				}
+			}
+			Expect(volumes).To(Equal([]string{"oidc-credentials"}))
+			Expect(cli.Get(ctx, client.ObjectKey{Name: render.OIDCSecretName, Namespace: render.DexNamespace}, &corev1.Secret{})).NotTo(Succeed())
+		})
+
+		It("should only render dex from one Authentication and promote another when it is deleted", func() {
+			Expect(cli.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "tigera-cli-secret", Namespace: rmeta.OperatorNamespace()},
+				Data:       map[string][]byte{render.ClientSecretSecretField: []byte("cli-secret")},
+			})).ToNot(HaveOccurred())
+			// An older Authentication does not take precedence over tigera-secure.
+			other := &operatorv1.Authentication{
+				ObjectMeta: metav1.ObjectMeta{Name: "team-auth", CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour))},
+				Spec:       *auth.Spec.DeepCopy(),
+			}
+			other.Spec.ManagerDomain = "https://team.example.com"
+			Expect(cli.Create(ctx, other)).ToNot(HaveOccurred())
+
+			r := &ReconcileAuthentication{cli, scheme, operatorv1.ProviderNone, mockStatus, "", nil, nil}
+			_, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Expect(cli.Get(ctx, client.ObjectKey{Name: "team-auth"}, other)).To(Succeed())
+			Expect(conditionStatuses(other)).To(Equal(map[operatorv1.StatusConditionType]operatorv1.ConditionStatus{
+				operatorv1.ComponentAvailable:   operatorv1.ConditionFalse,
+				operatorv1.ComponentProgressing: operatorv1.ConditionFalse,
+				operatorv1.ComponentDegraded:    operatorv1.ConditionTrue,
+			}))
+			Expect(other.Status.Conditions[2].Message).To(Equal("Only one Authentication is supported, Authentication tigera-secure is used instead"))
+			Expect(other.Status.IssuerURL).To(BeEmpty())
+			Expect(cli.Get(ctx, client.ObjectKey{Name: auth.Name}, auth)).To(Succeed())
+			Expect(auth.Status.IssuerURL).To(Equal("https://example.com/dex"))
+
+			d := &appsv1.Deployment{}
+			Expect(cli.Get(ctx, client.ObjectKey{Name: render.DexObjectName, Namespace: render.DexNamespace}, d)).To(Succeed())
+			Expect(d.OwnerReferences).To(HaveLen(1))
+			Expect(d.OwnerReferences[0].Name).To(Equal(auth.Name))
+
+			Expect(cli.Delete(ctx, auth)).To(Succeed())
+			_, err = r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Expect(cli.Get(ctx, client.ObjectKey{Name: "team-auth"}, other)).To(Succeed())
+			Expect(conditionStatuses(other)).To(HaveKeyWithValue(operatorv1.ComponentDegraded, operatorv1.ConditionFalse))
+			Expect(other.Status.IssuerURL).To(Equal("https://team.example.com/dex"))
+			Expect(cli.Get(ctx, client.ObjectKey{Name: render.DexObjectName, Namespace: render.DexNamespace}, d)).To(Succeed())
+			Expect(d.OwnerReferences[0].Name).To(Equal("team-auth"))
+		})
+
+		It("should render dex from the used Authentication when the status of an ignored one cannot be updated", func() {
+			Expect(cli.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "tigera-cli-secret", Namespace: rmeta.OperatorNamespace()},
+				Data:       map[string][]byte{render.ClientSecretSecretField: []byte("cli-secret")},
+			})).ToNot(HaveOccurred())
+			other := &operatorv1.Authentication{ObjectMeta: metav1.ObjectMeta{Name: "team-auth"}, Spec: *auth.Spec.DeepCopy()}
+			Expect(cli.Create(ctx, other)).ToNot(HaveOccurred())
+
+			failing := failingStatusClient{Client: cli, name: "team-auth"}
+			r := &ReconcileAuthentication{failing, scheme, operatorv1.ProviderNone, mockStatus, "", nil, nil}
+			_, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).To(MatchError("status update of team-auth failed"))
+
+			Expect(cli.Get(ctx, client.ObjectKey{Name: auth.Name}, auth)).To(Succeed())
+			Expect(auth.Status.IssuerURL).To(Equal("https://example.com/dex"))
+			Expect(cli.Get(ctx, client.ObjectKey{Name: render.DexObjectName, Namespace: render.DexNamespace}, &appsv1.Deployment{})).To(Succeed())
+
+			// The ignored Authentication reports its status once the update succeeds.
+			r = &ReconcileAuthentication{cli, scheme, operatorv1.ProviderNone, mockStatus, "", nil, nil}
+			_, err = r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(cli.Get(ctx, client.ObjectKey{Name: "team-auth"}, other)).To(Succeed())
+			Expect(conditionStatuses(other)).To(HaveKeyWithValue(operatorv1.ComponentDegraded, operatorv1.ConditionTrue))
+		})
+
+		It("should reject a static client secret without a client secret", func() {
+			Expect(cli.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "tigera-cli-secret", Namespace: rmeta.OperatorNamespace()},
+				Data:       map[string][]byte{"notes": []byte("the secret is elsewhere")},
+			})).ToNot(HaveOccurred())
+			r := &ReconcileAuthentication{cli, scheme, operatorv1.ProviderNone, mockStatus, "", nil, nil}
+			_, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).To(MatchError(`spec.staticClients[0].secretName: Invalid value: "tigera-cli-secret": secret tigera-operator/tigera-cli-secret has no clientSecret`))
+			mockStatus.AssertCalled(GinkgoT(), "SetDegraded", "Invalid Authentication provided", err.Error())
+
+			d := appsv1.Deployment{
+				TypeMeta:   metav1.TypeMeta{Kind: "Deployment", APIVersion: "v1"},
+				ObjectMeta: metav1.ObjectMeta{Name: render.DexObjectName, Namespace: render.DexNamespace},
+			}
+			Expect(test.GetResource(cli, &d)).NotTo(BeNil())
+		})
+
+		It("should reject static clients with the same ID as an invalid dex configuration", func() {
+			Expect(cli.Get(ctx, client.ObjectKey{Name: auth.Name}, auth)).To(Succeed())
+			auth.Spec.StaticClients = []operatorv1.StaticClient{{ID: "tigera-cli", Public: true}, {ID: "tigera-cli", Public: true}}
+			Expect(cli.Update(ctx, auth)).To(Succeed())
+			r := &ReconcileAuthentication{cli, scheme, operatorv1.ProviderNone, mockStatus, "", nil, nil}
+			_, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).To(MatchError(ContainSubstring("static client ID tigera-cli is not unique")))
+			mockStatus.AssertCalled(GinkgoT(), "SetDegraded", "Invalid Dex configuration", err.Error())
+		})
+
+		It("should set the Authentication as the owner of the namespaced and cluster-scoped dex objects", func() {
+			Expect(cli.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "tigera-cli-secret", Namespace: rmeta.OperatorNamespace()},
+				Data:       map[string][]byte{render.ClientSecretSecretField: []byte("cli-secret")},
+			})).ToNot(HaveOccurred())
+			r := &ReconcileAuthentication{cli, scheme, operatorv1.ProviderNone, mockStatus, "", nil, nil}
+			_, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			d := &appsv1.Deployment{}
+			Expect(cli.Get(ctx, client.ObjectKey{Name: render.DexObjectName, Namespace: render.DexNamespace}, d)).To(Succeed())
+			cr := &rbacv1.ClusterRole{}
+			Expect(cli.Get(ctx, client.ObjectKey{Name: render.DexObjectName}, cr)).To(Succeed())
+			for _, refs := range [][]metav1.OwnerReference{d.OwnerReferences, cr.OwnerReferences} {
+				Expect(refs).To(HaveLen(1))
+				Expect(refs[0].Kind).To(Equal("Authentication"))
+				Expect(refs[0].Name).To(Equal("tigera-secure"))
+			}
+		})
+
+		DescribeTable("should apply the CRDs of the dex storage when the operator manages them", func(deleteCRDs bool) {
+			Expect(cli.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "tigera-cli-secret", Namespace: rmeta.OperatorNamespace()},
+				Data:       map[string][]byte{render.ClientSecretSecretField: []byte("cli-secret")},
+			})).ToNot(HaveOccurred())
+			crds := operatorv1.DexStorageCRDsOperator
+			Expect(cli.Get(ctx, client.ObjectKey{Name: auth.Name}, auth)).To(Succeed())
+			auth.Spec.DexDeployment = &operatorv1.DexDeployment{StorageCRDs: &crds, DeleteStorageCRDs: deleteCRDs}
+			Expect(cli.Update(ctx, auth)).To(Succeed())
+
+			r := &ReconcileAuthentication{cli, scheme, operatorv1.ProviderNone, mockStatus, "", nil, nil}
+			_, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			crd := &apiextensionsv1.CustomResourceDefinition{}
+			Expect(cli.Get(ctx, client.ObjectKey{Name: "refreshtokens.dex.coreos.com"}, crd)).To(Succeed())
+			if deleteCRDs {
+				Expect(crd.OwnerReferences).To(HaveLen(1))
+				Expect(crd.OwnerReferences[0].Kind).To(Equal("Authentication"))
+			} else {
+				// Without an owner, the CRDs and the state of dex outlive the Authentication.
+				Expect(crd.OwnerReferences).To(BeEmpty())
+			}
+			cr := &rbacv1.ClusterRole{}
+			Expect(cli.Get(ctx, client.ObjectKey{Name: render.DexObjectName}, cr)).To(Succeed())
+			for _, rule := range cr.Rules {
+				Expect(rule.Resources).NotTo(ContainElement("customresourcedefinitions"))
+			}
+		},
+			Entry("kept when the Authentication is deleted", false),
+			Entry("deleted with the Authentication", true),
+		)
+
+		It("should remove the secrets copied into the dex namespace that are no longer needed", func() {
+			Expect(cli.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "tigera-cli-secret", Namespace: rmeta.OperatorNamespace()},
+				Data:       map[string][]byte{render.ClientSecretSecretField: []byte("cli-secret")},
+			})).ToNot(HaveOccurred())
+			Expect(cli.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "removed-pull-secret",
+					Namespace: render.DexNamespace,
+					Labels:    map[string]string{secret.CopiedFromLabel: rmeta.OperatorNamespace()},
+				},
+			})).ToNot(HaveOccurred())
+			Expect(cli.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "user-secret", Namespace: render.DexNamespace},
+			})).ToNot(HaveOccurred())
+			r := &ReconcileAuthentication{cli, scheme, operatorv1.ProviderNone, mockStatus, "", nil, nil}
+			_, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			s := &corev1.Secret{}
+			err = cli.Get(ctx, client.ObjectKey{Name: "removed-pull-secret", Namespace: render.DexNamespace}, s)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			Expect(cli.Get(ctx, client.ObjectKey{Name: "user-secret", Namespace: render.DexNamespace}, s)).To(Succeed())
+			Expect(cli.Get(ctx, client.ObjectKey{Name: "tigera-cli-secret", Namespace: render.DexNamespace}, s)).To(Succeed())
+			Expect(s.Labels).To(HaveKeyWithValue(secret.CopiedFromLabel, rmeta.OperatorNamespace()))
+		})
+
+		It("should rotate the client secret of dex and its clients on request", func() {
+			Expect(cli.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "tigera-cli-secret", Namespace: rmeta.OperatorNamespace()},
+				Data:       map[string][]byte{render.ClientSecretSecretField: []byte("cli-secret")},
+			})).ToNot(HaveOccurred())
+			r := &ReconcileAuthentication{cli, scheme, operatorv1.ProviderNone, mockStatus, "", nil, nil}
+			clientSecret := func(namespace string) string {
+				s := &corev1.Secret{}
+				Expect(cli.Get(ctx, client.ObjectKey{Name: render.DexObjectName, Namespace: namespace}, s)).To(Succeed())
+				return string(s.Data[render.ClientSecretSecretField])
+			}
+			secretHash := func() string {
+				d := &appsv1.Deployment{}
+				Expect(cli.Get(ctx, client.ObjectKey{Name: render.DexObjectName, Namespace: render.DexNamespace}, d)).To(Succeed())
+				return d.Spec.Template.Annotations["hash.operator.tigera.io/tigera-dex-secret"]
+			}
+			rotate := func(rotation string) {
+				Expect(cli.Get(ctx, client.ObjectKey{Name: auth.Name}, auth)).To(Succeed())
+				auth.Annotations = map[string]string{render.DexClientSecretRotationAnnotation: rotation}
+				Expect(cli.Update(ctx, auth)).To(Succeed())
+				_, err := r.Reconcile(ctx, reconcile.Request{})
+				Expect(err).ShouldNot(HaveOccurred())
+			}
+
+			_, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+			original, originalHash := clientSecret(rmeta.OperatorNamespace()), secretHash()
+			Expect(original).To(HaveLen(24))
+
+			rotate("1")
+			rotated := clientSecret(rmeta.OperatorNamespace())
+			Expect(rotated).NotTo(Equal(original))
+			Expect(clientSecret(render.DexNamespace)).To(Equal(rotated))
+			Expect(secretHash()).NotTo(Equal(originalHash))
+
+			// The secret is only rotated again when the rotation changes.
+			rotate("1")
+			Expect(clientSecret(rmeta.OperatorNamespace())).To(Equal(rotated))
+			rotate("2")
+			Expect(clientSecret(rmeta.OperatorNamespace())).NotTo(Equal(rotated))
+		})
+
+		DescribeTable("should move dex to the configured namespace and remove it from the previous one", func(clusterDomain string) {
+			mockStatus.On("RemoveDeployments", mock.Anything).Return()
+			Expect(cli.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "tigera-cli-secret", Namespace: rmeta.OperatorNamespace()},
+				Data:       map[string][]byte{render.ClientSecretSecretField: []byte("cli-secret")},
+			})).ToNot(HaveOccurred())
+			r := &ReconcileAuthentication{cli, scheme, operatorv1.ProviderNone, mockStatus, clusterDomain, nil, nil}
+			_, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(cli.Get(ctx, client.ObjectKey{Name: auth.Name}, auth)).To(Succeed())
+			Expect(auth.Status.DexNamespace).To(Equal(render.DexNamespace))
+
+			Expect(cli.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-dex"}})).ToNot(HaveOccurred())
+			auth.Spec.DexDeployment = &operatorv1.DexDeployment{Namespace: "team-dex"}
+			Expect(cli.Update(ctx, auth)).To(Succeed())
+			_, err = r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			d := &appsv1.Deployment{}
+			Expect(cli.Get(ctx, client.ObjectKey{Name: render.DexObjectName, Namespace: "team-dex"}, d)).To(Succeed())
+			err = cli.Get(ctx, client.ObjectKey{Name: render.DexObjectName, Namespace: render.DexNamespace}, d)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			tlsSecret := &corev1.Secret{}
+			Expect(cli.Get(ctx, client.ObjectKey{Name: render.DexTLSSecretName, Namespace: "team-dex"}, tlsSecret)).To(Succeed())
+			issuer, err := utils.GetCertificateIssuer(tlsSecret.Data[corev1.TLSCertKey])
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(issuer).To(Equal("tigera-dex.team-dex.svc.cluster.local"))
+
+			Expect(cli.Get(ctx, client.ObjectKey{Name: auth.Name}, auth)).To(Succeed())
+			Expect(auth.Status.DexNamespace).To(Equal("team-dex"))
+		},
+			Entry("with the cluster domain", "cluster.local"),
+			// The certificate is created for the normalized cluster domain, like the URLs of Dex.
+			Entry("with a fully qualified cluster domain", "cluster.local."),
+		)
+		It("should regenerate the dex certificate when the ClusterIP of the dex service changes", func() {
+			Expect(cli.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "tigera-cli-secret", Namespace: rmeta.OperatorNamespace()},
+				Data:       map[string][]byte{render.ClientSecretSecretField: []byte("cli-secret")},
+			})).ToNot(HaveOccurred())
+			auth.Spec.DexDeployment = &operatorv1.DexDeployment{CertificateIPAddresses: []string{"fd00::10"}}
+			Expect(cli.Update(ctx, auth)).To(Succeed())
+			r := &ReconcileAuthentication{cli, scheme, operatorv1.ProviderNone, mockStatus, "cluster.local", nil, nil}
+
+			certIPAddresses := func() []string {
+				tlsSecret := &corev1.Secret{}
+				ExpectWithOffset(1, cli.Get(ctx, client.ObjectKey{Name: render.DexTLSSecretName, Namespace: rmeta.OperatorNamespace()}, tlsSecret)).To(Succeed())
+				ips, err := utils.GetCertificateIPAddresses(tlsSecret.Data[corev1.TLSCertKey])
+				ExpectWithOffset(1, err).ShouldNot(HaveOccurred())
+				var addresses []string
+				for _, ip := range ips {
+					addresses = append(addresses, ip.String())
+				}
+				return addresses
+			}
+			setClusterIP := func(clusterIP string) {
+				svc := &corev1.Service{}
+				ExpectWithOffset(1, cli.Get(ctx, client.ObjectKey{Name: render.DexObjectName, Namespace: render.DexNamespace}, svc)).To(Succeed())
+				svc.Spec.ClusterIP = clusterIP
+				ExpectWithOffset(1, cli.Update(ctx, svc)).To(Succeed())
+				_, err := r.Reconcile(ctx, reconcile.Request{})
+				ExpectWithOffset(1, err).ShouldNot(HaveOccurred())
+			}
+
+			_, err := r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(certIPAddresses()).To(Equal([]string{"fd00::10"}))
+
+			setClusterIP("10.96.0.10")
+			Expect(certIPAddresses()).To(Equal([]string{"10.96.0.10", "fd00::10"}))
+
+			tlsSecret := &corev1.Secret{}
+			Expect(cli.Get(ctx, client.ObjectKey{Name: render.DexTLSSecretName, Namespace: rmeta.OperatorNamespace()}, tlsSecret)).To(Succeed())
+			_, err = r.Reconcile(ctx, reconcile.Request{})
+			Expect(err).ShouldNot(HaveOccurred())
+			unchanged := &corev1.Secret{}
+			Expect(cli.Get(ctx, client.ObjectKey{Name: render.DexTLSSecretName, Namespace: rmeta.OperatorNamespace()}, unchanged)).To(Succeed())
+			Expect(unchanged.Data).To(Equal(tlsSecret.Data))
+
+			setClusterIP("fd00::20")
+			Expect(certIPAddresses()).To(Equal([]string{"fd00::20", "fd00::10"}))
 		})
 	})
 
@@ -239,6 +977,7 @@ var _ = Describe("authentication controller tests", func() {
 		validFilter   = "(objectClass=posixGroup)"
 		invalidFilter = "(objectClass=posixGroup)pancake"
 		attribute     = "uid"
+		host          = "ldap.example.com:636"
 	)
 	DescribeTable("LDAP connector config options should be validated", func(ldap *operatorv1.AuthenticationLDAP, secretDN, secretPW, secretCA []byte, expectReconcilePass bool) {
 		// Apply prerequisites for the basic reconcile to succeed.
@@ -266,7 +1005,7 @@ var _ = Describe("authentication controller tests", func() {
 		Expect(cli.Create(ctx, idpSecret)).ToNot(HaveOccurred())
 		Expect(cli.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tigera-dex"}})).ToNot(HaveOccurred())
 		Expect(cli.Create(ctx, auth)).ToNot(HaveOccurred())
-		r := &ReconcileAuthentication{cli, scheme, operatorv1.ProviderNone, mockStatus, ""}
+		r := &ReconcileAuthentication{cli, scheme, operatorv1.ProviderNone, mockStatus, "", nil, nil}
 		_, err := r.Reconcile(ctx, reconcile.Request{})
 		if expectReconcilePass {
 			Expect(err).ToNot(HaveOccurred())
@@ -281,62 +1020,73 @@ var _ = Describe("authentication controller tests", func() {
 	},
 		Entry("Proper configuration",
 			&operatorv1.AuthenticationLDAP{
+				Host:        host,
 				UserSearch:  &operatorv1.UserSearch{BaseDN: validDN, Filter: validFilter, NameAttribute: attribute},
 				GroupSearch: &operatorv1.GroupSearch{BaseDN: validDN, Filter: validFilter, UserMatchers: []operatorv1.UserMatch{{UserAttribute: attribute, GroupAttribute: attribute}}}},
 			[]byte(validDN), []byte(validPW), []byte(validCA),
 			true),
 		Entry("Proper configuration w/o name attribute",
 			&operatorv1.AuthenticationLDAP{
+				Host:        host,
 				UserSearch:  &operatorv1.UserSearch{BaseDN: validDN, Filter: validFilter},
 				GroupSearch: &operatorv1.GroupSearch{BaseDN: validDN, Filter: validFilter, UserMatchers: []operatorv1.UserMatch{{UserAttribute: attribute, GroupAttribute: attribute}}}},
 			[]byte(validDN), []byte(validPW), []byte(validCA),
 			true),
 		Entry("Proper configuration w/o groupSearch",
 			&operatorv1.AuthenticationLDAP{
+				Host:       host,
 				UserSearch: &operatorv1.UserSearch{BaseDN: validDN, Filter: validFilter, NameAttribute: attribute}},
 			[]byte(validDN), []byte(validPW), []byte(validCA),
 			true),
 		Entry("Wrong DN in secret",
 			&operatorv1.AuthenticationLDAP{
+				Host:       host,
 				UserSearch: &operatorv1.UserSearch{BaseDN: validDN, Filter: validFilter, NameAttribute: attribute}},
 			[]byte(invalidDN), []byte(validPW), []byte(validCA),
 			false),
 		Entry("Missing PW in secret",
 			&operatorv1.AuthenticationLDAP{
+				Host:       host,
 				UserSearch: &operatorv1.UserSearch{BaseDN: validDN, Filter: validFilter, NameAttribute: attribute}},
 			[]byte(validDN), []byte(""), []byte(validCA),
 			false),
 		Entry("Missing CA field in secret",
 			&operatorv1.AuthenticationLDAP{
+				Host:       host,
 				UserSearch: &operatorv1.UserSearch{BaseDN: validDN, Filter: validFilter, NameAttribute: attribute}},
 			[]byte(validDN), []byte(validPW), []byte(""),
 			false),
 		Entry("Wrong DN in LDAP spec",
 			&operatorv1.AuthenticationLDAP{
+				Host:        host,
 				UserSearch:  &operatorv1.UserSearch{BaseDN: validDN, Filter: validFilter, NameAttribute: attribute},
 				GroupSearch: &operatorv1.GroupSearch{BaseDN: validDN, Filter: validFilter, UserMatchers: []operatorv1.UserMatch{{UserAttribute: attribute, GroupAttribute: attribute}}}},
 			[]byte(invalidDN), []byte(validPW), []byte(validCA),
 			false),
 		Entry("Wrong filter in LDAP userSearch spec",
 			&operatorv1.AuthenticationLDAP{
+				Host:        host,
 				UserSearch:  &operatorv1.UserSearch{BaseDN: validDN, Filter: invalidFilter, NameAttribute: attribute},
 				GroupSearch: &operatorv1.GroupSearch{BaseDN: validDN, Filter: validFilter, UserMatchers: []operatorv1.UserMatch{{UserAttribute: attribute, GroupAttribute: attribute}}}},
 			[]byte(validDN), []byte(validPW), []byte(validCA),
 			false),
 		Entry("Proper spec, filter omitted in userSearch spec",
 			&operatorv1.AuthenticationLDAP{
+				Host:        host,
 				UserSearch:  &operatorv1.UserSearch{BaseDN: validDN, NameAttribute: attribute},
 				GroupSearch: &operatorv1.GroupSearch{BaseDN: validDN, Filter: validFilter, UserMatchers: []operatorv1.UserMatch{{UserAttribute: attribute, GroupAttribute: attribute}}}},
 			[]byte(validDN), []byte(validPW), []byte(validCA),
 			true),
 		Entry("Wrong filter in LDAP groupSearch spec",
 			&operatorv1.AuthenticationLDAP{
+				Host:        host,
 				UserSearch:  &operatorv1.UserSearch{BaseDN: validDN, Filter: validFilter, NameAttribute: attribute},
 				GroupSearch: &operatorv1.GroupSearch{BaseDN: validDN, Filter: invalidFilter, UserMatchers: []operatorv1.UserMatch{{UserAttribute: attribute, GroupAttribute: attribute}}}},
 			[]byte(validDN), []byte(validPW), []byte(validCA),
 			false),
 		Entry("Proper spec, filter omitted in groupSearch spec",
 			&operatorv1.AuthenticationLDAP{
+				Host:        host,
 				UserSearch:  &operatorv1.UserSearch{BaseDN: validDN, Filter: validFilter, NameAttribute: attribute},
 				GroupSearch: &operatorv1.GroupSearch{BaseDN: validDN, UserMatchers: []operatorv1.UserMatch{{UserAttribute: attribute, GroupAttribute: attribute}}}},
 			[]byte(validDN), []byte(validPW), []byte(validCA),
@@ -345,14 +1095,14 @@ var _ = Describe("authentication controller tests", func() {
 	var (
 		iss  = "https://issuer.com"
 		ocp  = &operatorv1.AuthenticationOpenshift{IssuerURL: iss}
-		ldap = &operatorv1.AuthenticationLDAP{UserSearch: &operatorv1.UserSearch{BaseDN: validDN}}
+		ldap = &operatorv1.AuthenticationLDAP{Host: host, UserSearch: &operatorv1.UserSearch{BaseDN: validDN}}
 		oidc = &operatorv1.AuthenticationOIDC{IssuerURL: iss, UsernameClaim: "email"}
 	)
 	DescribeTable("should validate the authentication spec", func(auth *operatorv1.Authentication, expectPass bool) {
 		if expectPass {
-			Expect(validateAuthentication(auth)).NotTo(HaveOccurred())
+			Expect(render.ValidateAuthentication(auth).ToAggregate()).NotTo(HaveOccurred())
 		} else {
-			Expect(validateAuthentication(auth)).To(HaveOccurred())
+			Expect(render.ValidateAuthentication(auth).ToAggregate()).To(HaveOccurred())
 		}
 	},
 		Entry("Expect single Openshift config to pass validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{Openshift: ocp}}, true),
@@ -363,10 +1113,93 @@ var _ = Describe("authentication controller tests", func() {
 		Entry("Expect three configs to fail validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: oidc, LDAP: ldap, Openshift: ocp}}, false),
 		Entry("Expect prompt type to be used without other values", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: copyAndAddPromptTypes(oidc, []operatorv1.PromptType{operatorv1.PromptTypeNone})}}, true),
 		Entry("Expect prompt type to fail when none is combined", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: copyAndAddPromptTypes(oidc, []operatorv1.PromptType{operatorv1.PromptTypeNone, operatorv1.PromptTypeLogin})}}, false),
+		Entry("Expect unknown prompt types to fail validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: copyAndAddPromptTypes(oidc, []operatorv1.PromptType{"Always"})}}, false),
 		Entry("Expect prompt type to be able to be combined", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: copyAndAddPromptTypes(oidc, []operatorv1.PromptType{operatorv1.PromptTypeSelectAccount, operatorv1.PromptTypeLogin})}}, true),
+		Entry("Expect known grant types to pass validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: oidc, ManagerClient: &operatorv1.ManagerClient{GrantTypes: []operatorv1.GrantType{operatorv1.GrantTypeAuthorizationCode, operatorv1.GrantTypeDeviceCode}}}}, true),
+		Entry("Expect unknown grant types to fail validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: oidc, ManagerClient: &operatorv1.ManagerClient{GrantTypes: []operatorv1.GrantType{"Password"}}}}, false),
+		Entry("Expect an http issuer to fail validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: &operatorv1.AuthenticationOIDC{IssuerURL: "http://issuer.com", UsernameClaim: "email"}}}, false),
+		Entry("Expect an http Openshift issuer to fail validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{Openshift: &operatorv1.AuthenticationOpenshift{IssuerURL: "http://issuer.com"}}}, false),
+		Entry("Expect an issuer alias to pass validation when aliasing is allowed", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{AllowIssuerAliasing: true, OIDC: &operatorv1.AuthenticationOIDC{IssuerURL: iss, IssuerAlias: "https://broker.com", UsernameClaim: "email"}}}, true),
+		Entry("Expect an issuer alias to fail validation when aliasing is not allowed", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: &operatorv1.AuthenticationOIDC{IssuerURL: iss, IssuerAlias: "https://broker.com", UsernameClaim: "email"}}}, false),
+		Entry("Expect an http issuer alias to fail validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{AllowIssuerAliasing: true, OIDC: &operatorv1.AuthenticationOIDC{IssuerURL: iss, IssuerAlias: "http://broker.com", UsernameClaim: "email"}}}, false),
+		Entry("Expect a public static client to pass validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: oidc, StaticClients: []operatorv1.StaticClient{{ID: "cli", Public: true}}}}, true),
+		Entry("Expect a static client with a secret to pass validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: oidc, StaticClients: []operatorv1.StaticClient{{ID: "cli", SecretName: "cli-secret"}}}}, true),
+		Entry("Expect a public static client with a secret to fail validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: oidc, StaticClients: []operatorv1.StaticClient{{ID: "cli", Public: true, SecretName: "cli-secret"}}}}, false),
+		Entry("Expect a static client without a secret to fail validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: oidc, StaticClients: []operatorv1.StaticClient{{ID: "cli"}}}}, false),
+		Entry("Expect an LDAP config without a host to fail validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{LDAP: &operatorv1.AuthenticationLDAP{UserSearch: &operatorv1.UserSearch{BaseDN: validDN}}}}, false),
+		Entry("Expect an LDAP config without a user search to fail validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{LDAP: &operatorv1.AuthenticationLDAP{Host: host}}}, false),
+		Entry("Expect an empty username claim to pass validation, since it defaults to email", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: &operatorv1.AuthenticationOIDC{IssuerURL: iss}}}, true),
+		Entry("Expect a username claim that Dex issues to pass validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: &operatorv1.AuthenticationOIDC{IssuerURL: iss, UsernameClaim: "preferred_username"}}}, true),
+		Entry("Expect a username claim that Dex does not issue to fail validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: &operatorv1.AuthenticationOIDC{IssuerURL: iss, UsernameClaim: "upn"}}}, false),
+		Entry("Expect a relative redirect URI to fail validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: oidc, StaticClients: []operatorv1.StaticClient{{ID: "cli", Public: true, RedirectURIs: []string{"/callback"}}}}}, false),
+		Entry("Expect connector scopes with openid to pass validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: &operatorv1.AuthenticationOIDC{IssuerURL: iss, UsernameClaim: "email", ConnectorScopes: []string{"openid", "groups"}}}}, true),
+		Entry("Expect connector scopes without openid to fail validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: &operatorv1.AuthenticationOIDC{IssuerURL: iss, UsernameClaim: "email", ConnectorScopes: []string{"groups"}}}}, false),
+		Entry("Expect an unknown trusted peer to fail validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: oidc, StaticClients: []operatorv1.StaticClient{{ID: "cli", Public: true, TrustedPeers: []string{"other"}}}}}, false),
+		Entry("Expect a public static client that generates a secret to fail validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: oidc, StaticClients: []operatorv1.StaticClient{{ID: "cli", Public: true, GenerateSecret: true}}}}, false),
+		Entry("Expect kubectl to log in as a public static client to pass validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: oidc, StaticClients: []operatorv1.StaticClient{{ID: "kubectl", Public: true}}, KubectlConfig: &operatorv1.KubectlConfig{ClientID: "kubectl"}}}, true),
+		Entry("Expect kubectl to log in as an unknown client to fail validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: oidc, KubectlConfig: &operatorv1.KubectlConfig{ClientID: "kubectl"}}}, false),
+		Entry("Expect kubectl to log in as a confidential static client to fail validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: oidc, StaticClients: []operatorv1.StaticClient{{ID: "kubectl", SecretName: "kubectl-secret"}}, KubectlConfig: &operatorv1.KubectlConfig{ClientID: "kubectl"}}}, false),
+		Entry("Expect a label of spot instances to pass validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: oidc, DexDeployment: &operatorv1.DexDeployment{AvoidSpotNodes: &operatorv1.DexSpotNodes{Key: "eks.amazonaws.com/capacityType", Value: "SPOT"}}}}, true),
+		Entry("Expect an invalid label value of spot instances to fail validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: oidc, DexDeployment: &operatorv1.DexDeployment{AvoidSpotNodes: &operatorv1.DexSpotNodes{Key: "cloud.google.com/gke-spot", Value: "spot instance"}}}}, false),
+		Entry("Expect service account labels and annotations to pass validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: oidc, DexDeployment: &operatorv1.DexDeployment{ServiceAccountLabels: map[string]string{"team": "identity"}, ServiceAccountAnnotations: map[string]string{"eks.amazonaws.com/role-arn": "arn:aws:iam::123456789012:role/dex"}}}}, true),
+		Entry("Expect an invalid service account label value to fail validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: oidc, DexDeployment: &operatorv1.DexDeployment{ServiceAccountLabels: map[string]string{"team": "identity provider"}}}}, false),
+		Entry("Expect an invalid service account annotation key to fail validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: oidc, DexDeployment: &operatorv1.DexDeployment{ServiceAccountAnnotations: map[string]string{"vault role": "dex"}}}}, false),
+		Entry("Expect a PrometheusRule with the metrics service to pass validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: oidc, DexDeployment: &operatorv1.DexDeployment{MetricsService: true, PrometheusRule: &operatorv1.DexPrometheusRule{Namespace: "monitoring"}}}}, true),
+		Entry("Expect a PrometheusRule without the metrics service to fail validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: oidc, DexDeployment: &operatorv1.DexDeployment{PrometheusRule: &operatorv1.DexPrometheusRule{}}}}, false),
+		Entry("Expect discovery namespaces to pass validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: oidc, DiscoveryNamespaces: []string{"monitoring", "kube-public"}}}, true),
+		Entry("Expect an invalid discovery namespace to fail validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: oidc, DiscoveryNamespaces: []string{"Monitoring"}}}, false),
+		Entry("Expect a duplicate discovery namespace to fail validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: oidc, DiscoveryNamespaces: []string{"monitoring", "monitoring"}}}, false),
+		Entry("Expect a secrets store to pass validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: oidc, DexDeployment: &operatorv1.DexDeployment{IdentityProviderSecretsStore: &operatorv1.DexSecretsStore{SecretProviderClass: "oidc-credentials"}}}}, true),
+		Entry("Expect a secrets store with an invalid SecretProviderClass name to fail validation", &operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{OIDC: oidc, DexDeployment: &operatorv1.DexDeployment{IdentityProviderSecretsStore: &operatorv1.DexSecretsStore{SecretProviderClass: "OIDC_credentials"}}}}, false),
 	)
+
+	It("should report every problem with the path of its field", func() {
+		err := render.ValidateAuthentication(&operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{
+			OIDC: &operatorv1.AuthenticationOIDC{IssuerURL: "http://issuer.com", UsernameClaim: "upn", PromptTypes: []operatorv1.PromptType{"Always"}},
+			LDAP: ldap,
+			StaticClients: []operatorv1.StaticClient{
+				{ID: "cli", Public: true, SecretName: "cli-secret"},
+			},
+		}}).ToAggregate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("spec: Forbidden: only 1 identity provider connector is allowed, but spec.oidc, spec.ldap are specified"))
+		Expect(err.Error()).To(ContainSubstring(`spec.oidc.issuerURL: Invalid value: "http://issuer.com": "http://issuer.com" is not an https URL`))
+		Expect(err.Error()).To(ContainSubstring(`spec.oidc.usernameClaim: Unsupported value: "upn"`))
+		Expect(err.Error()).To(ContainSubstring(`spec.oidc.promptTypes[0]: Unsupported value: "Always"`))
+		Expect(err.Error()).To(ContainSubstring("spec.staticClients[0].secretName: Forbidden: a public static client must not have a secretName"))
+	})
 })
 
+// failingStatusClient fails the status updates of the object with the given name.
+type failingStatusClient struct {
+	client.Client
+	name string
+}
+
+func (c failingStatusClient) Status() client.StatusWriter {
+	return failingStatusWriter{c.Client.Status(), c.name}
+}
+
+type failingStatusWriter struct {
+	client.StatusWriter
+	name string
+}
+
+func (w failingStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if obj.GetName() == w.name {
+		return fmt.Errorf("status update of %s failed", w.name)
+	}
+	return w.StatusWriter.Update(ctx, obj, opts...)
+}
+
+func conditionStatuses(auth *operatorv1.Authentication) map[operatorv1.StatusConditionType]operatorv1.ConditionStatus {
+	statuses := map[operatorv1.StatusConditionType]operatorv1.ConditionStatus{}
+	for _, c := range auth.Status.Conditions {
+		statuses[c.Type] = c.Status
+	}
+	return statuses
+}
+
 func copyAndAddPromptTypes(auth *operatorv1.AuthenticationOIDC, promptTypes []operatorv1.PromptType) *operatorv1.AuthenticationOIDC {
 	copy := auth.DeepCopy()
 	copy.PromptTypes = promptTypes
@@ -20,6 +20,8 @@ import (
 	. "github.com/onsi/ginkgo"
 	"github.com/onsi/ginkgo/reporters"
 	. "github.com/onsi/gomega"
+
+	"github.com/tigera/operator/pkg/dns"
 )
 
 func TestStatus(t *testing.T) {
@@ -27,3 +29,8 @@ func TestStatus(t *testing.T) {
 	junitReporter := reporters.NewJUnitReporter("../../../report/authentication_suite.xml")
 	RunSpecsWithDefaultAndCustomReporters(t, "pkg/controller/authentication Suite", []Reporter{junitReporter})
 }
+
+var _ = BeforeSuite(func() {
+	// Keep the cluster domains in the tests independent of the resolv.conf of the host that runs them.
+	dns.DefaultClusterDomainDetector = dns.NewClusterDomainDetector("does-not.exist")
+})
@@ -0,0 +1,127 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authentication
+
+import (
+	"fmt"
+	"sync"
+
+	oprv1 "github.com/tigera/operator/api/v1"
+	"github.com/tigera/operator/pkg/render"
+	rmeta "github.com/tigera/operator/pkg/render/common/meta"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// watchedObjects holds the secrets, services and ConfigMaps that the last reconcile read. Their namespace and names
+// come from the Authentication, for example the namespace of the Dex deployment and the secrets of the static clients,
+// so they are not known when the watches of the controller are added. The watches reconcile on the changes of the
+// objects that are held here instead.
+type watchedObjects struct {
+	lock sync.RWMutex
+	keys map[watchedKey]bool
+}
+
+type watchedKey struct {
+	kind string
+	types.NamespacedName
+}
+
+const (
+	watchedSecret    = "Secret"
+	watchedService   = "Service"
+	watchedConfigMap = "ConfigMap"
+)
+
+func newWatchedObjects() *watchedObjects {
+	return &watchedObjects{keys: map[watchedKey]bool{}}
+}
+
+// setFor replaces the watched objects with the objects that Dex is rendered from for the Authentication: its secrets
+// in the operator namespace, their copies and its service in the Dex namespace, the ConfigMap into which OpenShift
+// injects the service CA, and the ConfigMaps with the CA certificates of the identity provider.
+func (w *watchedObjects) setFor(authentication *oprv1.Authentication) {
+	if w == nil {
+		return
+	}
+	dexNamespace := render.DexNamespaceFor(authentication)
+	secretNames := []string{
+		render.DexTLSSecretName, render.DexCertSecretName, render.OIDCSecretName, render.OpenshiftSecretName,
+		render.LDAPSecretName, render.DexObjectName,
+	}
+	for _, c := range authentication.Spec.StaticClients {
+		if c.SecretName != "" {
+			secretNames = append(secretNames, c.SecretName)
+		}
+	}
+
+	keys := map[watchedKey]bool{}
+	for _, namespace := range []string{rmeta.OperatorNamespace(), dexNamespace} {
+		for _, name := range secretNames {
+			keys[watchedKey{watchedSecret, types.NamespacedName{Name: name, Namespace: namespace}}] = true
+		}
+	}
+	keys[watchedKey{watchedService, types.NamespacedName{Name: render.DexObjectName, Namespace: dexNamespace}}] = true
+	keys[watchedKey{watchedConfigMap, types.NamespacedName{Name: render.DexServiceCAName(render.DexObjectName), Namespace: dexNamespace}}] = true
+	if authentication.Spec.DexDeployment != nil {
+		for _, name := range authentication.Spec.DexDeployment.IdentityProviderCAConfigMaps {
+			keys[watchedKey{watchedConfigMap, types.NamespacedName{Name: name, Namespace: rmeta.OperatorNamespace()}}] = true
+		}
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.keys = keys
+}
+
+// addSecrets adds the secrets that the Dex configuration references to the watched objects.
+func (w *watchedObjects) addSecrets(refs []render.DexSecretReference) {
+	if w == nil {
+		return
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for _, ref := range refs {
+		w.keys[watchedKey{watchedSecret, types.NamespacedName{Name: ref.Name, Namespace: ref.Namespace}}] = true
+	}
+}
+
+func (w *watchedObjects) contains(kind string, obj client.Object) bool {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	return w.keys[watchedKey{kind, types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}}]
+}
+
+// addWatches watches the secrets, services and ConfigMaps of all namespaces, and reconciles on the changes of the
+// watched objects.
+func (w *watchedObjects) addWatches(c controller.Controller) error {
+	for kind, obj := range map[string]client.Object{
+		watchedSecret:    &corev1.Secret{},
+		watchedService:   &corev1.Service{},
+		watchedConfigMap: &corev1.ConfigMap{},
+	} {
+		kind := kind
+		pred := predicate.NewPredicateFuncs(func(obj client.Object) bool { return w.contains(kind, obj) })
+		if err := c.Watch(&source.Kind{Type: obj}, &handler.EnqueueRequestForObject{}, pred); err != nil {
+			return fmt.Errorf("failed to watch the %s resources: %w", kind, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authentication
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	operatorv1 "github.com/tigera/operator/api/v1"
+	"github.com/tigera/operator/pkg/render"
+	rmeta "github.com/tigera/operator/pkg/render/common/meta"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("watched objects", func() {
+	secret := func(name, namespace string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	}
+
+	It("should watch the objects of Dex in the configured namespace and the secrets of the static clients", func() {
+		w := newWatchedObjects()
+		w.setFor(&operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{
+			DexDeployment: &operatorv1.DexDeployment{Namespace: "team-dex", IdentityProviderCAConfigMaps: []string{"idp-ca"}},
+			StaticClients: []operatorv1.StaticClient{{ID: "grafana", SecretName: "grafana-client"}},
+		}})
+
+		Expect(w.contains(watchedSecret, secret(render.DexTLSSecretName, rmeta.OperatorNamespace()))).To(BeTrue())
+		Expect(w.contains(watchedSecret, secret(render.DexTLSSecretName, "team-dex"))).To(BeTrue())
+		Expect(w.contains(watchedSecret, secret(render.DexTLSSecretName, render.DexNamespace))).To(BeFalse())
+		Expect(w.contains(watchedSecret, secret("grafana-client", rmeta.OperatorNamespace()))).To(BeTrue())
+		Expect(w.contains(watchedService, &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: render.DexObjectName, Namespace: "team-dex"}})).To(BeTrue())
+		Expect(w.contains(watchedConfigMap, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name: render.DexServiceCAName(render.DexObjectName), Namespace: "team-dex",
+		}})).To(BeTrue())
+		Expect(w.contains(watchedConfigMap, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: render.DexObjectName, Namespace: "team-dex"}})).To(BeFalse())
+		Expect(w.contains(watchedConfigMap, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "idp-ca", Namespace: rmeta.OperatorNamespace()}})).To(BeTrue())
+	})
+
+	It("should watch the referenced secrets until the Authentication changes", func() {
+		w := newWatchedObjects()
+		w.setFor(&operatorv1.Authentication{})
+		w.addSecrets([]render.DexSecretReference{{Name: "idp-ca", Namespace: rmeta.OperatorNamespace()}})
+		Expect(w.contains(watchedSecret, secret("idp-ca", rmeta.OperatorNamespace()))).To(BeTrue())
+
+		w.setFor(&operatorv1.Authentication{})
+		Expect(w.contains(watchedSecret, secret("idp-ca", rmeta.OperatorNamespace()))).To(BeFalse())
+		Expect(w.contains(watchedSecret, secret(render.DexTLSSecretName, render.DexNamespace))).To(BeTrue())
+	})
+})
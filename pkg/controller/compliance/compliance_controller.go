@@ -376,7 +376,7 @@ func (r *ReconcileCompliance) Reconcile(ctx context.Context, request reconcile.R
 		return reconcile.Result{}, err
 	}
 
-	if err := handler.CreateOrUpdateOrDelete(ctx, component, r.status); err != nil {
+	if err := handler.CreateOrUpdateOrDelete(ctx, render.WithInstallationMetadata(component, network), r.status); err != nil {
 		r.status.SetDegraded("Error creating / updating / deleting resource", err.Error())
 		return reconcile.Result{}, err
 	}
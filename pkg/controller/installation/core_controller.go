@@ -1084,7 +1084,7 @@ func (r *ReconcileInstallation) Reconcile(ctx context.Context, request reconcile
 	}
 
 	for _, component := range components {
-		if err := handler.CreateOrUpdateOrDelete(ctx, component, nil); err != nil {
+		if err := handler.CreateOrUpdateOrDelete(ctx, render.WithInstallationMetadata(component, &instance.Spec), nil); err != nil {
 			r.SetDegraded("Error creating / updating resource", err, reqLogger)
 			return reconcile.Result{}, err
 		}
@@ -49,6 +49,16 @@ func overrideInstallationSpec(cfg, override operatorv1.InstallationSpec) operato
 		inst.ImagePath = override.ImagePath
 	}
 
+	switch compareFields(inst.ImagePathFormat, override.ImagePathFormat) {
+	case BOnlySet, Different:
+		inst.ImagePathFormat = override.ImagePathFormat
+	}
+
+	switch compareFields(inst.ImageVariant, override.ImageVariant) {
+	case BOnlySet, Different:
+		inst.ImageVariant = override.ImageVariant
+	}
+
 	switch compareFields(inst.ImagePullSecrets, override.ImagePullSecrets) {
 	case BOnlySet, Different:
 		inst.ImagePullSecrets = make([]v1.LocalObjectReference, len(override.ImagePullSecrets))
@@ -114,6 +124,11 @@ func overrideInstallationSpec(cfg, override operatorv1.InstallationSpec) operato
 		copy(inst.ComponentResources, override.ComponentResources)
 	}
 
+	switch compareFields(inst.ComponentMetadata, override.ComponentMetadata) {
+	case BOnlySet, Different:
+		inst.ComponentMetadata = override.ComponentMetadata.DeepCopy()
+	}
+
 	switch compareFields(inst.TyphaAffinity, override.TyphaAffinity) {
 	case BOnlySet, Different:
 		inst.TyphaAffinity = override.TyphaAffinity
@@ -97,6 +97,30 @@ var _ = Describe("Installation merge tests", func() {
 		Entry("Both set not matching", "pathx", "pathy", "pathy"),
 	)
 
+	DescribeTable("merge ImagePathFormat", func(main, second, expect opv1.ImagePathFormat) {
+		m := opv1.InstallationSpec{ImagePathFormat: main}
+		s := opv1.InstallationSpec{ImagePathFormat: second}
+		inst := overrideInstallationSpec(m, s)
+		Expect(inst.ImagePathFormat).To(Equal(expect))
+	},
+		Entry("Both unset", opv1.ImagePathFormat(""), opv1.ImagePathFormat(""), opv1.ImagePathFormat("")),
+		Entry("Main only set", opv1.ImagePathFormatFlatten, opv1.ImagePathFormat(""), opv1.ImagePathFormatFlatten),
+		Entry("Second only set", opv1.ImagePathFormat(""), opv1.ImagePathFormatFlatten, opv1.ImagePathFormatFlatten),
+		Entry("Both set not matching", opv1.ImagePathFormatFlatten, opv1.ImagePathFormatNested, opv1.ImagePathFormatNested),
+	)
+
+	DescribeTable("merge ImageVariant", func(main, second, expect opv1.ImageVariant) {
+		m := opv1.InstallationSpec{ImageVariant: main}
+		s := opv1.InstallationSpec{ImageVariant: second}
+		inst := overrideInstallationSpec(m, s)
+		Expect(inst.ImageVariant).To(Equal(expect))
+	},
+		Entry("Both unset", opv1.ImageVariant(""), opv1.ImageVariant(""), opv1.ImageVariant("")),
+		Entry("Main only set", opv1.ImageVariantFIPS, opv1.ImageVariant(""), opv1.ImageVariantFIPS),
+		Entry("Second only set", opv1.ImageVariant(""), opv1.ImageVariantFIPS, opv1.ImageVariantFIPS),
+		Entry("Both set not matching", opv1.ImageVariantFIPS, opv1.ImageVariantStandard, opv1.ImageVariantStandard),
+	)
+
 	DescribeTable("merge imagePullSecrets", func(main, second, expect []v1.LocalObjectReference) {
 		m := opv1.InstallationSpec{}
 		s := opv1.InstallationSpec{}
@@ -603,6 +627,21 @@ var _ = Describe("Installation merge tests", func() {
 			[]opv1.ComponentResource{_typhaComp}),
 	)
 
+	DescribeTable("merge ComponentMetadata", func(main, second, expect *opv1.ComponentMetadata) {
+		m := opv1.InstallationSpec{ComponentMetadata: main}
+		s := opv1.InstallationSpec{ComponentMetadata: second}
+		inst := overrideInstallationSpec(m, s)
+		Expect(inst.ComponentMetadata).To(Equal(expect))
+	},
+		Entry("Both unset", nil, nil, nil),
+		Entry("Main only set", &opv1.ComponentMetadata{Labels: map[string]string{"a": "1"}}, nil,
+			&opv1.ComponentMetadata{Labels: map[string]string{"a": "1"}}),
+		Entry("Second only set", nil, &opv1.ComponentMetadata{Annotations: map[string]string{"b": "2"}},
+			&opv1.ComponentMetadata{Annotations: map[string]string{"b": "2"}}),
+		Entry("Both set not matching", &opv1.ComponentMetadata{Labels: map[string]string{"a": "1"}}, &opv1.ComponentMetadata{Annotations: map[string]string{"b": "2"}},
+			&opv1.ComponentMetadata{Annotations: map[string]string{"b": "2"}}),
+	)
+
 	Context("all fields handled", func() {
 		var defaulted opv1.InstallationSpec
 		BeforeEach(func() {
@@ -23,6 +23,9 @@ import (
 	operatorv1 "github.com/tigera/operator/api/v1"
 	"github.com/tigera/operator/pkg/render"
 	appsv1 "k8s.io/api/apps/v1"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 // validateCustomResource validates that the given custom resource is correct. This
@@ -314,6 +317,22 @@ func validateCustomResource(instance *operatorv1.Installation) error {
 		}
 	}
 
+	if md := instance.Spec.ComponentMetadata; md != nil {
+		fld := field.NewPath("spec", "componentMetadata")
+		errs := metav1validation.ValidateLabels(md.Labels, fld.Child("labels"))
+		errs = append(errs, apivalidation.ValidateAnnotations(md.Annotations, fld.Child("annotations"))...)
+		if len(errs) != 0 {
+			return fmt.Errorf("Installation %v", errs.ToAggregate())
+		}
+		for _, keys := range []map[string]string{md.Labels, md.Annotations} {
+			for key := range keys {
+				if render.IsOperatorOwnedKey(key) {
+					return fmt.Errorf("Installation spec.componentMetadata key %s is reserved for the operator", key)
+				}
+			}
+		}
+	}
+
 	validComponentNames := map[operatorv1.ComponentName]struct{}{
 		operatorv1.ComponentNameKubeControllers: {},
 		operatorv1.ComponentNameNode:            {},
@@ -192,6 +192,24 @@ var _ = Describe("Installation validation tests", func() {
 		Expect(validateCustomResource(instance)).To(HaveOccurred())
 	})
 
+	It("should validate componentMetadata", func() {
+		instance.Spec.ComponentMetadata = &operator.ComponentMetadata{
+			Labels:      map[string]string{"data-classification": "internal"},
+			Annotations: map[string]string{"backup.example.com/policy": "daily"},
+		}
+		Expect(validateCustomResource(instance)).NotTo(HaveOccurred())
+
+		instance.Spec.ComponentMetadata.Labels = map[string]string{"data-classification": "not a label value"}
+		Expect(validateCustomResource(instance)).To(HaveOccurred())
+
+		instance.Spec.ComponentMetadata.Labels = map[string]string{"k8s-app": "other"}
+		Expect(validateCustomResource(instance)).To(MatchError("Installation spec.componentMetadata key k8s-app is reserved for the operator"))
+
+		instance.Spec.ComponentMetadata.Labels = nil
+		instance.Spec.ComponentMetadata.Annotations = map[string]string{"hash.operator.tigera.io/config": "a"}
+		Expect(validateCustomResource(instance)).To(MatchError("Installation spec.componentMetadata key hash.operator.tigera.io/config is reserved for the operator"))
+	})
+
 	It("should validate HostPorts", func() {
 		instance.Spec.CalicoNetwork.HostPorts = nil
 		err := validateCustomResource(instance)
@@ -426,7 +426,7 @@ func (r *ReconcileLogCollector) Reconcile(ctx context.Context, request reconcile
 		return reconcile.Result{}, err
 	}
 
-	if err := handler.CreateOrUpdateOrDelete(ctx, component, r.status); err != nil {
+	if err := handler.CreateOrUpdateOrDelete(ctx, render.WithInstallationMetadata(component, installation), r.status); err != nil {
 		r.status.SetDegraded("Error creating / updating resource", err.Error())
 		return reconcile.Result{}, err
 	}
@@ -461,7 +461,7 @@ func (r *ReconcileLogCollector) Reconcile(ctx context.Context, request reconcile
 		// Create a component handler to manage the rendered component.
 		handler = utils.NewComponentHandler(log, r.client, r.scheme, instance)
 
-		if err := handler.CreateOrUpdateOrDelete(ctx, component, r.status); err != nil {
+		if err := handler.CreateOrUpdateOrDelete(ctx, render.WithInstallationMetadata(component, installation), r.status); err != nil {
 			r.status.SetDegraded("Error creating / updating resource", err.Error())
 			return reconcile.Result{}, err
 		}
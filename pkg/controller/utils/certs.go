@@ -20,6 +20,7 @@ import (
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"net"
 	"regexp"
 	"time"
 
@@ -118,6 +119,15 @@ func GetCertificateIssuer(certPem []byte) (string, error) {
 
 }
 
+// GetCertificateIPAddresses returns the IP SANs of a PEM block.
+func GetCertificateIPAddresses(certPem []byte) ([]net.IP, error) {
+	cert, err := parseCertificate(certPem)
+	if err != nil {
+		return nil, err
+	}
+	return cert.IPAddresses, nil
+}
+
 func parseCertificate(certBytes []byte) (*x509.Certificate, error) {
 	pemBlock, _ := pem.Decode(certBytes)
 	if pemBlock == nil {
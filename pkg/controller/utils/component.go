@@ -16,8 +16,11 @@ package utils
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
 	"sync"
 
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -29,18 +32,45 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	batchv1beta "k8s.io/api/batch/v1beta1"
 	v1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 
 	"github.com/tigera/operator/pkg/controller/status"
 	"github.com/tigera/operator/pkg/render"
+	"github.com/tigera/operator/pkg/render/common/diff"
 	rmeta "github.com/tigera/operator/pkg/render/common/meta"
 )
 
+// Labels that identify the owning CR of cluster-scoped objects whose owner is namespaced, since Kubernetes does not
+// allow an owner reference from a cluster-scoped object to a namespaced owner.
+const (
+	OwnerKindLabel      = "operator.tigera.io/owner-kind"
+	OwnerNameLabel      = "operator.tigera.io/owner-name"
+	OwnerNamespaceLabel = "operator.tigera.io/owner-namespace"
+)
+
+// DryRunAnnotation can be set to "true" on the CR that a handler is created for, so that the handler writes the
+// manifests of the components to a ConfigMap instead of applying them. The ConfigMap is named
+// "<kind>-<name>-dry-run" after the CR, lives in the namespace of the CR or else in the operator namespace, and holds
+// the manifests of each component under a key named after the component, for example "dexComponent.yaml".
+const DryRunAnnotation = "operator.tigera.io/dry-run"
+
+// RenderedHashAnnotation holds a hash of the object as the component rendered it when it was last applied. The fields
+// that a component renders are compared first before an object is updated, so a field that the component no longer
+// renders is noticed through this hash without a dry run of the update.
+const RenderedHashAnnotation = "operator.tigera.io/rendered-hash"
+
+// DiffLogLevel is the verbosity at which the component handler logs the fields that it changes in the objects that it
+// updates, for example with --zap-log-level=3. The values of the fields of secrets are redacted.
+const DiffLogLevel = 3
+
 type ComponentHandler interface {
 	CreateOrUpdateOrDelete(context.Context, render.Component, status.StatusManager) error
 }
@@ -67,7 +97,15 @@ func (c componentHandler) CreateOrUpdateOrDelete(ctx context.Context, component
 	cmpLog := c.log.WithValues("component", reflect.TypeOf(component))
 	cmpLog.V(2).Info("Checking if component is ready")
 	if !component.Ready() {
-		cmpLog.Info("Component is not ready, skipping")
+		cmpLog.Info("Component is not ready, skipping", "reason", render.NotReadyReason(component))
+		return nil
+	}
+
+	if c.cr != nil && c.cr.GetAnnotations()[DryRunAnnotation] == "true" {
+		if err := c.writeDryRunManifests(ctx, cmpLog, component); err != nil {
+			cmpLog.Error(err, "Failed to write the manifests of the component for the dry run")
+			return err
+		}
 		return nil
 	}
 	cmpLog.V(2).Info("Reconciling")
@@ -80,20 +118,46 @@ func (c componentHandler) CreateOrUpdateOrDelete(ctx context.Context, component
 	var cronJobs []types.NamespacedName
 
 	objsToCreate, objsToDelete := component.Objects()
-	osType := component.SupportedOSType()
+	osTypes := render.SupportedOSTypes(component)
 
-	for _, obj := range objsToCreate {
-		// Set CR instance as the owner and controller.
-		if err := controllerutil.SetControllerReference(c.cr, obj.(metav1.ObjectMetaAccessor).GetObjectMeta(), c.scheme); err != nil {
+	if d, ok := component.(render.ComponentWithDependencies); ok {
+		var err error
+		if objsToCreate, err = c.orderByDependencies(ctx, objsToCreate, d.Dependencies()); err != nil {
+			cmpLog.Info("Component is waiting for its dependencies", "reason", err.Error())
 			return err
 		}
+	}
+
+	if o, ok := component.(render.ComponentWithOrderedApply); ok && o.RequiresOrderedApply() {
+		objsToCreate = orderPodsLast(objsToCreate)
+	}
+
+	var retained []client.Object
+	if r, ok := component.(render.ComponentWithRetainedObjects); ok {
+		retained = r.RetainedObjects()
+	}
+
+	for _, obj := range objsToCreate {
+		// Set CR instance as the owner and controller, unless the object must outlive it.
+		if !containsObject(retained, obj) {
+			if err := c.setOwner(obj); err != nil {
+				return err
+			}
+		}
 
 		logCtx := ContextLoggerForResource(c.log, obj)
 		key := client.ObjectKeyFromObject(obj)
 
 		// Ensure that if the object is something the creates a pod that it is scheduled on nodes running the operating
 		// system as specified by the osType.
-		ensureOSSchedulingRestrictions(obj, osType)
+		if err := ensureOSSchedulingRestrictions(obj, osTypes); err != nil {
+			logCtx.Error(err, "Invalid operating system for object")
+			return err
+		}
+
+		if err := setRenderedHash(obj); err != nil {
+			return err
+		}
 
 		// Keep track of some objects so we can report on their status.
 		switch obj.(type) {
@@ -107,11 +171,9 @@ func (c componentHandler) CreateOrUpdateOrDelete(ctx context.Context, component
 			cronJobs = append(cronJobs, key)
 		}
 
-		cur, ok := obj.DeepCopyObject().(client.Object)
-		if !ok {
-			logCtx.V(2).Info("Failed converting object", "obj", obj)
-			return fmt.Errorf("Failed converting object %+v", obj)
-		}
+		// Read the current state into an empty object, since a copy of the desired object would keep the fields that are
+		// not set in the current state, and hide their changes from the comparison of the states.
+		cur := emptyObject(obj)
 		// Check to see if the object exists or not.
 		err := c.client.Get(ctx, key, cur)
 		if err != nil {
@@ -150,6 +212,15 @@ func (c componentHandler) CreateOrUpdateOrDelete(ctx context.Context, component
 					return err
 				}
 			default:
+				unchanged, err := c.unchanged(ctx, logCtx, cur, mobj)
+				if err != nil {
+					logCtx.WithValues("key", key).Info("Failed to compare object.")
+					return err
+				}
+				if unchanged {
+					logCtx.V(2).Info("Object is unchanged, skipping the update")
+					continue
+				}
 				if err := c.client.Update(ctx, mobj); err != nil {
 					logCtx.WithValues("key", key).Info("Failed to update object.")
 					return err
@@ -193,10 +264,81 @@ func (c componentHandler) CreateOrUpdateOrDelete(ctx context.Context, component
 	return nil
 }
 
+// emptyObject returns an empty object of the kind of obj.
+func emptyObject(obj client.Object) client.Object {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		empty := &unstructured.Unstructured{}
+		empty.SetGroupVersionKind(u.GroupVersionKind())
+		return empty
+	}
+	return reflect.New(reflect.TypeOf(obj).Elem()).Interface().(client.Object)
+}
+
+// setRenderedHash sets the RenderedHashAnnotation of the object to a hash of the object as it is rendered.
+func setRenderedHash(obj client.Object) error {
+	annotations := obj.GetAnnotations()
+	delete(annotations, RenderedHashAnnotation)
+	rendered, err := json.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("failed to hash %T %s: %w", obj, obj.GetName(), err)
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[RenderedHashAnnotation] = fmt.Sprintf("%x", sha1.Sum(rendered))
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+// unchanged returns true if updating the current object to the desired one would not change it. The fields that the
+// desired object sets are compared first, and a field that is no longer rendered changes the RenderedHashAnnotation of
+// the desired object. When they are equal, the update is sent as a dry run, so that the desired object is compared in
+// full after the API server defaults it. This reverts the changes to fields that the desired object leaves empty, such
+// as a hostNetwork that is set to true or a data key that is added to a ConfigMap. The fields that the update changes
+// are logged at the diff log level.
+func (c componentHandler) unchanged(ctx context.Context, logCtx logr.Logger, current, desired client.Object) (bool, error) {
+	diffs, err := diff.RenderedFieldDiffs(current, desired)
+	if err != nil {
+		return false, err
+	}
+	if len(diffs) == 0 {
+		updated := desired.DeepCopyObject().(client.Object)
+		if err := c.client.Update(ctx, updated, client.DryRunAll); err != nil {
+			return false, err
+		}
+		if diffs, err = diff.FieldDiffs(current, updated); err != nil || len(diffs) == 0 {
+			return err == nil, err
+		}
+	}
+	// The hash is left out of the logged changes, since its value says nothing about the fields that changed.
+	var changes []diff.FieldDiff
+	for _, d := range diffs {
+		if d.Path != "metadata.annotations."+RenderedHashAnnotation {
+			changes = append(changes, d)
+		}
+	}
+	if len(changes) == 0 {
+		logCtx.V(DiffLogLevel).Info("Updating object to remove the fields that are no longer rendered")
+	} else {
+		logCtx.V(DiffLogLevel).Info("Updating object", "changes", changes)
+	}
+	return false, nil
+}
+
+func hasCRDVersion(crd *apiextensionsv1.CustomResourceDefinition, version string) bool {
+	for _, v := range crd.Spec.Versions {
+		if v.Name == version {
+			return true
+		}
+	}
+	return false
+}
+
 // mergeState returns the object to pass to Update given the current and desired object states.
 func mergeState(desired client.Object, current runtime.Object) client.Object {
-	currentMeta := current.(metav1.ObjectMetaAccessor).GetObjectMeta()
-	desiredMeta := desired.(metav1.ObjectMetaAccessor).GetObjectMeta()
+	// Unstructured objects, of kinds that the operator has no types for, have no ObjectMeta to access.
+	currentMeta := current.(metav1.Object)
+	desiredMeta := metav1.Object(desired)
 
 	// Merge common metadata fields if not present on the desired state.
 	if desiredMeta.GetResourceVersion() == "" {
@@ -222,8 +364,25 @@ func mergeState(desired client.Object, current runtime.Object) client.Object {
 		// and we need to maintain them on updates.
 		cs := current.(*v1.Service)
 		ds := desired.(*v1.Service)
-		ds.Spec.ClusterIP = cs.Spec.ClusterIP
+		// An ExternalName service has no cluster IP, so a service that becomes one gives up its cluster IP.
+		if ds.Spec.Type != v1.ServiceTypeExternalName {
+			ds.Spec.ClusterIP = cs.Spec.ClusterIP
+		}
 		return ds
+	case *v1.ConfigMap:
+		// OpenShift injects the CA bundle of its service CA into ConfigMaps with the inject-cabundle annotation, which an
+		// update must not remove.
+		ccm := current.(*v1.ConfigMap)
+		dcm := desired.(*v1.ConfigMap)
+		if dcm.Annotations[rmeta.OpenShiftInjectCABundleAnnotation] == "true" {
+			if bundle, ok := ccm.Data[rmeta.OpenShiftServiceCAKey]; ok {
+				if dcm.Data == nil {
+					dcm.Data = map[string]string{}
+				}
+				dcm.Data[rmeta.OpenShiftServiceCAKey] = bundle
+			}
+		}
+		return dcm
 	case *batchv1.Job:
 		cj := current.(*batchv1.Job)
 		dj := desired.(*batchv1.Job)
@@ -288,18 +447,177 @@ func mergeState(desired client.Object, current runtime.Object) client.Object {
 		dsa.Spec.ElasticsearchRef = csa.Spec.ElasticsearchRef
 		dsa.Status = csa.Status
 		return dsa
+	case *apiextensionsv1.CustomResourceDefinition:
+		// The API server rejects a CustomResourceDefinition that drops a version in which objects are still stored, so
+		// the stored versions that are no longer desired keep being served until the objects are migrated.
+		ccrd := current.(*apiextensionsv1.CustomResourceDefinition)
+		dcrd := desired.(*apiextensionsv1.CustomResourceDefinition)
+		for _, stored := range ccrd.Status.StoredVersions {
+			if hasCRDVersion(dcrd, stored) {
+				continue
+			}
+			for _, v := range ccrd.Spec.Versions {
+				if v.Name == stored {
+					v.Served = true
+					v.Storage = false
+					dcrd.Spec.Versions = append(dcrd.Spec.Versions, v)
+				}
+			}
+		}
+		return dcrd
 	default:
 		// Default to just using the desired state, with an updated RV.
 		return desired
 	}
 }
 
-// ensureOSSchedulingRestrictions ensures that if obj is a type that creates pods and if osType is not OSTypeAny that a
+// setOwner sets the CR as the owner and controller of obj. Cluster-scoped objects of a namespaced CR are labeled with
+// the kind, name and namespace of the CR instead.
+func (c componentHandler) setOwner(obj client.Object) error {
+	if c.cr.GetNamespace() == "" || obj.GetNamespace() != "" {
+		return controllerutil.SetControllerReference(c.cr, obj, c.scheme)
+	}
+
+	gvk, err := apiutil.GVKForObject(c.cr.(runtime.Object), c.scheme)
+	if err != nil {
+		return err
+	}
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[OwnerKindLabel] = gvk.Kind
+	labels[OwnerNameLabel] = c.cr.GetName()
+	labels[OwnerNamespaceLabel] = c.cr.GetNamespace()
+	obj.SetLabels(labels)
+	return nil
+}
+
+// writeDryRunManifests writes the manifests of the component to the dry-run ConfigMap of the CR. The ConfigMap is only
+// written when the manifests changed, so that a reconcile without changes does not update it.
+func (c componentHandler) writeDryRunManifests(ctx context.Context, log logr.Logger, component render.Component) error {
+	manifests, err := render.ToYAML(component)
+	if err != nil {
+		return err
+	}
+	gvk, err := apiutil.GVKForObject(c.cr.(runtime.Object), c.scheme)
+	if err != nil {
+		return err
+	}
+	key := types.NamespacedName{
+		Name:      fmt.Sprintf("%s-%s-dry-run", strings.ToLower(gvk.Kind), c.cr.GetName()),
+		Namespace: c.cr.GetNamespace(),
+	}
+	if key.Namespace == "" {
+		key.Namespace = rmeta.OperatorNamespace()
+	}
+	dataKey := render.ComponentName(component) + ".yaml"
+
+	cm := &v1.ConfigMap{}
+	err = c.client.Get(ctx, key, cm)
+	switch {
+	case errors.IsNotFound(err):
+		cm = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Data:       map[string]string{dataKey: string(manifests)},
+		}
+		if err := c.setOwner(cm); err != nil {
+			return err
+		}
+		err = c.client.Create(ctx, cm)
+	case err != nil:
+		return err
+	case cm.Data[dataKey] == string(manifests):
+		return nil
+	default:
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[dataKey] = string(manifests)
+		err = c.client.Update(ctx, cm)
+	}
+	if err != nil {
+		return err
+	}
+	log.Info("Dry run is enabled, wrote the manifests to a ConfigMap instead of applying them", "configmap", key, "key", dataKey)
+	return nil
+}
+
+// orderByDependencies moves the dependencies that are part of objs to the front, so that they are applied before the
+// objects that need them. It returns an error if a dependency is neither part of objs nor exists in the cluster.
+func (c componentHandler) orderByDependencies(ctx context.Context, objs, deps []client.Object) ([]client.Object, error) {
+	var ordered, rest []client.Object
+	isDep := make([]bool, len(objs))
+	for _, dep := range deps {
+		found := false
+		for i, obj := range objs {
+			if !isDep[i] && sameObject(obj, dep) {
+				isDep[i] = true
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+
+		existing := dep.DeepCopyObject().(client.Object)
+		if err := c.client.Get(ctx, client.ObjectKeyFromObject(dep), existing); err != nil {
+			if errors.IsNotFound(err) {
+				return nil, fmt.Errorf("dependency %s/%s does not exist", dep.GetNamespace(), dep.GetName())
+			}
+			return nil, err
+		}
+	}
+
+	for i, obj := range objs {
+		if isDep[i] {
+			ordered = append(ordered, obj)
+		} else {
+			rest = append(rest, obj)
+		}
+	}
+	return append(ordered, rest...), nil
+}
+
+// orderPodsLast moves the objects that create pods behind all other objects, keeping the order of both.
+func orderPodsLast(objs []client.Object) []client.Object {
+	var ordered, pods []client.Object
+	for _, obj := range objs {
+		switch obj.(type) {
+		case *v1.PodTemplate, *apps.Deployment, *apps.DaemonSet, *apps.StatefulSet, *batchv1beta.CronJob, *batchv1.Job, *kbv1.Kibana, *esv1.Elasticsearch:
+			pods = append(pods, obj)
+		default:
+			ordered = append(ordered, obj)
+		}
+	}
+	return append(ordered, pods...)
+}
+
+// containsObject returns true if objs contains an object of the same type, name and namespace as obj.
+func containsObject(objs []client.Object, obj client.Object) bool {
+	for _, o := range objs {
+		if sameObject(o, obj) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameObject returns true if both objects are of the same type and have the same name and namespace.
+func sameObject(a, b client.Object) bool {
+	return reflect.TypeOf(a) == reflect.TypeOf(b) && client.ObjectKeyFromObject(a) == client.ObjectKeyFromObject(b)
+}
+
+// ensureOSSchedulingRestrictions ensures that if obj is a type that creates pods and if osTypes is not OSTypeAny that a
 // node selector is set on the pod template for the "kubernetes.io/os" label to ensure that the pod is scheduled
-// on a node running an operating system as specified by osType.
-func ensureOSSchedulingRestrictions(obj client.Object, osType rmeta.OSType) {
-	if osType == rmeta.OSTypeAny {
-		return
+// on a node running an operating system as specified by osTypes. When more than one OS type is supported, the pod
+// template must already select one of them, since the handler cannot tell which one the object is meant for.
+func ensureOSSchedulingRestrictions(obj client.Object, osTypes []rmeta.OSType) error {
+	for _, osType := range osTypes {
+		if osType == rmeta.OSTypeAny {
+			return nil
+		}
 	}
 
 	var podSpecs []*v1.PodSpec
@@ -325,23 +643,45 @@ func ensureOSSchedulingRestrictions(obj client.Object, osType rmeta.OSType) {
 			podSpecs = append(podSpecs, &nodeSets[i].PodTemplate.Spec)
 		}
 	default:
-		return
+		return nil
 	}
 
 	for _, podSpec := range podSpecs {
+		if len(osTypes) != 1 {
+			if !supportsOSType(osTypes, rmeta.OSType(podSpec.NodeSelector["kubernetes.io/os"])) {
+				return fmt.Errorf("pods of %s/%s must select one of the operating systems %v", obj.GetNamespace(), obj.GetName(), osTypes)
+			}
+			continue
+		}
 		if podSpec.NodeSelector == nil {
 			podSpec.NodeSelector = make(map[string]string)
 		}
-		podSpec.NodeSelector["kubernetes.io/os"] = string(osType)
+		podSpec.NodeSelector["kubernetes.io/os"] = string(osTypes[0])
 	}
+	return nil
+}
+
+func supportsOSType(osTypes []rmeta.OSType, osType rmeta.OSType) bool {
+	for _, t := range osTypes {
+		if t == osType {
+			return true
+		}
+	}
+	return false
 }
 
 // mergeAnnotations merges current and desired annotations. If both current and desired annotations contain the same key, the
-// desired annotation, i.e, the ones that the operators Components specify take preference.
+// desired annotation, i.e, the ones that the operators Components specify take preference. The current annotations that
+// the operator copied from the configuration of a CR, as listed by rmeta.ManagedAnnotationsAnnotation, are not kept, so
+// that they are removed once they are no longer configured.
 func mergeAnnotations(current, desired map[string]string) map[string]string {
+	managed := map[string]bool{}
+	for _, k := range rmeta.ManagedAnnotationKeys(current) {
+		managed[k] = true
+	}
 	for k, v := range current {
 		// Copy over annotations that should be copied.
-		if _, ok := desired[k]; !ok {
+		if _, ok := desired[k]; !ok && !managed[k] {
 			desired[k] = v
 		}
 	}
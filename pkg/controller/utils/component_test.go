@@ -18,17 +18,21 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/go-logr/logr"
 	"github.com/tigera/operator/pkg/common"
 
 	apps "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	batchv1beta "k8s.io/api/batch/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	esv1 "github.com/elastic/cloud-on-k8s/pkg/apis/elasticsearch/v1"
 	kbv1 "github.com/elastic/cloud-on-k8s/pkg/apis/kibana/v1"
 	ocsv1 "github.com/openshift/api/security/v1"
 	v1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -39,6 +43,7 @@ import (
 	"github.com/tigera/operator/pkg/controller/status"
 	"github.com/tigera/operator/pkg/controller/utils"
 	"github.com/tigera/operator/pkg/render"
+	"github.com/tigera/operator/pkg/render/common/diff"
 	rmeta "github.com/tigera/operator/pkg/render/common/meta"
 
 	. "github.com/onsi/ginkgo"
@@ -86,6 +91,397 @@ var _ = Describe("Component handler tests", func() {
 		handler = utils.NewComponentHandler(log, c, scheme, instance)
 	})
 
+	It("does not apply any objects when dry run is enabled", func() {
+		instance.Annotations = map[string]string{utils.DryRunAnnotation: "true"}
+		handler = utils.NewComponentHandler(log, c, scheme, instance)
+		fc := &fakeComponent{
+			supportedOSType: rmeta.OSTypeLinux,
+			objs: []client.Object{&v1.Namespace{
+				TypeMeta:   metav1.TypeMeta{Kind: "Namespace", APIVersion: "v1"},
+				ObjectMeta: metav1.ObjectMeta{Name: "test-namespace"},
+			}},
+		}
+
+		Expect(handler.CreateOrUpdateOrDelete(ctx, fc, sm)).To(Succeed())
+
+		ns := &v1.Namespace{}
+		err := c.Get(ctx, client.ObjectKey{Name: "test-namespace"}, ns)
+		Expect(err).To(HaveOccurred())
+
+		cm := &v1.ConfigMap{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "manager-tigera-secure-dry-run", Namespace: "tigera-operator"}, cm)).To(Succeed())
+		Expect(cm.Data).To(HaveKey("fakeComponent.yaml"))
+		Expect(cm.Data["fakeComponent.yaml"]).To(ContainSubstring("name: test-namespace"))
+
+		// The ConfigMap is not updated again while the manifests do not change.
+		Expect(handler.CreateOrUpdateOrDelete(ctx, fc, sm)).To(Succeed())
+		unchanged := &v1.ConfigMap{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "manager-tigera-secure-dry-run", Namespace: "tigera-operator"}, unchanged)).To(Succeed())
+		Expect(unchanged.ResourceVersion).To(Equal(cm.ResourceVersion))
+	})
+
+	It("applies the dependencies of a component before its other objects", func() {
+		rc := &recordingClient{Client: c}
+		handler = utils.NewComponentHandler(log, rc, scheme, instance)
+		fc := &fakeComponentWithDependencies{
+			fakeComponent: fakeComponent{
+				supportedOSType: rmeta.OSTypeLinux,
+				objs: []client.Object{
+					&apps.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "test-deployment", Namespace: "test-namespace"}},
+					&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test-secret", Namespace: "test-namespace"}},
+				},
+			},
+			deps: []client.Object{&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test-secret", Namespace: "test-namespace"}}},
+		}
+
+		Expect(handler.CreateOrUpdateOrDelete(ctx, fc, sm)).To(Succeed())
+		Expect(rc.created).To(Equal([]string{"test-secret", "test-deployment"}))
+	})
+
+	DescribeTable("applies the workloads of a component last when it requires an ordered apply", func(ordered bool, expected []string) {
+		rc := &recordingClient{Client: c}
+		handler = utils.NewComponentHandler(log, rc, scheme, instance)
+		fc := &fakeComponentWithOrderedApply{
+			fakeComponent: fakeComponent{
+				supportedOSType: rmeta.OSTypeLinux,
+				objs: []client.Object{
+					&apps.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "test-deployment", Namespace: "test-namespace"}},
+					&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test-configmap", Namespace: "test-namespace"}},
+					&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test-secret", Namespace: "test-namespace"}},
+				},
+			},
+			ordered: ordered,
+		}
+
+		Expect(handler.CreateOrUpdateOrDelete(ctx, fc, sm)).To(Succeed())
+		Expect(rc.created).To(Equal(expected))
+	},
+		Entry("in the rendered order", false, []string{"test-deployment", "test-configmap", "test-secret"}),
+		Entry("with the workloads last", true, []string{"test-configmap", "test-secret", "test-deployment"}),
+	)
+
+	It("does not apply a component while a dependency that it does not render is missing", func() {
+		fc := &fakeComponentWithDependencies{
+			fakeComponent: fakeComponent{
+				supportedOSType: rmeta.OSTypeLinux,
+				objs: []client.Object{
+					&apps.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "test-deployment", Namespace: "test-namespace"}},
+				},
+			},
+			deps: []client.Object{&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test-secret", Namespace: "test-namespace"}}},
+		}
+
+		Expect(handler.CreateOrUpdateOrDelete(ctx, fc, sm)).NotTo(Succeed())
+		key := client.ObjectKey{Name: "test-deployment", Namespace: "test-namespace"}
+		Expect(c.Get(ctx, key, &apps.Deployment{})).To(HaveOccurred())
+
+		Expect(c.Create(ctx, &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test-secret", Namespace: "test-namespace"}})).To(Succeed())
+		Expect(handler.CreateOrUpdateOrDelete(ctx, fc, sm)).To(Succeed())
+		Expect(c.Get(ctx, key, &apps.Deployment{})).To(Succeed())
+	})
+
+	It("sets the CR as the controller of the objects", func() {
+		fc := &fakeComponent{
+			supportedOSType: rmeta.OSTypeLinux,
+			objs: []client.Object{
+				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test-namespace"}},
+				&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test-configmap", Namespace: "test-namespace"}},
+			},
+		}
+		Expect(handler.CreateOrUpdateOrDelete(ctx, fc, sm)).To(Succeed())
+
+		ns := &v1.Namespace{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "test-namespace"}, ns)).To(Succeed())
+		Expect(ns.OwnerReferences).To(HaveLen(1))
+		Expect(ns.OwnerReferences[0].Kind).To(Equal("Manager"))
+		Expect(*ns.OwnerReferences[0].Controller).To(BeTrue())
+		cm := &v1.ConfigMap{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "test-configmap", Namespace: "test-namespace"}, cm)).To(Succeed())
+		Expect(cm.OwnerReferences).To(HaveLen(1))
+	})
+
+	It("does not set the CR as the owner of the objects that must outlive it", func() {
+		crd := &apiextensionsv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: "tests.example.com"}}
+		fc := &fakeComponentWithRetainedObjects{
+			fakeComponent: fakeComponent{
+				supportedOSType: rmeta.OSTypeLinux,
+				objs: []client.Object{
+					crd.DeepCopy(),
+					&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test-configmap", Namespace: "test-namespace"}},
+				},
+			},
+			retained: []client.Object{crd},
+		}
+		Expect(handler.CreateOrUpdateOrDelete(ctx, fc, sm)).To(Succeed())
+
+		current := &apiextensionsv1.CustomResourceDefinition{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "tests.example.com"}, current)).To(Succeed())
+		Expect(current.OwnerReferences).To(BeEmpty())
+		cm := &v1.ConfigMap{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "test-configmap", Namespace: "test-namespace"}, cm)).To(Succeed())
+		Expect(cm.OwnerReferences).To(HaveLen(1))
+	})
+
+	It("keeps the CA bundle that openshift injected into a ConfigMap", func() {
+		injected := func() *v1.ConfigMap {
+			return &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+				Name:        "test-service-ca",
+				Namespace:   "test-namespace",
+				Annotations: map[string]string{rmeta.OpenShiftInjectCABundleAnnotation: "true"},
+			}}
+		}
+		fc := &fakeComponent{supportedOSType: rmeta.OSTypeLinux, objs: []client.Object{injected()}}
+		Expect(handler.CreateOrUpdateOrDelete(ctx, fc, sm)).To(Succeed())
+
+		cm := &v1.ConfigMap{}
+		key := client.ObjectKey{Name: "test-service-ca", Namespace: "test-namespace"}
+		Expect(c.Get(ctx, key, cm)).To(Succeed())
+		cm.Data = map[string]string{rmeta.OpenShiftServiceCAKey: "service-ca"}
+		Expect(c.Update(ctx, cm)).To(Succeed())
+
+		fc = &fakeComponent{supportedOSType: rmeta.OSTypeLinux, objs: []client.Object{injected()}}
+		Expect(handler.CreateOrUpdateOrDelete(ctx, fc, sm)).To(Succeed())
+		Expect(c.Get(ctx, key, cm)).To(Succeed())
+		Expect(cm.Data).To(HaveKeyWithValue(rmeta.OpenShiftServiceCAKey, "service-ca"))
+	})
+
+	It("updates objects of kinds that the operator has no types for", func() {
+		rule := func(expr string) *unstructured.Unstructured {
+			u := &unstructured.Unstructured{}
+			u.SetGroupVersionKind(schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "PrometheusRule"})
+			u.SetName("test-rule")
+			u.SetNamespace("test-namespace")
+			u.Object["spec"] = map[string]interface{}{"expr": expr}
+			return u
+		}
+		fc := &fakeComponent{supportedOSType: rmeta.OSTypeLinux, objs: []client.Object{rule("up == 0")}}
+		Expect(handler.CreateOrUpdateOrDelete(ctx, fc, sm)).To(Succeed())
+		fc = &fakeComponent{supportedOSType: rmeta.OSTypeLinux, objs: []client.Object{rule("up < 1")}}
+		Expect(handler.CreateOrUpdateOrDelete(ctx, fc, sm)).To(Succeed())
+
+		current := rule("")
+		Expect(c.Get(ctx, client.ObjectKey{Name: "test-rule", Namespace: "test-namespace"}, current)).To(Succeed())
+		Expect(current.Object["spec"]).To(Equal(map[string]interface{}{"expr": "up < 1"}))
+	})
+
+	It("does not update objects that are unchanged", func() {
+		rc := &recordingClient{Client: c}
+		handler = utils.NewComponentHandler(log, rc, scheme, instance)
+		configMap := func(value string) *v1.ConfigMap {
+			return &v1.ConfigMap{
+				TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+				ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "test-namespace"},
+				Data:       map[string]string{"key": value},
+			}
+		}
+		Expect(handler.CreateOrUpdateOrDelete(ctx, &fakeComponent{supportedOSType: rmeta.OSTypeLinux, objs: []client.Object{configMap("a")}}, sm)).To(Succeed())
+		Expect(handler.CreateOrUpdateOrDelete(ctx, &fakeComponent{supportedOSType: rmeta.OSTypeLinux, objs: []client.Object{configMap("a")}}, sm)).To(Succeed())
+		Expect(rc.updated).To(BeEmpty())
+
+		Expect(handler.CreateOrUpdateOrDelete(ctx, &fakeComponent{supportedOSType: rmeta.OSTypeLinux, objs: []client.Object{configMap("b")}}, sm)).To(Succeed())
+		Expect(rc.updated).To(Equal([]string{"test-config"}))
+	})
+
+	It("does not update a deployment whose current state only differs by the fields that the API server defaults", func() {
+		defaults := func(obj client.Object) {
+			d := obj.(*apps.Deployment)
+			limit := int32(10)
+			d.Spec.RevisionHistoryLimit = &limit
+			d.Spec.Strategy = apps.DeploymentStrategy{Type: apps.RollingUpdateDeploymentStrategyType}
+			d.Spec.Template.Spec.RestartPolicy = v1.RestartPolicyAlways
+			d.Spec.Template.Spec.Containers[0].ImagePullPolicy = v1.PullIfNotPresent
+		}
+		rc := &recordingClient{Client: c, defaults: defaults}
+		handler = utils.NewComponentHandler(log, rc, scheme, instance)
+		deployment := func(args ...string) *apps.Deployment {
+			return &apps.Deployment{
+				TypeMeta:   metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+				ObjectMeta: metav1.ObjectMeta{Name: "test-deployment", Namespace: "test-namespace"},
+				Spec: apps.DeploymentSpec{Template: v1.PodTemplateSpec{Spec: v1.PodSpec{
+					Containers: []v1.Container{{Name: "test", Image: "test:1", Args: args}},
+				}}},
+			}
+		}
+
+		// The deployment is stored with the fields that the API server defaults.
+		Expect(handler.CreateOrUpdateOrDelete(ctx, &fakeComponent{supportedOSType: rmeta.OSTypeLinux,
+			objs: []client.Object{deployment("--debug")}}, sm)).To(Succeed())
+		Expect(rc.created).To(Equal([]string{"test-deployment"}))
+
+		Expect(handler.CreateOrUpdateOrDelete(ctx, &fakeComponent{supportedOSType: rmeta.OSTypeLinux,
+			objs: []client.Object{deployment("--debug")}}, sm)).To(Succeed())
+		Expect(rc.updated).To(BeEmpty())
+
+		// A field that is no longer rendered is removed.
+		Expect(handler.CreateOrUpdateOrDelete(ctx, &fakeComponent{supportedOSType: rmeta.OSTypeLinux,
+			objs: []client.Object{deployment()}}, sm)).To(Succeed())
+		Expect(rc.updated).To(Equal([]string{"test-deployment"}))
+		current := &apps.Deployment{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "test-deployment", Namespace: "test-namespace"}, current)).To(Succeed())
+		Expect(current.Spec.Template.Spec.Containers[0].Args).To(BeEmpty())
+	})
+
+	It("reverts the changes to the fields that a component leaves empty", func() {
+		defaults := func(obj client.Object) {
+			if d, ok := obj.(*apps.Deployment); ok {
+				d.Spec.Template.Spec.RestartPolicy = v1.RestartPolicyAlways
+			}
+		}
+		rc := &recordingClient{Client: c, defaults: defaults}
+		handler = utils.NewComponentHandler(log, rc, scheme, instance)
+		component := func() *fakeComponent {
+			return &fakeComponent{supportedOSType: rmeta.OSTypeLinux, objs: []client.Object{
+				&apps.Deployment{
+					TypeMeta:   metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+					ObjectMeta: metav1.ObjectMeta{Name: "test-deployment", Namespace: "test-namespace"},
+					Spec: apps.DeploymentSpec{Template: v1.PodTemplateSpec{Spec: v1.PodSpec{
+						Containers: []v1.Container{{Name: "test", Image: "test:1"}},
+					}}},
+				},
+				&v1.ConfigMap{
+					TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+					ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "test-namespace"},
+					Data:       map[string]string{"key": "a"},
+				},
+			}}
+		}
+		Expect(handler.CreateOrUpdateOrDelete(ctx, component(), sm)).To(Succeed())
+
+		// The objects are changed outside of the operator, in fields that the component does not set.
+		d := &apps.Deployment{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "test-deployment", Namespace: "test-namespace"}, d)).To(Succeed())
+		d.Spec.Template.Spec.HostNetwork = true
+		Expect(c.Update(ctx, d)).To(Succeed())
+		cm := &v1.ConfigMap{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "test-config", Namespace: "test-namespace"}, cm)).To(Succeed())
+		cm.Data["extra"] = "b"
+		Expect(c.Update(ctx, cm)).To(Succeed())
+
+		Expect(handler.CreateOrUpdateOrDelete(ctx, component(), sm)).To(Succeed())
+		Expect(rc.updated).To(ConsistOf("test-deployment", "test-config"))
+		d = &apps.Deployment{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "test-deployment", Namespace: "test-namespace"}, d)).To(Succeed())
+		Expect(d.Spec.Template.Spec.HostNetwork).To(BeFalse())
+		cm = &v1.ConfigMap{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "test-config", Namespace: "test-namespace"}, cm)).To(Succeed())
+		Expect(cm.Data).To(Equal(map[string]string{"key": "a"}))
+	})
+
+	It("logs the fields that it changes at the diff log level", func() {
+		rl := &recordingLogger{entries: &[]logEntry{}}
+		handler = utils.NewComponentHandler(rl, c, scheme, instance)
+		deployment := func(image string) *apps.Deployment {
+			return &apps.Deployment{
+				TypeMeta:   metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+				ObjectMeta: metav1.ObjectMeta{Name: "test-deployment", Namespace: "test-namespace"},
+				Spec: apps.DeploymentSpec{Template: v1.PodTemplateSpec{Spec: v1.PodSpec{
+					Containers: []v1.Container{{Name: "test", Image: image}},
+				}}},
+			}
+		}
+		Expect(handler.CreateOrUpdateOrDelete(ctx, &fakeComponent{supportedOSType: rmeta.OSTypeLinux, objs: []client.Object{deployment("test:1")}}, sm)).To(Succeed())
+		Expect(handler.CreateOrUpdateOrDelete(ctx, &fakeComponent{supportedOSType: rmeta.OSTypeLinux, objs: []client.Object{deployment("test:2")}}, sm)).To(Succeed())
+
+		var changes []interface{}
+		for _, e := range *rl.entries {
+			if e.level == utils.DiffLogLevel {
+				Expect(e.msg).To(Equal("Updating object"))
+				changes = append(changes, e.keysAndValues...)
+			}
+		}
+		Expect(changes).To(Equal([]interface{}{"changes", []diff.FieldDiff{
+			{Path: "spec.template.spec.containers[0].image", Old: "test:1", New: "test:2"},
+		}}))
+	})
+
+	It("keeps serving the stored versions of a CustomResourceDefinition that are no longer desired", func() {
+		version := func(name string, storage bool) apiextensionsv1.CustomResourceDefinitionVersion {
+			return apiextensionsv1.CustomResourceDefinitionVersion{Name: name, Served: true, Storage: storage}
+		}
+		Expect(c.Create(ctx, &apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: "tests.example.com"},
+			Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+				Versions: []apiextensionsv1.CustomResourceDefinitionVersion{version("v1alpha1", false), version("v1beta1", true)},
+			},
+			Status: apiextensionsv1.CustomResourceDefinitionStatus{StoredVersions: []string{"v1beta1"}},
+		})).To(Succeed())
+
+		fc := &fakeComponent{
+			supportedOSType: rmeta.OSTypeLinux,
+			objs: []client.Object{&apiextensionsv1.CustomResourceDefinition{
+				ObjectMeta: metav1.ObjectMeta{Name: "tests.example.com"},
+				Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+					Versions: []apiextensionsv1.CustomResourceDefinitionVersion{version("v1", true)},
+				},
+			}},
+		}
+		Expect(handler.CreateOrUpdateOrDelete(ctx, fc, sm)).To(Succeed())
+
+		current := &apiextensionsv1.CustomResourceDefinition{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "tests.example.com"}, current)).To(Succeed())
+		Expect(current.Spec.Versions).To(Equal([]apiextensionsv1.CustomResourceDefinitionVersion{
+			version("v1", true),
+			version("v1beta1", false),
+		}))
+	})
+
+	It("keeps the cluster IP of a service unless it becomes an ExternalName service", func() {
+		Expect(c.Create(ctx, &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-service", Namespace: "test-namespace"},
+			Spec:       v1.ServiceSpec{ClusterIP: "10.0.0.10"},
+		})).To(Succeed())
+		service := func(spec v1.ServiceSpec) *fakeComponent {
+			return &fakeComponent{
+				supportedOSType: rmeta.OSTypeLinux,
+				objs: []client.Object{&v1.Service{
+					TypeMeta:   metav1.TypeMeta{Kind: "Service", APIVersion: "v1"},
+					ObjectMeta: metav1.ObjectMeta{Name: "test-service", Namespace: "test-namespace"},
+					Spec:       spec,
+				}},
+			}
+		}
+		current := &v1.Service{}
+
+		Expect(handler.CreateOrUpdateOrDelete(ctx, service(v1.ServiceSpec{Selector: map[string]string{"k8s-app": "test"}}), sm)).To(Succeed())
+		Expect(c.Get(ctx, client.ObjectKey{Name: "test-service", Namespace: "test-namespace"}, current)).To(Succeed())
+		Expect(current.Spec.ClusterIP).To(Equal("10.0.0.10"))
+
+		Expect(handler.CreateOrUpdateOrDelete(ctx, service(v1.ServiceSpec{Type: v1.ServiceTypeExternalName, ExternalName: "dex.example.com"}), sm)).To(Succeed())
+		current = &v1.Service{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "test-service", Namespace: "test-namespace"}, current)).To(Succeed())
+		Expect(current.Spec.ClusterIP).To(BeEmpty())
+		Expect(current.Spec.ExternalName).To(Equal("dex.example.com"))
+	})
+
+	It("labels cluster-scoped objects of a namespaced CR instead of setting an owner reference", func() {
+		owner := &v1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "owner", Namespace: "owner-namespace"},
+		}
+		handler = utils.NewComponentHandler(log, c, scheme, owner)
+		fc := &fakeComponent{
+			supportedOSType: rmeta.OSTypeLinux,
+			objs: []client.Object{
+				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test-namespace"}},
+				&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test-configmap", Namespace: "owner-namespace"}},
+			},
+		}
+		Expect(handler.CreateOrUpdateOrDelete(ctx, fc, sm)).To(Succeed())
+
+		ns := &v1.Namespace{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "test-namespace"}, ns)).To(Succeed())
+		Expect(ns.OwnerReferences).To(BeEmpty())
+		Expect(ns.Labels).To(Equal(map[string]string{
+			utils.OwnerKindLabel:      "ConfigMap",
+			utils.OwnerNameLabel:      "owner",
+			utils.OwnerNamespaceLabel: "owner-namespace",
+		}))
+		cm := &v1.ConfigMap{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "test-configmap", Namespace: "owner-namespace"}, cm)).To(Succeed())
+		Expect(cm.OwnerReferences).To(HaveLen(1))
+		Expect(cm.Labels).NotTo(HaveKey(utils.OwnerKindLabel))
+	})
+
 	It("merges annotations and reconciles only operator added annotations", func() {
 		fc := &fakeComponent{
 			supportedOSType: rmeta.OSTypeLinux,
@@ -111,7 +507,7 @@ var _ = Describe("Component handler tests", func() {
 		}
 		ns := &v1.Namespace{}
 		c.Get(ctx, nsKey, ns)
-		Expect(ns.GetAnnotations()).To(Equal(expectedAnnotations))
+		Expect(withoutRenderedHash(ns.GetAnnotations())).To(Equal(expectedAnnotations))
 
 		By("ovewriting the namespace with SCC annotations")
 		annotations := map[string]string{
@@ -129,7 +525,7 @@ var _ = Describe("Component handler tests", func() {
 		}
 		ns = &v1.Namespace{}
 		c.Get(ctx, nsKey, ns)
-		Expect(ns.GetAnnotations()).To(Equal(expectedAnnotations))
+		Expect(withoutRenderedHash(ns.GetAnnotations())).To(Equal(expectedAnnotations))
 
 		// Re-initialize the fake component. Object metadata gets modified as part of CreateOrUpdate, leading
 		// to resource update conflicts.
@@ -156,7 +552,7 @@ var _ = Describe("Component handler tests", func() {
 		}
 		ns = &v1.Namespace{}
 		c.Get(ctx, nsKey, ns)
-		Expect(ns.GetAnnotations()).To(Equal(expectedAnnotations))
+		Expect(withoutRenderedHash(ns.GetAnnotations())).To(Equal(expectedAnnotations))
 
 		By("changing a desired annotation")
 		annotations = map[string]string{
@@ -178,7 +574,7 @@ var _ = Describe("Component handler tests", func() {
 		}
 		ns = &v1.Namespace{}
 		c.Get(ctx, nsKey, ns)
-		Expect(ns.GetAnnotations()).To(Equal(expectedAnnotations))
+		Expect(withoutRenderedHash(ns.GetAnnotations())).To(Equal(expectedAnnotations))
 
 		// Re-initialize the fake component. Object metadata gets modified as part of CreateOrUpdate, leading
 		// to resource update conflicts.
@@ -206,7 +602,38 @@ var _ = Describe("Component handler tests", func() {
 		}
 		ns = &v1.Namespace{}
 		c.Get(ctx, nsKey, ns)
-		Expect(ns.GetAnnotations()).To(Equal(expectedAnnotations))
+		Expect(withoutRenderedHash(ns.GetAnnotations())).To(Equal(expectedAnnotations))
+	})
+
+	It("removes the managed annotations that are no longer desired, and keeps the annotations of others", func() {
+		serviceAccount := func(annotations map[string]string) *v1.ServiceAccount {
+			sa := &v1.ServiceAccount{
+				TypeMeta:   metav1.TypeMeta{Kind: "ServiceAccount", APIVersion: "v1"},
+				ObjectMeta: metav1.ObjectMeta{Name: "test-sa", Namespace: "test-namespace"},
+			}
+			rmeta.SetManagedAnnotations(sa, annotations)
+			return sa
+		}
+		Expect(handler.CreateOrUpdateOrDelete(ctx, &fakeComponent{supportedOSType: rmeta.OSTypeLinux,
+			objs: []client.Object{serviceAccount(map[string]string{"a": "1", "b": "2"})}}, sm)).To(Succeed())
+
+		current := &v1.ServiceAccount{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "test-sa", Namespace: "test-namespace"}, current)).To(Succeed())
+		Expect(withoutRenderedHash(current.Annotations)).To(Equal(map[string]string{"a": "1", "b": "2", rmeta.ManagedAnnotationsAnnotation: "a,b"}))
+		current.Annotations["other"] = "kept"
+		Expect(c.Update(ctx, current)).To(Succeed())
+
+		Expect(handler.CreateOrUpdateOrDelete(ctx, &fakeComponent{supportedOSType: rmeta.OSTypeLinux,
+			objs: []client.Object{serviceAccount(map[string]string{"a": "3"})}}, sm)).To(Succeed())
+		current = &v1.ServiceAccount{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "test-sa", Namespace: "test-namespace"}, current)).To(Succeed())
+		Expect(withoutRenderedHash(current.Annotations)).To(Equal(map[string]string{"a": "3", "other": "kept", rmeta.ManagedAnnotationsAnnotation: "a"}))
+
+		Expect(handler.CreateOrUpdateOrDelete(ctx, &fakeComponent{supportedOSType: rmeta.OSTypeLinux,
+			objs: []client.Object{serviceAccount(nil)}}, sm)).To(Succeed())
+		current = &v1.ServiceAccount{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "test-sa", Namespace: "test-namespace"}, current)).To(Succeed())
+		Expect(withoutRenderedHash(current.Annotations)).To(Equal(map[string]string{"other": "kept"}))
 	})
 
 	DescribeTable("ensuring os node selectors", func(component render.Component, key client.ObjectKey, obj client.Object, expectedNodeSelectors map[string]string) {
@@ -604,6 +1031,51 @@ var _ = Describe("Component handler tests", func() {
 			},
 		},
 	)
+
+	It("should keep the operating system that each object of a mixed-OS component selects", func() {
+		fc := &fakeMultiOSComponent{
+			fakeComponent: fakeComponent{
+				supportedOSType: rmeta.OSTypeLinux,
+				objs: []client.Object{
+					&apps.Deployment{
+						ObjectMeta: metav1.ObjectMeta{Name: "test-deployment"},
+						Spec: apps.DeploymentSpec{Template: v1.PodTemplateSpec{Spec: v1.PodSpec{
+							NodeSelector: map[string]string{"kubernetes.io/os": "linux"},
+						}}},
+					},
+					&apps.DaemonSet{
+						ObjectMeta: metav1.ObjectMeta{Name: "test-daemonset"},
+						Spec: apps.DaemonSetSpec{Template: v1.PodTemplateSpec{Spec: v1.PodSpec{
+							NodeSelector: map[string]string{"kubernetes.io/os": "windows"},
+						}}},
+					},
+				},
+			},
+			supportedOSTypes: []rmeta.OSType{rmeta.OSTypeLinux, rmeta.OSTypeWindows},
+		}
+		Expect(handler.CreateOrUpdateOrDelete(ctx, fc, sm)).ShouldNot(HaveOccurred())
+
+		d := &apps.Deployment{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "test-deployment"}, d)).ShouldNot(HaveOccurred())
+		Expect(d.Spec.Template.Spec.NodeSelector).To(Equal(map[string]string{"kubernetes.io/os": "linux"}))
+		ds := &apps.DaemonSet{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "test-daemonset"}, ds)).ShouldNot(HaveOccurred())
+		Expect(ds.Spec.Template.Spec.NodeSelector).To(Equal(map[string]string{"kubernetes.io/os": "windows"}))
+	})
+
+	It("should reject an object of a mixed-OS component that does not select a supported operating system", func() {
+		fc := &fakeMultiOSComponent{
+			fakeComponent: fakeComponent{
+				supportedOSType: rmeta.OSTypeLinux,
+				objs: []client.Object{&apps.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-deployment"},
+				}},
+			},
+			supportedOSTypes: []rmeta.OSType{rmeta.OSTypeLinux, rmeta.OSTypeWindows},
+		}
+		Expect(handler.CreateOrUpdateOrDelete(ctx, fc, sm)).Should(HaveOccurred())
+		Expect(c.Get(ctx, client.ObjectKey{Name: "test-deployment"}, &apps.Deployment{})).Should(HaveOccurred())
+	})
 })
 
 // A fake component that only returns ready and always creates the "test-namespace" Namespace.
@@ -616,6 +1088,10 @@ func (c *fakeComponent) Ready() bool {
 	return true
 }
 
+func (c *fakeComponent) Validate() error {
+	return nil
+}
+
 func (c *fakeComponent) ResolveImages(is *operatorv1.ImageSet) error {
 	return nil
 }
@@ -627,3 +1103,120 @@ func (c *fakeComponent) Objects() ([]client.Object, []client.Object) {
 func (c *fakeComponent) SupportedOSType() rmeta.OSType {
 	return c.supportedOSType
 }
+
+// A fake component that renders objects for several operating systems.
+type fakeMultiOSComponent struct {
+	fakeComponent
+	supportedOSTypes []rmeta.OSType
+}
+
+func (c *fakeMultiOSComponent) SupportedOSTypes() []rmeta.OSType {
+	return c.supportedOSTypes
+}
+
+// A fake component that declares dependencies.
+type fakeComponentWithDependencies struct {
+	fakeComponent
+	deps []client.Object
+}
+
+func (c *fakeComponentWithDependencies) Dependencies() []client.Object {
+	return c.deps
+}
+
+// A fake component that may require an ordered apply.
+type fakeComponentWithOrderedApply struct {
+	fakeComponent
+	ordered bool
+}
+
+func (c *fakeComponentWithOrderedApply) RequiresOrderedApply() bool {
+	return c.ordered
+}
+
+// A fake component that renders objects that must outlive the CR.
+type fakeComponentWithRetainedObjects struct {
+	fakeComponent
+	retained []client.Object
+}
+
+func (c *fakeComponentWithRetainedObjects) RetainedObjects() []client.Object {
+	return c.retained
+}
+
+// A client that records the names of the objects that it creates and updates, in order. Like the API server, it
+// applies the defaults to the object of a dry run update, if it has any.
+type recordingClient struct {
+	client.Client
+	// defaults sets the fields that the API server defaults on the objects that it stores.
+	defaults func(client.Object)
+	created  []string
+	updated  []string
+}
+
+func (c *recordingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	c.created = append(c.created, obj.GetName())
+	if c.defaults != nil {
+		c.defaults(obj)
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *recordingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	updateOptions := &client.UpdateOptions{}
+	updateOptions.ApplyOptions(opts)
+	if len(updateOptions.DryRun) == 0 {
+		c.updated = append(c.updated, obj.GetName())
+	}
+	if c.defaults != nil {
+		c.defaults(obj)
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+// withoutRenderedHash returns a copy of the annotations without the hash that the handler sets on the objects that it
+// applies.
+func withoutRenderedHash(annotations map[string]string) map[string]string {
+	copied := map[string]string{}
+	for k, v := range annotations {
+		if k != utils.RenderedHashAnnotation {
+			copied[k] = v
+		}
+	}
+	return copied
+}
+
+type logEntry struct {
+	level         int
+	msg           string
+	keysAndValues []interface{}
+}
+
+// A logger that records the messages that it logs with their level. The keys and values of the context are not
+// recorded.
+type recordingLogger struct {
+	level   int
+	entries *[]logEntry
+}
+
+func (l *recordingLogger) Enabled() bool {
+	return true
+}
+
+func (l *recordingLogger) Info(msg string, keysAndValues ...interface{}) {
+	*l.entries = append(*l.entries, logEntry{level: l.level, msg: msg, keysAndValues: keysAndValues})
+}
+
+func (l *recordingLogger) Error(err error, msg string, keysAndValues ...interface{}) {}
+
+func (l *recordingLogger) V(level int) logr.Logger {
+	return &recordingLogger{level: l.level + level, entries: l.entries}
+}
+
+func (l *recordingLogger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	return l
+}
+
+func (l *recordingLogger) WithName(name string) logr.Logger {
+	return l
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultFailureEventInterval is the interval within which the same failure is emitted as an event only once.
+const DefaultFailureEventInterval = 5 * time.Minute
+
+// FailureEvents emits warning events for the failures of a controller to render its components. A failure persists
+// across reconciles until the user fixes it, so an event is only emitted again once the interval has passed since the
+// same failure was emitted for the same object. A nil FailureEvents emits nothing.
+type FailureEvents struct {
+	recorder record.EventRecorder
+	interval time.Duration
+	now      func() time.Time
+
+	lock    sync.Mutex
+	emitted map[failureEventKey]time.Time
+}
+
+type failureEventKey struct {
+	object  types.NamespacedName
+	uid     types.UID
+	reason  string
+	message string
+}
+
+// NewFailureEvents returns a FailureEvents that emits the events with the given recorder, and emits the same failure
+// at most once per interval.
+func NewFailureEvents(recorder record.EventRecorder, interval time.Duration) *FailureEvents {
+	return &FailureEvents{
+		recorder: recorder,
+		interval: interval,
+		now:      time.Now,
+		emitted:  map[failureEventKey]time.Time{},
+	}
+}
+
+// Warn emits a warning event on the object with the given reason and message, unless the same event was emitted for
+// the object within the interval.
+func (e *FailureEvents) Warn(obj client.Object, reason, message string) {
+	if e == nil {
+		return
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	now := e.now()
+	// Forget the failures that can be emitted again, so that failures that were fixed are not kept forever.
+	for k, t := range e.emitted {
+		if now.Sub(t) >= e.interval {
+			delete(e.emitted, k)
+		}
+	}
+	key := failureEventKey{object: client.ObjectKeyFromObject(obj), uid: obj.GetUID(), reason: reason, message: message}
+	if _, ok := e.emitted[key]; ok {
+		return
+	}
+	e.emitted[key] = now
+	e.recorder.Event(obj, corev1.EventTypeWarning, reason, message)
+}
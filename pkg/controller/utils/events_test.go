@@ -0,0 +1,71 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	operatorv1 "github.com/tigera/operator/api/v1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+var _ = Describe("FailureEvents", func() {
+	var (
+		recorder *record.FakeRecorder
+		events   *FailureEvents
+		now      time.Time
+		auth     *operatorv1.Authentication
+	)
+
+	BeforeEach(func() {
+		recorder = record.NewFakeRecorder(10)
+		events = NewFailureEvents(recorder, 5*time.Minute)
+		now = time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+		events.now = func() time.Time { return now }
+		auth = &operatorv1.Authentication{ObjectMeta: metav1.ObjectMeta{Name: "tigera-secure", UID: "1"}}
+	})
+
+	It("emits a failure again only once the interval has passed", func() {
+		events.Warn(auth, "ImageResolutionFailed", "missing images: tigera/dex")
+		Expect(recorder.Events).To(Receive(Equal("Warning ImageResolutionFailed missing images: tigera/dex")))
+
+		now = now.Add(4 * time.Minute)
+		events.Warn(auth, "ImageResolutionFailed", "missing images: tigera/dex")
+		Expect(recorder.Events).NotTo(Receive())
+
+		now = now.Add(time.Minute)
+		events.Warn(auth, "ImageResolutionFailed", "missing images: tigera/dex")
+		Expect(recorder.Events).To(Receive(Equal("Warning ImageResolutionFailed missing images: tigera/dex")))
+	})
+
+	It("emits a different failure or a failure of another object right away", func() {
+		events.Warn(auth, "ImageResolutionFailed", "missing images: tigera/dex")
+		events.Warn(auth, "ImageResolutionFailed", "missing images: tigera/key-cert-provisioner")
+		events.Warn(auth, "InvalidConfiguration", "missing images: tigera/dex")
+		other := &operatorv1.Authentication{ObjectMeta: metav1.ObjectMeta{Name: "tigera-secure", UID: "2"}}
+		events.Warn(other, "ImageResolutionFailed", "missing images: tigera/dex")
+		Expect(recorder.Events).To(HaveLen(4))
+	})
+
+	It("emits nothing when it is nil", func() {
+		var nilEvents *FailureEvents
+		Expect(func() { nilEvents.Warn(auth, "ImageResolutionFailed", "missing images: tigera/dex") }).NotTo(Panic())
+	})
+})
@@ -16,7 +16,9 @@ package imageset
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -147,12 +149,19 @@ func ValidateImageSet(is *operator.ImageSet) error {
 	return fmt.Errorf("ImageSet %s: %s", is.Name, strings.Join(errMsgs, "; "))
 }
 
+// ResolveImages calls ResolveImages on each of the comps. The components.ImageErrors that the comps return are
+// collected, so that the returned error unwraps to all of them.
 func ResolveImages(is *operator.ImageSet, comps ...render.Component) error {
 	errMsgs := []string{}
+	var imageErrs components.ImageErrors
 	for _, comp := range comps {
 		err := comp.ResolveImages(is)
 		if err != nil {
 			errMsgs = append(errMsgs, err.Error())
+			var errs components.ImageErrors
+			if errors.As(err, &errs) {
+				imageErrs = append(imageErrs, errs...)
+			}
 		}
 	}
 
@@ -160,5 +169,90 @@ func ResolveImages(is *operator.ImageSet, comps ...render.Component) error {
 		return nil
 	}
 
-	return fmt.Errorf("Invalid ImageSet: %s", strings.Join(errMsgs, ", "))
+	return &resolveError{msg: fmt.Sprintf("Invalid ImageSet: %s", strings.Join(errMsgs, ", ")), images: imageErrs}
+}
+
+// resolveError is returned by ResolveImages. It unwraps to the components.ImageErrors of the comps, if there are any.
+type resolveError struct {
+	msg    string
+	images components.ImageErrors
+}
+
+func (e *resolveError) Error() string {
+	return e.msg
+}
+
+func (e *resolveError) Unwrap() error {
+	if len(e.images) == 0 {
+		return nil
+	}
+	return e.images
+}
+
+// CompareImageSet compares the images of an ImageSet with the images that the components require. It returns the
+// images that the components require but the ImageSet lacks, and the images in the ImageSet that none of the
+// components require. Components that do not implement render.ComponentWithImages are not taken into account.
+func CompareImageSet(is *operator.ImageSet, comps ...render.Component) (missing, extra []string) {
+	required := map[string]bool{}
+	for _, comp := range comps {
+		if c, ok := comp.(render.ComponentWithImages); ok {
+			for _, img := range c.RequiredImages() {
+				required[img] = true
+			}
+		}
+	}
+
+	present := map[string]bool{}
+	if is != nil {
+		for _, img := range is.Spec.Images {
+			present[img.Image] = true
+			if !required[img.Image] {
+				extra = append(extra, img.Image)
+			}
+		}
+	}
+	for img := range required {
+		if !present[img] {
+			missing = append(missing, img)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(extra)
+	return missing, extra
+}
+
+// ValidateImageSetCompleteness checks that the ImageSet has an entry for every image that the comps require, so that it
+// can be called before any of the comps is rendered. The error lists all missing images at once. The images in the
+// ImageSet that none of the comps require are returned rather than reported as an error, since an ImageSet also has the
+// images of the components of other controllers. Without an ImageSet there is nothing to check.
+func ValidateImageSetCompleteness(is *operator.ImageSet, comps ...render.Component) (extra []string, err error) {
+	if is == nil {
+		return nil, nil
+	}
+	missing, extra := CompareImageSet(is, comps...)
+	if len(missing) != 0 {
+		return extra, fmt.Errorf("ImageSet %s: missing images: %s", is.Name, strings.Join(missing, ", "))
+	}
+	return extra, nil
+}
+
+// ValidateImageSetForComponents returns an error listing the images that the components require but the ImageSet
+// lacks, and the images in the ImageSet that none of the components require.
+func ValidateImageSetForComponents(is *operator.ImageSet, comps ...render.Component) error {
+	missing, extra := CompareImageSet(is, comps...)
+	if len(missing) == 0 && len(extra) == 0 {
+		return nil
+	}
+
+	errMsgs := []string{}
+	if len(missing) != 0 {
+		errMsgs = append(errMsgs, fmt.Sprintf("missing images: %s", strings.Join(missing, ", ")))
+	}
+	if len(extra) != 0 {
+		errMsgs = append(errMsgs, fmt.Sprintf("extra images: %s", strings.Join(extra, ", ")))
+	}
+	if is == nil {
+		return fmt.Errorf("no ImageSet: %s", strings.Join(errMsgs, "; "))
+	}
+	return fmt.Errorf("ImageSet %s: %s", is.Name, strings.Join(errMsgs, "; "))
 }
@@ -16,6 +16,7 @@ package imageset
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	. "github.com/onsi/ginkgo"
@@ -31,6 +32,8 @@ import (
 	operator "github.com/tigera/operator/api/v1"
 	"github.com/tigera/operator/pkg/apis"
 	"github.com/tigera/operator/pkg/components"
+	"github.com/tigera/operator/pkg/render"
+	rmeta "github.com/tigera/operator/pkg/render/common/meta"
 )
 
 var _ = Describe("imageset tests", func() {
@@ -112,4 +115,130 @@ var _ = Describe("imageset tests", func() {
 			Entry("Enterprise variant", operator.TigeraSecureEnterprise),
 		)
 	})
+
+	Context("Test imageset against components", func() {
+		It("should report the missing and extra images", func() {
+			is := &operator.ImageSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "enterprise-test"},
+				Spec: operator.ImageSetSpec{
+					Images: []operator.Image{
+						{Image: components.ComponentDex.Image, Digest: "sha256:xxxxxxxxx"},
+						{Image: components.ComponentCalicoTypha.Image, Digest: "sha256:xxxxxxxxx"},
+					},
+				},
+			}
+			comp := &fakeComponentWithImages{images: []string{components.ComponentDex.Image, components.ComponentCSRInitContainer.Image}}
+
+			missing, extra := CompareImageSet(is, comp)
+			Expect(missing).To(Equal([]string{components.ComponentCSRInitContainer.Image}))
+			Expect(extra).To(Equal([]string{components.ComponentCalicoTypha.Image}))
+			Expect(ValidateImageSetForComponents(is, comp)).To(MatchError(fmt.Sprintf(
+				"ImageSet enterprise-test: missing images: %s; extra images: %s",
+				components.ComponentCSRInitContainer.Image, components.ComponentCalicoTypha.Image)))
+		})
+
+		It("should accept an ImageSet with exactly the required images", func() {
+			is := &operator.ImageSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "enterprise-test"},
+				Spec: operator.ImageSetSpec{
+					Images: []operator.Image{{Image: components.ComponentDex.Image, Digest: "sha256:xxxxxxxxx"}},
+				},
+			}
+			Expect(ValidateImageSetForComponents(is, &fakeComponentWithImages{images: []string{components.ComponentDex.Image}})).To(Succeed())
+		})
+
+		It("should report all missing images at once but only return the extra images", func() {
+			is := &operator.ImageSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "enterprise-test"},
+				Spec: operator.ImageSetSpec{
+					Images: []operator.Image{{Image: components.ComponentCalicoTypha.Image, Digest: "sha256:xxxxxxxxx"}},
+				},
+			}
+			comps := []render.Component{
+				&fakeComponentWithImages{images: []string{components.ComponentDex.Image, components.ComponentCSRInitContainer.Image}},
+				&fakeComponentWithImages{images: []string{components.ComponentCSRInitContainer.Image}},
+			}
+
+			extra, err := ValidateImageSetCompleteness(is, comps...)
+			Expect(err).To(MatchError(fmt.Sprintf("ImageSet enterprise-test: missing images: %s, %s",
+				components.ComponentDex.Image, components.ComponentCSRInitContainer.Image)))
+			Expect(extra).To(Equal([]string{components.ComponentCalicoTypha.Image}))
+
+			is.Spec.Images = append(is.Spec.Images,
+				operator.Image{Image: components.ComponentDex.Image, Digest: "sha256:xxxxxxxxx"},
+				operator.Image{Image: components.ComponentCSRInitContainer.Image, Digest: "sha256:xxxxxxxxx"})
+			extra, err = ValidateImageSetCompleteness(is, comps...)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(extra).To(Equal([]string{components.ComponentCalicoTypha.Image}))
+		})
+
+		It("should not require an ImageSet", func() {
+			extra, err := ValidateImageSetCompleteness(nil, &fakeComponentWithImages{images: []string{components.ComponentDex.Image}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(extra).To(BeEmpty())
+		})
+
+		It("should collect the image errors of the components", func() {
+			dexErr := &components.ImageError{
+				Component: components.ComponentDex.Image,
+				Reference: "quay.io/tigera/dex",
+				Err:       fmt.Errorf("ImageSet did not contain image %s", components.ComponentDex.Image),
+			}
+			csrErr := &components.ImageError{
+				Component: components.ComponentCSRInitContainer.Image,
+				Reference: "quay.io/tigera/key-cert-provisioner",
+				Err:       fmt.Errorf("ImageSet did not contain image %s", components.ComponentCSRInitContainer.Image),
+			}
+			err := ResolveImages(nil,
+				&fakeComponentWithImages{err: components.ImageErrors{dexErr}},
+				&fakeComponentWithImages{err: fmt.Errorf("unstructured")},
+				&fakeComponentWithImages{},
+				&fakeComponentWithImages{err: components.ImageErrors{csrErr}},
+			)
+			Expect(err).To(MatchError(fmt.Sprintf("Invalid ImageSet: ImageSet did not contain image %s, unstructured, ImageSet did not contain image %s",
+				components.ComponentDex.Image, components.ComponentCSRInitContainer.Image)))
+
+			var imageErrs components.ImageErrors
+			Expect(errors.As(err, &imageErrs)).To(BeTrue())
+			Expect(imageErrs).To(Equal(components.ImageErrors{dexErr, csrErr}))
+		})
+
+		It("should not unwrap to image errors when the components return none", func() {
+			err := ResolveImages(nil, &fakeComponentWithImages{err: fmt.Errorf("unstructured")})
+			Expect(err).To(MatchError("Invalid ImageSet: unstructured"))
+			var imageErrs components.ImageErrors
+			Expect(errors.As(err, &imageErrs)).To(BeFalse())
+			Expect(ResolveImages(nil, &fakeComponentWithImages{})).To(Succeed())
+		})
+	})
 })
+
+// A fake component that only reports the images that it requires, and returns err from ResolveImages.
+type fakeComponentWithImages struct {
+	images []string
+	err    error
+}
+
+func (c *fakeComponentWithImages) ResolveImages(is *operator.ImageSet) error {
+	return c.err
+}
+
+func (c *fakeComponentWithImages) Objects() ([]client.Object, []client.Object) {
+	return nil, nil
+}
+
+func (c *fakeComponentWithImages) Ready() bool {
+	return true
+}
+
+func (c *fakeComponentWithImages) Validate() error {
+	return nil
+}
+
+func (c *fakeComponentWithImages) SupportedOSType() rmeta.OSType {
+	return rmeta.OSTypeAny
+}
+
+func (c *fakeComponentWithImages) RequiredImages() []string {
+	return c.images
+}
@@ -17,6 +17,7 @@ package utils
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -55,15 +56,13 @@ var OverlayInstanceKey = client.ObjectKey{Name: "overlay"}
 // ContextLoggerForResource provides a logger instance with context set for the provided object.
 func ContextLoggerForResource(log logr.Logger, obj client.Object) logr.Logger {
 	gvk := obj.GetObjectKind().GroupVersionKind()
-	name := obj.(metav1.ObjectMetaAccessor).GetObjectMeta().GetName()
-	namespace := obj.(metav1.ObjectMetaAccessor).GetObjectMeta().GetNamespace()
-	return log.WithValues("Name", name, "Namespace", namespace, "Kind", gvk.Kind)
+	return log.WithValues("Name", obj.GetName(), "Namespace", obj.GetNamespace(), "Kind", gvk.Kind)
 }
 
 // IgnoreObject returns true if the object has been marked as ignored by the user,
 // and returns false otherwise.
 func IgnoreObject(obj runtime.Object) bool {
-	a := obj.(metav1.ObjectMetaAccessor).GetObjectMeta().GetAnnotations()
+	a := obj.(metav1.Object).GetAnnotations()
 	if val, ok := a[unsupportedIgnoreAnnotation]; ok && val == "true" {
 		return true
 	}
@@ -228,6 +227,20 @@ func LogStorageExists(ctx context.Context, cli client.Client) (bool, error) {
 	return true, nil
 }
 
+// AvailableOSTypes returns the operating systems of the nodes that the Installation runs control plane components on,
+// which render.WithOSFilter filters the workloads of components by. The nodes are those that the kubernetes.io/os label
+// of the control plane node selector selects, or Linux nodes if the selector does not restrict the operating system.
+// The nodes themselves are not listed, so that a node that is cordoned or briefly missing does not remove workloads.
+func AvailableOSTypes(installation *operatorv1.InstallationSpec) []rmeta.OSType {
+	if installation == nil {
+		return nil
+	}
+	if os, ok := installation.ControlPlaneNodeSelector["kubernetes.io/os"]; ok {
+		return []rmeta.OSType{rmeta.OSType(os)}
+	}
+	return []rmeta.OSType{rmeta.OSTypeLinux}
+}
+
 // FetchLicenseKey returns the license if it has been installed. It's useful
 // to prevent rollout of TSEE components that might require it.
 // It will return an error if the license is not installed/cannot be read
@@ -365,15 +378,37 @@ func GetAmazonCloudIntegration(ctx context.Context, client client.Client) (*oper
 	return instance, nil
 }
 
-// GetAuthentication finds the authentication CR in your cluster.
+// GetAuthentication finds the authentication CR in your cluster. If there are several, the one that
+// GetAuthentications selects is returned.
 func GetAuthentication(ctx context.Context, cli client.Client) (*operatorv1.Authentication, error) {
-	authentication := &operatorv1.Authentication{}
-	err := cli.Get(ctx, DefaultTSEEInstanceKey, authentication)
-	if err != nil {
-		return nil, err
+	authentication, _, err := GetAuthentications(ctx, cli)
+	return authentication, err
+}
+
+// GetAuthentications returns the authentication CR that is in use and the ones that are ignored, since only one is
+// supported. The CR named tigera-secure is used if it exists, or else the oldest one, so that all controllers select
+// the same CR regardless of the order in which they were created. It returns a NotFound error if there is none.
+func GetAuthentications(ctx context.Context, cli client.Client) (*operatorv1.Authentication, []operatorv1.Authentication, error) {
+	list := &operatorv1.AuthenticationList{}
+	if err := cli.List(ctx, list); err != nil {
+		return nil, nil, err
+	}
+	if len(list.Items) == 0 {
+		return nil, nil, kerrors.NewNotFound(operatorv1.GroupVersion.WithResource("authentications").GroupResource(), DefaultTSEEInstanceKey.Name)
 	}
 
-	return authentication, nil
+	items := list.Items
+	sort.Slice(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if isDefault := a.Name == DefaultTSEEInstanceKey.Name; isDefault != (b.Name == DefaultTSEEInstanceKey.Name) {
+			return isDefault
+		}
+		if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+			return a.CreationTimestamp.Before(&b.CreationTimestamp)
+		}
+		return a.Name < b.Name
+	})
+	return &items[0], items[1:], nil
 }
 
 // GetElasticLicenseType returns the license type from elastic-licensing ConfigMap that ECK operator keeps updated.
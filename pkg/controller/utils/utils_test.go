@@ -16,20 +16,24 @@ package utils
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-logr/logr"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	operatorv1 "github.com/tigera/operator/api/v1"
 	"github.com/tigera/operator/pkg/apis"
 	"github.com/tigera/operator/pkg/render"
+	rmeta "github.com/tigera/operator/pkg/render/common/meta"
 
 	apps "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	batchv1beta "k8s.io/api/batch/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -85,3 +89,85 @@ var _ = Describe("Utils elasticsearch license type tests", func() {
 	})
 
 })
+
+var _ = Describe("GetAuthentications", func() {
+	var (
+		c   client.Client
+		ctx context.Context
+		t0  = metav1.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(apis.AddToScheme(scheme)).NotTo(HaveOccurred())
+		c = fake.NewFakeClientWithScheme(scheme)
+		ctx = context.Background()
+	})
+
+	create := func(name string, created metav1.Time) {
+		Expect(c.Create(ctx, &operatorv1.Authentication{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: created},
+		})).NotTo(HaveOccurred())
+	}
+	names := func(auths []operatorv1.Authentication) []string {
+		var n []string
+		for _, a := range auths {
+			n = append(n, a.Name)
+		}
+		return n
+	}
+
+	It("should return a NotFound error when there is no Authentication", func() {
+		_, _, err := GetAuthentications(ctx, c)
+		Expect(kerrors.IsNotFound(err)).To(BeTrue())
+		_, err = GetAuthentication(ctx, c)
+		Expect(kerrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should use an Authentication with any name when it is the only one", func() {
+		create("team-auth", t0)
+		used, ignored, err := GetAuthentications(ctx, c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(used.Name).To(Equal("team-auth"))
+		Expect(ignored).To(BeEmpty())
+	})
+
+	It("should use tigera-secure regardless of when it was created", func() {
+		create("a-auth", t0)
+		create(DefaultTSEEInstanceKey.Name, metav1.NewTime(t0.Add(time.Hour)))
+		used, ignored, err := GetAuthentications(ctx, c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(used.Name).To(Equal(DefaultTSEEInstanceKey.Name))
+		Expect(names(ignored)).To(Equal([]string{"a-auth"}))
+	})
+
+	It("should use the oldest Authentication, and the first by name when they are equally old", func() {
+		create("c-auth", metav1.NewTime(t0.Add(time.Hour)))
+		create("b-auth", t0)
+		create("a-auth", metav1.NewTime(t0.Add(time.Hour)))
+		used, ignored, err := GetAuthentications(ctx, c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(used.Name).To(Equal("b-auth"))
+		Expect(names(ignored)).To(Equal([]string{"a-auth", "c-auth"}))
+
+		auth, err := GetAuthentication(ctx, c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(auth.Name).To(Equal("b-auth"))
+	})
+})
+
+var _ = Describe("AvailableOSTypes", func() {
+	It("should return nothing without an Installation", func() {
+		Expect(AvailableOSTypes(nil)).To(BeEmpty())
+	})
+
+	It("should return Linux when the control plane node selector does not select an operating system", func() {
+		install := &operatorv1.InstallationSpec{ControlPlaneNodeSelector: map[string]string{"zone": "a"}}
+		Expect(AvailableOSTypes(install)).To(Equal([]rmeta.OSType{rmeta.OSTypeLinux}))
+	})
+
+	It("should return the operating system that the control plane node selector selects", func() {
+		install := &operatorv1.InstallationSpec{ControlPlaneNodeSelector: map[string]string{"kubernetes.io/os": "windows"}}
+		Expect(AvailableOSTypes(install)).To(Equal([]rmeta.OSType{rmeta.OSTypeWindows}))
+	})
+})
@@ -19,6 +19,10 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 const (
@@ -59,17 +63,145 @@ func GetClusterDomain(resolvConfPath string) (string, error) {
 	return clusterDomain, nil
 }
 
+// DefaultClusterDomainDetector detects the cluster domain from the resolv.conf at DefaultResolveConfPath.
+var DefaultClusterDomainDetector = NewClusterDomainDetector(DefaultResolveConfPath)
+
+// ClusterDomainDetector detects the cluster domain from the search domains in resolv.conf. The file is only read
+// once, the result is cached for the lifetime of the detector.
+type ClusterDomainDetector struct {
+	resolvConfPath string
+
+	once   sync.Once
+	domain string
+	err    error
+}
+
+// NewClusterDomainDetector returns a detector that reads the resolv.conf at the given path.
+func NewClusterDomainDetector(resolvConfPath string) *ClusterDomainDetector {
+	return &ClusterDomainDetector{resolvConfPath: resolvConfPath}
+}
+
+// Detect returns the detected cluster domain, or an error if it could not be detected.
+func (d *ClusterDomainDetector) Detect() (string, error) {
+	d.once.Do(func() {
+		d.domain, d.err = GetClusterDomain(d.resolvConfPath)
+	})
+	return d.domain, d.err
+}
+
+// ClusterDomain returns the normalized override if it is set. Otherwise it returns the detected cluster domain, falling
+// back to DefaultClusterDomain if it could not be detected.
+func (d *ClusterDomainDetector) ClusterDomain(override string) string {
+	if override = strings.Trim(override, "."); override != "" {
+		return override
+	}
+	if domain, err := d.Detect(); err == nil {
+		return NormalizeClusterDomain(domain)
+	}
+	return DefaultClusterDomain
+}
+
+// NormalizeClusterDomain strips the leading and trailing dots of a cluster domain, like the dot of the root of a fully
+// qualified domain, and returns DefaultClusterDomain for an empty cluster domain.
+func NormalizeClusterDomain(clusterDomain string) string {
+	if clusterDomain = strings.Trim(clusterDomain, "."); clusterDomain != "" {
+		return clusterDomain
+	}
+	return DefaultClusterDomain
+}
+
+// ValidateClusterDomain returns an error if the cluster domain is not a valid DNS subdomain as defined by RFC 1123, so
+// that the names of the services in the cluster domain are valid hostnames.
+func ValidateClusterDomain(clusterDomain string) error {
+	if errs := validation.IsDNS1123Subdomain(clusterDomain); len(errs) != 0 {
+		return fmt.Errorf("the cluster domain %q is invalid: %s", clusterDomain, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// Validate returns an error if the given cluster domain differs from the detected cluster domain. When the cluster
+// domain could not be detected, there is nothing to compare against and the given domain is accepted.
+func (d *ClusterDomainDetector) Validate(clusterDomain string) error {
+	detected, err := d.Detect()
+	if err != nil || detected == clusterDomain {
+		return nil
+	}
+	return fmt.Errorf("the cluster domain %s does not match the cluster domain %s that was detected from %s", clusterDomain, detected, d.resolvConfPath)
+}
+
+type serviceDNSNames struct {
+	shortNames bool
+	extraNames []string
+}
+
+// ServiceDNSNamesOption changes the DNS names returned by GetServiceDNSNames.
+type ServiceDNSNamesOption func(*serviceDNSNames)
+
+// WithShortNames adds the namespace-less short form <svc_name>.svc, which some legacy clients use.
+func WithShortNames() ServiceDNSNamesOption {
+	return func(n *serviceDNSNames) {
+		n.shortNames = true
+	}
+}
+
+// WithExtraNames appends the given names. Names that are not valid hostnames are left out, see ValidateDNSNames.
+func WithExtraNames(names ...string) ServiceDNSNamesOption {
+	return func(n *serviceDNSNames) {
+		n.extraNames = append(n.extraNames, names...)
+	}
+}
+
 // GetServiceDNSNames returns a list of a service's DNS names.
 // We return:
 // - <svc_name>
 // - <svc_name>.<ns>
 // - <svc_name>.<ns>.svc
 // - <svc_name>.<ns>.svc.<cluster-domain>
-func GetServiceDNSNames(name, namespace, clusterDomain string) []string {
-	return []string{
+// - <svc_name>.svc, with WithShortNames
+// - the valid names passed to WithExtraNames
+// Duplicates are removed, keeping the first occurrence, so the order is stable.
+func GetServiceDNSNames(name, namespace, clusterDomain string, opts ...ServiceDNSNamesOption) []string {
+	var o serviceDNSNames
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	names := []string{
 		name,
 		fmt.Sprintf("%s.%s", name, namespace),
 		fmt.Sprintf("%s.%s.svc", name, namespace),
 		fmt.Sprintf("%s.%s.svc.%s", name, namespace, clusterDomain),
 	}
+	if o.shortNames {
+		names = append(names, fmt.Sprintf("%s.svc", name))
+	}
+	for _, extra := range o.extraNames {
+		if len(validation.IsDNS1123Subdomain(extra)) == 0 {
+			names = append(names, extra)
+		}
+	}
+
+	var unique []string
+	seen := map[string]bool{}
+	for _, n := range names {
+		if !seen[n] {
+			seen[n] = true
+			unique = append(unique, n)
+		}
+	}
+	return unique
+}
+
+// ValidateDNSNames returns an error that lists the names that are not valid hostnames as defined by RFC 1123.
+func ValidateDNSNames(names ...string) error {
+	var problems []string
+	for _, name := range names {
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) != 0 {
+			problems = append(problems, fmt.Sprintf("%q: %s", name, strings.Join(errs, ", ")))
+		}
+	}
+	if len(problems) != 0 {
+		return fmt.Errorf("invalid DNS names: %s", strings.Join(problems, "; "))
+	}
+	return nil
 }
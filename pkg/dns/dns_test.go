@@ -15,7 +15,10 @@
 package dns_test
 
 import (
+	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
@@ -44,6 +47,82 @@ var _ = Describe("Common Tests", func() {
 		})
 	})
 
+	Context("Detect the cluster domain", func() {
+		var resolvConfPath string
+
+		BeforeEach(func() {
+			dir, err := ioutil.TempDir("", "dns")
+			Expect(err).NotTo(HaveOccurred())
+			resolvConfPath = filepath.Join(dir, "resolv.conf")
+			data, err := ioutil.ReadFile("testdata/resolv.conf")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ioutil.WriteFile(resolvConfPath, data, 0644)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(filepath.Dir(resolvConfPath))).To(Succeed())
+		})
+
+		It("Should only read resolv.conf once", func() {
+			detector := dns.NewClusterDomainDetector(resolvConfPath)
+			Expect(detector.Detect()).To(Equal("othername.local"))
+
+			Expect(os.Remove(resolvConfPath)).To(Succeed())
+			Expect(detector.Detect()).To(Equal("othername.local"))
+		})
+
+		It("Should cache the error when the cluster domain cannot be detected", func() {
+			detector := dns.NewClusterDomainDetector("does-not.exist")
+			_, err := detector.Detect()
+			Expect(err).To(HaveOccurred())
+			_, err = detector.Detect()
+			Expect(err).To(HaveOccurred())
+		})
+
+		DescribeTable("Should prefer an explicit cluster domain", func(path, override, expected string) {
+			if path == "" {
+				path = resolvConfPath
+			}
+			Expect(dns.NewClusterDomainDetector(path).ClusterDomain(override)).To(Equal(expected))
+		},
+			Entry("detected", "", "", "othername.local"),
+			Entry("override", "", "corp.local", "corp.local"),
+			Entry("not detected", "does-not.exist", "", dns.DefaultClusterDomain),
+			Entry("override when not detected", "does-not.exist", "corp.local", "corp.local"),
+			Entry("fully qualified override", "", "corp.local.", "corp.local"),
+			Entry("override of dots", "does-not.exist", ".", dns.DefaultClusterDomain),
+		)
+
+		DescribeTable("Should normalize the cluster domain", func(clusterDomain, expected string) {
+			Expect(dns.NormalizeClusterDomain(clusterDomain)).To(Equal(expected))
+		},
+			Entry("empty", "", dns.DefaultClusterDomain),
+			Entry("default", dns.DefaultClusterDomain, dns.DefaultClusterDomain),
+			Entry("fully qualified", "cluster.local.", dns.DefaultClusterDomain),
+			Entry("leading and trailing dots", "..corp.local..", "corp.local"),
+			Entry("custom", "corp.local", "corp.local"),
+		)
+
+		It("Should validate the cluster domain as an RFC 1123 subdomain", func() {
+			Expect(dns.ValidateClusterDomain("corp.local")).To(Succeed())
+			Expect(dns.ValidateClusterDomain("corp..local")).To(MatchError(HavePrefix(`the cluster domain "corp..local" is invalid: `)))
+			Expect(dns.ValidateClusterDomain("Corp.local")).NotTo(Succeed())
+			Expect(dns.ValidateClusterDomain("corp_local")).NotTo(Succeed())
+		})
+
+		It("Should reject a cluster domain that does not match the detected one", func() {
+			detector := dns.NewClusterDomainDetector(resolvConfPath)
+			Expect(detector.Validate("othername.local")).To(Succeed())
+
+			err := detector.Validate(dns.DefaultClusterDomain)
+			Expect(err).To(MatchError(fmt.Sprintf("the cluster domain cluster.local does not match the cluster domain othername.local that was detected from %s", resolvConfPath)))
+		})
+
+		It("Should accept any cluster domain when it cannot be detected", func() {
+			Expect(dns.NewClusterDomainDetector("does-not.exist").Validate("corp.local")).To(Succeed())
+		})
+	})
+
 	Context("Get all DNS names for a service", func() {
 		DescribeTable("Should return the correct services names", func(service, namespace, clusterDomain string, expectedDNSNames []string) {
 			names := dns.GetServiceDNSNames(service, namespace, clusterDomain)
@@ -52,5 +131,32 @@ var _ = Describe("Common Tests", func() {
 			Entry("default", "a", "b", dns.DefaultClusterDomain, []string{"a", "a.b", "a.b.svc", "a.b.svc.cluster.local"}),
 			Entry("default", "a", "b", "somedomain", []string{"a", "a.b", "a.b.svc", "a.b.svc.somedomain"}),
 		)
+
+		DescribeTable("Should add the requested names in a stable order", func(opts []dns.ServiceDNSNamesOption, expectedDNSNames []string) {
+			names := dns.GetServiceDNSNames("a", "b", dns.DefaultClusterDomain, opts...)
+			Expect(names).To(Equal(expectedDNSNames))
+		},
+			Entry("no options", nil,
+				[]string{"a", "a.b", "a.b.svc", "a.b.svc.cluster.local"}),
+			Entry("short names", []dns.ServiceDNSNamesOption{dns.WithShortNames()},
+				[]string{"a", "a.b", "a.b.svc", "a.b.svc.cluster.local", "a.svc"}),
+			Entry("extra names", []dns.ServiceDNSNamesOption{dns.WithExtraNames("a.example.com", "b.example.com")},
+				[]string{"a", "a.b", "a.b.svc", "a.b.svc.cluster.local", "a.example.com", "b.example.com"}),
+			Entry("duplicate names", []dns.ServiceDNSNamesOption{dns.WithShortNames(), dns.WithExtraNames("a.svc", "a.example.com", "a.b", "a.example.com")},
+				[]string{"a", "a.b", "a.b.svc", "a.b.svc.cluster.local", "a.svc", "a.example.com"}),
+			Entry("invalid extra names", []dns.ServiceDNSNamesOption{dns.WithExtraNames("a_b.example.com", "", "-a.example.com", "a.example.com")},
+				[]string{"a", "a.b", "a.b.svc", "a.b.svc.cluster.local", "a.example.com"}),
+		)
+
+		It("Should validate DNS names as RFC 1123 hostnames", func() {
+			Expect(dns.ValidateDNSNames("a", "a.example.com", "a-b.example.com")).NotTo(HaveOccurred())
+
+			err := dns.ValidateDNSNames("a.example.com", "a_b.example.com", "A.example.com")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(HavePrefix("invalid DNS names: "))
+			Expect(err.Error()).To(ContainSubstring(`"a_b.example.com"`))
+			Expect(err.Error()).To(ContainSubstring(`"A.example.com"`))
+			Expect(err.Error()).NotTo(ContainSubstring(`"a.example.com"`))
+		})
 	})
 })
@@ -63,8 +63,9 @@ type amazonCloudIntegrationComponent struct {
 func (c *amazonCloudIntegrationComponent) ResolveImages(is *operator.ImageSet) error {
 	reg := c.installation.Registry
 	path := c.installation.ImagePath
+	opts := components.ReferenceOptionsFor(c.installation)
 	var err error
-	c.image, err = components.GetReference(components.ComponentCloudControllers, reg, path, is)
+	c.image, err = components.GetReference(components.ComponentCloudControllers, reg, path, is, opts...)
 	return err
 }
 
@@ -124,6 +125,10 @@ func (c *amazonCloudIntegrationComponent) Ready() bool {
 	return true
 }
 
+func (c *amazonCloudIntegrationComponent) Validate() error {
+	return nil
+}
+
 // serviceAccount creates the service account used by the API server.
 func (c *amazonCloudIntegrationComponent) serviceAccount() *corev1.ServiceAccount {
 	return &corev1.ServiceAccount{
@@ -132,15 +132,16 @@ type apiServerComponent struct {
 func (c *apiServerComponent) ResolveImages(is *operator.ImageSet) error {
 	reg := c.installation.Registry
 	path := c.installation.ImagePath
+	opts := components.ReferenceOptionsFor(c.installation)
 	var err error
-	c.apiServerImage, err = components.GetReference(components.ComponentAPIServer, reg, path, is)
+	c.apiServerImage, err = components.GetReference(components.ComponentAPIServer, reg, path, is, opts...)
 
 	errMsgs := []string{}
 	if err != nil {
 		errMsgs = append(errMsgs, err.Error())
 	}
 
-	c.queryServerImage, err = components.GetReference(components.ComponentQueryServer, reg, path, is)
+	c.queryServerImage, err = components.GetReference(components.ComponentQueryServer, reg, path, is, opts...)
 	if err != nil {
 		errMsgs = append(errMsgs, err.Error())
 	}
@@ -217,6 +218,10 @@ func (c *apiServerComponent) Ready() bool {
 	return true
 }
 
+func (c *apiServerComponent) Validate() error {
+	return nil
+}
+
 // apiServiceRegistration creates an API service that registers Tigera Secure APIs (and API server).
 func (c *apiServerComponent) apiServiceRegistration(cert []byte) *apiregv1.APIService {
 	s := &apiregv1.APIService{
@@ -0,0 +1,250 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/go-ldap/ldap"
+
+	oprv1 "github.com/tigera/operator/api/v1"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// dexUsernameClaims are the claims of the ID tokens that Dex issues, which can hold the username. The components that
+// verify the tokens read the username claim from the tokens of Dex rather than from those of the identity provider.
+var dexUsernameClaims = []string{"email", "name", "preferred_username", "sub"}
+
+// ValidateAuthentication makes sure that the authentication spec is ready for use. Every problem is reported, each with
+// the path of the field that has to be modified. The controller rejects an Authentication with these errors before Dex
+// is rendered, and the Dex config reports them as problems, so that each rule is only written here.
+func ValidateAuthentication(authentication *oprv1.Authentication) field.ErrorList {
+	var errs field.ErrorList
+	spec := field.NewPath("spec")
+	oidc := authentication.Spec.OIDC
+	ldp := authentication.Spec.LDAP
+	ocp := authentication.Spec.Openshift
+
+	// We support using only one connector at once.
+	var connectors []string
+	if oidc != nil {
+		connectors = append(connectors, spec.Child("oidc").String())
+	}
+	if ldp != nil {
+		connectors = append(connectors, spec.Child("ldap").String())
+	}
+	if ocp != nil {
+		connectors = append(connectors, spec.Child("openshift").String())
+	}
+	if len(connectors) == 0 {
+		errs = append(errs, field.Required(spec, "no identity provider connector was specified, please add one of oidc, ldap or openshift"))
+	} else if len(connectors) > 1 {
+		errs = append(errs, field.Forbidden(spec, fmt.Sprintf("only 1 identity provider connector is allowed, but %s are specified", strings.Join(connectors, ", "))))
+	}
+
+	if oidc != nil {
+		fld := spec.Child("oidc")
+		// If the user has specified the deprecated and the new prefix field, but with different values, we cannot proceed.
+		if oidc.UsernamePrefix != "" && authentication.Spec.UsernamePrefix != "" && oidc.UsernamePrefix != authentication.Spec.UsernamePrefix {
+			errs = append(errs, field.Invalid(fld.Child("usernamePrefix"), oidc.UsernamePrefix, "differs from spec.usernamePrefix, please remove this deprecated field"))
+		}
+		if oidc.GroupsPrefix != "" && authentication.Spec.GroupsPrefix != "" && oidc.GroupsPrefix != authentication.Spec.GroupsPrefix {
+			errs = append(errs, field.Invalid(fld.Child("groupsPrefix"), oidc.GroupsPrefix, "differs from spec.groupsPrefix, please remove this deprecated field"))
+		}
+
+		if err := ValidateIssuerURL(oidc.IssuerURL); err != nil {
+			errs = append(errs, field.Invalid(fld.Child("issuerURL"), oidc.IssuerURL, err.Error()))
+		}
+		if oidc.IssuerAlias != "" {
+			if !authentication.Spec.AllowIssuerAliasing {
+				errs = append(errs, field.Forbidden(fld.Child("issuerAlias"), "issuer aliasing is not allowed, please set spec.allowIssuerAliasing"))
+			} else if err := ValidateIssuerURL(oidc.IssuerAlias); err != nil {
+				errs = append(errs, field.Invalid(fld.Child("issuerAlias"), oidc.IssuerAlias, err.Error()))
+			}
+		}
+
+		// An empty username claim defaults to email.
+		if oidc.UsernameClaim != "" && !containsString(dexUsernameClaims, oidc.UsernameClaim) {
+			errs = append(errs, field.NotSupported(fld.Child("usernameClaim"), oidc.UsernameClaim, dexUsernameClaims))
+		}
+		if oidc.ConnectorScopes != nil && !containsString(oidc.ConnectorScopes, "openid") {
+			errs = append(errs, field.Invalid(fld.Child("connectorScopes"), oidc.ConnectorScopes, `must include "openid"`))
+		}
+		if strings.TrimSpace(oidc.GroupsClaim) != oidc.GroupsClaim {
+			errs = append(errs, field.Invalid(fld.Child("groupsClaim"), oidc.GroupsClaim, "must not have leading or trailing whitespace"))
+		}
+
+		for i, pt := range oidc.PromptTypes {
+			if pt.Value() == "" {
+				errs = append(errs, field.NotSupported(fld.Child("promptTypes").Index(i), pt, []string{
+					string(oprv1.PromptTypeNone), string(oprv1.PromptTypeLogin), string(oprv1.PromptTypeConsent), string(oprv1.PromptTypeSelectAccount),
+				}))
+			} else if pt == oprv1.PromptTypeNone && len(oidc.PromptTypes) > 1 {
+				errs = append(errs, field.Invalid(fld.Child("promptTypes").Index(i), pt, "prompt type None cannot be combined with other prompt types"))
+			}
+		}
+	}
+
+	if authentication.Spec.ManagerClient != nil {
+		for i, gt := range authentication.Spec.ManagerClient.GrantTypes {
+			if gt.Value() == "" {
+				errs = append(errs, field.NotSupported(spec.Child("managerClient", "grantTypes").Index(i), gt, []string{
+					string(oprv1.GrantTypeAuthorizationCode), string(oprv1.GrantTypeRefreshToken), string(oprv1.GrantTypeDeviceCode),
+				}))
+			}
+		}
+	}
+
+	// The uniqueness of the IDs is validated with the rest of the Dex config when Dex is rendered.
+	clientIDs := map[string]bool{DexClientId: true}
+	for i, c := range authentication.Spec.StaticClients {
+		fld := spec.Child("staticClients").Index(i)
+		if c.ID == "" {
+			errs = append(errs, field.Required(fld.Child("id"), "static clients must have an id"))
+		}
+		clientIDs[c.ID] = true
+		if c.Public && c.SecretName != "" {
+			errs = append(errs, field.Forbidden(fld.Child("secretName"), "a public static client must not have a secretName"))
+		}
+		if c.Public && c.GenerateSecret {
+			errs = append(errs, field.Forbidden(fld.Child("generateSecret"), "a public static client must not have a secret"))
+		}
+		if !c.Public && c.SecretName == "" {
+			errs = append(errs, field.Required(fld.Child("secretName"), "a static client must either be public or have a secretName"))
+		}
+		for j, uri := range c.RedirectURIs {
+			if u, err := url.Parse(uri); err != nil || u.Scheme == "" || u.Host == "" {
+				errs = append(errs, field.Invalid(fld.Child("redirectURIs").Index(j), uri, "must be an absolute URL"))
+			}
+		}
+	}
+	for i, c := range authentication.Spec.StaticClients {
+		for j, peer := range c.TrustedPeers {
+			if !clientIDs[peer] {
+				errs = append(errs, field.NotFound(spec.Child("staticClients").Index(i).Child("trustedPeers").Index(j), peer))
+			}
+		}
+	}
+	if kc := authentication.Spec.KubectlConfig; kc != nil {
+		fld := spec.Child("kubectlConfig")
+		var staticClient *oprv1.StaticClient
+		for i := range authentication.Spec.StaticClients {
+			if authentication.Spec.StaticClients[i].ID == kc.ClientID {
+				staticClient = &authentication.Spec.StaticClients[i]
+			}
+		}
+		if staticClient == nil {
+			errs = append(errs, field.NotFound(fld.Child("clientID"), kc.ClientID))
+		} else if !staticClient.Public {
+			errs = append(errs, field.Invalid(fld.Child("clientID"), kc.ClientID, "must be the ID of a public static client"))
+		}
+		if kc.Namespace != "" {
+			for _, msg := range validation.IsDNS1123Label(kc.Namespace) {
+				errs = append(errs, field.Invalid(fld.Child("namespace"), kc.Namespace, msg))
+			}
+		}
+	}
+	discoveryNamespaces := map[string]bool{}
+	for i, namespace := range authentication.Spec.DiscoveryNamespaces {
+		fld := spec.Child("discoveryNamespaces").Index(i)
+		if discoveryNamespaces[namespace] {
+			errs = append(errs, field.Duplicate(fld, namespace))
+		}
+		discoveryNamespaces[namespace] = true
+		for _, msg := range validation.IsDNS1123Label(namespace) {
+			errs = append(errs, field.Invalid(fld, namespace, msg))
+		}
+	}
+
+	if ocp != nil {
+		if err := ValidateIssuerURL(ocp.IssuerURL); err != nil {
+			errs = append(errs, field.Invalid(spec.Child("openshift", "issuerURL"), ocp.IssuerURL, err.Error()))
+		}
+	}
+
+	if ldp != nil {
+		fld := spec.Child("ldap")
+		if ldp.Host == "" {
+			errs = append(errs, field.Required(fld.Child("host"), ""))
+		}
+		if ldp.UserSearch == nil {
+			errs = append(errs, field.Required(fld.Child("userSearch"), ""))
+		} else {
+			if _, err := ldap.ParseDN(ldp.UserSearch.BaseDN); err != nil {
+				errs = append(errs, field.Invalid(fld.Child("userSearch", "baseDN"), ldp.UserSearch.BaseDN, fmt.Sprintf("invalid dn: %v", err)))
+			}
+			if ldp.UserSearch.Filter != "" {
+				if _, err := ldap.CompileFilter(ldp.UserSearch.Filter); err != nil {
+					errs = append(errs, field.Invalid(fld.Child("userSearch", "filter"), ldp.UserSearch.Filter, fmt.Sprintf("invalid filter: %v", err)))
+				}
+			}
+		}
+		if ldp.GroupSearch != nil {
+			if _, err := ldap.ParseDN(ldp.GroupSearch.BaseDN); err != nil {
+				errs = append(errs, field.Invalid(fld.Child("groupSearch", "baseDN"), ldp.GroupSearch.BaseDN, fmt.Sprintf("invalid dn: %v", err)))
+			}
+			if ldp.GroupSearch.Filter != "" {
+				if _, err := ldap.CompileFilter(ldp.GroupSearch.Filter); err != nil {
+					errs = append(errs, field.Invalid(fld.Child("groupSearch", "filter"), ldp.GroupSearch.Filter, fmt.Sprintf("invalid filter: %v", err)))
+				}
+			}
+		}
+	}
+
+	if dd := authentication.Spec.DexDeployment; dd != nil && dd.IdentityProviderSecretsStore != nil {
+		fld := spec.Child("dexDeployment", "identityProviderSecretsStore")
+		store := dd.IdentityProviderSecretsStore
+		for _, msg := range validation.IsDNS1123Subdomain(store.SecretProviderClass) {
+			errs = append(errs, field.Invalid(fld.Child("secretProviderClass"), store.SecretProviderClass, msg))
+		}
+		if store.NodePublishSecretName != "" {
+			for _, msg := range validation.IsDNS1123Subdomain(store.NodePublishSecretName) {
+				errs = append(errs, field.Invalid(fld.Child("nodePublishSecretName"), store.NodePublishSecretName, msg))
+			}
+		}
+	}
+	if dd := authentication.Spec.DexDeployment; dd != nil && dd.AvoidSpotNodes != nil {
+		fld := spec.Child("dexDeployment", "avoidSpotNodes")
+		for _, msg := range validation.IsQualifiedName(dd.AvoidSpotNodes.Key) {
+			errs = append(errs, field.Invalid(fld.Child("key"), dd.AvoidSpotNodes.Key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(dd.AvoidSpotNodes.Value) {
+			errs = append(errs, field.Invalid(fld.Child("value"), dd.AvoidSpotNodes.Value, msg))
+		}
+	}
+	if dd := authentication.Spec.DexDeployment; dd != nil {
+		fld := spec.Child("dexDeployment")
+		errs = append(errs, metav1validation.ValidateLabels(dd.ServiceAccountLabels, fld.Child("serviceAccountLabels"))...)
+		errs = append(errs, apivalidation.ValidateAnnotations(dd.ServiceAccountAnnotations, fld.Child("serviceAccountAnnotations"))...)
+	}
+	if dd := authentication.Spec.DexDeployment; dd != nil && dd.PrometheusRule != nil {
+		fld := spec.Child("dexDeployment", "prometheusRule")
+		if !dd.MetricsService {
+			errs = append(errs, field.Invalid(fld, "", "requires dexDeployment.metricsService"))
+		}
+		if ns := dd.PrometheusRule.Namespace; ns != "" {
+			for _, msg := range validation.IsDNS1123Label(ns) {
+				errs = append(errs, field.Invalid(fld.Child("namespace"), ns, msg))
+			}
+		}
+	}
+
+	return errs
+}
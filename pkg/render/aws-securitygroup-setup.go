@@ -44,8 +44,9 @@ func (c *awsSGSetupComponent) SupportedOSType() rmeta.OSType {
 func (c *awsSGSetupComponent) ResolveImages(is *operator.ImageSet) error {
 	reg := c.installcr.Registry
 	path := c.installcr.ImagePath
+	opts := components.ReferenceOptionsFor(c.installcr)
 	var err error
-	c.image, err = components.GetReference(components.ComponentOperatorInit, reg, path, is)
+	c.image, err = components.GetReference(components.ComponentOperatorInit, reg, path, is, opts...)
 	return err
 }
 
@@ -62,6 +63,10 @@ func (c *awsSGSetupComponent) Ready() bool {
 	return true
 }
 
+func (c *awsSGSetupComponent) Validate() error {
+	return nil
+}
+
 func (c *awsSGSetupComponent) setupJob() *batchv1.Job {
 	return &batchv1.Job{
 		TypeMeta: metav1.TypeMeta{Kind: "Job", APIVersion: "batch/v1"},
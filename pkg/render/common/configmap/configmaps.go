@@ -1,8 +1,11 @@
 package configmap
 
 import (
+	rmeta "github.com/tigera/operator/pkg/render/common/meta"
+
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // CopyToNamespace returns a new list of config maps generated from the ones given but with the namespace changed to the
@@ -17,3 +20,29 @@ func CopyToNamespace(ns string, oConfigMaps ...*v1.ConfigMap) []*v1.ConfigMap {
 	}
 	return configMaps
 }
+
+// ToRuntimeObjects converts the given list of config maps to a list of client.Objects
+func ToRuntimeObjects(configMaps ...*v1.ConfigMap) []client.Object {
+	var objs []client.Object
+	for _, configMap := range configMaps {
+		if configMap == nil {
+			continue
+		}
+		objs = append(objs, configMap)
+	}
+	return objs
+}
+
+// AnnotationHash generates a hash based off of the data in the given config maps that can be used by Deployments or
+// DaemonSets to trigger a restart/rolling update based on changes to the data of one or more config maps.
+func AnnotationHash(configMaps ...*v1.ConfigMap) string {
+	var annoteArr []interface{}
+	for _, configMap := range configMaps {
+		if configMap == nil {
+			continue
+		}
+		annoteArr = append(annoteArr, configMap.Data, configMap.BinaryData)
+	}
+
+	return rmeta.AnnotationHash(annoteArr)
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configmap
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("config map helpers", func() {
+	var ca, theme *v1.ConfigMap
+
+	BeforeEach(func() {
+		ca = &v1.ConfigMap{
+			TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "tigera-ca-bundle",
+				Namespace:       "tigera-operator",
+				Labels:          map[string]string{"app": "ca"},
+				ResourceVersion: "42",
+			},
+			Data: map[string]string{"ca.crt": "certificate"},
+		}
+		theme = &v1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "tigera-dex-theme", Namespace: "tigera-operator"},
+			BinaryData: map[string][]byte{"logo.png": {0x89, 0x50, 0x4e, 0x47}},
+		}
+	})
+
+	It("should copy the data of config maps into another namespace", func() {
+		copies := CopyToNamespace("tigera-dex", ca, theme)
+		Expect(copies).To(HaveLen(2))
+		Expect(copies[0].ObjectMeta).To(Equal(metav1.ObjectMeta{Name: "tigera-ca-bundle", Namespace: "tigera-dex"}))
+		Expect(copies[0].Data).To(Equal(ca.Data))
+		Expect(copies[1].ObjectMeta).To(Equal(metav1.ObjectMeta{Name: "tigera-dex-theme", Namespace: "tigera-dex"}))
+		Expect(copies[1].BinaryData).To(Equal(theme.BinaryData))
+
+		copies[0].Data["ca.crt"] = "changed"
+		Expect(ca.Data["ca.crt"]).To(Equal("certificate"))
+		Expect(ca.Namespace).To(Equal("tigera-operator"))
+	})
+
+	It("should convert config maps to objects and skip nil config maps", func() {
+		objs := ToRuntimeObjects(ca, nil, theme)
+		Expect(objs).To(HaveLen(2))
+		Expect(objs[0]).To(BeIdenticalTo(ca))
+		Expect(objs[1]).To(BeIdenticalTo(theme))
+		Expect(ToRuntimeObjects()).To(BeEmpty())
+	})
+
+	Context("annotation hash", func() {
+		It("should be stable and ignore metadata and nil config maps", func() {
+			hash := AnnotationHash(ca, theme)
+			Expect(hash).To(Equal(AnnotationHash(ca, nil, theme)))
+			Expect(hash).To(Equal(AnnotationHash(CopyToNamespace("tigera-dex", ca, theme)...)))
+		})
+
+		It("should change with the data of a config map", func() {
+			hash := AnnotationHash(ca, theme)
+			ca.Data["ca.crt"] = "rotated certificate"
+			Expect(AnnotationHash(ca, theme)).NotTo(Equal(hash))
+		})
+
+		It("should change with the binary data of a config map", func() {
+			hash := AnnotationHash(ca, theme)
+			theme.BinaryData["logo.png"] = []byte{0x00}
+			Expect(AnnotationHash(ca, theme)).NotTo(Equal(hash))
+		})
+
+		It("should not confuse data with binary data", func() {
+			asData := &v1.ConfigMap{Data: map[string]string{"key": "value"}}
+			asBinaryData := &v1.ConfigMap{BinaryData: map[string][]byte{"key": []byte("value")}}
+			Expect(AnnotationHash(asData)).NotTo(Equal(AnnotationHash(asBinaryData)))
+		})
+	})
+})
@@ -0,0 +1,216 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ObjectKey identifies a rendered object by its GroupVersionKind, namespace and name.
+type ObjectKey struct {
+	GVK       schema.GroupVersionKind
+	Namespace string
+	Name      string
+}
+
+func (k ObjectKey) String() string {
+	kind := k.GVK.Kind
+	if k.GVK.Group != "" {
+		kind = fmt.Sprintf("%s.%s", k.GVK.Kind, k.GVK.Group)
+	}
+	if k.Namespace == "" {
+		return fmt.Sprintf("%s/%s", kind, k.Name)
+	}
+	return fmt.Sprintf("%s/%s/%s", kind, k.Namespace, k.Name)
+}
+
+// ObjectChange describes an object that is present in both sets of objects, together with the paths of the fields
+// that differ between them, e.g. "spec.template.spec.containers[0].image".
+type ObjectChange struct {
+	Key    ObjectKey
+	Fields []string
+}
+
+// ObjectDiff is the difference between two sets of rendered objects. All lists are sorted by the string
+// representation of the keys.
+type ObjectDiff struct {
+	Added   []ObjectKey
+	Removed []ObjectKey
+	Changed []ObjectChange
+}
+
+// Empty returns true if the two sets of objects are equivalent.
+func (d ObjectDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// String returns a one line summary of the diff that is suitable for logging.
+func (d ObjectDiff) String() string {
+	var added, removed, changed []string
+	for _, k := range d.Added {
+		added = append(added, k.String())
+	}
+	for _, k := range d.Removed {
+		removed = append(removed, k.String())
+	}
+	for _, c := range d.Changed {
+		changed = append(changed, fmt.Sprintf("%s(%s)", c.Key, strings.Join(c.Fields, ",")))
+	}
+	return fmt.Sprintf("added: [%s], removed: [%s], changed: [%s]",
+		strings.Join(added, " "), strings.Join(removed, " "), strings.Join(changed, " "))
+}
+
+// DiffObjects compares the objects that were previously rendered with the newly rendered ones, e.g. the first return
+// values of two calls to the Objects() method of a component. Objects are matched by their GroupVersionKind,
+// namespace and name. If an object does not have its TypeMeta set, the name of its Go type is used as the kind.
+func DiffObjects(old, new []client.Object) ObjectDiff {
+	oldByKey := byKey(old)
+	newByKey := byKey(new)
+
+	var diff ObjectDiff
+	for key, n := range newByKey {
+		o, ok := oldByKey[key]
+		if !ok {
+			diff.Added = append(diff.Added, key)
+			continue
+		}
+		if fields := diffFields(o, n); len(fields) > 0 {
+			diff.Changed = append(diff.Changed, ObjectChange{Key: key, Fields: fields})
+		}
+	}
+	for key := range oldByKey {
+		if _, ok := newByKey[key]; !ok {
+			diff.Removed = append(diff.Removed, key)
+		}
+	}
+
+	sortKeys(diff.Added)
+	sortKeys(diff.Removed)
+	sort.Slice(diff.Changed, func(i, j int) bool {
+		return diff.Changed[i].Key.String() < diff.Changed[j].Key.String()
+	})
+	return diff
+}
+
+func byKey(objs []client.Object) map[ObjectKey]client.Object {
+	m := make(map[ObjectKey]client.Object, len(objs))
+	for _, obj := range objs {
+		if obj == nil || reflect.ValueOf(obj).IsNil() {
+			continue
+		}
+		m[keyOf(obj)] = obj
+	}
+	return m
+}
+
+func keyOf(obj client.Object) ObjectKey {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if gvk.Kind == "" {
+		gvk.Kind = reflect.Indirect(reflect.ValueOf(obj)).Type().Name()
+	}
+	return ObjectKey{GVK: gvk, Namespace: obj.GetNamespace(), Name: obj.GetName()}
+}
+
+func sortKeys(keys []ObjectKey) {
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+}
+
+// diffFields returns the sorted paths of the fields that differ between the two objects. The TypeMeta of the objects
+// is ignored since it is part of the key the objects were matched by.
+func diffFields(old, new client.Object) []string {
+	o, err := runtime.DefaultUnstructuredConverter.ToUnstructured(old)
+	if err != nil {
+		return []string{"<unknown>"}
+	}
+	n, err := runtime.DefaultUnstructuredConverter.ToUnstructured(new)
+	if err != nil {
+		return []string{"<unknown>"}
+	}
+	for _, m := range []map[string]interface{}{o, n} {
+		delete(m, "apiVersion")
+		delete(m, "kind")
+	}
+
+	var fields []string
+	diffValues("", o, n, false, func(path string, _, _ interface{}) { fields = append(fields, path) })
+	sort.Strings(fields)
+	return fields
+}
+
+// diffValues calls changed with every field that differs between the old and the new value at the given path. Maps are
+// compared by key and lists of the same length by index, so that the paths point at the fields that changed. Empty
+// values are the same as unset ones. With renderedOnly, only the fields that the new value sets are compared, so that
+// the fields that are only set in the old value, such as the fields that the API server defaults, are ignored.
+func diffValues(path string, old, new interface{}, renderedOnly bool, changed func(path string, old, new interface{})) {
+	if isEmpty(new) && (renderedOnly || isEmpty(old)) {
+		return
+	}
+	switch o := old.(type) {
+	case map[string]interface{}:
+		n, ok := new.(map[string]interface{})
+		if !ok {
+			break
+		}
+		if !renderedOnly {
+			for k, ov := range o {
+				if _, ok := n[k]; !ok {
+					diffValues(join(path, k), ov, nil, renderedOnly, changed)
+				}
+			}
+		}
+		for k, nv := range n {
+			diffValues(join(path, k), o[k], nv, renderedOnly, changed)
+		}
+		return
+	case []interface{}:
+		n, ok := new.([]interface{})
+		if !ok || len(o) != len(n) {
+			break
+		}
+		for i := range o {
+			diffValues(fmt.Sprintf("%s[%d]", path, i), o[i], n[i], renderedOnly, changed)
+		}
+		return
+	}
+	if !reflect.DeepEqual(old, new) {
+		changed(path, old, new)
+	}
+}
+
+func isEmpty(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(t) == 0
+	case []interface{}:
+		return len(t) == 0
+	}
+	return false
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
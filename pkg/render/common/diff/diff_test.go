@@ -0,0 +1,131 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("DiffObjects", func() {
+	var deployment, configMap, service func() client.Object
+
+	BeforeEach(func() {
+		deployment = func() client.Object {
+			return &appsv1.Deployment{
+				TypeMeta:   metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+				ObjectMeta: metav1.ObjectMeta{Name: "dex", Namespace: "tigera-dex"},
+				Spec: appsv1.DeploymentSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "tigera-dex", Image: "dex:v1"}},
+						},
+					},
+				},
+			}
+		}
+		configMap = func() client.Object {
+			return &corev1.ConfigMap{
+				TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+				ObjectMeta: metav1.ObjectMeta{Name: "tigera-dex", Namespace: "tigera-dex"},
+				Data:       map[string]string{"config.yaml": "issuer: https://example.com/dex"},
+			}
+		}
+		service = func() client.Object {
+			return &corev1.Service{
+				TypeMeta:   metav1.TypeMeta{Kind: "Service", APIVersion: "v1"},
+				ObjectMeta: metav1.ObjectMeta{Name: "tigera-dex", Namespace: "tigera-dex"},
+			}
+		}
+	})
+
+	It("should return an empty diff for equivalent objects", func() {
+		diff := DiffObjects([]client.Object{deployment(), configMap()}, []client.Object{configMap(), deployment()})
+		Expect(diff.Empty()).To(BeTrue())
+	})
+
+	It("should report added and removed objects", func() {
+		diff := DiffObjects([]client.Object{deployment(), configMap()}, []client.Object{deployment(), service()})
+
+		Expect(diff.Added).To(ConsistOf(ObjectKey{
+			GVK:       schema.GroupVersionKind{Version: "v1", Kind: "Service"},
+			Namespace: "tigera-dex",
+			Name:      "tigera-dex",
+		}))
+		Expect(diff.Removed).To(ConsistOf(ObjectKey{
+			GVK:       schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+			Namespace: "tigera-dex",
+			Name:      "tigera-dex",
+		}))
+		Expect(diff.Changed).To(BeEmpty())
+		Expect(diff.String()).To(Equal("added: [Service/tigera-dex/tigera-dex], removed: [ConfigMap/tigera-dex/tigera-dex], changed: []"))
+	})
+
+	It("should distinguish objects of different kinds with the same name", func() {
+		diff := DiffObjects([]client.Object{configMap()}, []client.Object{service()})
+		Expect(diff.Added).To(HaveLen(1))
+		Expect(diff.Removed).To(HaveLen(1))
+	})
+
+	It("should report the fields that changed", func() {
+		d := deployment().(*appsv1.Deployment)
+		d.Spec.Template.Spec.Containers[0].Image = "dex:v2"
+		d.Spec.Replicas = new(int32)
+		d.Labels = map[string]string{"k8s-app": "tigera-dex"}
+		cm := configMap().(*corev1.ConfigMap)
+		cm.Data = nil
+
+		diff := DiffObjects([]client.Object{deployment(), configMap()}, []client.Object{d, cm})
+
+		Expect(diff.Added).To(BeEmpty())
+		Expect(diff.Removed).To(BeEmpty())
+		Expect(diff.Changed).To(Equal([]ObjectChange{
+			{
+				Key:    ObjectKey{GVK: schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, Namespace: "tigera-dex", Name: "tigera-dex"},
+				Fields: []string{"data"},
+			},
+			{
+				Key: ObjectKey{GVK: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, Namespace: "tigera-dex", Name: "dex"},
+				Fields: []string{
+					"metadata.labels",
+					"spec.replicas",
+					"spec.template.spec.containers[0].image",
+				},
+			},
+		}))
+		Expect(diff.String()).To(ContainSubstring("Deployment.apps/tigera-dex/dex(metadata.labels,spec.replicas,spec.template.spec.containers[0].image)"))
+	})
+
+	It("should report a list whose length changed as a whole", func() {
+		d := deployment().(*appsv1.Deployment)
+		d.Spec.Template.Spec.Containers = append(d.Spec.Template.Spec.Containers, corev1.Container{Name: "sidecar"})
+
+		diff := DiffObjects([]client.Object{deployment()}, []client.Object{d})
+		Expect(diff.Changed).To(HaveLen(1))
+		Expect(diff.Changed[0].Fields).To(Equal([]string{"spec.template.spec.containers"}))
+	})
+
+	It("should fall back to the type name when the TypeMeta is not set", func() {
+		diff := DiffObjects(nil, []client.Object{&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "tigera-dex"}}})
+		Expect(diff.Added).To(ConsistOf(ObjectKey{GVK: schema.GroupVersionKind{Kind: "Secret"}, Name: "tigera-dex"}))
+		Expect(diff.Added[0].String()).To(Equal("Secret/tigera-dex"))
+	})
+})
@@ -0,0 +1,120 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const redacted = "<redacted>"
+
+// FieldDiff is a field that differs between the current and the desired state of an object. Old or New is nil if the
+// field is not set in that state.
+type FieldDiff struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// FieldDiffs returns the fields that differ between the current and the desired state of an object, ordered by their
+// path. The type meta, the status and the metadata that the API server maintains are not compared, so that the states
+// of an object that is read from the API server and of one that is rendered can be compared. The values of the data of
+// secrets are redacted.
+func FieldDiffs(current, desired runtime.Object) ([]FieldDiff, error) {
+	return fieldDiffs(current, desired, false)
+}
+
+// RenderedFieldDiffs returns the fields that the desired state of an object sets and that differ in its current state,
+// like FieldDiffs. The fields that only the current state sets, such as the fields that the API server defaults, are
+// not compared, so an object that is read from the API server has no diffs to the object that it was rendered from.
+func RenderedFieldDiffs(current, desired runtime.Object) ([]FieldDiff, error) {
+	return fieldDiffs(current, desired, true)
+}
+
+func fieldDiffs(current, desired runtime.Object, renderedOnly bool) ([]FieldDiff, error) {
+	cur, err := comparableContent(current)
+	if err != nil {
+		return nil, err
+	}
+	des, err := comparableContent(desired)
+	if err != nil {
+		return nil, err
+	}
+	var diffs []FieldDiff
+	diffValues("", cur, des, renderedOnly, func(path string, old, new interface{}) {
+		diffs = append(diffs, FieldDiff{Path: path, Old: old, New: new})
+	})
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+
+	if isSecret(desired) {
+		for i := range diffs {
+			if !isSecretData(diffs[i].Path) {
+				continue
+			}
+			if diffs[i].Old != nil {
+				diffs[i].Old = redacted
+			}
+			if diffs[i].New != nil {
+				diffs[i].New = redacted
+			}
+		}
+	}
+	return diffs, nil
+}
+
+// comparableContent returns the content of the object without the fields that FieldDiffs does not compare.
+func comparableContent(obj runtime.Object) (map[string]interface{}, error) {
+	var content map[string]interface{}
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		content = u.DeepCopy().UnstructuredContent()
+	} else {
+		var err error
+		if content, err = runtime.DefaultUnstructuredConverter.ToUnstructured(obj); err != nil {
+			return nil, fmt.Errorf("failed to convert %T for comparison: %w", obj, err)
+		}
+	}
+	delete(content, "apiVersion")
+	delete(content, "kind")
+	delete(content, "status")
+	if meta, ok := content["metadata"].(map[string]interface{}); ok {
+		for _, f := range []string{"creationTimestamp", "generation", "managedFields", "resourceVersion", "selfLink", "uid"} {
+			delete(meta, f)
+		}
+	}
+	return content, nil
+}
+
+func isSecretData(path string) bool {
+	for _, f := range []string{"data", "stringData"} {
+		if path == f || strings.HasPrefix(path, f+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func isSecret(obj runtime.Object) bool {
+	if _, ok := obj.(*corev1.Secret); ok {
+		return true
+	}
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	return gvk.Group == "" && gvk.Kind == "Secret"
+}
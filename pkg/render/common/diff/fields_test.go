@@ -0,0 +1,122 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("FieldDiffs", func() {
+	It("returns the paths of the changed fields with their values", func() {
+		current := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test-namespace", Labels: map[string]string{"app": "test"}},
+			Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "test", Image: "test:1"}},
+			}}},
+		}
+		desired := current.DeepCopy()
+		desired.Labels = map[string]string{"app": "test", "version": "2"}
+		desired.Spec.Template.Spec.Containers[0].Image = "test:2"
+		desired.Spec.Template.Spec.Containers[0].Args = []string{"--debug"}
+
+		diffs, err := FieldDiffs(current, desired)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diffs).To(Equal([]FieldDiff{
+			{Path: "metadata.labels.version", New: "2"},
+			{Path: "spec.template.spec.containers[0].args", New: []interface{}{"--debug"}},
+			{Path: "spec.template.spec.containers[0].image", Old: "test:1", New: "test:2"},
+		}))
+	})
+
+	It("does not compare the fields that the API server maintains", func() {
+		current := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test-namespace", ResourceVersion: "42", UID: "1", Generation: 2},
+			Data:       map[string]string{"key": "value"},
+		}
+		desired := &corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test-namespace", Annotations: map[string]string{}},
+			Data:       map[string]string{"key": "value"},
+		}
+
+		diffs, err := FieldDiffs(current, desired)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diffs).To(BeEmpty())
+	})
+
+	It("redacts the data of secrets", func() {
+		current := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test-namespace"},
+			Data:       map[string][]byte{"password": []byte("old"), "removed": []byte("old")},
+		}
+		desired := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test-namespace", Labels: map[string]string{"app": "test"}},
+			Data:       map[string][]byte{"password": []byte("new")},
+		}
+
+		diffs, err := FieldDiffs(current, desired)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diffs).To(Equal([]FieldDiff{
+			{Path: "data.password", Old: "<redacted>", New: "<redacted>"},
+			{Path: "data.removed", Old: "<redacted>"},
+			{Path: "metadata.labels", New: map[string]interface{}{"app": "test"}},
+		}))
+	})
+})
+
+var _ = Describe("RenderedFieldDiffs", func() {
+	rendered := func(image string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test-namespace"},
+			Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "test", Image: image}},
+			}}},
+		}
+	}
+	// serverDefaulted returns the deployment as the API server stores it, with the fields that it defaults.
+	serverDefaulted := func(image string) *appsv1.Deployment {
+		d := rendered(image)
+		limit := int32(10)
+		d.ResourceVersion = "42"
+		d.Spec.RevisionHistoryLimit = &limit
+		d.Spec.Strategy = appsv1.DeploymentStrategy{Type: appsv1.RollingUpdateDeploymentStrategyType}
+		d.Spec.Template.Spec.RestartPolicy = corev1.RestartPolicyAlways
+		d.Spec.Template.Spec.Containers[0].ImagePullPolicy = corev1.PullIfNotPresent
+		d.Spec.Template.Spec.Containers[0].TerminationMessagePath = corev1.TerminationMessagePathDefault
+		return d
+	}
+
+	It("ignores the fields that the API server defaults", func() {
+		diffs, err := RenderedFieldDiffs(serverDefaulted("test:1"), rendered("test:1"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diffs).To(BeEmpty())
+
+		diffs, err = FieldDiffs(serverDefaulted("test:1"), rendered("test:1"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diffs).NotTo(BeEmpty())
+	})
+
+	It("returns the rendered fields that changed", func() {
+		diffs, err := RenderedFieldDiffs(serverDefaulted("test:1"), rendered("test:2"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diffs).To(Equal([]FieldDiff{{Path: "spec.template.spec.containers[0].image", Old: "test:1", New: "test:2"}}))
+	})
+})
@@ -4,11 +4,15 @@ import (
 	"crypto/sha1"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
 	operatorv1 "github.com/tigera/operator/api/v1"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // This type helps ensure that we only use defined os types
@@ -26,6 +30,28 @@ const (
 	// NOTE: Do not change this field since we use this value to identify
 	// certificates managed by this operator.
 	TigeraOperatorCAIssuerPrefix = "tigera-operator-signer"
+
+	// The recommended labels that tooling uses to group the objects of an application. These are informational only,
+	// selectors keep using the k8s-app label.
+	AppNameLabel      = "app.kubernetes.io/name"
+	AppInstanceLabel  = "app.kubernetes.io/instance"
+	AppComponentLabel = "app.kubernetes.io/component"
+	AppPartOfLabel    = "app.kubernetes.io/part-of"
+	AppManagedByLabel = "app.kubernetes.io/managed-by"
+
+	AppPartOf    = "calico"
+	AppManagedBy = "tigera-operator"
+
+	// The annotations with which the service CA of OpenShift issues a serving certificate for a service into the
+	// secret that the annotation names, and injects its CA bundle into a ConfigMap under the OpenShiftServiceCAKey.
+	OpenShiftServingCertAnnotation    = "service.beta.openshift.io/serving-cert-secret-name"
+	OpenShiftInjectCABundleAnnotation = "service.beta.openshift.io/inject-cabundle"
+	OpenShiftServiceCAKey             = "service-ca.crt"
+
+	// ManagedAnnotationsAnnotation lists the keys of the annotations that the operator copies onto an object from the
+	// configuration of its CR. The other annotations of the object are kept when it is updated, but the listed ones are
+	// removed once they are no longer configured.
+	ManagedAnnotationsAnnotation = "operator.tigera.io/managed-annotations"
 )
 
 var (
@@ -105,3 +131,91 @@ func GetResourceRequirements(i *operatorv1.InstallationSpec, name operatorv1.Com
 	}
 	return corev1.ResourceRequirements{}
 }
+
+// AppLabels returns the recommended app.kubernetes.io labels for an application that is managed by the operator.
+func AppLabels(name, instance, component string) map[string]string {
+	return map[string]string{
+		AppNameLabel:      name,
+		AppInstanceLabel:  instance,
+		AppComponentLabel: component,
+		AppPartOfLabel:    AppPartOf,
+		AppManagedByLabel: AppManagedBy,
+	}
+}
+
+// AddAppLabels adds the labels to every object, and to the pod template of every workload. Existing labels with the
+// same keys are overwritten.
+func AddAppLabels(objs []client.Object, labels map[string]string) {
+	for _, obj := range objs {
+		obj.SetLabels(mergeLabels(obj.GetLabels(), labels))
+
+		var template *corev1.PodTemplateSpec
+		switch o := obj.(type) {
+		case *appsv1.Deployment:
+			template = &o.Spec.Template
+		case *appsv1.DaemonSet:
+			template = &o.Spec.Template
+		case *appsv1.StatefulSet:
+			template = &o.Spec.Template
+		}
+		if template != nil {
+			template.Labels = mergeLabels(template.Labels, labels)
+		}
+	}
+}
+
+func mergeLabels(existing, labels map[string]string) map[string]string {
+	if existing == nil {
+		existing = map[string]string{}
+	}
+	for k, v := range labels {
+		existing[k] = v
+	}
+	return existing
+}
+
+// SetManagedAnnotations adds the configured annotations to the object and lists their keys in the
+// ManagedAnnotationsAnnotation, next to the keys that it already lists, so that they are removed from the object once
+// they are no longer configured. Existing annotations with the same keys are overwritten.
+func SetManagedAnnotations(obj client.Object, annotations map[string]string) {
+	if len(annotations) == 0 {
+		return
+	}
+	existing := obj.GetAnnotations()
+	if existing == nil {
+		existing = map[string]string{}
+	}
+	listed := map[string]bool{}
+	var keys []string
+	for _, k := range ManagedAnnotationKeys(existing) {
+		if k != ManagedAnnotationsAnnotation {
+			listed[k] = true
+			keys = append(keys, k)
+		}
+	}
+	for k, v := range annotations {
+		existing[k] = v
+		if !listed[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	existing[ManagedAnnotationsAnnotation] = strings.Join(keys, ",")
+	obj.SetAnnotations(existing)
+}
+
+// ManagedAnnotationKeys returns the keys that the ManagedAnnotationsAnnotation of the annotations lists, and the key of
+// the ManagedAnnotationsAnnotation itself when it is set.
+func ManagedAnnotationKeys(annotations map[string]string) []string {
+	list, ok := annotations[ManagedAnnotationsAnnotation]
+	if !ok {
+		return nil
+	}
+	keys := []string{ManagedAnnotationsAnnotation}
+	for _, k := range strings.Split(list, ",") {
+		if k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
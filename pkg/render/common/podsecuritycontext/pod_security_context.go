@@ -2,14 +2,135 @@ package podsecuritycontext
 
 import (
 	"github.com/tigera/operator/pkg/ptr"
+	rmeta "github.com/tigera/operator/pkg/render/common/meta"
 	corev1 "k8s.io/api/core/v1"
 )
 
+// The user and group that containers with a restricted security context run as by default.
+const (
+	nonRootUserID  = 10001
+	nonRootGroupID = 10001
+)
+
+// The user that Windows containers run as by default, the unprivileged user of the Windows container images.
+const windowsUserName = "ContainerUser"
+
+// Option overrides the user and groups of a security context.
+type Option func(*ids)
+
+type ids struct {
+	user, group, fsGroup *int64
+	userName             *string
+	assigned             bool
+}
+
+// WithRunAsUser runs the containers as the given user.
+func WithRunAsUser(uid int64) Option {
+	return func(i *ids) { i.user = &uid }
+}
+
+// WithRunAsGroup runs the containers as the given group.
+func WithRunAsGroup(gid int64) Option {
+	return func(i *ids) { i.group = &gid }
+}
+
+// WithFSGroup sets the group that owns the volumes of the pod. It only applies to pod security contexts.
+func WithFSGroup(gid int64) Option {
+	return func(i *ids) { i.fsGroup = &gid }
+}
+
+// WithRunAsUserName runs Windows containers as the given user. It only applies to Windows security contexts.
+func WithRunAsUserName(name string) Option {
+	return func(i *ids) { i.userName = &name }
+}
+
+// WithAssignedIDs leaves the user and groups unset, so that the platform assigns them, like the security context
+// constraints of OpenShift assign a user from the range of the namespace. It takes precedence over the other options.
+func WithAssignedIDs() Option {
+	return func(i *ids) { i.assigned = true }
+}
+
+func newIDs(i ids, opts []Option) ids {
+	for _, opt := range opts {
+		opt(&i)
+	}
+	if i.assigned {
+		return ids{}
+	}
+	return i
+}
+
 // NewBaseContext returns the non root non privileged security context that most of the containers running should
 // be using.
-func NewBaseContext() *corev1.SecurityContext {
+func NewBaseContext(opts ...Option) *corev1.SecurityContext {
+	i := newIDs(ids{}, opts)
 	return &corev1.SecurityContext{
+		RunAsUser:                i.user,
+		RunAsGroup:               i.group,
 		RunAsNonRoot:             ptr.BoolToPtr(true),
 		AllowPrivilegeEscalation: ptr.BoolToPtr(false),
 	}
 }
+
+// NewBasePodContext returns the pod security context that goes with NewBaseContext. It only sets the user and groups
+// of the given options.
+func NewBasePodContext(opts ...Option) *corev1.PodSecurityContext {
+	i := newIDs(ids{}, opts)
+	return &corev1.PodSecurityContext{
+		RunAsUser:  i.user,
+		RunAsGroup: i.group,
+		FSGroup:    i.fsGroup,
+	}
+}
+
+// NewRestrictedContext returns a security context that satisfies the restricted Pod Security Standard: the container
+// runs as a non-root user and group, without privileges, privilege escalation or capabilities, and with the seccomp
+// profile of the container runtime.
+func NewRestrictedContext(opts ...Option) *corev1.SecurityContext {
+	i := newIDs(ids{user: ptr.Int64ToPtr(nonRootUserID), group: ptr.Int64ToPtr(nonRootGroupID)}, opts)
+	return &corev1.SecurityContext{
+		Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+		Privileged:               ptr.BoolToPtr(false),
+		RunAsUser:                i.user,
+		RunAsGroup:               i.group,
+		RunAsNonRoot:             ptr.BoolToPtr(true),
+		AllowPrivilegeEscalation: ptr.BoolToPtr(false),
+		SeccompProfile:           &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+	}
+}
+
+// NewRestrictedPodContext returns the pod security context that goes with NewRestrictedContext, so that the containers
+// of the pod that do not set these fields themselves satisfy the restricted Pod Security Standard as well.
+func NewRestrictedPodContext(opts ...Option) *corev1.PodSecurityContext {
+	i := newIDs(ids{user: ptr.Int64ToPtr(nonRootUserID), group: ptr.Int64ToPtr(nonRootGroupID)}, opts)
+	return &corev1.PodSecurityContext{
+		RunAsUser:      i.user,
+		RunAsGroup:     i.group,
+		FSGroup:        i.fsGroup,
+		RunAsNonRoot:   ptr.BoolToPtr(true),
+		SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+	}
+}
+
+// NewWindowsContext returns the security context of Windows containers. Windows does not support the Linux fields of
+// the base context, like the user ID or privilege escalation, so the container only runs as an unprivileged user name.
+func NewWindowsContext(opts ...Option) *corev1.SecurityContext {
+	userName := windowsUserName
+	i := newIDs(ids{userName: &userName}, opts)
+	if i.userName == nil {
+		return &corev1.SecurityContext{}
+	}
+	return &corev1.SecurityContext{
+		WindowsOptions: &corev1.WindowsSecurityContextOptions{RunAsUserName: i.userName},
+	}
+}
+
+// NewContextForOS returns the base context for containers that run on Linux and the Windows context for containers
+// that run on Windows. Containers that may run on any operating system get the base context, like the containers of
+// components that are not aware of Windows.
+func NewContextForOS(os rmeta.OSType, opts ...Option) *corev1.SecurityContext {
+	if os == rmeta.OSTypeWindows {
+		return NewWindowsContext(opts...)
+	}
+	return NewBaseContext(opts...)
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podsecuritycontext
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	rmeta "github.com/tigera/operator/pkg/render/common/meta"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("pod security context helpers", func() {
+	It("should not change the base context", func() {
+		sc := NewBaseContext()
+		Expect(*sc.RunAsNonRoot).To(BeTrue())
+		Expect(*sc.AllowPrivilegeEscalation).To(BeFalse())
+		Expect(sc.SeccompProfile).To(BeNil())
+		Expect(sc.RunAsUser).To(BeNil())
+	})
+
+	It("should satisfy the restricted Pod Security Standard with the restricted container context", func() {
+		sc := NewRestrictedContext()
+
+		// Privilege escalation must be disallowed.
+		Expect(sc.AllowPrivilegeEscalation).NotTo(BeNil())
+		Expect(*sc.AllowPrivilegeEscalation).To(BeFalse())
+		// The container may not be privileged.
+		Expect(sc.Privileged).NotTo(BeNil())
+		Expect(*sc.Privileged).To(BeFalse())
+		// All capabilities must be dropped, and none may be added.
+		Expect(sc.Capabilities).NotTo(BeNil())
+		Expect(sc.Capabilities.Drop).To(ConsistOf(corev1.Capability("ALL")))
+		Expect(sc.Capabilities.Add).To(BeEmpty())
+		// The container must run as non-root, with a user and group other than 0.
+		Expect(sc.RunAsNonRoot).NotTo(BeNil())
+		Expect(*sc.RunAsNonRoot).To(BeTrue())
+		Expect(sc.RunAsUser).NotTo(BeNil())
+		Expect(*sc.RunAsUser).NotTo(BeZero())
+		Expect(sc.RunAsGroup).NotTo(BeNil())
+		Expect(*sc.RunAsGroup).NotTo(BeZero())
+		// The seccomp profile must be RuntimeDefault or Localhost.
+		Expect(sc.SeccompProfile).To(Equal(&corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault}))
+	})
+
+	It("should satisfy the restricted Pod Security Standard with the restricted pod context", func() {
+		psc := NewRestrictedPodContext()
+
+		Expect(psc.RunAsNonRoot).NotTo(BeNil())
+		Expect(*psc.RunAsNonRoot).To(BeTrue())
+		Expect(psc.RunAsUser).NotTo(BeNil())
+		Expect(*psc.RunAsUser).NotTo(BeZero())
+		Expect(psc.RunAsGroup).NotTo(BeNil())
+		Expect(*psc.RunAsGroup).NotTo(BeZero())
+		Expect(psc.SeccompProfile).To(Equal(&corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault}))
+	})
+
+	It("should run the containers and the pod of the restricted contexts as the same user and group", func() {
+		sc, psc := NewRestrictedContext(), NewRestrictedPodContext()
+		Expect(sc.RunAsUser).To(Equal(psc.RunAsUser))
+		Expect(sc.RunAsGroup).To(Equal(psc.RunAsGroup))
+	})
+
+	It("should run the contexts as the user and groups of the options", func() {
+		opts := []Option{WithRunAsUser(1000), WithRunAsGroup(2000), WithFSGroup(3000)}
+
+		sc := NewRestrictedContext(opts...)
+		Expect(*sc.RunAsUser).To(BeEquivalentTo(1000))
+		Expect(*sc.RunAsGroup).To(BeEquivalentTo(2000))
+
+		psc := NewRestrictedPodContext(opts...)
+		Expect(*psc.RunAsUser).To(BeEquivalentTo(1000))
+		Expect(*psc.RunAsGroup).To(BeEquivalentTo(2000))
+		Expect(*psc.FSGroup).To(BeEquivalentTo(3000))
+
+		sc = NewBaseContext(opts...)
+		Expect(*sc.RunAsUser).To(BeEquivalentTo(1000))
+		Expect(*sc.RunAsGroup).To(BeEquivalentTo(2000))
+
+		psc = NewBasePodContext(WithRunAsUser(1000))
+		Expect(*psc.RunAsUser).To(BeEquivalentTo(1000))
+		Expect(psc.RunAsGroup).To(BeNil())
+		Expect(psc.FSGroup).To(BeNil())
+	})
+
+	It("should leave the user and groups to the platform when they are assigned", func() {
+		sc := NewRestrictedContext(WithRunAsUser(1000), WithAssignedIDs())
+		Expect(sc.RunAsUser).To(BeNil())
+		Expect(sc.RunAsGroup).To(BeNil())
+		Expect(*sc.RunAsNonRoot).To(BeTrue())
+
+		psc := NewRestrictedPodContext(WithAssignedIDs(), WithFSGroup(3000))
+		Expect(psc.RunAsUser).To(BeNil())
+		Expect(psc.RunAsGroup).To(BeNil())
+		Expect(psc.FSGroup).To(BeNil())
+		Expect(*psc.RunAsNonRoot).To(BeTrue())
+	})
+
+	It("should only set the Windows options in the Windows context", func() {
+		sc := NewWindowsContext(WithRunAsUser(1000), WithRunAsGroup(2000))
+		Expect(sc).To(Equal(&corev1.SecurityContext{
+			WindowsOptions: &corev1.WindowsSecurityContextOptions{RunAsUserName: ptrTo("ContainerUser")},
+		}))
+
+		sc = NewWindowsContext(WithRunAsUserName("ContainerAdministrator"))
+		Expect(*sc.WindowsOptions.RunAsUserName).To(Equal("ContainerAdministrator"))
+
+		Expect(NewWindowsContext(WithAssignedIDs())).To(Equal(&corev1.SecurityContext{}))
+	})
+
+	It("should select the context for the operating system", func() {
+		Expect(NewContextForOS(rmeta.OSTypeLinux)).To(Equal(NewBaseContext()))
+		Expect(NewContextForOS(rmeta.OSTypeAny)).To(Equal(NewBaseContext()))
+		Expect(NewContextForOS(rmeta.OSTypeWindows)).To(Equal(NewWindowsContext()))
+		Expect(NewContextForOS(rmeta.OSTypeLinux, WithRunAsUser(1000)).RunAsUser).To(Equal(NewBaseContext(WithRunAsUser(1000)).RunAsUser))
+	})
+})
+
+func ptrTo(s string) *string {
+	return &s
+}
@@ -0,0 +1,102 @@
+package secret
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CABundleKey is the key of the certificates in the secret that MergeCABundles returns.
+const CABundleKey = "ca-bundle.crt"
+
+// MergeCABundles returns a secret with the given name and namespace that contains the certificates of the given secrets
+// and config maps as a single PEM bundle, for example to let Dex trust the CA of an identity provider, the operator CA
+// and an intermediate CA. The certificates are taken from all values of the sources, in the order of the sources and of
+// their keys, so that the bundle only changes when a source does. A certificate that occurs more than once is only
+// added once, and PEM blocks that are not certificates, such as private keys, are skipped. An error is returned when a
+// certificate cannot be parsed, when a source is neither a secret nor a config map, or when there are no certificates.
+func MergeCABundles(name, namespace string, sources ...client.Object) (*corev1.Secret, error) {
+	var bundle bytes.Buffer
+	seen := map[string]bool{}
+	for _, source := range sources {
+		values, err := sourceValues(source)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range sortedKeys(values) {
+			rest := values[key]
+			for {
+				var block *pem.Block
+				block, rest = pem.Decode(rest)
+				if block == nil {
+					break
+				}
+				if block.Type != "CERTIFICATE" {
+					continue
+				}
+				if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+					return nil, fmt.Errorf("%s/%s has an invalid certificate in %s: %s", source.GetNamespace(), source.GetName(), key, err)
+				}
+				if seen[string(block.Bytes)] {
+					continue
+				}
+				seen[string(block.Bytes)] = true
+				if err := pem.Encode(&bundle, &pem.Block{Type: block.Type, Bytes: block.Bytes}); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if bundle.Len() == 0 {
+		return nil, fmt.Errorf("no certificates found for CA bundle %s/%s", namespace, name)
+	}
+
+	return &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Data:       map[string][]byte{CABundleKey: bundle.Bytes()},
+	}, nil
+}
+
+// sourceValues returns the values of a secret or config map by key. Nil sources have no values.
+func sourceValues(source client.Object) (map[string][]byte, error) {
+	values := map[string][]byte{}
+	switch s := source.(type) {
+	case nil:
+	case *corev1.Secret:
+		if s == nil {
+			break
+		}
+		for k, v := range s.Data {
+			values[k] = v
+		}
+	case *corev1.ConfigMap:
+		if s == nil {
+			break
+		}
+		for k, v := range s.Data {
+			values[k] = []byte(v)
+		}
+		for k, v := range s.BinaryData {
+			values[k] = v
+		}
+	default:
+		return nil, fmt.Errorf("cannot read certificates from %T %s/%s", source, source.GetNamespace(), source.GetName())
+	}
+	return values, nil
+}
+
+func sortedKeys(values map[string][]byte) []string {
+	var keys []string
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"encoding/pem"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tigera/operator/pkg/tls"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("CA bundles", func() {
+	newCA := func(name string) (cert, key []byte) {
+		ca, err := tls.MakeCA(name)
+		Expect(err).NotTo(HaveOccurred())
+		cert, key, err = ca.Config.GetPEMBytes()
+		Expect(err).NotTo(HaveOccurred())
+		return cert, key
+	}
+	certificates := func(bundle *corev1.Secret) []string {
+		var names []string
+		rest := bundle.Data[CABundleKey]
+		for {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				return names
+			}
+			Expect(block.Type).To(Equal("CERTIFICATE"))
+			names = append(names, string(block.Bytes))
+		}
+	}
+
+	var idpCA, operatorCA, intermediateCA, operatorKey []byte
+	var idp *corev1.ConfigMap
+	var operator, intermediate *corev1.Secret
+
+	BeforeEach(func() {
+		idpCA, _ = newCA("idp")
+		operatorCA, operatorKey = newCA("operator")
+		intermediateCA, _ = newCA("intermediate")
+		idp = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "idp-ca", Namespace: "tigera-operator"},
+			Data:       map[string]string{"ca.crt": string(idpCA)},
+		}
+		operator = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "operator-ca", Namespace: "tigera-operator"},
+			Data:       map[string][]byte{corev1.TLSCertKey: operatorCA, corev1.TLSPrivateKeyKey: operatorKey},
+		}
+		intermediate = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "intermediate-ca", Namespace: "tigera-operator"},
+			// The intermediate is usually delivered together with the CA of the identity provider.
+			Data: map[string][]byte{"ca.crt": append(append([]byte{}, intermediateCA...), idpCA...)},
+		}
+	})
+
+	It("should merge the certificates of secrets and config maps into one bundle", func() {
+		bundle, err := MergeCABundles("tigera-dex-ca-bundle", "tigera-dex", idp, operator, nil, intermediate)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bundle.ObjectMeta).To(Equal(metav1.ObjectMeta{Name: "tigera-dex-ca-bundle", Namespace: "tigera-dex"}))
+		Expect(bundle.Data).To(HaveLen(1))
+
+		// The certificate of the identity provider is only added once, and the private key is skipped.
+		expected := []string{}
+		for _, pemBytes := range [][]byte{idpCA, operatorCA, intermediateCA} {
+			block, _ := pem.Decode(pemBytes)
+			expected = append(expected, string(block.Bytes))
+		}
+		Expect(certificates(bundle)).To(Equal(expected))
+	})
+
+	It("should produce the same bundle from the same sources", func() {
+		first, err := MergeCABundles("bundle", "tigera-dex", idp, operator, intermediate)
+		Expect(err).NotTo(HaveOccurred())
+		second, err := MergeCABundles("bundle", "tigera-dex", idp.DeepCopy(), operator.DeepCopy(), intermediate.DeepCopy())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second.Data).To(Equal(first.Data))
+	})
+
+	It("should change the bundle when a source changes", func() {
+		before, err := MergeCABundles("bundle", "tigera-dex", idp, operator)
+		Expect(err).NotTo(HaveOccurred())
+		rotated, _ := newCA("idp")
+		idp.Data["ca.crt"] = string(rotated)
+		after, err := MergeCABundles("bundle", "tigera-dex", idp, operator)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(after.Data).NotTo(Equal(before.Data))
+	})
+
+	It("should reject certificates that do not parse", func() {
+		idp.Data["ca.crt"] = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("not a certificate")}))
+		_, err := MergeCABundles("bundle", "tigera-dex", idp, operator)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(HavePrefix("tigera-operator/idp-ca has an invalid certificate in ca.crt"))
+	})
+
+	It("should reject sources without certificates", func() {
+		_, err := MergeCABundles("bundle", "tigera-dex", &corev1.Secret{Data: map[string][]byte{"key": operatorKey}})
+		Expect(err).To(MatchError("no certificates found for CA bundle tigera-dex/bundle"))
+	})
+
+	It("should reject sources that are neither secrets nor config maps", func() {
+		_, err := MergeCABundles("bundle", "tigera-dex", &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: "ns"}})
+		Expect(err).To(HaveOccurred())
+	})
+})
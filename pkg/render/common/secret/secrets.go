@@ -3,6 +3,7 @@ package secret
 import (
 	"bytes"
 	"fmt"
+	"strings"
 	"time"
 
 	rmeta "github.com/tigera/operator/pkg/render/common/meta"
@@ -77,11 +78,15 @@ func getSecretFromTLSConfig(
 	}, nil
 }
 
+// lastAppliedConfigAnnotation is set by kubectl on the objects that it applies and describes the original object only.
+const lastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
 // CopyToNamespace returns a new list of secrets generated from the ones given but with the namespace changed to the
-// given one.
+// given one. The copies keep the type and data of the originals, but none of their other metadata. A secret that is
+// given more than once is only copied once.
 func CopyToNamespace(ns string, oSecrets ...*corev1.Secret) []*corev1.Secret {
 	var secrets []*corev1.Secret
-	for _, s := range oSecrets {
+	for _, s := range uniqueByName(oSecrets) {
 		x := s.DeepCopy()
 		x.ObjectMeta = metav1.ObjectMeta{Name: s.Name, Namespace: ns}
 
@@ -90,6 +95,103 @@ func CopyToNamespace(ns string, oSecrets ...*corev1.Secret) []*corev1.Secret {
 	return secrets
 }
 
+// CopyKeysToNamespace is like CopyToNamespace, but the copies only contain the given keys of the originals, so that the
+// other data of the originals is not exposed in the namespace. An error is returned when an original lacks a key.
+func CopyKeysToNamespace(ns string, keys []string, oSecrets ...*corev1.Secret) ([]*corev1.Secret, error) {
+	secrets := CopyToNamespace(ns, oSecrets...)
+	if err := KeepKeys(keys, secrets...); err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// KeepKeys removes all but the given keys from the data of the given copies of secrets. An error is returned when a
+// copy lacks a key.
+func KeepKeys(keys []string, secrets ...*corev1.Secret) error {
+	for _, s := range secrets {
+		data := map[string][]byte{}
+		var missing []string
+		for _, key := range keys {
+			if v, ok := s.Data[key]; ok {
+				data[key] = v
+			} else {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) != 0 {
+			return fmt.Errorf("secret %s lacks the keys %s", s.Name, strings.Join(missing, ", "))
+		}
+		s.Data = data
+		s.StringData = nil
+	}
+	return nil
+}
+
+// CopyToNamespaceWithMetadata is like CopyToNamespace, but the copies also keep the labels and annotations of the
+// originals, so that tools selecting on them find the copies too. Metadata that is specific to the original object,
+// such as its resource version, UID and owners, is still dropped.
+func CopyToNamespaceWithMetadata(ns string, oSecrets ...*corev1.Secret) []*corev1.Secret {
+	oSecrets = uniqueByName(oSecrets)
+	secrets := CopyToNamespace(ns, oSecrets...)
+	for i, s := range oSecrets {
+		if len(s.Labels) != 0 {
+			secrets[i].Labels = map[string]string{}
+			for k, v := range s.Labels {
+				secrets[i].Labels[k] = v
+			}
+		}
+		for k, v := range s.Annotations {
+			if k == lastAppliedConfigAnnotation {
+				continue
+			}
+			if secrets[i].Annotations == nil {
+				secrets[i].Annotations = map[string]string{}
+			}
+			secrets[i].Annotations[k] = v
+		}
+	}
+	return secrets
+}
+
+// CopiedFromLabel is set on copies of secrets to the namespace of the originals. It tells the copies that the operator
+// manages apart from the secrets that users created in the same namespace.
+const CopiedFromLabel = "operator.tigera.io/copied-from"
+
+// LabelCopies sets the CopiedFromLabel on the given copies of secrets from the given namespace and returns them.
+func LabelCopies(from string, copies ...*corev1.Secret) []*corev1.Secret {
+	for _, s := range copies {
+		if s.Labels == nil {
+			s.Labels = map[string]string{}
+		}
+		s.Labels[CopiedFromLabel] = from
+	}
+	return copies
+}
+
+// StaleCopies returns the existing secrets in the given namespace that are labeled as copies, but are not among the
+// wanted secrets, such as the copies of pull secrets that were removed from the installation. Secrets without the
+// CopiedFromLabel are never returned.
+func StaleCopies(ns string, existing []corev1.Secret, wanted ...*corev1.Secret) []*corev1.Secret {
+	keep := map[string]bool{}
+	for _, s := range wanted {
+		if s.Namespace == ns {
+			keep[s.Name] = true
+		}
+	}
+
+	var stale []*corev1.Secret
+	for _, s := range existing {
+		if _, ok := s.Labels[CopiedFromLabel]; !ok || s.Namespace != ns || keep[s.Name] {
+			continue
+		}
+		stale = append(stale, &corev1.Secret{
+			TypeMeta:   metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: s.Name, Namespace: s.Namespace},
+		})
+	}
+	return stale
+}
+
 // ToRuntimeObjects converts the given list of secrets to a list of client.Objects
 func ToRuntimeObjects(secrets ...*corev1.Secret) []client.Object {
 	var objs []client.Object
@@ -120,11 +222,27 @@ func GetEnvVarSource(secretName, key string, optional bool) *corev1.EnvVarSource
 	}
 }
 
-// GetReferenceList retrieves the object references from the secrets and returns that list.
+// GetReferenceList retrieves the object references from the secrets and returns that list. A secret that is given more
+// than once is only referenced once, since the API server drops duplicate references and a deployment that is rendered
+// with them would be updated on every reconcile.
 func GetReferenceList(secrets []*corev1.Secret) []corev1.LocalObjectReference {
 	var ps []corev1.LocalObjectReference
-	for _, x := range secrets {
+	for _, x := range uniqueByName(secrets) {
 		ps = append(ps, corev1.LocalObjectReference{Name: x.Name})
 	}
 	return ps
 }
+
+// uniqueByName returns the given secrets without the ones whose name was seen before, in the order they were given.
+func uniqueByName(secrets []*corev1.Secret) []*corev1.Secret {
+	var unique []*corev1.Secret
+	seen := map[string]bool{}
+	for _, s := range secrets {
+		if seen[s.Name] {
+			continue
+		}
+		seen[s.Name] = true
+		unique = append(unique, s)
+	}
+	return unique
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("secret copies", func() {
+	original := func(secretType corev1.SecretType) *corev1.Secret {
+		return &corev1.Secret{
+			TypeMeta: metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "my-secret",
+				Namespace:       "tigera-operator",
+				ResourceVersion: "42",
+				UID:             "0b6e5c1e-6e0d-4c4b-9a4e-6f1f0d6c8f52",
+				Labels:          map[string]string{"team": "auth"},
+				Annotations: map[string]string{
+					"owner":                     "platform",
+					lastAppliedConfigAnnotation: "{}",
+				},
+				OwnerReferences: []metav1.OwnerReference{{Kind: "Authentication", Name: "tigera-secure"}},
+			},
+			Type: secretType,
+			Data: map[string][]byte{"key": []byte("value")},
+		}
+	}
+
+	DescribeTable("CopyToNamespace", func(secretType corev1.SecretType) {
+		copies := CopyToNamespace("tigera-dex", original(secretType))
+		Expect(copies).To(HaveLen(1))
+		Expect(copies[0].ObjectMeta).To(Equal(metav1.ObjectMeta{Name: "my-secret", Namespace: "tigera-dex"}))
+		Expect(copies[0].Type).To(Equal(secretType))
+		Expect(copies[0].Data).To(Equal(map[string][]byte{"key": []byte("value")}))
+	},
+		Entry("tls", corev1.SecretTypeTLS),
+		Entry("dockerconfigjson", corev1.SecretTypeDockerConfigJson),
+		Entry("opaque", corev1.SecretTypeOpaque),
+	)
+
+	DescribeTable("CopyToNamespaceWithMetadata", func(secretType corev1.SecretType) {
+		copies := CopyToNamespaceWithMetadata("tigera-dex", original(secretType))
+		Expect(copies).To(HaveLen(1))
+		Expect(copies[0].ObjectMeta).To(Equal(metav1.ObjectMeta{
+			Name:        "my-secret",
+			Namespace:   "tigera-dex",
+			Labels:      map[string]string{"team": "auth"},
+			Annotations: map[string]string{"owner": "platform"},
+		}))
+		Expect(copies[0].Type).To(Equal(secretType))
+		Expect(copies[0].Data).To(Equal(map[string][]byte{"key": []byte("value")}))
+	},
+		Entry("tls", corev1.SecretTypeTLS),
+		Entry("dockerconfigjson", corev1.SecretTypeDockerConfigJson),
+		Entry("opaque", corev1.SecretTypeOpaque),
+	)
+
+	It("should not share the labels and annotations of the copies with the original", func() {
+		s := original(corev1.SecretTypeOpaque)
+		copies := CopyToNamespaceWithMetadata("tigera-dex", s)
+		copies[0].Labels["team"] = "other"
+		copies[0].Annotations["owner"] = "other"
+		Expect(s.Labels["team"]).To(Equal("auth"))
+		Expect(s.Annotations["owner"]).To(Equal("platform"))
+	})
+})
+
+var _ = Describe("duplicated secrets", func() {
+	pullSecret := func(name, data string) *corev1.Secret {
+		return &corev1.Secret{
+			TypeMeta:   metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "tigera-operator"},
+			Type:       corev1.SecretTypeDockerConfigJson,
+			Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(data)},
+		}
+	}
+	var secrets []*corev1.Secret
+
+	BeforeEach(func() {
+		secrets = []*corev1.Secret{
+			pullSecret("registry-b", "first"),
+			pullSecret("registry-a", "first"),
+			pullSecret("registry-b", "second"),
+			pullSecret("registry-c", "first"),
+			pullSecret("registry-a", "second"),
+		}
+	})
+
+	It("should reference each secret once in the order first seen", func() {
+		Expect(GetReferenceList(secrets)).To(Equal([]corev1.LocalObjectReference{
+			{Name: "registry-b"}, {Name: "registry-a"}, {Name: "registry-c"},
+		}))
+	})
+
+	It("should copy each secret once in the order first seen", func() {
+		for _, copies := range [][]*corev1.Secret{
+			CopyToNamespace("tigera-dex", secrets...),
+			CopyToNamespaceWithMetadata("tigera-dex", secrets...),
+		} {
+			Expect(copies).To(HaveLen(3))
+			for i, name := range []string{"registry-b", "registry-a", "registry-c"} {
+				Expect(copies[i].Name).To(Equal(name))
+				Expect(copies[i].Namespace).To(Equal("tigera-dex"))
+				Expect(copies[i].Data[corev1.DockerConfigJsonKey]).To(Equal([]byte("first")))
+			}
+		}
+	})
+})
+
+var _ = Describe("secret copy tracking", func() {
+	existing := func(name, ns string, copied bool) corev1.Secret {
+		s := corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns, ResourceVersion: "7"}}
+		if copied {
+			s.Labels = map[string]string{CopiedFromLabel: "tigera-operator"}
+		}
+		return s
+	}
+
+	It("should label copies with the namespace of the originals", func() {
+		copies := LabelCopies("tigera-operator", CopyToNamespace("tigera-dex", &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "pull-secret", Namespace: "tigera-operator"},
+		})...)
+		Expect(copies[0].Labels).To(Equal(map[string]string{CopiedFromLabel: "tigera-operator"}))
+	})
+
+	It("should only return the copies that are no longer wanted", func() {
+		wanted := CopyToNamespace("tigera-dex", &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "kept-copy"}})
+		stale := StaleCopies("tigera-dex", []corev1.Secret{
+			existing("kept-copy", "tigera-dex", true),
+			existing("stale-copy", "tigera-dex", true),
+			existing("user-secret", "tigera-dex", false),
+			existing("other-copy", "other-namespace", true),
+		}, wanted...)
+		Expect(stale).To(Equal([]*corev1.Secret{{
+			TypeMeta:   metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "stale-copy", Namespace: "tigera-dex"},
+		}}))
+	})
+})
+
+var _ = Describe("secret key filtering", func() {
+	var original *corev1.Secret
+
+	BeforeEach(func() {
+		original = &corev1.Secret{
+			TypeMeta:   metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "idp", Namespace: "tigera-operator"},
+			Data: map[string][]byte{
+				"clientID":     []byte("id"),
+				"clientSecret": []byte("secret"),
+				"kubeconfig":   []byte("apiVersion: v1"),
+			},
+		}
+	})
+
+	It("should only copy the given keys", func() {
+		copies, err := CopyKeysToNamespace("tigera-dex", []string{"clientID", "clientSecret"}, original)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(copies).To(HaveLen(1))
+		Expect(copies[0].ObjectMeta).To(Equal(metav1.ObjectMeta{Name: "idp", Namespace: "tigera-dex"}))
+		Expect(copies[0].Data).To(Equal(map[string][]byte{"clientID": []byte("id"), "clientSecret": []byte("secret")}))
+		Expect(original.Data).To(HaveKey("kubeconfig"))
+	})
+
+	It("should return an error instead of a copy that lacks keys", func() {
+		copies, err := CopyKeysToNamespace("tigera-dex", []string{"clientID", "rootCA", "bindPW"}, original)
+		Expect(err).To(MatchError("secret idp lacks the keys rootCA, bindPW"))
+		Expect(copies).To(BeNil())
+	})
+})
@@ -0,0 +1,95 @@
+package secret
+
+import (
+	gotls "crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TLSErrorReason tells why a certificate and key cannot be used for a TLS secret.
+type TLSErrorReason string
+
+const (
+	// The certificate is not a PEM encoded X.509 certificate.
+	TLSErrorInvalidCertificate TLSErrorReason = "InvalidCertificate"
+	// The key is not a PEM encoded PKCS #1, PKCS #8 or EC private key.
+	TLSErrorInvalidKey TLSErrorReason = "InvalidKey"
+	// The key is not the private key of the certificate.
+	TLSErrorKeyMismatch TLSErrorReason = "KeyMismatch"
+)
+
+// TLSError is returned when a certificate and key cannot be used for a TLS secret.
+type TLSError struct {
+	Reason TLSErrorReason
+	Err    error
+}
+
+func (e *TLSError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Reason, e.Err)
+}
+
+func (e *TLSError) Unwrap() error {
+	return e.Err
+}
+
+// NewTLSSecret returns a secret of type kubernetes.io/tls with the given PEM encoded certificate and private key in the
+// tls.crt and tls.key keys. A *TLSError is returned when the certificate or key does not parse, or when the key is not
+// the key of the certificate.
+func NewTLSSecret(name, namespace string, cert, key []byte) (*corev1.Secret, error) {
+	if err := ValidateTLSPair(cert, key); err != nil {
+		return nil, err
+	}
+	return &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       cert,
+			corev1.TLSPrivateKeyKey: key,
+		},
+	}, nil
+}
+
+// ValidateTLSPair returns a *TLSError when the PEM encoded certificate or private key does not parse, or when the key is
+// not the key of the certificate. Only the first certificate, the leaf, is checked against the key.
+func ValidateTLSPair(cert, key []byte) error {
+	certBlock, _ := pem.Decode(cert)
+	if certBlock == nil || certBlock.Type != "CERTIFICATE" {
+		return &TLSError{Reason: TLSErrorInvalidCertificate, Err: errors.New("no PEM encoded certificate found")}
+	}
+	if _, err := x509.ParseCertificate(certBlock.Bytes); err != nil {
+		return &TLSError{Reason: TLSErrorInvalidCertificate, Err: err}
+	}
+
+	keyBlock, _ := pem.Decode(key)
+	if keyBlock == nil {
+		return &TLSError{Reason: TLSErrorInvalidKey, Err: errors.New("no PEM encoded private key found")}
+	}
+	if err := parsePrivateKey(keyBlock.Bytes); err != nil {
+		return &TLSError{Reason: TLSErrorInvalidKey, Err: err}
+	}
+
+	// The key parses, so the pair can only be rejected because the key does not belong to the certificate.
+	if _, err := gotls.X509KeyPair(cert, key); err != nil {
+		return &TLSError{Reason: TLSErrorKeyMismatch, Err: err}
+	}
+	return nil
+}
+
+func parsePrivateKey(der []byte) error {
+	if _, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return nil
+	}
+	if _, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		return nil
+	}
+	if _, err := x509.ParseECPrivateKey(der); err == nil {
+		return nil
+	}
+	return errors.New("the private key is not a PKCS #1, PKCS #8 or EC private key")
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"encoding/pem"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("TLS secrets", func() {
+	pair := func() (cert, key []byte) {
+		s, err := CreateTLSSecret(nil, "pair", "tigera-operator", corev1.TLSPrivateKeyKey, corev1.TLSCertKey, time.Hour, nil, "localhost")
+		Expect(err).NotTo(HaveOccurred())
+		return s.Data[corev1.TLSCertKey], s.Data[corev1.TLSPrivateKeyKey]
+	}
+
+	It("should build a typed TLS secret from a matching pair", func() {
+		cert, key := pair()
+		s, err := NewTLSSecret("tigera-dex-tls", "tigera-operator", cert, key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.ObjectMeta).To(Equal(metav1.ObjectMeta{Name: "tigera-dex-tls", Namespace: "tigera-operator"}))
+		Expect(s.Type).To(Equal(corev1.SecretTypeTLS))
+		Expect(s.Data).To(Equal(map[string][]byte{corev1.TLSCertKey: cert, corev1.TLSPrivateKeyKey: key}))
+	})
+
+	DescribeTable("should reject unusable pairs", func(mutate func(cert, key []byte) ([]byte, []byte), reason TLSErrorReason) {
+		cert, key := mutate(pair())
+		s, err := NewTLSSecret("tigera-dex-tls", "tigera-operator", cert, key)
+		Expect(s).To(BeNil())
+		var tlsErr *TLSError
+		Expect(errors.As(err, &tlsErr)).To(BeTrue())
+		Expect(tlsErr.Reason).To(Equal(reason))
+		Expect(ValidateTLSPair(cert, key)).To(Equal(err))
+	},
+		Entry("certificate is not PEM", func(cert, key []byte) ([]byte, []byte) {
+			return []byte("certificate"), key
+		}, TLSErrorInvalidCertificate),
+		Entry("certificate does not parse", func(cert, key []byte) ([]byte, []byte) {
+			return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("garbage")}), key
+		}, TLSErrorInvalidCertificate),
+		Entry("key is not PEM", func(cert, key []byte) ([]byte, []byte) {
+			return cert, []byte("key")
+		}, TLSErrorInvalidKey),
+		Entry("key does not parse", func(cert, key []byte) ([]byte, []byte) {
+			return cert, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte("garbage")})
+		}, TLSErrorInvalidKey),
+		Entry("key of another certificate", func(cert, key []byte) ([]byte, []byte) {
+			_, otherKey := pair()
+			return cert, otherKey
+		}, TLSErrorKeyMismatch),
+	)
+})
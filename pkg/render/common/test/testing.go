@@ -58,8 +58,7 @@ func ExpectResource(resource runtime.Object, name, ns, group, version, kind stri
 func GetResource(resources []client.Object, name, ns, group, version, kind string) client.Object {
 	for _, resource := range resources {
 		gvk := schema.GroupVersionKind{Group: group, Version: version, Kind: kind}
-		if name == resource.(metav1.ObjectMetaAccessor).GetObjectMeta().GetName() &&
-			ns == resource.(metav1.ObjectMetaAccessor).GetObjectMeta().GetNamespace() &&
+		if name == resource.GetName() && ns == resource.GetNamespace() &&
 			gvk == resource.GetObjectKind().GroupVersionKind() {
 			return resource
 		}
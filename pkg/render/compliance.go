@@ -116,30 +116,31 @@ type complianceComponent struct {
 func (c *complianceComponent) ResolveImages(is *operatorv1.ImageSet) error {
 	reg := c.installation.Registry
 	path := c.installation.ImagePath
+	opts := components.ReferenceOptionsFor(c.installation)
 	var err error
-	c.benchmarkerImage, err = components.GetReference(components.ComponentComplianceBenchmarker, reg, path, is)
+	c.benchmarkerImage, err = components.GetReference(components.ComponentComplianceBenchmarker, reg, path, is, opts...)
 
 	errMsgs := []string{}
 	if err != nil {
 		errMsgs = append(errMsgs, err.Error())
 	}
 
-	c.snapshotterImage, err = components.GetReference(components.ComponentComplianceSnapshotter, reg, path, is)
+	c.snapshotterImage, err = components.GetReference(components.ComponentComplianceSnapshotter, reg, path, is, opts...)
 	if err != nil {
 		errMsgs = append(errMsgs, err.Error())
 	}
 
-	c.serverImage, err = components.GetReference(components.ComponentComplianceServer, reg, path, is)
+	c.serverImage, err = components.GetReference(components.ComponentComplianceServer, reg, path, is, opts...)
 	if err != nil {
 		errMsgs = append(errMsgs, err.Error())
 	}
 
-	c.controllerImage, err = components.GetReference(components.ComponentComplianceController, reg, path, is)
+	c.controllerImage, err = components.GetReference(components.ComponentComplianceController, reg, path, is, opts...)
 	if err != nil {
 		errMsgs = append(errMsgs, err.Error())
 	}
 
-	c.reporterImage, err = components.GetReference(components.ComponentComplianceReporter, reg, path, is)
+	c.reporterImage, err = components.GetReference(components.ComponentComplianceReporter, reg, path, is, opts...)
 	if err != nil {
 		errMsgs = append(errMsgs, err.Error())
 	}
@@ -262,6 +263,10 @@ func (c *complianceComponent) Ready() bool {
 	return true
 }
 
+func (c *complianceComponent) Validate() error {
+	return nil
+}
+
 var complianceBoolTrue = true
 var complianceReplicas int32 = 1
 
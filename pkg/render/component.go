@@ -15,6 +15,9 @@
 package render
 
 import (
+	"fmt"
+	"strings"
+
 	operator "github.com/tigera/operator/api/v1"
 	rmeta "github.com/tigera/operator/pkg/render/common/meta"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -34,8 +37,111 @@ type Component interface {
 	// Ready returns true if the component is ready to be created.
 	Ready() bool
 
+	// Validate checks the inputs of the component before any of its objects are applied, so that misconfiguration
+	// is reported up front rather than discovered through a failing workload. Components that have nothing to
+	// verify return nil. Problems are reported as a *ValidationError.
+	Validate() error
+
 	// SupportedOSTypes returns operating systems that is supported of the components returned by the Objects() function.
 	// The "componentHandler" converts the returned OSTypes to a node selectors for the "kubernetes.io/os" label on client.Objects
 	// that create pods. Return OSTypeAny means that no node selector should be set for the "kubernetes.io/os" label.
 	SupportedOSType() rmeta.OSType
 }
+
+// MultiOSComponent is implemented by components that render objects for more than one operating system. Each object
+// that creates pods must then select one of the returned OSTypes through the "kubernetes.io/os" node selector itself.
+type MultiOSComponent interface {
+	SupportedOSTypes() []rmeta.OSType
+}
+
+// SupportedOSTypes returns the operating systems that the component supports. Components that do not implement
+// MultiOSComponent support exactly the OSType returned by SupportedOSType().
+func SupportedOSTypes(c Component) []rmeta.OSType {
+	if m, ok := c.(MultiOSComponent); ok {
+		return m.SupportedOSTypes()
+	}
+	return []rmeta.OSType{c.SupportedOSType()}
+}
+
+// ComponentWithDependencies is implemented by components whose objects need other objects to exist first, for example
+// a deployment that mounts a secret. The handler applies the dependencies that the component renders itself before
+// any of its other objects, and does not apply the component while a dependency that it does not render is missing.
+type ComponentWithDependencies interface {
+	// Dependencies returns the objects that must exist before the component is applied. Only the type, name and
+	// namespace of the returned objects are used.
+	Dependencies() []client.Object
+}
+
+// ComponentWithRetainedObjects is implemented by components that render objects which must outlive the CR that the
+// component is reconciled for, for example CustomResourceDefinitions that hold state. The handler applies these objects
+// without making the CR their owner, so that they are not garbage collected with it.
+type ComponentWithRetainedObjects interface {
+	// RetainedObjects returns the objects that are not owned by the CR. Only the type, name and namespace of the
+	// returned objects are used.
+	RetainedObjects() []client.Object
+}
+
+// ComponentWithOrderedApply is implemented by components whose workloads must not be applied before the other objects
+// of the component, for example because their pods need a CustomResourceDefinition or RBAC of the component as soon as
+// they start. The handler then applies the objects that create pods after all other objects.
+type ComponentWithOrderedApply interface {
+	// RequiresOrderedApply returns true if the objects of the component must be applied in order with the current
+	// configuration.
+	RequiresOrderedApply() bool
+}
+
+// ComponentWithImages is implemented by components that can tell which images they require with their current
+// configuration, so that an ImageSet can be generated or validated before the component is reconciled.
+type ComponentWithImages interface {
+	// RequiredImages returns the names of the images, without registry or version, that ResolveImages looks up.
+	RequiredImages() []string
+}
+
+// ReadinessReporter is implemented by components that can explain why Ready returned false.
+type ReadinessReporter interface {
+	// NotReadyReason describes what the component is waiting for, or returns "" if it is ready.
+	NotReadyReason() string
+}
+
+// NotReadyReason returns the reason that the component reports for not being ready. Components that do not report
+// one get a generic reason.
+func NotReadyReason(c Component) string {
+	if r, ok := c.(ReadinessReporter); ok {
+		if reason := r.NotReadyReason(); reason != "" {
+			return reason
+		}
+	}
+	return "component is not ready"
+}
+
+// ValidationError is returned by Component.Validate when the inputs of a component are invalid. It lists every
+// problem that was found, so that all of them can be reported at once.
+type ValidationError struct {
+	// Component is the name of the component that failed validation.
+	Component string
+	// Problems describes each of the problems that were found with the inputs.
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid configuration for %s: %s", e.Component, strings.Join(e.Problems, "; "))
+}
+
+// MarshalError is returned by Component.Validate when a config that the component renders into an object cannot be
+// marshalled.
+type MarshalError struct {
+	// Component is the name of the component whose config could not be marshalled.
+	Component string
+	// Object names the config that could not be marshalled.
+	Object string
+	// Err is the error of the marshaller.
+	Err error
+}
+
+func (e *MarshalError) Error() string {
+	return fmt.Sprintf("failed to marshal the %s of %s: %v", e.Object, e.Component, e.Err)
+}
+
+func (e *MarshalError) Unwrap() error {
+	return e.Err
+}
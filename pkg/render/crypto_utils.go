@@ -21,12 +21,15 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"fmt"
 	"math/big"
-	mrand "math/rand"
+	"net"
 	"strings"
 	"time"
 
+	operatorv1 "github.com/tigera/operator/api/v1"
 	rmeta "github.com/tigera/operator/pkg/render/common/meta"
+	"github.com/tigera/operator/pkg/render/common/secret"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -59,26 +62,24 @@ func voltronTunnelSecret() *corev1.Secret {
 	}
 }
 
-func CreateDexTLSSecret(dexCommonName string) *corev1.Secret {
-	key, cert := createSelfSignedSecret(dexCommonName, []string{dexCommonName})
-	return &corev1.Secret{
-		TypeMeta: metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      DexTLSSecretName,
-			Namespace: rmeta.OperatorNamespace(),
-		},
-		Type: corev1.SecretTypeTLS,
-		Data: map[string][]byte{
-			corev1.TLSCertKey:       []byte(cert),
-			corev1.TLSPrivateKeyKey: []byte(key),
-		},
+// CreateDexTLSSecret creates a self-signed certificate and key for Dex. The certificate is valid for the given DNS
+// names, or only for the common name if none are given, and for the given IP addresses.
+func CreateDexTLSSecret(dexCommonName string, dnsNames []string, ipAddresses ...net.IP) *corev1.Secret {
+	if len(dnsNames) == 0 {
+		dnsNames = []string{dexCommonName}
+	}
+	key, cert := createSelfSignedSecret(dexCommonName, dnsNames, ipAddresses...)
+	s, err := secret.NewTLSSecret(DexTLSSecretName, rmeta.OperatorNamespace(), []byte(cert), []byte(key))
+	if err != nil { // The pair was just created, so this is not possible.
+		panic(err)
 	}
+	return s
 }
 
 // Secrets to establish a tunnel between Voltron and Guardian
 // Differs from other secrets in the way that it needs a DNS name and KeyUsage.
-func createSelfSignedSecret(cn string, altNames []string) (string, string) {
-	template := template(cn, altNames)
+func createSelfSignedSecret(cn string, altNames []string, ipAddresses ...net.IP) (string, string) {
+	template := template(cn, altNames, ipAddresses)
 	privateKey, err := rsa.GenerateKey(rand.Reader, VoltronKeySizeBits)
 	if err != nil {
 		panic(err)
@@ -103,12 +104,13 @@ func createSelfSignedSecret(cn string, altNames []string) (string, string) {
 	return keyPem.String(), certPem.String()
 }
 
-func template(cn string, altNames []string) *x509.Certificate {
+func template(cn string, altNames []string, ipAddresses []net.IP) *x509.Certificate {
 	return &x509.Certificate{
 		IsCA:                  true,
 		BasicConstraintsValid: true,
 		SerialNumber:          big.NewInt(1),
 		DNSNames:              altNames,
+		IPAddresses:           ipAddresses,
 		Subject:               pkix.Name{CommonName: cn},
 		NotBefore:             time.Now(),
 		// For now use the same lifetime as the other certs we generate. This will change when we implement rotation.
@@ -117,17 +119,24 @@ func template(cn string, altNames []string) *x509.Certificate {
 	}
 }
 
+// generatePassword returns a password of cryptographically random letters and digits.
 func generatePassword(length int) string {
-	mrand.Seed(time.Now().UnixNano())
 	chars := []rune("ABCDEFGHIJKLMNOPQRSTUVWXYZ" +
 		"abcdefghijklmnopqrstuvwxyz0123456789")
 	var b strings.Builder
 	for i := 0; i < length; i++ {
-		b.WriteRune(chars[mrand.Intn(len(chars))])
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
+		if err != nil {
+			// The random source of the operating system is not expected to fail.
+			panic(fmt.Sprintf("failed to generate a password: %v", err))
+		}
+		b.WriteRune(chars[n.Int64()])
 	}
 	return b.String()
 }
 
+// CreateDexClientSecret returns a secret with a new random client secret, which the manager uses to authenticate with
+// Dex.
 func CreateDexClientSecret() *corev1.Secret {
 	return &corev1.Secret{
 		TypeMeta: metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
@@ -141,6 +150,35 @@ func CreateDexClientSecret() *corev1.Secret {
 	}
 }
 
+// CreateStaticClientSecret returns a secret with the given name and a new random client secret for a static client of
+// Dex.
+func CreateStaticClientSecret(name string) *corev1.Secret {
+	return &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: rmeta.OperatorNamespace(),
+		},
+		Data: map[string][]byte{
+			ClientSecretSecretField: []byte(generatePassword(24)),
+		},
+	}
+}
+
+// RotateDexClientSecret returns the client secret that Dex should use: current, or a new client secret when the
+// Authentication requests a rotation that current was not generated for. The new secret records the rotation, so that
+// later reconciles keep it. Dex and its clients pick up the new client secret together, since their pods are annotated
+// with the hash of the secret.
+func RotateDexClientSecret(authentication *operatorv1.Authentication, current *corev1.Secret) *corev1.Secret {
+	rotation, ok := authentication.Annotations[DexClientSecretRotationAnnotation]
+	if !ok || string(current.Data[dexClientSecretRotationField]) == rotation {
+		return current
+	}
+	rotated := CreateDexClientSecret()
+	rotated.Data[dexClientSecretRotationField] = []byte(rotation)
+	return rotated
+}
+
 // CreateCertificateSecret is a convenience method for creating a secret that contains only a ca or cert to trust.
 func CreateCertificateSecret(caPem []byte, secretName string, namespace string) *corev1.Secret {
 	return &corev1.Secret{
@@ -17,6 +17,7 @@ package render
 import (
 	"encoding/base64"
 	"fmt"
+	"net"
 	"strings"
 
 	"github.com/tigera/operator/pkg/components"
@@ -46,8 +47,9 @@ func CreateCSRInitContainer(
 	keyName string,
 	certName string,
 	dnsNames []string,
-	appNameLabel string) corev1.Container {
-	return corev1.Container{
+	appNameLabel string,
+	ipAddresses ...net.IP) corev1.Container {
+	container := corev1.Container{
 		Name:  CSRInitContainerName,
 		Image: image,
 		VolumeMounts: []corev1.VolumeMount{
@@ -86,6 +88,14 @@ func CreateCSRInitContainer(
 			AllowPrivilegeEscalation: ptr.BoolToPtr(false),
 		},
 	}
+	if len(ipAddresses) != 0 {
+		ips := make([]string, len(ipAddresses))
+		for i, ip := range ipAddresses {
+			ips[i] = ip.String()
+		}
+		container.Env = append(container.Env, corev1.EnvVar{Name: "IP_ADDRESSES", Value: strings.Join(ips, ",")})
+	}
+	return container
 }
 
 // ResolveCsrInitImage resolves the image needed for the CSR init image taking into account the specified ImageSet
@@ -95,6 +105,7 @@ func ResolveCSRInitImage(inst *operator.InstallationSpec, is *operator.ImageSet)
 		inst.Registry,
 		inst.ImagePath,
 		is,
+		components.ReferenceOptionsFor(inst)...,
 	)
 }
 
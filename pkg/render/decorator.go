@@ -0,0 +1,193 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"reflect"
+	"strings"
+
+	operator "github.com/tigera/operator/api/v1"
+	rmeta "github.com/tigera/operator/pkg/render/common/meta"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// componentDecorator wraps a component and forwards the optional interfaces of the wrapped component, so that the
+// handler still applies its dependencies, retained objects and apply order, and so that a decorator only overrides the
+// methods that it changes.
+type componentDecorator struct {
+	Component
+}
+
+func (d componentDecorator) SupportedOSTypes() []rmeta.OSType {
+	return SupportedOSTypes(d.Component)
+}
+
+func (d componentDecorator) Dependencies() []client.Object {
+	if c, ok := d.Component.(ComponentWithDependencies); ok {
+		return c.Dependencies()
+	}
+	return nil
+}
+
+func (d componentDecorator) RetainedObjects() []client.Object {
+	if c, ok := d.Component.(ComponentWithRetainedObjects); ok {
+		return c.RetainedObjects()
+	}
+	return nil
+}
+
+func (d componentDecorator) RequiresOrderedApply() bool {
+	if c, ok := d.Component.(ComponentWithOrderedApply); ok {
+		return c.RequiresOrderedApply()
+	}
+	return false
+}
+
+func (d componentDecorator) RequiredImages() []string {
+	if c, ok := d.Component.(ComponentWithImages); ok {
+		return c.RequiredImages()
+	}
+	return nil
+}
+
+func (d componentDecorator) NotReadyReason() string {
+	if c, ok := d.Component.(ReadinessReporter); ok {
+		return c.NotReadyReason()
+	}
+	return ""
+}
+
+func (d componentDecorator) unwrap() Component {
+	return d.Component
+}
+
+// ComponentName returns the name of the type of the component, without the decorators that wrap it, for example
+// "dexComponent".
+func ComponentName(c Component) string {
+	for {
+		d, ok := c.(interface{ unwrap() Component })
+		if !ok {
+			break
+		}
+		c = d.unwrap()
+	}
+	t := reflect.TypeOf(c)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// IsOperatorOwnedKey returns true if the label or annotation key is one that the operator sets on the objects that it
+// renders: the k8s-app label that selectors use, the recommended app.kubernetes.io labels and the keys under the
+// operator.tigera.io domain.
+func IsOperatorOwnedKey(key string) bool {
+	if key == "k8s-app" {
+		return true
+	}
+	i := strings.Index(key, "/")
+	if i < 0 {
+		return false
+	}
+	prefix := key[:i]
+	for _, domain := range []string{"operator.tigera.io", "app.kubernetes.io"} {
+		if prefix == domain || strings.HasSuffix(prefix, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+type metadataComponent struct {
+	componentDecorator
+	metadata *operator.ComponentMetadata
+}
+
+// WithInstallationMetadata returns the component with the labels and annotations of the ComponentMetadata of the
+// Installation added to every object that it renders, and to the pod templates of its workloads. The keys that the
+// component sets itself and the keys that the operator owns are not replaced. The annotations are listed as managed,
+// so that they are removed from the objects once they are removed from the Installation. The component is returned
+// as is when the Installation has no ComponentMetadata.
+func WithInstallationMetadata(c Component, installation *operator.InstallationSpec) Component {
+	if installation == nil || installation.ComponentMetadata == nil {
+		return c
+	}
+	return &metadataComponent{componentDecorator: componentDecorator{c}, metadata: installation.ComponentMetadata}
+}
+
+func (c *metadataComponent) Objects() ([]client.Object, []client.Object) {
+	objsToCreate, objsToDelete := c.Component.Objects()
+	for _, obj := range objsToCreate {
+		obj.SetLabels(withMissingKeys(obj.GetLabels(), c.metadata.Labels))
+		rmeta.SetManagedAnnotations(obj, missingKeys(obj.GetAnnotations(), c.metadata.Annotations))
+
+		if template := podTemplate(obj); template != nil {
+			template.Labels = withMissingKeys(template.Labels, c.metadata.Labels)
+			template.Annotations = withMissingKeys(template.Annotations, c.metadata.Annotations)
+		}
+	}
+	return objsToCreate, objsToDelete
+}
+
+// missingKeys returns the entries of add whose keys are neither set in existing nor owned by the operator.
+func missingKeys(existing, add map[string]string) map[string]string {
+	missing := map[string]string{}
+	for k, v := range add {
+		if _, ok := existing[k]; ok || IsOperatorOwnedKey(k) {
+			continue
+		}
+		missing[k] = v
+	}
+	return missing
+}
+
+// withMissingKeys returns a copy of existing with the missing keys of add added. Existing maps are not modified, since
+// components share label maps between pod templates and selectors.
+func withMissingKeys(existing, add map[string]string) map[string]string {
+	missing := missingKeys(existing, add)
+	if len(missing) == 0 {
+		return existing
+	}
+	merged := make(map[string]string, len(existing)+len(missing))
+	for k, v := range existing {
+		merged[k] = v
+	}
+	for k, v := range missing {
+		merged[k] = v
+	}
+	return merged
+}
+
+// podTemplate returns the pod template of an object that creates pods, or nil.
+func podTemplate(obj client.Object) *corev1.PodTemplateSpec {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		return &o.Spec.Template
+	case *appsv1.DaemonSet:
+		return &o.Spec.Template
+	case *appsv1.StatefulSet:
+		return &o.Spec.Template
+	case *batchv1.Job:
+		return &o.Spec.Template
+	case *batchv1beta.CronJob:
+		return &o.Spec.JobTemplate.Spec.Template
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	operatorv1 "github.com/tigera/operator/api/v1"
+	"github.com/tigera/operator/pkg/render"
+	rmeta "github.com/tigera/operator/pkg/render/common/meta"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// decoratedComponent renders a deployment whose selector shares its label map with the pod template, like most
+// components do, and a ConfigMap with an annotation of its own.
+type decoratedComponent struct {
+	os           rmeta.OSType
+	nodeSelector map[string]string
+}
+
+func (c *decoratedComponent) ResolveImages(*operatorv1.ImageSet) error { return nil }
+func (c *decoratedComponent) Ready() bool                              { return true }
+func (c *decoratedComponent) Validate() error                          { return nil }
+func (c *decoratedComponent) SupportedOSType() rmeta.OSType            { return c.os }
+func (c *decoratedComponent) Dependencies() []client.Object {
+	return []client.Object{&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test-secret", Namespace: "test-namespace"}}}
+}
+
+func (c *decoratedComponent) Objects() ([]client.Object, []client.Object) {
+	labels := map[string]string{"k8s-app": "test"}
+	return []client.Object{
+		&corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "test-namespace", Annotations: map[string]string{"owner": "component"}},
+		},
+		&appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test-namespace", Labels: labels},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: labels},
+					Spec:       corev1.PodSpec{NodeSelector: c.nodeSelector},
+				},
+			},
+		},
+	}, nil
+}
+
+var _ = Describe("WithInstallationMetadata", func() {
+	installation := &operatorv1.InstallationSpec{ComponentMetadata: &operatorv1.ComponentMetadata{
+		Labels:      map[string]string{"data-classification": "internal", "k8s-app": "other"},
+		Annotations: map[string]string{"backup.example.com/policy": "daily", "owner": "installation"},
+	}}
+
+	It("adds the metadata to every object and pod template without replacing the keys of the component", func() {
+		objs, _ := render.WithInstallationMetadata(&decoratedComponent{os: rmeta.OSTypeLinux}, installation).Objects()
+
+		cm := objs[0].(*corev1.ConfigMap)
+		Expect(cm.Labels).To(Equal(map[string]string{"data-classification": "internal"}))
+		Expect(cm.Annotations).To(Equal(map[string]string{
+			"owner":                            "component",
+			"backup.example.com/policy":        "daily",
+			rmeta.ManagedAnnotationsAnnotation: "backup.example.com/policy",
+		}))
+
+		d := objs[1].(*appsv1.Deployment)
+		Expect(d.Labels).To(Equal(map[string]string{"k8s-app": "test", "data-classification": "internal"}))
+		Expect(d.Spec.Template.Labels).To(Equal(map[string]string{"k8s-app": "test", "data-classification": "internal"}))
+		Expect(d.Spec.Template.Annotations).To(Equal(map[string]string{"backup.example.com/policy": "daily", "owner": "installation"}))
+		Expect(d.Spec.Selector.MatchLabels).To(Equal(map[string]string{"k8s-app": "test"}))
+	})
+
+	It("keeps the optional interfaces of the component", func() {
+		c := render.WithInstallationMetadata(&decoratedComponent{os: rmeta.OSTypeLinux}, installation)
+		Expect(c.(render.ComponentWithDependencies).Dependencies()).To(HaveLen(1))
+		Expect(render.SupportedOSTypes(c)).To(Equal([]rmeta.OSType{rmeta.OSTypeLinux}))
+	})
+
+	It("names the component after the type that it wraps", func() {
+		c := render.WithInstallationMetadata(&decoratedComponent{os: rmeta.OSTypeLinux}, installation)
+		Expect(render.ComponentName(render.WithOSFilter(c, nil))).To(Equal("decoratedComponent"))
+	})
+
+	It("returns the component as is without metadata", func() {
+		c := &decoratedComponent{}
+		Expect(render.WithInstallationMetadata(c, &operatorv1.InstallationSpec{})).To(BeIdenticalTo(c))
+	})
+})
@@ -16,11 +16,18 @@ package render
 
 import (
 	"fmt"
+	"net"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	oprv1 "github.com/tigera/operator/api/v1"
 	"github.com/tigera/operator/pkg/components"
 	"github.com/tigera/operator/pkg/dns"
+	"github.com/tigera/operator/pkg/ptr"
+	"github.com/tigera/operator/pkg/render/common/configmap"
 	rmeta "github.com/tigera/operator/pkg/render/common/meta"
 	"github.com/tigera/operator/pkg/render/common/podsecuritycontext"
 	"github.com/tigera/operator/pkg/render/common/secret"
@@ -29,6 +36,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -39,6 +47,8 @@ const (
 	DexNamespace  = "tigera-dex"
 	DexObjectName = "tigera-dex"
 	DexPort       = 5556
+	// The port of the telemetry endpoint of Dex.
+	DexMetricsPort = 5558
 	// This is the secret containing just a cert that a client should mount in order to trust Dex.
 	DexCertSecretName = "tigera-dex-tls-crt"
 	// This is the secret that Dex mounts, containing a key and a cert.
@@ -47,118 +57,720 @@ const (
 	// Constants related to Dex configurations
 	DexClientId = "tigera-manager"
 
+	// Endpoints of Dex that the probes query, relative to its issuer path.
+	dexDiscoveryPath = "/.well-known/openid-configuration"
+	dexHealthzPath   = "/healthz"
+
+	// The config of Dex references the secrets it reads as $VAR, which Dex expands from its env. Newer versions of Dex
+	// only expand the env when this variable is not false, so it is set explicitly.
+	dexExpandEnv = "DEX_EXPAND_ENV"
+
+	// The API group of the resources in which the kubernetes storage of Dex stores its state, and the annotation that
+	// records the version of Dex that the CustomResourceDefinitions of the storage were applied for.
+	dexStorageGroup      = "dex.coreos.com"
+	dexVersionAnnotation = "operator.tigera.io/dex-version"
+
+	// Annotations that keep Istio and Linkerd from injecting their sidecar into the Dex pod.
+	istioInjectAnnotation   = "sidecar.istio.io/inject"
+	linkerdInjectAnnotation = "linkerd.io/inject"
+
+	// Paths of the config of Dex. With a split connector config, the base config and the connectors config are merged
+	// into an emptyDir, since Dex has no mechanism to include one config file in another.
+	dexBaseConfigPath       = "/etc/dex/baseCfg/config.yaml"
+	dexConnectorsConfigDir  = "/etc/dex/connectors"
+	dexConnectorsConfigKey  = "connectors.yaml"
+	dexMergedConfigDir      = "/etc/dex/cfg"
+	dexConnectorsConfigName = "%s-connectors"
+
+	// The service that exposes the telemetry endpoint of Dex, apart from the Dex service.
+	dexMetricsServiceName = "%s-metrics"
+	dexMetricsPortName    = "metrics"
+
+	// Defaults of how long Dex keeps serving after its pod is asked to stop, and of how long the pod may take to stop.
+	dexPreStopSleepSeconds           = 5
+	dexTerminationGracePeriodSeconds = 30
+
+	// The default of how long a rollout of Dex may make no progress before it is reported as failed.
+	dexProgressDeadlineSeconds = 600
+
 	// Common name to add to the Dex TLS secret.
 	DexCNPattern = "tigera-dex.tigera-dex.svc.%s"
 )
 
-func Dex(
+// DexConfiguration contains the configuration of the Dex component. Installation and DexConfig are required, the
+// other fields are optional.
+type DexConfiguration struct {
+	PullSecrets  []*corev1.Secret
+	Openshift    bool
+	Installation *oprv1.InstallationSpec
+	DexConfig    DexConfig
+
+	// Replicas is the number of Dex pods. Default: 1
+	Replicas *int32
+
+	// Resources are the compute resources of the Dex container.
+	Resources *corev1.ResourceRequirements
+
+	// Affinity replaces the affinity of the Dex pods, including DexDeployment.Affinity and the preferences that
+	// DexDeployment.CoLocateWithManager and DexDeployment.AvoidSpotNodes configure.
+	Affinity *corev1.Affinity
+}
+
+func Dex(cfg DexConfiguration) Component {
+	return &dexComponent{
+		dexConfig:    cfg.DexConfig,
+		pullSecrets:  cfg.PullSecrets,
+		openshift:    cfg.Openshift,
+		installation: cfg.Installation,
+		connector:    cfg.DexConfig.Connector(),
+		replicas:     cfg.Replicas,
+		resources:    cfg.Resources,
+		podAffinity:  cfg.Affinity,
+	}
+}
+
+// DexWithArgs returns the Dex component for the given arguments, without any of the optional settings of
+// DexConfiguration. The clusterDomain is only kept for the callers of the former signature, Dex uses the cluster domain
+// that dexConfig was created with, which is detected when none is given to it.
+//
+// Deprecated: Use Dex instead.
+func DexWithArgs(
 	pullSecrets []*corev1.Secret,
 	openshift bool,
 	installation *oprv1.InstallationSpec,
 	dexConfig DexConfig,
 	clusterDomain string,
 ) Component {
-
-	return &dexComponent{
-		dexConfig:     dexConfig,
-		pullSecrets:   pullSecrets,
-		openshift:     openshift,
-		installation:  installation,
-		connector:     dexConfig.Connector(),
-		clusterDomain: clusterDomain,
-	}
+	return Dex(DexConfiguration{
+		PullSecrets:  pullSecrets,
+		Openshift:    openshift,
+		Installation: installation,
+		DexConfig:    dexConfig,
+	})
 }
 
 type dexComponent struct {
-	dexConfig     DexConfig
-	pullSecrets   []*corev1.Secret
-	openshift     bool
-	installation  *oprv1.InstallationSpec
-	connector     map[string]interface{}
-	image         string
-	csrInitImage  string
-	clusterDomain string
+	dexConfig    DexConfig
+	pullSecrets  []*corev1.Secret
+	openshift    bool
+	installation *oprv1.InstallationSpec
+	connector    map[string]interface{}
+	image        string
+	csrInitImage string
+	replicas     *int32
+	resources    *corev1.ResourceRequirements
+	podAffinity  *corev1.Affinity
 }
 
+// ResolveImages resolves the images of Dex and, with certificate management, of the CSR init container. When an
+// ImageSet is given, both are pinned by the digests in it, which must be valid sha256 digests. The image overrides of
+// the DexDeployment only select the registry and image path, so a pinned image keeps its digest. When the
+// DexDeployment requires digests, an ImageSet must be given. The images that cannot be resolved are returned as
+// components.ImageErrors.
 func (c *dexComponent) ResolveImages(is *oprv1.ImageSet) error {
-	reg := c.installation.Registry
-	path := c.installation.ImagePath
-	var err error
-	c.image, err = components.GetReference(components.ComponentDex, reg, path, is)
+	var errs components.ImageErrors
 
-	var errMsgs []string
-	if err != nil {
-		errMsgs = append(errMsgs, err.Error())
+	reg, imagePath := c.imageLocation(components.ComponentDex.Image)
+	repository := components.GetRepository(components.ComponentDex, reg, imagePath, components.ReferenceOptionsFor(c.installation)...)
+	if err := c.checkImageSet(is, components.ComponentDex.Image); err != nil {
+		errs = append(errs, &components.ImageError{Component: components.ComponentDex.Image, Reference: repository, Err: err})
+	} else if c.image, err = components.GetReference(components.ComponentDex, reg, imagePath, is, components.ReferenceOptionsFor(c.installation)...); err != nil {
+		errs = append(errs, &components.ImageError{Component: components.ComponentDex.Image, Reference: repository, Err: err})
 	}
 
-	if c.installation.CertificateManagement != nil {
-		c.csrInitImage, err = ResolveCSRInitImage(c.installation, is)
-		if err != nil {
-			errMsgs = append(errMsgs, err.Error())
+	if c.csrInit() {
+		reg, imagePath := c.imageLocation(components.ComponentCSRInitContainer.Image)
+		repository := components.GetRepository(components.ComponentCSRInitContainer, reg, imagePath, components.ReferenceOptionsFor(c.installation)...)
+		if err := c.checkImageSet(is, components.ComponentCSRInitContainer.Image); err != nil {
+			errs = append(errs, &components.ImageError{Component: components.ComponentCSRInitContainer.Image, Reference: repository, Err: err})
+		} else if c.csrInitImage, err = components.GetReference(components.ComponentCSRInitContainer, reg, imagePath, is, components.ReferenceOptionsFor(c.installation)...); err != nil {
+			errs = append(errs, &components.ImageError{Component: components.ComponentCSRInitContainer.Image, Reference: repository, Err: err})
 		}
 	}
 
-	if len(errMsgs) != 0 {
-		return fmt.Errorf(strings.Join(errMsgs, ","))
+	if len(errs) != 0 {
+		return errs
 	}
 	return nil
 }
 
+// checkImageSet returns an error when the ImageSet cannot pin the given image, or when there is no ImageSet although
+// the DexDeployment requires digests.
+func (c *dexComponent) checkImageSet(is *oprv1.ImageSet, image string) error {
+	if is == nil && c.imageDigestsRequired() {
+		return fmt.Errorf("the image %s must be pinned by digest, but there is no ImageSet for the release %s", image, components.EnterpriseRelease)
+	}
+	return components.ValidateImageSetDigests(is, image)
+}
+
+func (c *dexComponent) imageDigestsRequired() bool {
+	digests := c.dexConfig.DexDeployment().ImageDigests
+	return digests != nil && *digests == oprv1.DexImageDigestsRequired
+}
+
+// imageLocation returns the registry and image path of the given image. The fields of the image override in the
+// DexDeployment take precedence over those of the Installation.
+func (c *dexComponent) imageLocation(image string) (string, string) {
+	reg := c.installation.Registry
+	imagePath := c.installation.ImagePath
+	override := c.dexConfig.DexDeployment().ImageOverrides[path.Base(image)]
+	if override.Registry != "" {
+		reg = override.Registry
+		if reg != components.UseDefault && !strings.HasSuffix(reg, "/") {
+			reg += "/"
+		}
+	}
+	if override.ImagePath != "" {
+		imagePath = override.ImagePath
+	}
+	return reg, imagePath
+}
+
+func (c *dexComponent) RequiredImages() []string {
+	images := []string{components.ComponentDex.Image}
+	if c.csrInit() {
+		images = append(images, components.ComponentCSRInitContainer.Image)
+	}
+	return images
+}
+
 func (*dexComponent) SupportedOSType() rmeta.OSType {
 	return rmeta.OSTypeLinux
 }
 
 func (c *dexComponent) Objects() ([]client.Object, []client.Object) {
+	var objs, objsToDelete []client.Object
+	if c.external() {
+		objs, objsToDelete = c.externalObjects()
+	} else {
+		objs, objsToDelete = c.deployedObjects()
+	}
+
+	// The kubeconfig user for kubectl and the discovery details describe the issuer, which is the same for a deployed
+	// and an external Dex.
+	published := configmap.ToRuntimeObjects(append([]*corev1.ConfigMap{c.dexConfig.KubectlConfigMap()}, c.dexConfig.DiscoveryConfigMaps()...)...)
+	if len(published) != 0 {
+		rmeta.AddAppLabels(published, rmeta.AppLabels(DexObjectName, c.name(), "identity-provider"))
+		objs = append(objs, published...)
+	}
+	objsToDelete = append(objsToDelete, c.staleConfigMaps(DexKubectlConfigLabel, c.dexConfig.KubectlConfigMaps(), published)...)
+	objsToDelete = append(objsToDelete, c.staleConfigMaps(DexDiscoveryLabel, c.dexConfig.DiscoveryConfigMapCopies(), published)...)
+
+	rule := c.prometheusRule()
+	if rule != nil {
+		monitoring := []client.Object{rule, c.serviceMonitor(rule)}
+		rmeta.AddAppLabels(monitoring, rmeta.AppLabels(DexObjectName, c.name(), "identity-provider"))
+		objs = append(objs, monitoring...)
+	}
+	objsToDelete = append(objsToDelete, c.stalePrometheusRules(rule)...)
+
+	// The published and the monitoring objects are sorted in with the others, so that the order does not depend on
+	// which of them are rendered.
+	sortObjects(objs)
+	return objs, objsToDelete
+}
+
+// staleConfigMaps returns the existing ConfigMaps that carry the given label with the name of this instance, but are
+// not rendered anymore.
+func (c *dexComponent) staleConfigMaps(label string, existing []corev1.ConfigMap, rendered []client.Object) []client.Object {
+	var stale []client.Object
+	for i := range existing {
+		cm := &existing[i]
+		if cm.Labels[label] != c.name() {
+			continue
+		}
+		found := false
+		for _, obj := range rendered {
+			found = found || (obj.GetName() == cm.Name && obj.GetNamespace() == cm.Namespace)
+		}
+		if !found {
+			stale = append(stale, &corev1.ConfigMap{
+				TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+				ObjectMeta: metav1.ObjectMeta{Name: cm.Name, Namespace: cm.Namespace},
+			})
+		}
+	}
+	return stale
+}
+
+// csrInit returns true if the init container requests the certificate of Dex from the certificate management of the
+// Installation.
+func (c *dexComponent) csrInit() bool {
+	return c.installation.CertificateManagement != nil && !c.servingCertificate()
+}
+
+// servingCertificate returns true if the service CA of OpenShift issues the certificate of Dex.
+func (c *dexComponent) servingCertificate() bool {
+	return c.openshift && !c.external() && c.dexConfig.DexDeployment().ServiceServingCertificate
+}
+
+// DexServiceCAName returns the name of the ConfigMap into which OpenShift injects the CA of the serving certificate of
+// the given Dex instance.
+func DexServiceCAName(instance string) string {
+	return fmt.Sprintf("%s-service-ca", instance)
+}
+
+// DexIdpCABundleName returns the name of the secret with the CA bundle that the given Dex instance trusts for its
+// identity provider.
+func DexIdpCABundleName(instance string) string {
+	return fmt.Sprintf("%s-idp-ca-bundle", instance)
+}
+
+// servingCertSecretName returns the name of the secret into which OpenShift issues the serving certificate of Dex.
+func (c *dexComponent) servingCertSecretName() string {
+	return fmt.Sprintf("%s-serving-cert", c.name())
+}
+
+// serviceCAConfigMap returns the ConfigMap into which OpenShift injects the CA of the serving certificate, from which
+// the operator publishes it to the clients of Dex. Its data is left to OpenShift.
+func (c *dexComponent) serviceCAConfigMap() *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        DexServiceCAName(c.name()),
+			Namespace:   c.namespace(),
+			Annotations: map[string]string{rmeta.OpenShiftInjectCABundleAnnotation: "true"},
+		},
+	}
+}
+
+// external returns true if the Dex service points to an external Dex instead of a Dex that the operator deploys.
+func (c *dexComponent) external() bool {
+	return c.dexConfig.DexDeployment().ExternalHost != ""
+}
+
+// externalObjects returns the ExternalName service that points to the external Dex and the secret with its certificate
+// that the manager trusts. The objects of a deployed Dex are removed, apart from the secrets in the operator namespace
+// that the installation was configured with and the CustomResourceDefinitions of the storage that are shared by all
+// instances.
+func (c *dexComponent) externalObjects() ([]client.Object, []client.Object) {
+	objs := []client.Object{c.externalService(), c.dexConfig.CreateCertSecret()}
+	rmeta.AddAppLabels(objs, rmeta.AppLabels(DexObjectName, c.name(), "identity-provider"))
+	sortObjects(objs)
+
+	deployed, objsToDelete := c.deployedObjects()
+	for _, obj := range deployed {
+		if _, ok := obj.(*apiextensionsv1.CustomResourceDefinition); ok {
+			continue
+		}
+		if _, ok := obj.(*corev1.Service); ok && obj.GetName() == c.name() {
+			// The service of a deployed Dex is replaced by the ExternalName service.
+			continue
+		}
+		if obj.GetNamespace() != rmeta.OperatorNamespace() {
+			objsToDelete = append(objsToDelete, obj)
+		}
+	}
+	return objs, objsToDelete
+}
+
+func (c *dexComponent) deployedObjects() ([]client.Object, []client.Object) {
 	objs := []client.Object{
 		c.serviceAccount(),
 		c.deployment(),
 		c.service(),
-		c.clusterRole(),
-		c.clusterRoleBinding(),
-		c.configMap(),
+	}
+	objs = append(objs, configmap.ToRuntimeObjects(c.configMaps()...)...)
+	if c.servingCertificate() {
+		objs = append(objs, c.serviceCAConfigMap())
+	}
+	objs = append(objs, c.storageCRDs()...)
+	if c.metricsEnabled() {
+		objs = append(objs, c.metricsService())
+	}
+	if c.namespaceScoped() {
+		objs = append(objs, c.roleBinding())
+	} else {
+		if c.storageRoleScoped() {
+			objs = append(objs, c.storageRole(), c.storageRoleBinding())
+		}
+		if c.rbacEnabled(c.dexConfig.DexDeployment().ClusterRBAC) && len(c.clusterRoleRules()) != 0 {
+			objs = append(objs, c.clusterRole(), c.clusterRoleBinding())
+		}
 	}
 	objs = append(objs, secret.ToRuntimeObjects(c.dexConfig.RequiredSecrets(rmeta.OperatorNamespace())...)...)
 	objs = append(objs, c.dexConfig.CreateCertSecret())
-	objs = append(objs, secret.ToRuntimeObjects(c.dexConfig.RequiredSecrets(DexNamespace)...)...)
-	objs = append(objs, secret.ToRuntimeObjects(secret.CopyToNamespace(DexNamespace, c.pullSecrets...)...)...)
+	copies := append(c.dexConfig.RequiredSecrets(c.namespace()), secret.CopyToNamespace(c.namespace(), c.pullSecrets...)...)
+	objs = append(objs, secret.ToRuntimeObjects(secret.LabelCopies(rmeta.OperatorNamespace(), copies...)...)...)
+
+	if c.csrInit() && c.rbacEnabled(c.dexConfig.DexDeployment().CSRClusterRoleBinding) {
+		objs = append(objs, csrClusterRoleBinding(c.name(), c.namespace()))
+	}
+
+	rmeta.AddAppLabels(objs, rmeta.AppLabels(DexObjectName, c.name(), "identity-provider"))
+	sortObjects(objs)
+
+	// Copies whose originals are no longer copied, for example since a pull secret was removed from the installation or
+	// another identity provider was configured, are removed.
+	objsToDelete := secret.ToRuntimeObjects(secret.StaleCopies(c.namespace(), c.dexConfig.SecretCopies(), copies...)...)
+	if !c.splitConnectorConfig() {
+		objsToDelete = append(objsToDelete, &corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: c.connectorsConfigMapName(), Namespace: c.namespace()},
+		})
+	}
+	if !c.metricsEnabled() {
+		objsToDelete = append(objsToDelete, &corev1.Service{
+			TypeMeta:   metav1.TypeMeta{Kind: "Service", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: c.metricsServiceName(), Namespace: c.namespace()},
+		})
+	}
+	if !c.storageRoleScoped() {
+		objsToDelete = append(objsToDelete, c.storageRole(), c.storageRoleBinding())
+	} else if c.rbacEnabled(c.dexConfig.DexDeployment().ClusterRBAC) && len(c.clusterRoleRules()) == 0 {
+		// Dex needs no cluster-wide permissions anymore.
+		objsToDelete = append(objsToDelete, c.clusterRole(), c.clusterRoleBinding())
+	}
+	if c.openshift && !c.servingCertificate() {
+		objsToDelete = append(objsToDelete, &corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: DexServiceCAName(c.name()), Namespace: c.namespace()},
+		})
+	}
+	if previous := c.dexConfig.PreviousNamespace(); previous != "" {
+		objsToDelete = append(objsToDelete, movedObjects(objs, c.namespace(), previous)...)
+	}
+	return objs, objsToDelete
+}
+
+// movedObjects returns copies of the objects in namespace, placed in the namespace that they were moved from.
+func movedObjects(objs []client.Object, namespace, previous string) []client.Object {
+	var moved []client.Object
+	for _, obj := range objs {
+		if obj.GetNamespace() != namespace {
+			continue
+		}
+		old := obj.DeepCopyObject().(client.Object)
+		old.SetNamespace(previous)
+		moved = append(moved, old)
+	}
+	return moved
+}
+
+// sortObjects orders objects so that every render produces the same list: namespaces and CustomResourceDefinitions
+// first, then RBAC, then configuration and finally workloads. Objects within a group are sorted by kind, namespace and
+// name.
+func sortObjects(objs []client.Object) {
+	sort.SliceStable(objs, func(i, j int) bool {
+		a, b := objs[i], objs[j]
+		if ra, rb := objectRank(a), objectRank(b); ra != rb {
+			return ra < rb
+		}
+		if ka, kb := a.GetObjectKind().GroupVersionKind().Kind, b.GetObjectKind().GroupVersionKind().Kind; ka != kb {
+			return ka < kb
+		}
+		if a.GetNamespace() != b.GetNamespace() {
+			return a.GetNamespace() < b.GetNamespace()
+		}
+		return a.GetName() < b.GetName()
+	})
+}
 
-	if c.installation.CertificateManagement != nil {
-		objs = append(objs, csrClusterRoleBinding(DexObjectName, DexNamespace))
+func objectRank(obj client.Object) int {
+	switch obj.(type) {
+	case *corev1.Namespace, *apiextensionsv1.CustomResourceDefinition:
+		return 0
+	case *corev1.ServiceAccount, *rbacv1.ClusterRole, *rbacv1.ClusterRoleBinding, *rbacv1.Role, *rbacv1.RoleBinding:
+		return 1
+	case *corev1.ConfigMap, *corev1.Secret:
+		return 2
+	default:
+		return 3
 	}
+}
+
+// DexCertSANs returns the DNS names that the Dex TLS certificate is valid for: the DNS names of the Dex service in the
+// given namespace including its short form, followed by any valid extra names that are not already included.
+func DexCertSANs(namespace, clusterDomain string, extra []string) []string {
+	return DexInstanceCertSANs(DexObjectName, namespace, clusterDomain, extra)
+}
 
-	return objs, nil
+// DexInstanceCertSANs is like DexCertSANs, for the service of the Dex instance with the given name.
+func DexInstanceCertSANs(name, namespace, clusterDomain string, extra []string) []string {
+	return dns.GetServiceDNSNames(name, namespace, dns.NormalizeClusterDomain(clusterDomain), dns.WithShortNames(), dns.WithExtraNames(extra...))
+}
+
+// DexCertIPAddresses returns the IP addresses that the Dex TLS certificate is valid for: the ClusterIP of the Dex
+// service once it is allocated, followed by the extra addresses. Values that are not IP addresses, such as the
+// ClusterIP "None" of a headless service, and duplicates are left out.
+func DexCertIPAddresses(clusterIP string, extra []string) []net.IP {
+	var ips []net.IP
+	seen := map[string]bool{}
+	for _, s := range append([]string{clusterIP}, extra...) {
+		ip := net.ParseIP(s)
+		if ip == nil || seen[ip.String()] {
+			continue
+		}
+		seen[ip.String()] = true
+		ips = append(ips, ip)
+	}
+	return ips
 }
 
-// Method to satisfy the Component interface.
+// Ready returns false until all secrets that Dex mounts exist with their required fields and the manager domain is set.
 func (c *dexComponent) Ready() bool {
-	return true
+	return c.NotReadyReason() == ""
+}
+
+func (c *dexComponent) NotReadyReason() string {
+	return strings.Join(c.dexConfig.MissingPrerequisites(), "; ")
+}
+
+// Dependencies returns the secrets that the Dex pod mounts or reads its environment from, such as the TLS secret and
+// the identity provider credentials, so that they are in place before the deployment is applied.
+func (c *dexComponent) Dependencies() []client.Object {
+	if c.external() {
+		return nil
+	}
+	var deps []client.Object
+	for _, s := range c.dexConfig.RequiredSecrets(c.namespace()) {
+		deps = append(deps, s)
+	}
+	return deps
 }
 
+// Validate also checks that the rendered pod does not share the host namespaces, and reports the cluster-scoped objects
+// that are still rendered when Dex is installed namespace-scoped, since those have to be applied by someone with the
+// permission to do so. The objects are only checked once the config is valid, since they cannot be rendered before.
+func (c *dexComponent) Validate() error {
+	if err := c.dexConfig.Validate(); err != nil {
+		return err
+	}
+	// The config holds values of the Authentication and its secrets, so check that it marshals before it is rendered.
+	if _, err := yaml.Marshal(c.config()); err != nil {
+		return &MarshalError{Component: c.name(), Object: "config", Err: err}
+	}
+	if _, err := yaml.Marshal(map[string]interface{}{"connectors": c.connectors()}); err != nil {
+		return &MarshalError{Component: c.name(), Object: "connectors config", Err: err}
+	}
+
+	var problems []string
+	if replicas := *c.replicaCount(); replicas > 1 && !c.dexConfig.StorageType().Shared() {
+		problems = append(problems, fmt.Sprintf("the %s storage of Dex cannot be shared by %d replicas, use 1 replica or the %s or %s storage",
+			c.dexConfig.StorageType(), replicas, DexStorageKubernetes, DexStoragePostgres))
+	}
+
+	objs, _ := c.Objects()
+	for _, obj := range objs {
+		if d, ok := obj.(*appsv1.Deployment); ok {
+			spec := d.Spec.Template.Spec
+			var startup int32
+			for _, container := range spec.Containers {
+				for _, p := range []*corev1.Probe{container.StartupProbe, container.LivenessProbe, container.ReadinessProbe} {
+					if p != nil && startupSeconds(p) > startup {
+						startup = startupSeconds(p)
+					}
+				}
+			}
+			if deadline := *d.Spec.ProgressDeadlineSeconds; deadline <= startup {
+				problems = append(problems, fmt.Sprintf("the progress deadline of %ds must exceed the %ds that the probes of Dex allow for its startup", deadline, startup))
+			}
+			if minReady := d.Spec.MinReadySeconds; minReady < 0 {
+				problems = append(problems, fmt.Sprintf("the minimum ready seconds of %d may not be negative", minReady))
+			} else if deadline := *d.Spec.ProgressDeadlineSeconds; minReady >= deadline {
+				// The API server rejects a deployment that would be reported as failed before its pods are available.
+				problems = append(problems, fmt.Sprintf("the minimum ready seconds of %d must be less than the progress deadline of %ds", minReady, deadline))
+			}
+		}
+	}
+
+	if affinity := c.affinity(); affinity != nil && excludesNodeSelector(affinity.NodeAffinity, c.installation.ControlPlaneNodeSelector) {
+		problems = append(problems, "the required node affinity of Dex excludes the nodes that the control plane node selector of the Installation selects")
+	}
+
+	if c.namespaceScoped() {
+		var clusterScoped []string
+		for _, obj := range objs {
+			if obj.GetNamespace() == "" {
+				clusterScoped = append(clusterScoped, fmt.Sprintf("%s %s", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName()))
+			}
+		}
+		if len(clusterScoped) != 0 {
+			problems = append(problems, fmt.Sprintf("the namespace-scoped install still requires the cluster-scoped objects: %s", strings.Join(clusterScoped, ", ")))
+		}
+	}
+
+	if len(problems) != 0 {
+		return &ValidationError{Component: c.name(), Problems: problems}
+	}
+	return nil
+}
+
+func (c *dexComponent) namespace() string {
+	return c.dexConfig.Namespace()
+}
+
+func (c *dexComponent) name() string {
+	return c.dexConfig.Name()
+}
+
+// namespaceScoped returns true when Dex is installed without a ClusterRole and ClusterRoleBinding of its own.
+func (c *dexComponent) namespaceScoped() bool {
+	return c.dexConfig.DexDeployment().StorageClusterRole != ""
+}
+
+// storageRoleScoped returns true when Dex is granted access to its storage by a Role in its own namespace, rather than by
+// its ClusterRole.
+func (c *dexComponent) storageRoleScoped() bool {
+	scope := c.dexConfig.DexDeployment().StorageRBACScope
+	return !c.namespaceScoped() && scope != nil && *scope == oprv1.DexStorageRBACScopeNamespace
+}
+
+// rbacEnabled returns true unless the RBAC objects that the option controls are granted externally.
+func (c *dexComponent) rbacEnabled(option *oprv1.DexRBACType) bool {
+	return option == nil || *option != oprv1.DexRBACDisabled
+}
+
+// serviceAccount returns the service account of Dex with the configured labels and annotations. The configured
+// annotations are listed as managed, so that the annotations that are no longer configured are removed on update.
 func (c *dexComponent) serviceAccount() *corev1.ServiceAccount {
-	return &corev1.ServiceAccount{
+	sa := &corev1.ServiceAccount{
 		TypeMeta:   metav1.TypeMeta{Kind: "ServiceAccount", APIVersion: "v1"},
-		ObjectMeta: metav1.ObjectMeta{Name: DexObjectName, Namespace: DexNamespace},
+		ObjectMeta: metav1.ObjectMeta{Name: c.name(), Namespace: c.namespace()},
+	}
+	dd := c.dexConfig.DexDeployment()
+	if len(dd.ServiceAccountLabels) != 0 {
+		sa.Labels = map[string]string{}
+		for k, v := range dd.ServiceAccountLabels {
+			sa.Labels[k] = v
+		}
 	}
+	rmeta.SetManagedAnnotations(sa, dd.ServiceAccountAnnotations)
+	return sa
 }
 
 func (c *dexComponent) clusterRole() client.Object {
 	return &rbacv1.ClusterRole{
 		TypeMeta: metav1.TypeMeta{Kind: "ClusterRole", APIVersion: "rbac.authorization.k8s.io/v1"},
 		ObjectMeta: metav1.ObjectMeta{
-			Name: DexObjectName,
+			Name: c.name(),
 		},
-		Rules: []rbacv1.PolicyRule{
-			{
-				APIGroups: []string{"dex.coreos.com"},
-				Resources: []string{"*"},
-				Verbs:     []string{"*"},
+		Rules: c.clusterRoleRules(),
+	}
+}
+
+// clusterRoleRules grant Dex access to its storage, unless a Role in its namespace does, and, unless the operator
+// applies the CustomResourceDefinitions of the storage, allow Dex to create them.
+func (c *dexComponent) clusterRoleRules() []rbacv1.PolicyRule {
+	var rules []rbacv1.PolicyRule
+	if !c.storageRoleScoped() {
+		rules = append(rules, c.storageRule())
+	}
+	if !c.operatorManagesStorageCRDs() {
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups: []string{"apiextensions.k8s.io"},
+			Resources: []string{"customresourcedefinitions"},
+			Verbs:     []string{"create"},
+		})
+	}
+	return rules
+}
+
+func (c *dexComponent) operatorManagesStorageCRDs() bool {
+	crds := c.dexConfig.DexDeployment().StorageCRDs
+	return crds != nil && *crds == oprv1.DexStorageCRDsOperator
+}
+
+// storageCRDs returns the CustomResourceDefinitions of the kubernetes storage of Dex, when the operator applies them.
+// They are the definitions that the installed version of Dex would create itself, and are updated with the version of
+// Dex that the operator installs.
+func (c *dexComponent) storageCRDs() []client.Object {
+	if !c.operatorManagesStorageCRDs() || c.dexConfig.StorageType() != DexStorageKubernetes {
+		return nil
+	}
+	var crds []client.Object
+	for _, k := range dexStorageKinds {
+		crds = append(crds, &apiextensionsv1.CustomResourceDefinition{
+			TypeMeta: metav1.TypeMeta{Kind: "CustomResourceDefinition", APIVersion: "apiextensions.k8s.io/v1"},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        fmt.Sprintf("%s.%s", k.plural, dexStorageGroup),
+				Annotations: map[string]string{dexVersionAnnotation: components.ComponentDex.Version},
 			},
-			{
-				APIGroups: []string{"apiextensions.k8s.io"},
-				Resources: []string{"customresourcedefinitions"},
-				Verbs:     []string{"create"},
+			Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+				Group: dexStorageGroup,
+				Names: apiextensionsv1.CustomResourceDefinitionNames{
+					Plural:   k.plural,
+					Singular: strings.ToLower(k.kind),
+					Kind:     k.kind,
+					ListKind: k.kind + "List",
+				},
+				Scope: apiextensionsv1.NamespaceScoped,
+				Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+					{
+						Name:    "v1",
+						Served:  true,
+						Storage: true,
+						Schema: &apiextensionsv1.CustomResourceValidation{
+							OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+								Type:                   "object",
+								XPreserveUnknownFields: ptr.BoolToPtr(true),
+							},
+						},
+					},
+				},
 			},
-		},
+		})
+	}
+	return crds
+}
+
+// RequiresOrderedApply returns true when the Dex pod needs objects of the component as soon as it starts: with
+// certificate management, the init container requests the certificate of Dex with the permissions of the CSR cluster
+// role binding, and with the CustomResourceDefinitions of the storage that the operator applies, Dex cannot create them
+// itself.
+func (c *dexComponent) RequiresOrderedApply() bool {
+	if c.external() {
+		return false
+	}
+	return c.csrInit() || len(c.storageCRDs()) != 0
+}
+
+// RetainedObjects returns the CustomResourceDefinitions of the storage of Dex, so that the state of Dex is kept when
+// the Authentication is deleted, unless their deletion is requested.
+func (c *dexComponent) RetainedObjects() []client.Object {
+	if c.dexConfig.DexDeployment().DeleteStorageCRDs {
+		return nil
+	}
+	return c.storageCRDs()
+}
+
+// dexStorageKinds are the kinds in which the kubernetes storage of Dex stores its state, with the plural names of their
+// resources. Dex derives the plural names naively from the kinds, hence offlinesessionses and signingkeies.
+var dexStorageKinds = []struct{ kind, plural string }{
+	{"AuthCode", "authcodes"},
+	{"AuthRequest", "authrequests"},
+	{"Connector", "connectors"},
+	{"DeviceRequest", "devicerequests"},
+	{"DeviceToken", "devicetokens"},
+	{"OAuth2Client", "oauth2clients"},
+	{"OfflineSessions", "offlinesessionses"},
+	{"Password", "passwords"},
+	{"RefreshToken", "refreshtokens"},
+	{"SigningKey", "signingkeies"},
+}
+
+func dexStorageResources() []string {
+	var resources []string
+	for _, k := range dexStorageKinds {
+		resources = append(resources, k.plural)
+	}
+	return resources
+}
+
+// storageRule grants Dex the verbs it needs on the resources of its storage, or all verbs on all dex.coreos.com resources
+// when the wildcard rule is selected.
+func (c *dexComponent) storageRule() rbacv1.PolicyRule {
+	if r := c.dexConfig.DexDeployment().StorageRules; r != nil && *r == oprv1.DexStorageRulesWildcard {
+		return rbacv1.PolicyRule{
+			APIGroups: []string{dexStorageGroup},
+			Resources: []string{"*"},
+			Verbs:     []string{"*"},
+		}
+	}
+	return rbacv1.PolicyRule{
+		APIGroups: []string{dexStorageGroup},
+		Resources: dexStorageResources(),
+		Verbs:     []string{"create", "delete", "get", "list", "update"},
 	}
 }
 
@@ -166,18 +778,75 @@ func (c *dexComponent) clusterRoleBinding() client.Object {
 	return &rbacv1.ClusterRoleBinding{
 		TypeMeta: metav1.TypeMeta{Kind: "ClusterRoleBinding", APIVersion: "rbac.authorization.k8s.io/v1"},
 		ObjectMeta: metav1.ObjectMeta{
-			Name: DexObjectName,
+			Name: c.name(),
 		},
 		RoleRef: rbacv1.RoleRef{
 			APIGroup: "rbac.authorization.k8s.io",
 			Kind:     "ClusterRole",
-			Name:     DexObjectName,
+			Name:     c.name(),
 		},
 		Subjects: []rbacv1.Subject{
 			{
 				Kind:      "ServiceAccount",
-				Name:      DexObjectName,
-				Namespace: DexNamespace,
+				Name:      c.name(),
+				Namespace: c.namespace(),
+			},
+		},
+	}
+}
+
+// storageRole grants Dex access to its storage in its own namespace.
+func (c *dexComponent) storageRole() client.Object {
+	return &rbacv1.Role{
+		TypeMeta: metav1.TypeMeta{Kind: "Role", APIVersion: "rbac.authorization.k8s.io/v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-storage", c.name()),
+			Namespace: c.namespace(),
+		},
+		Rules: []rbacv1.PolicyRule{c.storageRule()},
+	}
+}
+
+func (c *dexComponent) storageRoleBinding() client.Object {
+	return &rbacv1.RoleBinding{
+		TypeMeta: metav1.TypeMeta{Kind: "RoleBinding", APIVersion: "rbac.authorization.k8s.io/v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-storage", c.name()),
+			Namespace: c.namespace(),
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: "rbac.authorization.k8s.io",
+			Kind:     "Role",
+			Name:     fmt.Sprintf("%s-storage", c.name()),
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      "ServiceAccount",
+				Name:      c.name(),
+				Namespace: c.namespace(),
+			},
+		},
+	}
+}
+
+// roleBinding grants Dex access to its storage in its own namespace through the pre-provisioned ClusterRole.
+func (c *dexComponent) roleBinding() client.Object {
+	return &rbacv1.RoleBinding{
+		TypeMeta: metav1.TypeMeta{Kind: "RoleBinding", APIVersion: "rbac.authorization.k8s.io/v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      c.name(),
+			Namespace: c.namespace(),
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: "rbac.authorization.k8s.io",
+			Kind:     "ClusterRole",
+			Name:     c.dexConfig.DexDeployment().StorageClusterRole,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      "ServiceAccount",
+				Name:      c.name(),
+				Namespace: c.namespace(),
 			},
 		},
 	}
@@ -185,92 +854,260 @@ func (c *dexComponent) clusterRoleBinding() client.Object {
 
 func (c *dexComponent) deployment() client.Object {
 	var initContainers []corev1.Container
-	if c.installation.CertificateManagement != nil {
-		initContainers = append(initContainers, CreateCSRInitContainer(
+	if c.csrInit() {
+		csrInit := CreateCSRInitContainer(
 			c.installation.CertificateManagement,
 			c.csrInitImage,
 			"tls",
-			DexObjectName,
+			c.name(),
 			corev1.TLSPrivateKeyKey,
 			corev1.TLSCertKey,
-			dns.GetServiceDNSNames(DexObjectName, DexNamespace, c.clusterDomain),
-			DexNamespace))
+			DexInstanceCertSANs(c.name(), c.namespace(), c.dexConfig.ClusterDomain(), nil),
+			c.namespace(),
+			DexCertIPAddresses(c.dexConfig.ServiceClusterIP(), c.dexConfig.DexDeployment().CertificateIPAddresses)...)
+		// The timeout is only passed when it is configured, so that the init container keeps the default timeout of
+		// the key-cert-provisioner otherwise.
+		if t := c.dexConfig.DexDeployment().CSRTimeoutSeconds; t != nil {
+			timeout := time.Duration(*t) * time.Second
+			csrInit.Env = append(csrInit.Env, corev1.EnvVar{Name: "TIMEOUT", Value: timeout.String()})
+		}
+		initContainers = append(initContainers, csrInit)
+	}
+	if c.splitConnectorConfig() {
+		initContainers = append(initContainers, c.mergeConfigInitContainer())
 	}
+	for i := range initContainers {
+		initContainers[i].TerminationMessagePolicy = c.terminationMessagePolicy()
+		initContainers[i].SecurityContext = c.initContainerSecurityContext()
+		if r := c.dexConfig.DexDeployment().InitContainerResources; r != nil {
+			initContainers[i].Resources = *r
+		}
+	}
+
+	// Sort the pull secrets, so that the order in which they were passed in does not change the pod template.
+	pullSecrets := secret.GetReferenceList(c.pullSecrets)
+	sort.Slice(pullSecrets, func(i, j int) bool { return pullSecrets[i].Name < pullSecrets[j].Name })
+
 	return &appsv1.Deployment{
 		TypeMeta: metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      DexObjectName,
-			Namespace: DexNamespace,
+			Name:      c.name(),
+			Namespace: c.namespace(),
 			Labels: map[string]string{
-				"k8s-app": DexObjectName,
+				"k8s-app": c.name(),
 			},
 		},
 		Spec: appsv1.DeploymentSpec{
 			Selector: &metav1.LabelSelector{
 				MatchLabels: map[string]string{
-					"k8s-app": DexObjectName,
+					"k8s-app": c.name(),
 				},
 			},
-			Replicas: &replicas,
+			Replicas:                c.replicaCount(),
+			ProgressDeadlineSeconds: ptr.Int32ToPtr(progressDeadlineSeconds(c.dexConfig.DexDeployment())),
+			MinReadySeconds:         minReadySeconds(c.dexConfig.DexDeployment()),
 			Strategy: appsv1.DeploymentStrategy{
 				Type: appsv1.RecreateDeploymentStrategyType,
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:      DexObjectName,
-					Namespace: DexNamespace,
-					Labels: map[string]string{
-						"k8s-app": DexObjectName,
-					},
-					Annotations: c.dexConfig.RequiredAnnotations(),
+					Name:        c.name(),
+					Namespace:   c.namespace(),
+					Labels:      c.podLabels(),
+					Annotations: c.podAnnotations(),
 				},
 				Spec: corev1.PodSpec{
 					NodeSelector:       c.installation.ControlPlaneNodeSelector,
-					ServiceAccountName: DexObjectName,
+					ServiceAccountName: c.name(),
+					HostNetwork:        false,
+					HostPID:            false,
+					HostIPC:            false,
 					Tolerations:        append(c.installation.ControlPlaneTolerations, rmeta.TolerateMaster),
-					ImagePullSecrets:   secret.GetReferenceList(c.pullSecrets),
+					ImagePullSecrets:   pullSecrets,
 					InitContainers:     initContainers,
+					Affinity:           c.affinity(),
+					DNSPolicy:          c.dnsPolicy(),
+					DNSConfig:          c.dexConfig.DexDeployment().DNSConfig,
+
+					ReadinessGates:                c.dexConfig.DexDeployment().ReadinessGates,
+					SecurityContext:               c.podSecurityContext(),
+					ShareProcessNamespace:         c.dexConfig.DexDeployment().ShareProcessNamespace,
+					TerminationGracePeriodSeconds: ptr.Int64ToPtr(terminationGracePeriodSeconds(c.dexConfig.DexDeployment())),
 					Containers: []corev1.Container{
 						{
-							Name:            DexObjectName,
+							Name:            c.name(),
 							Image:           c.image,
-							Env:             c.dexConfig.RequiredEnv(""),
-							LivenessProbe:   c.probe(),
-							SecurityContext: podsecuritycontext.NewBaseContext(),
+							Env:             append(c.dexConfig.RequiredEnv(""), corev1.EnvVar{Name: dexExpandEnv, Value: "true"}),
+							LivenessProbe:   c.livenessProbe(),
+							ReadinessProbe:  c.readinessProbe(),
+							SecurityContext: c.containerSecurityContext(),
+							Resources:       c.containerResources(),
 
-							Command: []string{"/usr/local/bin/dex", "serve", "/etc/dex/baseCfg/config.yaml"},
+							TerminationMessagePolicy: c.terminationMessagePolicy(),
+							Lifecycle:                c.lifecycle(),
 
-							Ports: []corev1.ContainerPort{
-								{
-									Name:          "https",
-									ContainerPort: DexPort,
-								},
-							},
+							Command: c.command(),
 
-							VolumeMounts: c.dexConfig.RequiredVolumeMounts(),
+							Ports: c.containerPorts(),
+
+							VolumeMounts: c.volumeMounts(),
 						},
 					},
-					Volumes: c.dexConfig.RequiredVolumes(),
+					Volumes: c.volumes(),
 				},
 			},
 		},
 	}
 }
 
+func (c *dexComponent) splitConnectorConfig() bool {
+	return c.dexConfig.DexDeployment().SplitConnectorConfig
+}
+
+func (c *dexComponent) metricsEnabled() bool {
+	return c.dexConfig.DexDeployment().MetricsService
+}
+
+func (c *dexComponent) metricsServiceName() string {
+	return fmt.Sprintf(dexMetricsServiceName, c.name())
+}
+
+// metricsServiceLabels are the labels by which the ServiceMonitor of Dex selects its metrics service.
+func (c *dexComponent) metricsServiceLabels() map[string]string {
+	return map[string]string{"k8s-app": c.metricsServiceName()}
+}
+
+// containerPorts returns the port of the web listener of Dex, plus the port of its telemetry endpoint when the metrics
+// service is enabled.
+func (c *dexComponent) containerPorts() []corev1.ContainerPort {
+	ports := []corev1.ContainerPort{
+		{
+			Name:          c.webListener(),
+			ContainerPort: DexPort,
+		},
+	}
+	if c.metricsEnabled() {
+		ports = append(ports, corev1.ContainerPort{Name: dexMetricsPortName, ContainerPort: DexMetricsPort})
+	}
+	return ports
+}
+
+// configPath returns the path of the config that Dex serves, which is merged by an init container when the connector
+// config is split from the base config.
+func (c *dexComponent) configPath() string {
+	if c.splitConnectorConfig() {
+		return path.Join(dexMergedConfigDir, "config.yaml")
+	}
+	return dexBaseConfigPath
+}
+
+// command serves the config of Dex. When the credentials of the identity provider are mounted from a secrets store,
+// Dex is started by a shell that exports them from their files first, since the config of Dex references them as env.
+// A missing file fails the container with a message that names it.
+func (c *dexComponent) command() []string {
+	serve := []string{"/usr/local/bin/dex", "serve", c.configPath()}
+	files := c.dexConfig.SecretsStoreFiles()
+	if len(files) == 0 {
+		return serve
+	}
+	var envs []string
+	for env := range files {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+	script := []string{"set -e"}
+	for _, env := range envs {
+		file := files[env]
+		script = append(script,
+			fmt.Sprintf(`[ -s %[1]s ] || { echo "the secrets store has no file %[1]s" >&2; exit 1; }`, file),
+			fmt.Sprintf(`export %s="$(cat %s)"`, env, file),
+		)
+	}
+	script = append(script, "exec "+strings.Join(serve, " "))
+	return []string{"/bin/sh", "-c", strings.Join(script, "\n")}
+}
+
+func (c *dexComponent) connectorsConfigMapName() string {
+	return fmt.Sprintf(dexConnectorsConfigName, c.name())
+}
+
+// volumes returns the volumes of the Dex config, plus the connectors config and the merged config when the connector
+// config is split from the base config.
+func (c *dexComponent) volumes() []corev1.Volume {
+	volumes := c.dexConfig.RequiredVolumes()
+	if c.servingCertificate() {
+		// The serving certificate has the same keys as the certificate of the operator.
+		for i := range volumes {
+			if volumes[i].Name == "tls" {
+				volumes[i].VolumeSource = certificateVolumeSource(nil, c.servingCertSecretName())
+			}
+		}
+	}
+	if c.splitConnectorConfig() {
+		volumes = append(volumes,
+			corev1.Volume{
+				Name: "connectors",
+				VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: c.connectorsConfigMapName()},
+					Items:                []corev1.KeyToPath{{Key: dexConnectorsConfigKey, Path: dexConnectorsConfigKey}},
+				}},
+			},
+			corev1.Volume{
+				Name:         "merged-config",
+				VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+			},
+		)
+	}
+	return volumes
+}
+
+func (c *dexComponent) volumeMounts() []corev1.VolumeMount {
+	mounts := c.dexConfig.RequiredVolumeMounts()
+	if c.splitConnectorConfig() {
+		mounts = append(mounts, corev1.VolumeMount{Name: "merged-config", MountPath: dexMergedConfigDir, ReadOnly: true})
+	}
+	return mounts
+}
+
+// mergeConfigInitContainer concatenates the base config and the connectors config into the config that Dex serves.
+// Both are YAML maps with distinct keys, so their concatenation is the config with the connectors of the split config.
+func (c *dexComponent) mergeConfigInitContainer() corev1.Container {
+	merged := path.Join(dexMergedConfigDir, "config.yaml")
+	connectors := path.Join(dexConnectorsConfigDir, dexConnectorsConfigKey)
+	return corev1.Container{
+		Name:    "merge-config",
+		Image:   c.image,
+		Command: []string{"/bin/sh", "-c", fmt.Sprintf("cat %s %s > %s", dexBaseConfigPath, connectors, merged)},
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: "config", MountPath: path.Dir(dexBaseConfigPath), ReadOnly: true},
+			{Name: "connectors", MountPath: dexConnectorsConfigDir, ReadOnly: true},
+			{Name: "merged-config", MountPath: dexMergedConfigDir},
+		},
+	}
+}
+
 func (c *dexComponent) service() client.Object {
+	var annotations map[string]string
+	if c.servingCertificate() {
+		annotations = map[string]string{rmeta.OpenShiftServingCertAnnotation: c.servingCertSecretName()}
+	}
 	return &corev1.Service{
 		TypeMeta: metav1.TypeMeta{Kind: "Service", APIVersion: "v1"},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      DexObjectName,
-			Namespace: DexNamespace,
+			Name:        c.name(),
+			Namespace:   c.namespace(),
+			Annotations: annotations,
 		},
 		Spec: corev1.ServiceSpec{
 			Selector: map[string]string{
-				"k8s-app": DexObjectName,
+				"k8s-app": c.name(),
 			},
+			SessionAffinity:          c.sessionAffinity(),
+			SessionAffinityConfig:    c.sessionAffinityConfig(),
+			PublishNotReadyAddresses: c.dexConfig.DexDeployment().PublishNotReadyAddresses,
 			Ports: []corev1.ServicePort{
 				{
-					Name: DexObjectName,
+					Name: c.name(),
 					Port: DexPort,
 					TargetPort: intstr.IntOrString{
 						Type:   intstr.Int,
@@ -283,14 +1120,375 @@ func (c *dexComponent) service() client.Object {
 	}
 }
 
+// metricsService exposes the telemetry endpoint of Dex, which serves plain HTTP, apart from the Dex service.
+func (c *dexComponent) metricsService() *corev1.Service {
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{Kind: "Service", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      c.metricsServiceName(),
+			Namespace: c.namespace(),
+			Labels:    c.metricsServiceLabels(),
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeClusterIP,
+			Selector: map[string]string{
+				"k8s-app": c.name(),
+			},
+			Ports: []corev1.ServicePort{
+				{
+					Name:       dexMetricsPortName,
+					Port:       DexMetricsPort,
+					TargetPort: intstr.FromString(dexMetricsPortName),
+					Protocol:   corev1.ProtocolTCP,
+				},
+			},
+		},
+	}
+}
+
+// externalService points the Dex service to the external Dex. The port documents where the external Dex is expected to
+// listen, since an ExternalName service only resolves to the host.
+func (c *dexComponent) externalService() *corev1.Service {
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{Kind: "Service", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      c.name(),
+			Namespace: c.namespace(),
+		},
+		Spec: corev1.ServiceSpec{
+			Type:         corev1.ServiceTypeExternalName,
+			ExternalName: c.dexConfig.DexDeployment().ExternalHost,
+			Ports: []corev1.ServicePort{
+				{
+					Name:     c.name(),
+					Port:     DexPort,
+					Protocol: corev1.ProtocolTCP,
+				},
+			},
+		},
+	}
+}
+
+func (c *dexComponent) sessionAffinity() corev1.ServiceAffinity {
+	if a := c.dexConfig.DexDeployment().SessionAffinity; a != nil {
+		return *a
+	}
+	return corev1.ServiceAffinityNone
+}
+
+// sessionAffinityConfig returns the configured timeout of the ClientIP session affinity. Without a timeout, the API
+// server defaults it.
+func (c *dexComponent) sessionAffinityConfig() *corev1.SessionAffinityConfig {
+	timeout := c.dexConfig.DexDeployment().SessionAffinityTimeoutSeconds
+	if timeout == nil || c.sessionAffinity() != corev1.ServiceAffinityClientIP {
+		return nil
+	}
+	return &corev1.SessionAffinityConfig{ClientIP: &corev1.ClientIPConfig{TimeoutSeconds: timeout}}
+}
+
 // Perform a HTTP GET to determine if an endpoint is available.
-func (c *dexComponent) probe() *corev1.Probe {
+// livenessProbe queries the discovery endpoint, unless the healthz endpoint is selected.
+func (c *dexComponent) livenessProbe() *corev1.Probe {
+	if c.useHealthzProbe() {
+		return c.probe(c.dexConfig.IssuerPath() + dexHealthzPath)
+	}
+	return c.probe(c.discoveryPath())
+}
+
+// readinessProbe queries the discovery endpoint when the liveness probe does not.
+func (c *dexComponent) readinessProbe() *corev1.Probe {
+	if c.useHealthzProbe() {
+		return c.probe(c.discoveryPath())
+	}
+	return nil
+}
+
+// discoveryPath returns the path of the OIDC discovery endpoint, below the issuer path unless another well-known path
+// prefix is configured.
+func (c *dexComponent) discoveryPath() string {
+	if prefix := c.dexConfig.DexDeployment().WellKnownPathPrefix; prefix != "" {
+		return strings.TrimSuffix(prefix, "/") + dexDiscoveryPath
+	}
+	return c.dexConfig.IssuerPath() + dexDiscoveryPath
+}
+
+func (c *dexComponent) useHealthzProbe() bool {
+	lp := c.dexConfig.DexDeployment().LivenessProbe
+	return lp != nil && *lp == oprv1.DexProbeEndpointHealthz
+}
+
+// terminationMessagePolicy defaults to FallbackToLogsOnError, so that the reason of a crash is not lost when Dex exits
+// before writing the termination message file.
+func (c *dexComponent) terminationMessagePolicy() corev1.TerminationMessagePolicy {
+	if p := c.dexConfig.DexDeployment().TerminationMessagePolicy; p != nil {
+		return *p
+	}
+	return corev1.TerminationMessageFallbackToLogsOnError
+}
+
+// podLabels returns the labels of the pod template: the selector label plus the configured pod labels, which may not
+// replace it.
+func (c *dexComponent) podLabels() map[string]string {
+	labels := map[string]string{}
+	for k, v := range c.dexConfig.DexDeployment().PodLabels {
+		labels[k] = v
+	}
+	labels["k8s-app"] = c.name()
+	return labels
+}
+
+// podAnnotations returns the configured pod annotations merged with the required annotations, which win on collision so
+// that Dex is still restarted when its configuration changes. The annotations that disable service mesh injection can
+// be replaced by the configured pod annotations.
+func (c *dexComponent) podAnnotations() map[string]string {
+	annotations := map[string]string{}
+	if c.dexConfig.DexDeployment().DisableServiceMeshInjection {
+		annotations[istioInjectAnnotation] = "false"
+		annotations[linkerdInjectAnnotation] = "disabled"
+	}
+	for k, v := range c.dexConfig.DexDeployment().PodAnnotations {
+		annotations[k] = v
+	}
+	for k, v := range c.dexConfig.RequiredAnnotations() {
+		annotations[k] = v
+	}
+	annotations[dexConfigMapsAnnotation] = configmap.AnnotationHash(c.configMaps()...)
+	return annotations
+}
+
+// replicaCount returns the configured number of Dex pods, or a single pod by default.
+func (c *dexComponent) replicaCount() *int32 {
+	if c.replicas != nil {
+		return c.replicas
+	}
+	return &replicas
+}
+
+// containerResources returns the configured resources of the Dex container, or none by default.
+func (c *dexComponent) containerResources() corev1.ResourceRequirements {
+	if c.resources != nil {
+		return *c.resources
+	}
+	return corev1.ResourceRequirements{}
+}
+
+// affinity returns the configured affinity, or prefers the nodes of the Manager when Dex is co-located with it and
+// the nodes that are not spot instances when those are avoided. There is no anti-affinity between Dex pods by default
+// that these preferences could conflict with.
+func (c *dexComponent) affinity() *corev1.Affinity {
+	if c.podAffinity != nil {
+		return c.podAffinity
+	}
+	dd := c.dexConfig.DexDeployment()
+	if dd.Affinity != nil {
+		return dd.Affinity
+	}
+	if !dd.CoLocateWithManager && dd.AvoidSpotNodes == nil {
+		return nil
+	}
+	affinity := &corev1.Affinity{}
+	if dd.CoLocateWithManager {
+		affinity.PodAffinity = &corev1.PodAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{{
+				Weight: 100,
+				PodAffinityTerm: corev1.PodAffinityTerm{
+					LabelSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"k8s-app": "tigera-manager"},
+					},
+					Namespaces:  []string{ManagerNamespace},
+					TopologyKey: "kubernetes.io/hostname",
+				},
+			}},
+		}
+	}
+	if spot := dd.AvoidSpotNodes; spot != nil {
+		affinity.NodeAffinity = &corev1.NodeAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{{
+				Weight:     spotNodesWeight(spot),
+				Preference: corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{spotNodesRequirement(spot)}},
+			}},
+		}
+	}
+	return affinity
+}
+
+// excludesNodeSelector returns true if every term of the required node affinity has a requirement that the labels of
+// the node selector do not satisfy, so that no node satisfies both. Only the requirements on the keys of the node
+// selector are checked, since the other labels of the nodes are not known.
+func excludesNodeSelector(affinity *corev1.NodeAffinity, nodeSelector map[string]string) bool {
+	if affinity == nil || affinity.RequiredDuringSchedulingIgnoredDuringExecution == nil || len(nodeSelector) == 0 {
+		return false
+	}
+	terms := affinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	for _, term := range terms {
+		excluded := false
+		for _, req := range term.MatchExpressions {
+			value, ok := nodeSelector[req.Key]
+			if !ok {
+				continue
+			}
+			switch req.Operator {
+			case corev1.NodeSelectorOpIn:
+				excluded = excluded || !containsString(req.Values, value)
+			case corev1.NodeSelectorOpNotIn:
+				excluded = excluded || containsString(req.Values, value)
+			case corev1.NodeSelectorOpDoesNotExist:
+				excluded = true
+			}
+		}
+		if !excluded {
+			return false
+		}
+	}
+	return len(terms) != 0
+}
+
+// spotNodesRequirement selects the nodes that are not spot instances. NotIn also selects the nodes without the label.
+func spotNodesRequirement(spot *oprv1.DexSpotNodes) corev1.NodeSelectorRequirement {
+	if spot.Value == "" {
+		return corev1.NodeSelectorRequirement{Key: spot.Key, Operator: corev1.NodeSelectorOpDoesNotExist}
+	}
+	return corev1.NodeSelectorRequirement{Key: spot.Key, Operator: corev1.NodeSelectorOpNotIn, Values: []string{spot.Value}}
+}
+
+func spotNodesWeight(spot *oprv1.DexSpotNodes) int32 {
+	if spot.Weight != nil {
+		return *spot.Weight
+	}
+	return 100
+}
+
+// restricted returns true when the security contexts of Dex satisfy the restricted Pod Security Standard.
+func (c *dexComponent) restricted() bool {
+	pss := c.dexConfig.DexDeployment().PodSecurityStandard
+	return pss != nil && *pss == oprv1.DexPodSecurityStandardRestricted
+}
+
+// securityContextOptions returns the user and groups that the Authentication CR configures for Dex.
+func (c *dexComponent) securityContextOptions() []podsecuritycontext.Option {
+	runAs := c.dexConfig.DexDeployment().RunAs
+	if runAs == nil {
+		return nil
+	}
+	if runAs.PlatformAssigned {
+		return []podsecuritycontext.Option{podsecuritycontext.WithAssignedIDs()}
+	}
+	var opts []podsecuritycontext.Option
+	if runAs.RunAsUser != nil {
+		opts = append(opts, podsecuritycontext.WithRunAsUser(*runAs.RunAsUser))
+	}
+	if runAs.RunAsGroup != nil {
+		opts = append(opts, podsecuritycontext.WithRunAsGroup(*runAs.RunAsGroup))
+	}
+	if runAs.FSGroup != nil {
+		opts = append(opts, podsecuritycontext.WithFSGroup(*runAs.FSGroup))
+	}
+	return opts
+}
+
+func (c *dexComponent) containerSecurityContext() *corev1.SecurityContext {
+	if c.restricted() {
+		return podsecuritycontext.NewRestrictedContext(c.securityContextOptions()...)
+	}
+	return podsecuritycontext.NewBaseContext(c.securityContextOptions()...)
+}
+
+// podSecurityContext is only set for the restricted Pod Security Standard or an override of the user and groups, so
+// that the pod of other installations is unchanged.
+func (c *dexComponent) podSecurityContext() *corev1.PodSecurityContext {
+	if c.restricted() {
+		return podsecuritycontext.NewRestrictedPodContext(c.securityContextOptions()...)
+	}
+	if c.dexConfig.DexDeployment().RunAs != nil {
+		return podsecuritycontext.NewBasePodContext(c.securityContextOptions()...)
+	}
+	return nil
+}
+
+// initContainerSecurityContext returns the configured security context of the init containers, or the restricted
+// context for the restricted Pod Security Standard, or the base context without privileges.
+func (c *dexComponent) initContainerSecurityContext() *corev1.SecurityContext {
+	if sc := c.dexConfig.DexDeployment().InitContainerSecurityContext; sc != nil {
+		return sc
+	}
+	if c.restricted() {
+		return podsecuritycontext.NewRestrictedContext(c.securityContextOptions()...)
+	}
+	sc := podsecuritycontext.NewBaseContext(c.securityContextOptions()...)
+	sc.Privileged = ptr.BoolToPtr(false)
+	return sc
+}
+
+// lifecycle delays the shutdown of Dex with a preStop hook, so that load balancers deregister the pod before Dex stops
+// accepting connections.
+func (c *dexComponent) lifecycle() *corev1.Lifecycle {
+	seconds := preStopSleepSeconds(c.dexConfig.DexDeployment())
+	if seconds == 0 {
+		return nil
+	}
+	return &corev1.Lifecycle{
+		PreStop: &corev1.Handler{
+			Exec: &corev1.ExecAction{Command: []string{"sleep", strconv.Itoa(int(seconds))}},
+		},
+	}
+}
+
+func preStopSleepSeconds(dd *oprv1.DexDeployment) int32 {
+	if dd.PreStopSleepSeconds != nil {
+		return *dd.PreStopSleepSeconds
+	}
+	return dexPreStopSleepSeconds
+}
+
+func terminationGracePeriodSeconds(dd *oprv1.DexDeployment) int64 {
+	if dd.TerminationGracePeriodSeconds != nil {
+		return *dd.TerminationGracePeriodSeconds
+	}
+	return dexTerminationGracePeriodSeconds
+}
+
+func progressDeadlineSeconds(dd *oprv1.DexDeployment) int32 {
+	if dd.ProgressDeadlineSeconds != nil {
+		return *dd.ProgressDeadlineSeconds
+	}
+	return dexProgressDeadlineSeconds
+}
+
+func minReadySeconds(dd *oprv1.DexDeployment) int32 {
+	if dd.MinReadySeconds != nil {
+		return *dd.MinReadySeconds
+	}
+	return 0
+}
+
+// startupSeconds returns how long a probe allows a container to start before the container is restarted or the pod is
+// kept out of service: the initial delay plus the failures that the probe tolerates.
+func startupSeconds(p *corev1.Probe) int32 {
+	failureThreshold := p.FailureThreshold
+	if failureThreshold == 0 {
+		failureThreshold = 3
+	}
+	periodSeconds := p.PeriodSeconds
+	if periodSeconds == 0 {
+		periodSeconds = 10
+	}
+	return p.InitialDelaySeconds + failureThreshold*periodSeconds
+}
+
+func (c *dexComponent) dnsPolicy() corev1.DNSPolicy {
+	if p := c.dexConfig.DexDeployment().DNSPolicy; p != nil {
+		return *p
+	}
+	return corev1.DNSClusterFirst
+}
+
+func (c *dexComponent) probe(path string) *corev1.Probe {
 	return &corev1.Probe{
 		Handler: corev1.Handler{
 			HTTPGet: &corev1.HTTPGetAction{
-				Path:   "/dex/.well-known/openid-configuration",
+				Path:   path,
 				Port:   intstr.FromInt(DexPort),
-				Scheme: corev1.URISchemeHTTPS,
+				Scheme: c.dexConfig.WebScheme(),
 			},
 		},
 		InitialDelaySeconds: 90,
@@ -298,7 +1496,31 @@ func (c *dexComponent) probe() *corev1.Probe {
 	}
 }
 
-func (c *dexComponent) configMap() *corev1.ConfigMap {
+// webListener returns the name of the web listener of Dex, which is also the name of its port.
+func (c *dexComponent) webListener() string {
+	return strings.ToLower(string(c.dexConfig.WebScheme()))
+}
+
+// web returns the configuration of the web listener of Dex. The TLS certificate is only served when TLS is not
+// terminated upstream, and the configured response headers are only rendered when there are any.
+func (c *dexComponent) web() map[string]interface{} {
+	web := map[string]interface{}{
+		c.webListener():           "0.0.0.0:5556",
+		"allowedOrigins":          []string{"*"},
+		"discoveryAllowedOrigins": []string{"*"},
+	}
+	if c.dexConfig.WebScheme() == corev1.URISchemeHTTPS {
+		web["tlsCert"] = "/etc/dex/tls/tls.crt"
+		web["tlsKey"] = "/etc/dex/tls/tls.key"
+	}
+	if headers := c.dexConfig.DexDeployment().ResponseHeaders; len(headers) != 0 {
+		web["headers"] = headers
+	}
+	return web
+}
+
+// config returns the config of Dex that is marshalled into its ConfigMap.
+func (c *dexComponent) config() map[string]interface{} {
 	redirectURIs := []string{
 		"https://localhost:9443/login/oidc/callback",
 		"https://127.0.0.1:9443/login/oidc/callback",
@@ -312,47 +1534,81 @@ func (c *dexComponent) configMap() *corev1.ConfigMap {
 	}
 
 	data := map[string]interface{}{
-		"issuer": fmt.Sprintf("%s/dex", c.dexConfig.ManagerURI()),
-		"storage": map[string]interface{}{
-			"type": "kubernetes",
-			"config": map[string]bool{
-				"inCluster": true,
-			},
-		},
-		"web": map[string]interface{}{
-			"https":                   "0.0.0.0:5556",
-			"tlsCert":                 "/etc/dex/tls/tls.crt",
-			"tlsKey":                  "/etc/dex/tls/tls.key",
-			"allowedOrigins":          []string{"*"},
-			"discoveryAllowedOrigins": []string{"*"},
-		},
-		"connectors": []map[string]interface{}{c.connector},
+		"issuer":  c.dexConfig.Issuer(),
+		"storage": c.dexConfig.Storage(),
+		"web":     c.web(),
 		"oauth2": map[string]interface{}{
 			"skipApprovalScreen": true,
 			"responseTypes":      []string{"id_token", "code", "token"},
 		},
-		"staticClients": []map[string]interface{}{
+		"staticClients": append([]map[string]interface{}{
 			{
 				"id":           DexClientId,
 				"redirectURIs": redirectURIs,
-				"name":         "Calico Enterprise Manager",
+				"name":         c.dexConfig.ManagerClientName(),
 				"secretEnv":    dexSecretEnv,
+				"grantTypes":   c.dexConfig.ManagerGrantTypes(),
 			},
-		},
+		}, c.dexConfig.StaticClients()...),
+	}
+	if !c.splitConnectorConfig() {
+		data["connectors"] = c.connectors()
+	}
+	if c.metricsEnabled() {
+		data["telemetry"] = map[string]interface{}{
+			"http": fmt.Sprintf("0.0.0.0:%d", DexMetricsPort),
+		}
 	}
 
-	bytes, err := yaml.Marshal(data)
-	if err != nil { // Don't think this is possible.
+	return data
+}
+
+// configMaps returns the ConfigMaps with the config that Dex reads: the base config, and the connectors config when it
+// is split from the base config.
+func (c *dexComponent) configMaps() []*corev1.ConfigMap {
+	configMaps := []*corev1.ConfigMap{c.configMap()}
+	if c.splitConnectorConfig() {
+		configMaps = append(configMaps, c.connectorsConfigMap())
+	}
+	return configMaps
+}
+
+func (c *dexComponent) configMap() *corev1.ConfigMap {
+	bytes, err := yaml.Marshal(c.config())
+	if err != nil { // Validate reports this.
 		panic(err)
 	}
 	return &corev1.ConfigMap{
 		TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      DexObjectName,
-			Namespace: DexNamespace,
+			Name:      c.name(),
+			Namespace: c.namespace(),
 		},
 		Data: map[string]string{
 			"config.yaml": string(bytes),
 		},
 	}
 }
+
+func (c *dexComponent) connectors() []map[string]interface{} {
+	return []map[string]interface{}{c.connector}
+}
+
+// connectorsConfigMap returns the ConfigMap of the connectors config, which holds only the connectors key that the base
+// config leaves out when the connector config is split from it.
+func (c *dexComponent) connectorsConfigMap() *corev1.ConfigMap {
+	bytes, err := yaml.Marshal(map[string]interface{}{"connectors": c.connectors()})
+	if err != nil { // Validate reports this.
+		panic(err)
+	}
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      c.connectorsConfigMapName(),
+			Namespace: c.namespace(),
+		},
+		Data: map[string]string{
+			dexConnectorsConfigKey: string(bytes),
+		},
+	}
+}
@@ -15,15 +15,31 @@
 package render
 
 import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
 	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/tigera/operator/pkg/components"
+	"github.com/tigera/operator/pkg/dns"
+	"github.com/tigera/operator/pkg/ptr"
+	"github.com/tigera/operator/pkg/render/common/configmap"
 	rmeta "github.com/tigera/operator/pkg/render/common/meta"
 	"github.com/tigera/operator/pkg/render/common/secret"
 
 	oprv1 "github.com/tigera/operator/api/v1"
+	"gopkg.in/yaml.v2"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 const (
@@ -34,12 +50,19 @@ const (
 	connectorTypeLDAP      = "ldap"
 
 	// Various annotations to keep the pod up-to-date
-	authenticationAnnotation = "hash.operator.tigera.io/tigera-dex-auth"
-	dexConfigMapAnnotation   = "hash.operator.tigera.io/tigera-dex-config"
-	dexIdpSecretAnnotation   = "hash.operator.tigera.io/tigera-idp-secret"
-	dexSecretAnnotation      = "hash.operator.tigera.io/tigera-dex-secret"
-	dexTLSSecretAnnotation   = "hash.operator.tigera.io/tigera-dex-tls-secret"
-	dexCertSecretAnnotation  = "hash.operator.tigera.io/tigera-dex-cert-secret"
+	authenticationAnnotation   = "hash.operator.tigera.io/tigera-dex-auth"
+	dexConfigMapAnnotation     = "hash.operator.tigera.io/tigera-dex-config"
+	dexConfigMapsAnnotation    = "hash.operator.tigera.io/tigera-dex-configmaps"
+	dexIdpSecretAnnotation     = "hash.operator.tigera.io/tigera-idp-secret"
+	dexIdpCABundleAnnotation   = "hash.operator.tigera.io/tigera-idp-ca-bundle"
+	dexSecretAnnotation        = "hash.operator.tigera.io/tigera-dex-secret"
+	dexTLSSecretAnnotation     = "hash.operator.tigera.io/tigera-dex-tls-secret"
+	dexCertSecretAnnotation    = "hash.operator.tigera.io/tigera-dex-cert-secret"
+	dexStaticClientsAnnotation = "hash.operator.tigera.io/tigera-dex-static-clients"
+	dexVerifierAnnotation      = "hash.operator.tigera.io/tigera-dex-verifier"
+
+	// The name of the Manager client that Dex shows to users by default.
+	defaultManagerClientName = "Calico Enterprise Manager"
 
 	// Constants related to secrets.
 	serviceAccountSecretField    = "serviceAccountSecret"
@@ -55,32 +78,143 @@ const (
 	ClientIDSecretField          = "clientID"
 	BindDNSecretField            = "bindDN"
 	BindPWSecretField            = "bindPW"
+	// The secrets-store CSI driver, and the volume and the directory in which Dex mounts the credentials of the
+	// identity provider from it.
+	secretsStoreDriver    = "secrets-store.csi.k8s.io"
+	secretsStoreVolume    = "idp-secrets-store"
+	secretsStoreMountPath = "/etc/dex/idp"
+	// The volume of the merged CA bundle that Dex trusts the identity provider with. It is named apart from the
+	// "secrets" volume, since the Google service account is mounted from that one.
+	idpCABundleVolume = "idp-ca"
+	// The rotation of the Authentication that the client secret of Dex was generated for.
+	dexClientSecretRotationField = "rotation"
+
+	// DexClientSecretRotationAnnotation on the Authentication requests a new client secret for Dex whenever its value
+	// changes, for example to the current time.
+	DexClientSecretRotationAnnotation = "operator.tigera.io/rotate-dex-client-secret"
+
+	// OIDC well-known-config related constants. The paths are relative to the issuer path of Dex.
+	serviceURI   = "%s://%s.%s.svc.%s:5556%s"
+	jwksPath     = "/keys"
+	tokenPath    = "/token"
+	userInfoPath = "/userinfo"
 
-	// OIDC well-known-config related constants.
-	jwksURI     = "https://tigera-dex.tigera-dex.svc.%s:5556/dex/keys"
-	tokenURI    = "https://tigera-dex.tigera-dex.svc.%s:5556/dex/token"
-	userInfoURI = "https://tigera-dex.tigera-dex.svc.%s:5556/dex/userinfo"
+	// Where verifiers mount the certificate that Dex is trusted with.
+	dexVerifierCertDir  = "/etc/dex-verifier"
+	dexVerifierCertFile = "tls-dex.crt"
 
 	// Env related constants.
 	googleAdminEmailEnv = "ADMIN_EMAIL"
 	clientIDEnv         = "CLIENT_ID"
 	clientSecretEnv     = "CLIENT_SECRET"
 	dexSecretEnv        = "DEX_SECRET"
+	dexCAFileEnv        = "DEX_CA_FILE"
 	bindDNEnv           = "BIND_DN"
 	bindPWEnv           = "BIND_PW"
+	// The secret of the additional static client at the given index.
+	staticClientSecretEnv = "STATIC_CLIENT_SECRET_%d"
 
 	// Default claims to use to data from a JWT.
 	DefaultGroupsClaim   = "groups"
 	defaultUsernameClaim = "email"
 
+	// DexKubectlConfigLabel marks the ConfigMaps with a kubeconfig user for kubectl, with the name of the Dex instance
+	// that they log in to as its value.
+	DexKubectlConfigLabel = "operator.tigera.io/dex-kubectl-config"
+	// DexDiscoveryLabel marks the ConfigMaps with the discovery details of a Dex instance, with the name of the
+	// instance as its value.
+	DexDiscoveryLabel = "operator.tigera.io/dex-discovery"
+
 	// Other constants
 	googleIssuer = "https://accounts.google.com"
 )
 
+// idpSecretEnv are the env vars that the connector config references for the fields of the identity provider secret.
+var idpSecretEnv = map[string]string{
+	ClientIDSecretField:     clientIDEnv,
+	ClientSecretSecretField: clientSecretEnv,
+	BindDNSecretField:       bindDNEnv,
+	BindPWSecretField:       bindPWEnv,
+}
+
+// discoveryKeys are the keys of the discovery ConfigMaps for the env vars with which a component validates the tokens
+// of Dex, so that both hold the same values.
+var discoveryKeys = map[string]string{
+	"DEX_ISSUER":          "issuer",
+	"DEX_URL":             "url",
+	"DEX_JWKS_URL":        "jwks-uri",
+	"DEX_CLIENT_ID":       "client-id",
+	"DEX_USERNAME_CLAIM":  "username-claim",
+	"DEX_GROUPS_CLAIM":    "groups-claim",
+	"DEX_USERNAME_PREFIX": "username-prefix",
+	"DEX_GROUPS_PREFIX":   "groups-prefix",
+}
+
+// dexWebHeaders are the response headers that Dex sets from the headers of its web config, which are the only ones
+// that it supports.
+var dexWebHeaders = []string{
+	"Content-Security-Policy",
+	"Strict-Transport-Security",
+	"X-Content-Type-Options",
+	"X-Frame-Options",
+	"X-XSS-Protection",
+}
+
 // DexConfig is a config for DexIdP itself.
 type DexConfig interface {
 	Connector() map[string]interface{}
+	// ConnectorType returns the type of the connector to the identity provider, e.g. oidc or ldap.
+	ConnectorType() string
 	CreateCertSecret() *corev1.Secret
+	// ManagerGrantTypes returns the OAuth2 grant types that the Manager client is allowed to use.
+	ManagerGrantTypes() []string
+	// ManagerClientName returns the name of the Manager client that Dex shows to users.
+	ManagerClientName() string
+	// StaticClients returns the additional static clients that Dex registers besides the Manager client.
+	StaticClients() []map[string]interface{}
+	// DexDeployment returns the configuration of the Dex deployment. It is never nil.
+	DexDeployment() *oprv1.DexDeployment
+	// Issuer returns the issuer URL of Dex.
+	Issuer() string
+	// IssuerPath returns the path below the manager URI at which Dex serves its issuer.
+	IssuerPath() string
+	// PreviousNamespace returns the namespace in which Dex was last installed, if it differs from Namespace().
+	PreviousNamespace() string
+	// SecretCopies returns the secrets that the operator copied into the namespace of Dex before, as configured with
+	// WithSecretCopies.
+	SecretCopies() []corev1.Secret
+	// KubectlConfigMap returns the ConfigMap with the kubeconfig user for kubectl, or nil if it is not configured.
+	KubectlConfigMap() *corev1.ConfigMap
+	// KubectlConfigMaps returns the ConfigMaps with a kubeconfig user that the operator rendered before, as configured
+	// with WithKubectlConfigMaps.
+	KubectlConfigMaps() []corev1.ConfigMap
+	// DiscoveryConfigMaps returns the ConfigMaps with the discovery details of Dex, one for each of the discovery
+	// namespaces.
+	DiscoveryConfigMaps() []*corev1.ConfigMap
+	// DiscoveryConfigMapCopies returns the ConfigMaps with discovery details that the operator rendered before, as
+	// configured with WithDiscoveryConfigMapCopies.
+	DiscoveryConfigMapCopies() []corev1.ConfigMap
+	// PrometheusRules returns the PrometheusRules with alerts for Dex that the operator rendered before, as configured
+	// with WithPrometheusRules.
+	PrometheusRules() []unstructured.Unstructured
+	// CertificateExpiry returns when the certificate of Dex expires, if the operator knows the certificate.
+	CertificateExpiry() (time.Time, bool)
+	// Storage returns the storage section of the Dex config, as configured with WithStorage.
+	Storage() map[string]interface{}
+	// StorageType returns the storage backend of Dex.
+	StorageType() DexStorageType
+	// SecretReferences returns the keys of the secrets that the connector and the static clients read, so that they
+	// can be checked before Dex is deployed.
+	SecretReferences() []DexSecretReference
+	// MissingPrerequisites lists what Dex is still waiting for before it can be deployed, such as a manager domain
+	// or secrets that do not exist yet or lack required fields.
+	MissingPrerequisites() []string
+	// Validate checks that the Authentication and the secrets that this config is based on are complete and
+	// consistent. Problems are reported as a *ValidationError.
+	Validate() error
+	// SecretsStoreFiles returns the files of the secrets store volume that Dex reads the env of its connector from,
+	// keyed by the env var, or nil if the credentials of the identity provider are read from a secret.
+	SecretsStoreFiles() map[string]string
 	DexKeyValidatorConfig
 }
 
@@ -98,6 +232,30 @@ type DexKeyValidatorConfig interface {
 	RequiredVolumeMounts() []corev1.VolumeMount
 	// RequiredVolumes returns volumes that are related to dex.
 	RequiredVolumes() []corev1.Volume
+	// Namespace returns the namespace in which Dex is installed.
+	Namespace() string
+	// Name returns the name of the Dex instance, which is also the name of its objects and its service.
+	Name() string
+	// WebScheme returns the scheme of the web listener of Dex, which is HTTP when TLS is terminated upstream.
+	WebScheme() corev1.URIScheme
+	// ClusterDomain returns the cluster domain of the Dex service. It is detected when no cluster domain was given.
+	ClusterDomain() string
+	// ServiceClusterIP returns the ClusterIP of the Dex service, as configured with WithServiceClusterIP.
+	ServiceClusterIP() string
+	// Verifier returns the settings with which a component verifies the tokens that Dex issues. The prefix is applied
+	// to the names of the env vars and the volume of the CA, so that several components in one pod can each embed one.
+	Verifier(prefix string) DexVerifier
+}
+
+// DexVerifier is what a component embeds to verify the tokens that Dex issues: the env vars with the issuer, client ID
+// and claims of Dex, the volume with the certificate that Dex is trusted with and its mount, and the annotations that
+// roll out the pods of the component when any of these change. The CA file env var points at the certificate in the
+// mount.
+type DexVerifier struct {
+	Env         []corev1.EnvVar
+	Volume      corev1.Volume
+	VolumeMount corev1.VolumeMount
+	Annotations map[string]string
 }
 
 // DexRelyingPartyConfig is a config for relying parties / applications that use Dex as their IdP.
@@ -121,15 +279,116 @@ func NewDexRelyingPartyConfig(
 	authentication *oprv1.Authentication,
 	certSecret *corev1.Secret,
 	dexSecret *corev1.Secret,
-	clusterDomain string) DexRelyingPartyConfig {
-	return &dexRelyingPartyConfig{baseCfg(nil, authentication, nil, dexSecret, nil, certSecret, clusterDomain)}
+	clusterDomain string,
+	opts ...DexOption) DexRelyingPartyConfig {
+	return &dexRelyingPartyConfig{baseCfg(nil, authentication, nil, dexSecret, nil, nil, certSecret, clusterDomain, opts...)}
 }
 
 func NewDexKeyValidatorConfig(
 	authentication *oprv1.Authentication,
 	certSecret *corev1.Secret,
-	clusterDomain string) DexKeyValidatorConfig {
-	return &dexKeyValidatorConfig{baseCfg(nil, authentication, nil, nil, nil, certSecret, clusterDomain)}
+	clusterDomain string,
+	opts ...DexOption) DexKeyValidatorConfig {
+	return &dexKeyValidatorConfig{baseCfg(nil, authentication, nil, nil, nil, nil, certSecret, clusterDomain, opts...)}
+}
+
+// DexOption customizes the configuration of a Dex instance and of the components that use it.
+type DexOption func(*dexBaseCfg)
+
+// WithSecretCopies configures the secrets that the operator copied into the namespace of Dex before, which are the
+// secrets there with the secret.CopiedFromLabel. The copies that Dex no longer needs are removed.
+func WithSecretCopies(copies []corev1.Secret) DexOption {
+	return func(d *dexBaseCfg) {
+		d.secretCopies = copies
+	}
+}
+
+// WithKubectlConfigMaps configures the ConfigMaps with a kubeconfig user that the operator rendered before, which are
+// the ConfigMaps with the DexKubectlConfigLabel. The ConfigMaps of this instance that are no longer configured are
+// removed.
+func WithKubectlConfigMaps(configMaps []corev1.ConfigMap) DexOption {
+	return func(d *dexBaseCfg) {
+		d.kubectlConfigMaps = configMaps
+	}
+}
+
+// WithPrometheusRules configures the PrometheusRules with alerts for Dex that the operator rendered before, which are
+// the PrometheusRules with the DexPrometheusRuleLabel. The rules of this instance that are no longer configured are
+// removed.
+func WithPrometheusRules(rules []unstructured.Unstructured) DexOption {
+	return func(d *dexBaseCfg) {
+		d.prometheusRules = rules
+	}
+}
+
+// WithIdentityProviderCABundle configures the secret in the operator namespace with the CA bundle that Dex trusts for
+// the identity provider, which secret.MergeCABundles created from the root CA of the identity provider secret and the
+// IdentityProviderCAConfigMaps of the DexDeployment. Dex mounts it instead of the root CA of the identity provider
+// secret.
+func WithIdentityProviderCABundle(bundle *corev1.Secret) DexOption {
+	return func(d *dexBaseCfg) {
+		d.idpCABundle = bundle
+	}
+}
+
+// WithServiceCA configures the CA bundle that the service CA of OpenShift injected for the serving certificate of Dex.
+// The clients of Dex trust it instead of the certificate of the operator or the CA of the certificate management.
+func WithServiceCA(ca []byte) DexOption {
+	return func(d *dexBaseCfg) {
+		d.serviceCA = ca
+	}
+}
+
+// WithDiscoveryConfigMapCopies configures the ConfigMaps with discovery details that the operator rendered before,
+// which are the ConfigMaps with the DexDiscoveryLabel. The copies of this instance in namespaces that are no longer
+// configured are removed.
+func WithDiscoveryConfigMapCopies(configMaps []corev1.ConfigMap) DexOption {
+	return func(d *dexBaseCfg) {
+		d.discoveryConfigMaps = configMaps
+	}
+}
+
+// WithClusterDomainDetector configures the detector that provides the cluster domain when none is given, and that
+// the given cluster domain is validated against. It defaults to dns.DefaultClusterDomainDetector.
+func WithClusterDomainDetector(detector *dns.ClusterDomainDetector) DexOption {
+	return func(d *dexBaseCfg) {
+		d.clusterDomainDetector = detector
+	}
+}
+
+// WithServiceClusterIP configures the ClusterIP of the Dex service once it is allocated, which is one of the IP SANs
+// of the Dex certificate.
+func WithServiceClusterIP(clusterIP string) DexOption {
+	return func(d *dexBaseCfg) {
+		d.serviceClusterIP = clusterIP
+	}
+}
+
+// DexStorageType is a storage backend in which Dex keeps its state.
+type DexStorageType string
+
+const (
+	// The custom resources of Dex in the cluster.
+	DexStorageKubernetes DexStorageType = "kubernetes"
+	DexStoragePostgres   DexStorageType = "postgres"
+	// A database file in the Dex pod.
+	DexStorageSQLite3 DexStorageType = "sqlite3"
+	// The memory of the Dex process, which is lost when Dex restarts.
+	DexStorageMemory DexStorageType = "memory"
+)
+
+// Shared returns whether several replicas of Dex can share the storage, which the sqlite3 and memory storage cannot.
+func (t DexStorageType) Shared() bool {
+	return t != DexStorageSQLite3 && t != DexStorageMemory
+}
+
+// WithStorage configures the storage backend of Dex and the config of the backend as Dex expects it, for example
+// {"file": "/var/dex/dex.db"} for sqlite3. Dex uses the kubernetes storage with its in-cluster config by default.
+func WithStorage(storageType DexStorageType, config map[string]interface{}) DexOption {
+	return func(d *dexBaseCfg) {
+		d.storageType = storageType
+		d.storageConfig = config
+	}
 }
 
 // Create a new DexConfig.
@@ -139,8 +398,10 @@ func NewDexConfig(
 	tlsSecret *corev1.Secret,
 	dexSecret *corev1.Secret,
 	idpSecret *corev1.Secret,
-	clusterDomain string) DexConfig {
-	return &dexConfig{baseCfg(certificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterDomain)}
+	staticClientSecrets []*corev1.Secret,
+	clusterDomain string,
+	opts ...DexOption) DexConfig {
+	return &dexConfig{baseCfg(certificateManagement, authentication, tlsSecret, dexSecret, idpSecret, staticClientSecrets, nil, clusterDomain, opts...)}
 }
 
 type dexKeyValidatorConfig struct {
@@ -162,8 +423,10 @@ func baseCfg(
 	tlsSecret *corev1.Secret,
 	dexSecret *corev1.Secret,
 	idpSecret *corev1.Secret,
+	staticClientSecrets []*corev1.Secret,
 	certSecret *corev1.Secret,
-	clusterDomain string) *dexBaseCfg {
+	clusterDomain string,
+	opts ...DexOption) *dexBaseCfg {
 
 	// If the manager domain is not a URL, prepend https://.
 	baseUrl := authentication.Spec.ManagerDomain
@@ -184,17 +447,34 @@ func baseCfg(
 		connType = connectorTypeLDAP
 	}
 
-	return &dexBaseCfg{
+	// Keep the static client secrets sorted by name, so that the secrets and annotations that are derived from them
+	// do not depend on the order in which the secrets were read.
+	staticClientSecrets = append([]*corev1.Secret(nil), staticClientSecrets...)
+	sort.SliceStable(staticClientSecrets, func(i, j int) bool {
+		return staticClientSecrets[i].Name < staticClientSecrets[j].Name
+	})
+
+	cfg := &dexBaseCfg{
 		certificateManagement: certificateManagement,
 		authentication:        authentication,
 		tlsSecret:             tlsSecret,
 		idpSecret:             idpSecret,
 		dexSecret:             dexSecret,
+		staticClientSecrets:   staticClientSecrets,
 		certSecret:            certSecret,
 		connectorType:         connType,
 		managerURI:            baseUrl,
 		clusterDomain:         clusterDomain,
+		clusterDomainDetector: dns.DefaultClusterDomainDetector,
+		storageType:           DexStorageKubernetes,
+		storageConfig:         map[string]interface{}{"inCluster": true},
+		namespace:             DexNamespaceFor(authentication),
+	}
+	for _, opt := range opts {
+		opt(cfg)
 	}
+	cfg.clusterDomain = cfg.clusterDomainDetector.ClusterDomain(clusterDomain)
+	return cfg
 }
 
 type dexBaseCfg struct {
@@ -203,16 +483,89 @@ type dexBaseCfg struct {
 	tlsSecret             *corev1.Secret
 	idpSecret             *corev1.Secret
 	dexSecret             *corev1.Secret
+	staticClientSecrets   []*corev1.Secret
 	certSecret            *corev1.Secret
 	managerURI            string
 	connectorType         string
 	clusterDomain         string
+	clusterDomainDetector *dns.ClusterDomainDetector
+	serviceClusterIP      string
+	storageType           DexStorageType
+	storageConfig         map[string]interface{}
+	secretCopies          []corev1.Secret
+	kubectlConfigMaps     []corev1.ConfigMap
+	discoveryConfigMaps   []corev1.ConfigMap
+	serviceCA             []byte
+	idpCABundle           *corev1.Secret
+	prometheusRules       []unstructured.Unstructured
+	namespace             string
+}
+
+// DexNamespaceFor returns the namespace in which Dex is installed for the given Authentication.
+func DexNamespaceFor(authentication *oprv1.Authentication) string {
+	if authentication.Spec.DexDeployment != nil && authentication.Spec.DexDeployment.Namespace != "" {
+		return authentication.Spec.DexDeployment.Namespace
+	}
+	return DexNamespace
+}
+
+// DexCommonName returns the common name of the certificate of the Dex service in the given namespace.
+func DexCommonName(namespace, clusterDomain string) string {
+	return fmt.Sprintf("%s.%s.svc.%s", DexObjectName, namespace, dns.NormalizeClusterDomain(clusterDomain))
 }
 
 func (d *dexBaseCfg) ManagerURI() string {
 	return d.managerURI
 }
 
+func (d *dexBaseCfg) Namespace() string {
+	return d.namespace
+}
+
+func (d *dexBaseCfg) Name() string {
+	return DexObjectName
+}
+
+func (d *dexBaseCfg) ClusterDomain() string {
+	return d.clusterDomain
+}
+
+func (d *dexBaseCfg) ServiceClusterIP() string {
+	return d.serviceClusterIP
+}
+
+func (d *dexBaseCfg) WebScheme() corev1.URIScheme {
+	if dex := d.authentication.Spec.DexDeployment; dex != nil && dex.TLSTermination != nil && *dex.TLSTermination == oprv1.DexTLSTerminationUpstream {
+		return corev1.URISchemeHTTP
+	}
+	return corev1.URISchemeHTTPS
+}
+
+func (d *dexBaseCfg) IssuerPath() string {
+	return "/dex"
+}
+
+func (d *dexBaseCfg) Issuer() string {
+	return d.ManagerURI() + d.IssuerPath()
+}
+
+func (d *dexBaseCfg) ConnectorType() string {
+	return d.connectorType
+}
+
+// serviceURI returns the address of the given path on the Dex service.
+func (d *dexBaseCfg) serviceURI(path string) string {
+	return fmt.Sprintf(serviceURI, strings.ToLower(string(d.WebScheme())), d.Name(), d.namespace, d.clusterDomain, path)
+}
+
+func (d *dexBaseCfg) tlsSecretName() string {
+	return fmt.Sprintf("%s-tls", d.Name())
+}
+
+func (d *dexBaseCfg) certSecretName() string {
+	return fmt.Sprintf("%s-tls-crt", d.Name())
+}
+
 func (d *dexBaseCfg) UsernameClaim() string {
 	claim := defaultUsernameClaim
 	if d.connectorType == connectorTypeOIDC && d.authentication.Spec.OIDC.UsernameClaim != "" {
@@ -232,23 +585,68 @@ func (d *dexBaseCfg) RequestedScopes() []string {
 	return []string{"openid", "email", "profile"}
 }
 
+// connectorScopes returns the scopes that the connector requests from the OIDC provider. For backwards compatibility,
+// these are the scopes that the Manager requests if the connector scopes are not configured.
+func (d *dexConfig) connectorScopes() []string {
+	if d.authentication.Spec.OIDC.ConnectorScopes != nil {
+		return d.authentication.Spec.OIDC.ConnectorScopes
+	}
+	return d.RequestedScopes()
+}
+
 func (d *dexBaseCfg) RequiredSecrets(namespace string) []*corev1.Secret {
 	var secrets []*corev1.Secret
 	if d.tlsSecret != nil {
-		secrets = append(secrets, secret.CopyToNamespace(namespace, d.tlsSecret)...)
+		secrets = append(secrets, d.copySecrets(namespace, d.tlsSecret)...)
 	}
 	if d.certSecret != nil {
-		secrets = append(secrets, secret.CopyToNamespace(namespace, d.certSecret)...)
+		secrets = append(secrets, d.copySecrets(namespace, d.certSecret)...)
 	}
 	if d.dexSecret != nil {
-		secrets = append(secrets, secret.CopyToNamespace(namespace, d.dexSecret)...)
+		secrets = append(secrets, d.copySecrets(namespace, d.dexSecret)...)
 	}
 	if d.idpSecret != nil {
-		secrets = append(secrets, secret.CopyToNamespace(namespace, d.idpSecret)...)
+		copies := d.copySecrets(namespace, d.idpSecret)
+		// A secret that lacks keys is not copied, which MissingPrerequisites reports.
+		if namespace == d.idpSecret.Namespace || secret.KeepKeys(d.idpSecretKeys(), copies...) == nil {
+			secrets = append(secrets, copies...)
+		}
+	}
+	if d.idpCABundle != nil {
+		secrets = append(secrets, d.copySecrets(namespace, d.idpCABundle)...)
 	}
+	secrets = append(secrets, d.copySecrets(namespace, d.staticClientSecrets...)...)
 	return secrets
 }
 
+// copySecrets copies the secrets to the namespace. The copies keep the labels and annotations of the originals when
+// the DexDeployment sets CopySecretMetadata.
+func (d *dexBaseCfg) copySecrets(namespace string, secrets ...*corev1.Secret) []*corev1.Secret {
+	if d.authentication != nil && d.authentication.Spec.DexDeployment != nil && d.authentication.Spec.DexDeployment.CopySecretMetadata {
+		return secret.CopyToNamespaceWithMetadata(namespace, secrets...)
+	}
+	return secret.CopyToNamespace(namespace, secrets...)
+}
+
+// idpSecretKeys returns the keys of the identity provider secret that Dex reads: the required fields and the optional
+// fields that the secret contains. Only these keys are copied into other namespaces.
+func (d *dexBaseCfg) idpSecretKeys() []string {
+	keys := RequiredIdpSecretFields(d.authentication)
+	for _, key := range []string{adminEmailSecretField, BindDNSecretField, BindPWSecretField, ClientIDSecretField, ClientSecretSecretField, RootCASecretField, serviceAccountSecretField} {
+		if _, ok := d.idpSecret.Data[key]; !ok {
+			continue
+		}
+		required := false
+		for _, k := range keys {
+			required = required || k == key
+		}
+		if !required {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
 // RequiredAnnotations returns the annotations that are relevant for a Dex deployment.
 func (d *dexConfig) RequiredAnnotations() map[string]string {
 	var annotations = map[string]string{
@@ -256,15 +654,30 @@ func (d *dexConfig) RequiredAnnotations() map[string]string {
 	}
 
 	if d.tlsSecret != nil {
-		annotations[dexTLSSecretAnnotation] = rmeta.AnnotationHash(d.tlsSecret.Data)
+		// Only the certificate and key that Dex serves are hashed, so that Dex restarts when they are renewed, but not
+		// when the metadata or other data of the secret change.
+		annotations[dexTLSSecretAnnotation] = rmeta.AnnotationHash(map[string][]byte{
+			corev1.TLSCertKey:       d.tlsSecret.Data[corev1.TLSCertKey],
+			corev1.TLSPrivateKeyKey: d.tlsSecret.Data[corev1.TLSPrivateKeyKey],
+		})
 	}
 
 	if d.idpSecret != nil {
 		annotations[dexIdpSecretAnnotation] = rmeta.AnnotationHash(d.idpSecret.Data)
 	}
+	if d.idpCABundle != nil {
+		annotations[dexIdpCABundleAnnotation] = rmeta.AnnotationHash(d.idpCABundle.Data)
+	}
 	if d.dexSecret != nil {
 		annotations[dexSecretAnnotation] = rmeta.AnnotationHash(d.dexSecret.Data)
 	}
+	if len(d.authentication.Spec.StaticClients) != 0 {
+		var secretData []map[string][]byte
+		for _, s := range d.staticClientSecrets {
+			secretData = append(secretData, s.Data)
+		}
+		annotations[dexStaticClientsAnnotation] = rmeta.AnnotationHash([]interface{}{d.StaticClients(), secretData})
+	}
 	return annotations
 }
 
@@ -291,11 +704,16 @@ func (d *dexKeyValidatorConfig) RequiredAnnotations() map[string]string {
 
 // Append variables that are necessary for using the dex authenticator.
 func (d *dexKeyValidatorConfig) RequiredEnv(prefix string) []corev1.EnvVar {
+	return d.validatorEnv(prefix)
+}
+
+// validatorEnv returns the env vars with which a component validates the tokens of Dex.
+func (d *dexBaseCfg) validatorEnv(prefix string) []corev1.EnvVar {
 	return []corev1.EnvVar{
 		{Name: fmt.Sprintf("%sDEX_ENABLED", prefix), Value: strconv.FormatBool(true)},
-		{Name: fmt.Sprintf("%sDEX_ISSUER", prefix), Value: fmt.Sprintf("%s/dex", d.ManagerURI())},
-		{Name: fmt.Sprintf("%sDEX_URL", prefix), Value: fmt.Sprintf("https://tigera-dex.tigera-dex.svc.%s:5556/", d.clusterDomain)},
-		{Name: fmt.Sprintf("%sDEX_JWKS_URL", prefix), Value: fmt.Sprintf(jwksURI, d.clusterDomain)},
+		{Name: fmt.Sprintf("%sDEX_ISSUER", prefix), Value: d.Issuer()},
+		{Name: fmt.Sprintf("%sDEX_URL", prefix), Value: d.serviceURI("/")},
+		{Name: fmt.Sprintf("%sDEX_JWKS_URL", prefix), Value: d.serviceURI(d.IssuerPath() + jwksPath)},
 		{Name: fmt.Sprintf("%sDEX_CLIENT_ID", prefix), Value: DexClientId},
 		{Name: fmt.Sprintf("%sDEX_USERNAME_CLAIM", prefix), Value: d.UsernameClaim()},
 		{Name: fmt.Sprintf("%sDEX_GROUPS_CLAIM", prefix), Value: DefaultGroupsClaim},
@@ -315,7 +733,13 @@ func (d *dexConfig) RequiredEnv(string) []corev1.EnvVar {
 		{Name: dexSecretEnv, ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{Key: ClientSecretSecretField, LocalObjectReference: corev1.LocalObjectReference{Name: d.dexSecret.Name}}}},
 	}
 	if d.idpSecret != nil {
+		// Walk the keys in a fixed order, so that every render produces the same env.
+		var keys []string
 		for key := range d.idpSecret.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
 			switch key {
 			case ClientIDSecretField:
 				env = append(env, corev1.EnvVar{Name: clientIDEnv, ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{Key: ClientIDSecretField, LocalObjectReference: corev1.LocalObjectReference{Name: d.idpSecret.Name}}}})
@@ -335,19 +759,24 @@ func (d *dexConfig) RequiredEnv(string) []corev1.EnvVar {
 			}
 		}
 	}
+	for i, c := range d.authentication.Spec.StaticClients {
+		if !c.Public {
+			env = append(env, corev1.EnvVar{Name: fmt.Sprintf(staticClientSecretEnv, i), ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{Key: ClientSecretSecretField, LocalObjectReference: corev1.LocalObjectReference{Name: c.SecretName}}}})
+		}
+	}
 
 	return env
 }
 
 func (d *dexConfig) RequiredVolumes() []corev1.Volume {
 
-	tlsVolumeSource := certificateVolumeSource(d.certificateManagement, DexTLSSecretName)
+	tlsVolumeSource := certificateVolumeSource(d.certificateManagement, d.tlsSecretName())
 	defaultMode := int32(420)
 	volumes := []corev1.Volume{
 		{
 			Name: "config",
 			VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
-				LocalObjectReference: corev1.LocalObjectReference{Name: DexObjectName}, Items: []corev1.KeyToPath{{Key: "config.yaml", Path: "config.yaml"}}}},
+				LocalObjectReference: corev1.LocalObjectReference{Name: d.Name()}, Items: []corev1.KeyToPath{{Key: "config.yaml", Path: "config.yaml"}}}},
 		},
 		{
 			Name:         "tls",
@@ -364,7 +793,14 @@ func (d *dexConfig) RequiredVolumes() []corev1.Volume {
 		)
 	}
 
-	if d.idpSecret != nil && d.idpSecret.Data[RootCASecretField] != nil {
+	if d.idpCABundle != nil {
+		volumes = append(volumes,
+			corev1.Volume{
+				Name:         idpCABundleVolume,
+				VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{DefaultMode: &defaultMode, SecretName: d.idpCABundle.Name, Items: []corev1.KeyToPath{{Key: secret.CABundleKey, Path: "idp.pem"}}}},
+			},
+		)
+	} else if d.idpSecret != nil && d.idpSecret.Data[RootCASecretField] != nil {
 		volumes = append(volumes,
 			corev1.Volume{
 				Name:         "secrets",
@@ -372,6 +808,18 @@ func (d *dexConfig) RequiredVolumes() []corev1.Volume {
 			},
 		)
 	}
+
+	if store := d.secretsStore(); store != nil {
+		csi := &corev1.CSIVolumeSource{
+			Driver:           secretsStoreDriver,
+			ReadOnly:         ptr.BoolToPtr(true),
+			VolumeAttributes: map[string]string{"secretProviderClass": store.SecretProviderClass},
+		}
+		if store.NodePublishSecretName != "" {
+			csi.NodePublishSecretRef = &corev1.LocalObjectReference{Name: store.NodePublishSecretName}
+		}
+		volumes = append(volumes, corev1.Volume{Name: secretsStoreVolume, VolumeSource: corev1.VolumeSource{CSI: csi}})
+	}
 	return volumes
 }
 
@@ -379,10 +827,10 @@ func (d *dexConfig) RequiredVolumes() []corev1.Volume {
 func (d *dexKeyValidatorConfig) RequiredVolumes() []corev1.Volume {
 	return []corev1.Volume{
 		{
-			Name: DexCertSecretName,
+			Name: d.certSecretName(),
 			VolumeSource: corev1.VolumeSource{
 				Secret: &corev1.SecretVolumeSource{
-					SecretName: DexCertSecretName,
+					SecretName: d.certSecretName(),
 					Items: []corev1.KeyToPath{
 						{Key: corev1.TLSCertKey, Path: "tls-dex.crt"},
 					},
@@ -396,10 +844,10 @@ func (d *dexKeyValidatorConfig) RequiredVolumes() []corev1.Volume {
 func (d *dexRelyingPartyConfig) RequiredVolumes() []corev1.Volume {
 	return []corev1.Volume{
 		{
-			Name: DexCertSecretName,
+			Name: d.certSecretName(),
 			VolumeSource: corev1.VolumeSource{
 				Secret: &corev1.SecretVolumeSource{
-					SecretName: DexCertSecretName,
+					SecretName: d.certSecretName(),
 					Items: []corev1.KeyToPath{
 						{Key: corev1.TLSCertKey, Path: "tls-dex.crt"},
 					},
@@ -411,7 +859,41 @@ func (d *dexRelyingPartyConfig) RequiredVolumes() []corev1.Volume {
 
 // AppendDexVolumeMount adds mount for ubi base image trusted cert location
 func (d *dexKeyValidatorConfig) RequiredVolumeMounts() []corev1.VolumeMount {
-	return []corev1.VolumeMount{{Name: DexCertSecretName, MountPath: "/etc/ssl/certs"}}
+	return []corev1.VolumeMount{{Name: d.certSecretName(), MountPath: "/etc/ssl/certs"}}
+}
+
+func (d *dexBaseCfg) Verifier(prefix string) DexVerifier {
+	// The volume is named after the prefix, and mounted apart from the trusted certs of the image, so that it does not
+	// clash with the volumes of other verifiers in the pod.
+	volumeName := d.certSecretName()
+	if p := strings.Trim(strings.ToLower(strings.ReplaceAll(prefix, "_", "-")), "-"); p != "" {
+		volumeName = fmt.Sprintf("%s-%s", p, volumeName)
+	}
+	mountPath := path.Join(dexVerifierCertDir, volumeName)
+
+	// The hash covers the settings of Dex without the prefix and the path of the CA, so that all verifiers of a Dex
+	// share the annotation.
+	settings := d.validatorEnv("")
+	env := d.validatorEnv(prefix)
+	env = append(env, corev1.EnvVar{Name: prefix + dexCAFileEnv, Value: path.Join(mountPath, dexVerifierCertFile)})
+	return DexVerifier{
+		Env: env,
+		Volume: corev1.Volume{
+			Name: volumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: d.certSecretName(),
+					Items:      []corev1.KeyToPath{{Key: corev1.TLSCertKey, Path: dexVerifierCertFile}},
+				},
+			},
+		},
+		VolumeMount: corev1.VolumeMount{Name: volumeName, MountPath: mountPath, ReadOnly: true},
+		Annotations: map[string]string{
+			dexVerifierAnnotation: rmeta.AnnotationHash(settings),
+			// Hashed like the cert secret in RequiredAnnotations, so that the annotations agree when both are used.
+			dexCertSecretAnnotation: rmeta.AnnotationHash(map[string][]byte{corev1.TLSCertKey: d.trustedCert()}),
+		},
+	}
 }
 
 // AppendDexVolumeMount adds mount for ubi base image trusted cert location
@@ -428,59 +910,597 @@ func (d *dexConfig) RequiredVolumeMounts() []corev1.VolumeMount {
 			ReadOnly:  true,
 		},
 	}
-	if d.idpSecret.Data[serviceAccountSecretField] != nil {
+	if d.idpSecret != nil && d.idpSecret.Data[serviceAccountSecretField] != nil {
 		volumeMounts = append(volumeMounts, corev1.VolumeMount{
 			Name:      "secrets",
 			MountPath: "/etc/dex/secrets",
 			ReadOnly:  true,
 		})
 	}
-	if d.idpSecret.Data[RootCASecretField] != nil {
+	if d.idpCABundle != nil {
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      idpCABundleVolume,
+			MountPath: "/etc/ssl/certs/",
+			ReadOnly:  true,
+		})
+	} else if d.idpSecret != nil && d.idpSecret.Data[RootCASecretField] != nil {
 		volumeMounts = append(volumeMounts, corev1.VolumeMount{
 			Name:      "secrets",
 			MountPath: "/etc/ssl/certs/",
 			ReadOnly:  true,
 		})
 	}
+	if d.secretsStore() != nil {
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      secretsStoreVolume,
+			MountPath: secretsStoreMountPath,
+			ReadOnly:  true,
+		})
+	}
 	return volumeMounts
 }
 
+// secretsStore returns the secrets store that the credentials of the identity provider are mounted from, or nil if
+// they are read from the secret of the identity provider.
+func (d *dexConfig) secretsStore() *oprv1.DexSecretsStore {
+	if d.connectorType == "" {
+		return nil
+	}
+	return d.DexDeployment().IdentityProviderSecretsStore
+}
+
+// SecretsStoreFiles returns the files of the secrets store volume that Dex reads the env of its connector from, keyed
+// by the env var. The root CA is not included, since the connector config references its file directly.
+func (d *dexConfig) SecretsStoreFiles() map[string]string {
+	if d.secretsStore() == nil {
+		return nil
+	}
+	files := map[string]string{}
+	for _, key := range RequiredIdpSecretFields(d.authentication) {
+		if env, ok := idpSecretEnv[key]; ok {
+			files[env] = path.Join(secretsStoreMountPath, key)
+		}
+	}
+	return files
+}
+
+// rootCALocation returns the file of the root CA of the identity provider.
+func (d *dexConfig) rootCALocation() string {
+	if d.secretsStore() != nil && d.idpCABundle == nil {
+		return path.Join(secretsStoreMountPath, RootCASecretField)
+	}
+	return rootCASecretLocation
+}
+
 // AppendDexVolumeMount adds mount for ubi base image trusted cert location
 func (d *dexRelyingPartyConfig) RequiredVolumeMounts() []corev1.VolumeMount {
-	return []corev1.VolumeMount{{Name: DexCertSecretName, MountPath: "/usr/share/elasticsearch/config/dex/"}}
+	return []corev1.VolumeMount{{Name: d.certSecretName(), MountPath: "/usr/share/elasticsearch/config/dex/"}}
 }
 
 func (d *dexRelyingPartyConfig) DexIssuer() string {
-	return fmt.Sprintf("%s/dex", d.ManagerURI())
+	return d.Issuer()
 }
 
 func (d *dexRelyingPartyConfig) AuthURI() string {
-	return fmt.Sprintf("%s/dex/auth", d.ManagerURI())
+	return fmt.Sprintf("%s/auth", d.Issuer())
 }
 
 func (d *dexRelyingPartyConfig) JWKSURI() string {
-	return fmt.Sprintf(jwksURI, d.clusterDomain)
+	return d.serviceURI(d.IssuerPath() + jwksPath)
 }
 
 func (d *dexRelyingPartyConfig) TokenURI() string {
-	return fmt.Sprintf(tokenURI, d.clusterDomain)
+	return d.serviceURI(d.IssuerPath() + tokenPath)
 }
 
 func (d *dexRelyingPartyConfig) UserInfoURI() string {
-	return fmt.Sprintf(userInfoURI, d.clusterDomain)
+	return d.serviceURI(d.IssuerPath() + userInfoPath)
+}
+
+// DexSecretReference is a key of a secret that Dex is configured with.
+type DexSecretReference struct {
+	Name      string
+	Namespace string
+	Key       string
+	// Field is the path of the field of the Authentication that makes Dex read the secret, e.g.
+	// spec.staticClients[0].secretName.
+	Field string
+}
+
+func (r DexSecretReference) String() string {
+	return fmt.Sprintf("%s/%s[%s]", r.Namespace, r.Name, r.Key)
+}
+
+// IdpSecretName returns the name of the secret in the operator namespace with the credentials of the identity provider,
+// or an empty string if no identity provider is configured.
+func IdpSecretName(authentication *oprv1.Authentication) string {
+	if authentication.Spec.OIDC != nil {
+		return OIDCSecretName
+	} else if authentication.Spec.Openshift != nil {
+		return OpenshiftSecretName
+	} else if authentication.Spec.LDAP != nil {
+		return LDAPSecretName
+	}
+	return ""
+}
+
+// SecretReferences returns the keys that the connector reads from the secret of the identity provider and the keys that
+// the static clients that are not public read from their secrets. The secrets of Dex itself and the secrets that are
+// generated for static clients are not included, since the operator creates them when they are missing.
+func (d *dexConfig) SecretReferences() []DexSecretReference {
+	var refs []DexSecretReference
+	if name := IdpSecretName(d.authentication); name != "" && d.secretsStore() == nil {
+		for _, key := range RequiredIdpSecretFields(d.authentication) {
+			refs = append(refs, DexSecretReference{Name: name, Namespace: rmeta.OperatorNamespace(), Key: key, Field: IdpFieldPath(d.authentication)})
+		}
+	}
+	for i, c := range d.authentication.Spec.StaticClients {
+		if !c.Public && c.SecretName != "" && !c.GenerateSecret {
+			refs = append(refs, DexSecretReference{
+				Name:      c.SecretName,
+				Namespace: rmeta.OperatorNamespace(),
+				Key:       ClientSecretSecretField,
+				Field:     fmt.Sprintf("spec.staticClients[%d].secretName", i),
+			})
+		}
+	}
+	return refs
+}
+
+// IdpFieldPath returns the path of the field of the Authentication that configures the identity provider, or an empty
+// string if no identity provider is configured.
+func IdpFieldPath(authentication *oprv1.Authentication) string {
+	if authentication.Spec.OIDC != nil {
+		return "spec.oidc"
+	} else if authentication.Spec.Openshift != nil {
+		return "spec.openshift"
+	} else if authentication.Spec.LDAP != nil {
+		return "spec.ldap"
+	}
+	return ""
+}
+
+// RequiredIdpSecretFields returns the fields that the secret of the configured identity provider must contain.
+func RequiredIdpSecretFields(authentication *oprv1.Authentication) []string {
+	if authentication.Spec.OIDC != nil {
+		return []string{ClientIDSecretField, ClientSecretSecretField}
+	} else if authentication.Spec.Openshift != nil {
+		return []string{ClientIDSecretField, ClientSecretSecretField, RootCASecretField}
+	} else if authentication.Spec.LDAP != nil {
+		return []string{BindDNSecretField, BindPWSecretField, RootCASecretField}
+	}
+	return nil
+}
+
+// Validate checks the Authentication and the secrets that are used to configure Dex and reports all problems at once.
+func (d *dexConfig) Validate() error {
+	problems := d.MissingPrerequisites()
+
+	if err := dns.ValidateClusterDomain(d.clusterDomain); err != nil {
+		problems = append(problems, err.Error())
+	} else if err := d.clusterDomainDetector.Validate(d.clusterDomain); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if d.authentication.Spec.ManagerDomain != "" {
+		if u, err := url.Parse(d.managerURI); err != nil || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("manager domain %q is not a valid URL", d.authentication.Spec.ManagerDomain))
+		}
+	}
+
+	// The rules of the Authentication itself are shared with the controller, which reports them with the same paths.
+	for _, err := range ValidateAuthentication(d.authentication) {
+		problems = append(problems, err.Error())
+	}
+	if d.connectorType == connectorTypeOIDC && d.authentication.Spec.OIDC.OverrideClaimMapping != nil && d.claimMapping() == nil {
+		problems = append(problems, "OIDC override claim mapping is set, but no claim mapping is configured")
+	}
+
+	switch d.storageType {
+	case DexStorageKubernetes, DexStoragePostgres, DexStorageSQLite3, DexStorageMemory:
+	default:
+		problems = append(problems, fmt.Sprintf("the storage type %q of Dex is not supported", d.storageType))
+	}
+	if dd := d.DexDeployment(); dd.DNSPolicy != nil && *dd.DNSPolicy == corev1.DNSNone && dd.DNSConfig == nil {
+		problems = append(problems, "DNS policy None requires a DNS config")
+	}
+	if d.certificateManagement == nil && d.tlsSecret != nil && len(d.tlsSecret.Data[corev1.TLSCertKey]) != 0 && len(d.tlsSecret.Data[corev1.TLSPrivateKeyKey]) != 0 {
+		if err := secret.ValidateTLSPair(d.tlsSecret.Data[corev1.TLSCertKey], d.tlsSecret.Data[corev1.TLSPrivateKeyKey]); err != nil {
+			problems = append(problems, fmt.Sprintf("the TLS secret %s is invalid: %v", d.tlsSecret.Name, err))
+		}
+	}
+	if sleep, grace := preStopSleepSeconds(d.DexDeployment()), terminationGracePeriodSeconds(d.DexDeployment()); int64(sleep) >= grace {
+		problems = append(problems, fmt.Sprintf("the preStop sleep of %ds must be less than the termination grace period of %ds", sleep, grace))
+	}
+	if dd := d.DexDeployment(); dd.SessionAffinityTimeoutSeconds != nil && (dd.SessionAffinity == nil || *dd.SessionAffinity != corev1.ServiceAffinityClientIP) {
+		problems = append(problems, "the session affinity timeout can only be set for the ClientIP session affinity")
+	}
+	for _, gate := range d.DexDeployment().ReadinessGates {
+		if errs := validation.IsQualifiedName(string(gate.ConditionType)); len(errs) != 0 {
+			problems = append(problems, fmt.Sprintf("the condition type %q of a readiness gate is invalid: %s", gate.ConditionType, strings.Join(errs, ", ")))
+		}
+	}
+	if host := d.DexDeployment().ExternalHost; host != "" {
+		if errs := validation.IsDNS1123Subdomain(host); len(errs) != 0 {
+			problems = append(problems, fmt.Sprintf("the external host %q of Dex is not a valid host name: %s", host, strings.Join(errs, ", ")))
+		}
+	}
+	if d.DexDeployment().ExternalHost != "" && d.DexDeployment().IdentityProviderSecretsStore != nil {
+		problems = append(problems, "the credentials of the identity provider cannot be mounted from a secrets store when Dex runs outside of this cluster")
+	}
+	if runAs := d.DexDeployment().RunAs; runAs != nil && runAs.PlatformAssigned && (runAs.RunAsUser != nil || runAs.RunAsGroup != nil || runAs.FSGroup != nil) {
+		problems = append(problems, "the user and groups of Dex cannot be set when the platform assigns them")
+	}
+	if prefix := d.DexDeployment().WellKnownPathPrefix; prefix != "" {
+		if u, err := url.Parse(prefix); err != nil || !strings.HasPrefix(prefix, "/") || u.Path != prefix {
+			problems = append(problems, fmt.Sprintf("the well-known path prefix %q must be a path that starts with a slash", prefix))
+		}
+	}
+	for _, ip := range d.DexDeployment().CertificateIPAddresses {
+		if net.ParseIP(ip) == nil {
+			problems = append(problems, fmt.Sprintf("certificate IP address %q is not a valid IP address", ip))
+		}
+	}
+	var headers []string
+	for name := range d.DexDeployment().ResponseHeaders {
+		headers = append(headers, name)
+	}
+	sort.Strings(headers)
+	for _, name := range headers {
+		if !containsString(dexWebHeaders, name) {
+			problems = append(problems, fmt.Sprintf("response header %q is not supported by Dex, expected one of %s", name, strings.Join(dexWebHeaders, ", ")))
+		}
+	}
+	var overrides []string
+	for name := range d.DexDeployment().ImageOverrides {
+		overrides = append(overrides, name)
+	}
+	sort.Strings(overrides)
+	for _, name := range overrides {
+		if name != path.Base(components.ComponentDex.Image) && name != path.Base(components.ComponentCSRInitContainer.Image) {
+			problems = append(problems, fmt.Sprintf("image override %q does not name an image of Dex, expected %s or %s",
+				name, path.Base(components.ComponentDex.Image), path.Base(components.ComponentCSRInitContainer.Image)))
+		}
+	}
+	if _, ok := d.DexDeployment().PodLabels["k8s-app"]; ok {
+		problems = append(problems, "pod labels may not replace the k8s-app label of the deployment selector")
+	}
+	for _, k := range []string{rmeta.AppNameLabel, rmeta.AppInstanceLabel, rmeta.AppComponentLabel, rmeta.AppPartOfLabel, rmeta.AppManagedByLabel} {
+		if _, ok := d.DexDeployment().ServiceAccountLabels[k]; ok {
+			problems = append(problems, fmt.Sprintf("service account labels may not replace the %s label that the operator sets", k))
+		}
+	}
+	if _, ok := d.DexDeployment().ServiceAccountAnnotations[rmeta.ManagedAnnotationsAnnotation]; ok {
+		problems = append(problems, fmt.Sprintf("service account annotations may not set the %s annotation", rmeta.ManagedAnnotationsAnnotation))
+	}
+	if d.namespace == rmeta.OperatorNamespace() {
+		// The secrets that Dex reads are copied from the operator namespace and would overwrite the originals.
+		problems = append(problems, fmt.Sprintf("Dex may not be installed in the operator namespace %s", d.namespace))
+	}
+
+	// Dex refuses to start when two static clients have the same ID, and the Manager client is one of them.
+	clientIDs := map[string]bool{DexClientId: true}
+	duplicates := map[string]bool{}
+	for _, c := range d.authentication.Spec.StaticClients {
+		if clientIDs[c.ID] && !duplicates[c.ID] {
+			duplicates[c.ID] = true
+			if c.ID == DexClientId {
+				problems = append(problems, fmt.Sprintf("static client ID %s is reserved for the Manager", c.ID))
+			} else {
+				problems = append(problems, fmt.Sprintf("static client ID %s is not unique", c.ID))
+			}
+		}
+		clientIDs[c.ID] = true
+	}
+
+	if len(problems) != 0 {
+		return &ValidationError{Component: d.Name(), Problems: problems}
+	}
+	return nil
+}
+
+func (d *dexConfig) MissingPrerequisites() []string {
+	var missing []string
+	if d.authentication.Spec.ManagerDomain == "" {
+		missing = append(missing, "manager domain is not set")
+	}
+	if d.certificateManagement == nil {
+		missing = append(missing, missingSecretFields(d.tlsSecret, d.tlsSecretName(), corev1.TLSCertKey, corev1.TLSPrivateKeyKey)...)
+	}
+	missing = append(missing, missingSecretFields(d.dexSecret, d.Name(), ClientSecretSecretField)...)
+	if d.connectorType != "" && d.secretsStore() == nil {
+		missing = append(missing, missingSecretFields(d.idpSecret, "identity provider", RequiredIdpSecretFields(d.authentication)...)...)
+	}
+	for _, c := range d.authentication.Spec.StaticClients {
+		if !c.Public && c.SecretName != "" {
+			missing = append(missing, missingSecretFields(d.staticClientSecret(c.SecretName), c.SecretName, ClientSecretSecretField)...)
+		}
+	}
+	return missing
+}
+
+// ValidateIssuerURL returns an error if the issuer is not an absolute https URL, which OpenID Connect requires.
+func ValidateIssuerURL(issuer string) error {
+	u, err := url.Parse(issuer)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("%q is not an https URL", issuer)
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// missingSecretFields returns a problem for every field that the secret should contain but does not, or a single
+// problem if the secret itself is missing.
+func missingSecretFields(s *corev1.Secret, name string, fields ...string) []string {
+	if s == nil {
+		return []string{fmt.Sprintf("%s secret is missing", name)}
+	}
+	var problems []string
+	for _, field := range fields {
+		if len(s.Data[field]) == 0 {
+			problems = append(problems, fmt.Sprintf("secret %s/%s is missing field %s", s.Namespace, s.Name, field))
+		}
+	}
+	return problems
+}
+
+// ManagerGrantTypes returns the grant types of the Manager client, translated to the values that Dex expects. If none
+// are configured, the client may use the authorization code and refresh token grants. Unknown grant types are skipped.
+func (d *dexConfig) ManagerGrantTypes() []string {
+	if d.authentication.Spec.ManagerClient == nil || len(d.authentication.Spec.ManagerClient.GrantTypes) == 0 {
+		return []string{oprv1.GrantTypeAuthorizationCode.Value(), oprv1.GrantTypeRefreshToken.Value()}
+	}
+	var grantTypes []string
+	for _, v := range d.authentication.Spec.ManagerClient.GrantTypes {
+		if grantType := v.Value(); grantType != "" {
+			grantTypes = append(grantTypes, grantType)
+		}
+	}
+	return grantTypes
+}
+
+// ManagerClientName returns the name of the Manager client that Dex shows to users on its login and approval pages, or
+// the default name when none is configured.
+func (d *dexConfig) ManagerClientName() string {
+	if d.authentication.Spec.ManagerClient == nil || d.authentication.Spec.ManagerClient.Name == "" {
+		return defaultManagerClientName
+	}
+	return d.authentication.Spec.ManagerClient.Name
+}
+
+// StaticClients returns the additional static clients in the format that Dex expects. Public clients are rendered
+// without a secret, all other clients read their secret from the environment of the Dex container.
+func (d *dexConfig) StaticClients() []map[string]interface{} {
+	var clients []map[string]interface{}
+	for i, c := range d.authentication.Spec.StaticClients {
+		client := map[string]interface{}{
+			"id": c.ID,
+		}
+		if c.Name != "" {
+			client["name"] = c.Name
+		}
+		if len(c.RedirectURIs) != 0 {
+			client["redirectURIs"] = c.RedirectURIs
+		}
+		if c.Public {
+			client["public"] = true
+		} else {
+			client["secretEnv"] = fmt.Sprintf(staticClientSecretEnv, i)
+		}
+		if len(c.TrustedPeers) != 0 {
+			client["trustedPeers"] = c.TrustedPeers
+		}
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+func (d *dexConfig) DexDeployment() *oprv1.DexDeployment {
+	if d.authentication.Spec.DexDeployment == nil {
+		return &oprv1.DexDeployment{}
+	}
+	return d.authentication.Spec.DexDeployment
+}
+
+func (d *dexConfig) SecretCopies() []corev1.Secret {
+	return d.secretCopies
+}
+
+func (d *dexConfig) KubectlConfigMaps() []corev1.ConfigMap {
+	return d.kubectlConfigMaps
+}
+
+// KubectlConfigMap returns the ConfigMap with a kubeconfig user that logs in to the issuer of Dex with the oidc-login
+// plugin of kubectl, trusting the certificate of Dex. The scopes default to the scopes that the Manager requests, of
+// which openid is left out, since the plugin always requests it.
+func (d *dexConfig) KubectlConfigMap() *corev1.ConfigMap {
+	kc := d.authentication.Spec.KubectlConfig
+	if kc == nil {
+		return nil
+	}
+	namespace := kc.Namespace
+	if namespace == "" {
+		namespace = d.namespace
+	}
+	scopes := kc.Scopes
+	if len(scopes) == 0 {
+		scopes = d.RequestedScopes()
+	}
+	ca := d.trustedCert()
+
+	args := []string{"oidc-login", "get-token", "--oidc-issuer-url=" + d.Issuer(), "--oidc-client-id=" + kc.ClientID}
+	for _, scope := range scopes {
+		if scope != "openid" {
+			args = append(args, "--oidc-extra-scope="+scope)
+		}
+	}
+	if len(ca) != 0 {
+		args = append(args, "--certificate-authority-data="+base64.StdEncoding.EncodeToString(ca))
+	}
+	user, err := yaml.Marshal(map[string]interface{}{
+		"users": []map[string]interface{}{{
+			"name": "oidc",
+			"user": map[string]interface{}{
+				"exec": map[string]interface{}{
+					"apiVersion": "client.authentication.k8s.io/v1beta1",
+					"command":    "kubectl",
+					"args":       args,
+				},
+			},
+		}},
+	})
+	if err != nil {
+		// Maps of strings always marshal.
+		panic(err)
+	}
+
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-kubectl", d.Name()),
+			Namespace: namespace,
+			Labels:    map[string]string{DexKubectlConfigLabel: d.Name()},
+		},
+		Data: map[string]string{
+			"issuer":    d.Issuer(),
+			"client-id": kc.ClientID,
+			"ca.crt":    string(ca),
+			"user.yaml": string(user),
+		},
+	}
+}
+
+func (d *dexConfig) PrometheusRules() []unstructured.Unstructured {
+	return d.prometheusRules
+}
+
+// CertificateExpiry returns when the certificate of Dex that the operator created or was given expires. The
+// certificates that Dex requests through certificate management or that OpenShift issues are not known.
+func (d *dexConfig) CertificateExpiry() (time.Time, bool) {
+	if d.certificateManagement != nil || len(d.serviceCA) != 0 || d.tlsSecret == nil {
+		return time.Time{}, false
+	}
+	block, _ := pem.Decode(d.tlsSecret.Data[corev1.TLSCertKey])
+	if block == nil {
+		return time.Time{}, false
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return cert.NotAfter, true
+}
+
+func (d *dexConfig) DiscoveryConfigMapCopies() []corev1.ConfigMap {
+	return d.discoveryConfigMaps
+}
+
+// DiscoveryConfigMaps returns a ConfigMap with the issuer, the URLs, the client ID and the claims of Dex, and the
+// certificate that it is trusted with, in each of the discovery namespaces. The details are the values of the env vars
+// with which the components of Calico validate the tokens of Dex, so that workloads elsewhere validate them the same
+// way.
+func (d *dexConfig) DiscoveryConfigMaps() []*corev1.ConfigMap {
+	if len(d.authentication.Spec.DiscoveryNamespaces) == 0 {
+		return nil
+	}
+	discovery := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-oidc-info", d.Name())},
+		Data:       map[string]string{"ca.crt": string(d.trustedCert())},
+	}
+	for _, env := range d.validatorEnv("") {
+		if key, ok := discoveryKeys[env.Name]; ok {
+			discovery.Data[key] = env.Value
+		}
+	}
+
+	var configMaps []*corev1.ConfigMap
+	seen := map[string]bool{}
+	for _, namespace := range d.authentication.Spec.DiscoveryNamespaces {
+		if seen[namespace] {
+			continue
+		}
+		seen[namespace] = true
+		cm := configmap.CopyToNamespace(namespace, discovery)[0]
+		cm.Labels = map[string]string{DexDiscoveryLabel: d.Name()}
+		configMaps = append(configMaps, cm)
+	}
+	return configMaps
+}
+
+func (d *dexConfig) Storage() map[string]interface{} {
+	storage := map[string]interface{}{"type": d.storageType}
+	if d.storageConfig != nil {
+		storage["config"] = d.storageConfig
+	}
+	return storage
+}
+
+func (d *dexConfig) StorageType() DexStorageType {
+	return d.storageType
+}
+
+func (d *dexConfig) PreviousNamespace() string {
+	previous := d.authentication.Status.DexNamespace
+	if previous == "" {
+		// Installs that predate the status field were always in the default namespace.
+		previous = DexNamespace
+	}
+	if previous == d.namespace {
+		return ""
+	}
+	return previous
+}
+
+func (d *dexConfig) staticClientSecret(name string) *corev1.Secret {
+	for _, s := range d.staticClientSecrets {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
 }
 
 // CreateCertSecret creates the secret containing the certificate that others should mount in order to trust dex.
 func (d *dexConfig) CreateCertSecret() *corev1.Secret {
-	var certBytes []byte
+	return CreateCertificateSecret(d.trustedCert(), d.certSecretName(), rmeta.OperatorNamespace())
+}
 
-	if d.certificateManagement != nil {
-		certBytes = d.certificateManagement.CACert
-	} else {
-		certBytes = d.tlsSecret.Data[corev1.TLSCertKey]
+// trustedCert returns the certificate that the clients of Dex trust it with.
+func (d *dexBaseCfg) trustedCert() []byte {
+	switch {
+	case d.certSecret != nil:
+		return d.certSecret.Data[corev1.TLSCertKey]
+	case len(d.serviceCA) != 0:
+		return d.serviceCA
+	case d.certificateManagement != nil:
+		return d.certificateManagement.CACert
+	case d.tlsSecret != nil:
+		return d.tlsSecret.Data[corev1.TLSCertKey]
 	}
-	return CreateCertificateSecret(certBytes, DexCertSecretName, rmeta.OperatorNamespace())
+	return nil
+}
 
+// claimMapping returns the claims that the OIDC connector maps onto the standard claims, or nil if there are none.
+func (d *dexConfig) claimMapping() map[string]string {
+	groupsClaim := d.authentication.Spec.OIDC.GroupsClaim
+	if groupsClaim == "" || groupsClaim == DefaultGroupsClaim {
+		return nil
+	}
+	return map[string]string{
+		"groups": groupsClaim,
+	}
 }
 
 // This func prepares the configuration and objects that will be rendered related to the connector and its secrets.
@@ -494,8 +1514,8 @@ func (d *dexConfig) Connector() map[string]interface{} {
 			"issuer":       d.authentication.Spec.OIDC.IssuerURL,
 			"clientID":     fmt.Sprintf("$%s", clientIDEnv),
 			"clientSecret": fmt.Sprintf("$%s", clientSecretEnv),
-			"redirectURI":  fmt.Sprintf("%s/dex/callback", d.ManagerURI()),
-			"scopes":       d.RequestedScopes(),
+			"redirectURI":  fmt.Sprintf("%s/callback", d.Issuer()),
+			"scopes":       d.connectorScopes(),
 			"userNameKey":  d.UsernameClaim(),
 			"userIDKey":    d.UsernameClaim(),
 			"insecureSkipEmailVerified": d.authentication.Spec.OIDC.EmailVerification != nil &&
@@ -507,27 +1527,29 @@ func (d *dexConfig) Connector() map[string]interface{} {
 		}
 		promptTypes := d.authentication.Spec.OIDC.PromptTypes
 		if promptTypes != nil {
-			length := len(promptTypes)
-			prompts := make([]string, length)
-			for i, v := range promptTypes {
-				switch v {
-				case oprv1.PromptTypeNone:
-					prompts[i] = "none"
-				case oprv1.PromptTypeSelectAccount:
-					prompts[i] = "select_account"
-				case oprv1.PromptTypeLogin:
-					prompts[i] = "login"
-				case oprv1.PromptTypeConsent:
-					prompts[i] = "consent"
+			var prompts []string
+			for _, v := range promptTypes {
+				if prompt := v.Value(); prompt != "" {
+					prompts = append(prompts, prompt)
 				}
 			}
 			// RFC specifies space delimited case sensitive list: https://openid.net/specs/openid-connect-core-1_0.html#AuthRequest
 			config["promptType"] = strings.Join(prompts, " ")
 		}
-		groupsClaim := d.authentication.Spec.OIDC.GroupsClaim
-		if groupsClaim != "" && groupsClaim != DefaultGroupsClaim {
-			config["claimMapping"] = map[string]string{
-				"groups": groupsClaim,
+		if d.authentication.Spec.AllowIssuerAliasing && d.authentication.Spec.OIDC.IssuerAlias != "" {
+			// Dex accepts the alias as the issuer of the discovery document and the ID tokens of the provider.
+			config["insecureIssuer"] = d.authentication.Spec.OIDC.IssuerAlias
+		}
+		if d.authentication.Spec.OIDC.GetUserInfo != nil {
+			config["getUserInfo"] = *d.authentication.Spec.OIDC.GetUserInfo
+		}
+		if d.authentication.Spec.OIDC.BasicAuthUnsupported != nil {
+			config["basicAuthUnsupported"] = *d.authentication.Spec.OIDC.BasicAuthUnsupported
+		}
+		if claimMapping := d.claimMapping(); claimMapping != nil {
+			config["claimMapping"] = claimMapping
+			if d.authentication.Spec.OIDC.OverrideClaimMapping != nil {
+				config["overrideClaimMapping"] = *d.authentication.Spec.OIDC.OverrideClaimMapping
 			}
 		}
 
@@ -536,10 +1558,10 @@ func (d *dexConfig) Connector() map[string]interface{} {
 			"issuer":       googleIssuer,
 			"clientID":     fmt.Sprintf("$%s", clientIDEnv),
 			"clientSecret": fmt.Sprintf("$%s", clientSecretEnv),
-			"redirectURI":  fmt.Sprintf("%s/dex/callback", d.ManagerURI()),
-			"scopes":       d.RequestedScopes(),
+			"redirectURI":  fmt.Sprintf("%s/callback", d.Issuer()),
+			"scopes":       d.connectorScopes(),
 		}
-		if d.idpSecret.Data[serviceAccountSecretField] != nil && d.idpSecret.Data[adminEmailSecretField] != nil {
+		if d.idpSecret != nil && d.idpSecret.Data[serviceAccountSecretField] != nil && d.idpSecret.Data[adminEmailSecretField] != nil {
 			config[serviceAccountFilePathField] = serviceAccountSecretLocation
 			config[adminEmailSecretField] = fmt.Sprintf("$%s", googleAdminEmailEnv)
 		}
@@ -549,8 +1571,8 @@ func (d *dexConfig) Connector() map[string]interface{} {
 			"issuer":          d.authentication.Spec.Openshift.IssuerURL,
 			"clientID":        fmt.Sprintf("$%s", clientIDEnv),
 			"clientSecret":    fmt.Sprintf("$%s", clientSecretEnv),
-			"redirectURI":     fmt.Sprintf("%s/dex/callback", d.ManagerURI()),
-			RootCASecretField: rootCASecretLocation,
+			"redirectURI":     fmt.Sprintf("%s/callback", d.Issuer()),
+			RootCASecretField: d.rootCALocation(),
 		}
 	case connectorTypeLDAP:
 		config = map[string]interface{}{
@@ -558,7 +1580,7 @@ func (d *dexConfig) Connector() map[string]interface{} {
 			"bindDN":          fmt.Sprintf("$%s", bindDNEnv),
 			"bindPW":          fmt.Sprintf("$%s", bindPWEnv),
 			"startTLS":        d.authentication.Spec.LDAP.StartTLS != nil && *d.authentication.Spec.LDAP.StartTLS,
-			RootCASecretField: rootCASecretLocation,
+			RootCASecretField: d.rootCALocation(),
 			"userSearch": map[string]string{
 				"baseDN":    d.authentication.Spec.LDAP.UserSearch.BaseDN,
 				"filter":    d.authentication.Spec.LDAP.UserSearch.Filter,
@@ -15,6 +15,10 @@
 package render_test
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 
 	. "github.com/onsi/ginkgo"
@@ -23,8 +27,10 @@ import (
 
 	operatorv1 "github.com/tigera/operator/api/v1"
 	"github.com/tigera/operator/pkg/dns"
+	"github.com/tigera/operator/pkg/ptr"
 	"github.com/tigera/operator/pkg/render"
 	rmeta "github.com/tigera/operator/pkg/render/common/meta"
+	rsecret "github.com/tigera/operator/pkg/render/common/secret"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -71,11 +77,11 @@ var _ = Describe("dex config tests", func() {
 		},
 	}
 	dexSecret := render.CreateDexClientSecret()
-	tlsSecret := render.CreateDexTLSSecret("tigera-dex.tigera-dex.svc.cluster.local")
+	tlsSecret := render.CreateDexTLSSecret("tigera-dex.tigera-dex.svc.cluster.local", nil)
 
 	Context("OIDC connector config options", func() {
 		It("should configure insecureSkipEmailVerified ", func() {
-			connector := render.NewDexConfig(nil, authentication, tlsSecret, dexSecret, idpSecret, dns.DefaultClusterDomain).Connector()
+			connector := render.NewDexConfig(nil, authentication, tlsSecret, dexSecret, idpSecret, nil, dns.DefaultClusterDomain).Connector()
 			cfg := connector["config"].(map[string]interface{})
 			Expect(cfg["insecureSkipEmailVerified"]).To(Equal(true))
 		})
@@ -83,9 +89,9 @@ var _ = Describe("dex config tests", func() {
 
 	Context("Hashes should be consistent and not be affected by fields with pointers", func() {
 		It("should produce consistent hashes for dex config", func() {
-			hashes1 := render.NewDexConfig(nil, authentication, tlsSecret, dexSecret, idpSecret, dns.DefaultClusterDomain).RequiredAnnotations()
-			hashes2 := render.NewDexConfig(nil, authentication.DeepCopy(), tlsSecret, dexSecret, idpSecret, dns.DefaultClusterDomain).RequiredAnnotations()
-			hashes3 := render.NewDexConfig(nil, authenticationDiff, tlsSecret, dexSecret, idpSecret, dns.DefaultClusterDomain).RequiredAnnotations()
+			hashes1 := render.NewDexConfig(nil, authentication, tlsSecret, dexSecret, idpSecret, nil, dns.DefaultClusterDomain).RequiredAnnotations()
+			hashes2 := render.NewDexConfig(nil, authentication.DeepCopy(), tlsSecret, dexSecret, idpSecret, nil, dns.DefaultClusterDomain).RequiredAnnotations()
+			hashes3 := render.NewDexConfig(nil, authenticationDiff, tlsSecret, dexSecret, idpSecret, nil, dns.DefaultClusterDomain).RequiredAnnotations()
 			Expect(hashes1).To(HaveLen(4))
 			Expect(hashes2).To(HaveLen(4))
 			Expect(hashes3).To(HaveLen(4))
@@ -93,6 +99,26 @@ var _ = Describe("dex config tests", func() {
 			Expect(reflect.DeepEqual(hashes1, hashes3)).To(BeFalse())
 		})
 
+		It("should only change the hash of the TLS secret when the serving certificate changes", func() {
+			hash := func(s *corev1.Secret) string {
+				annotations := render.NewDexConfig(nil, authentication, s, dexSecret, idpSecret, nil, dns.DefaultClusterDomain).RequiredAnnotations()
+				Expect(annotations).To(HaveKey("hash.operator.tigera.io/tigera-dex-tls-secret"))
+				return annotations["hash.operator.tigera.io/tigera-dex-tls-secret"]
+			}
+			original := hash(tlsSecret)
+
+			edited := tlsSecret.DeepCopy()
+			edited.Labels = map[string]string{"team": "identity"}
+			edited.Annotations = map[string]string{"note": "renew in june"}
+			edited.ResourceVersion = "42"
+			edited.Data["notes"] = []byte("not served by dex")
+			Expect(hash(edited)).To(Equal(original))
+
+			renewed := render.CreateDexTLSSecret("tigera-dex.tigera-dex.svc.cluster.local", nil)
+			Expect(renewed.Data[corev1.TLSCertKey]).NotTo(Equal(tlsSecret.Data[corev1.TLSCertKey]))
+			Expect(hash(renewed)).NotTo(Equal(original))
+		})
+
 		It("should produce consistent hashes for rp's", func() {
 			hashes1 := render.NewDexRelyingPartyConfig(authentication, tlsSecret, idpSecret, dns.DefaultClusterDomain).RequiredAnnotations()
 			hashes2 := render.NewDexRelyingPartyConfig(authentication.DeepCopy(), tlsSecret, idpSecret, dns.DefaultClusterDomain).RequiredAnnotations()
@@ -116,6 +142,128 @@ var _ = Describe("dex config tests", func() {
 		})
 	})
 
+	Context("token verifiers", func() {
+		envValue := func(env []corev1.EnvVar, name string) string {
+			for _, e := range env {
+				if e.Name == name {
+					return e.Value
+				}
+			}
+			Fail(fmt.Sprintf("env var %s is missing", name))
+			return ""
+		}
+
+		It("should let several verifiers coexist in one pod", func() {
+			certSecret := render.CreateCertificateSecret(tlsSecret.Data[corev1.TLSCertKey], render.DexCertSecretName, rmeta.OperatorNamespace())
+			cfg := render.NewDexKeyValidatorConfig(authentication, certSecret, dns.DefaultClusterDomain)
+			a, b := cfg.Verifier("A_"), cfg.Verifier("B_")
+
+			Expect(envValue(a.Env, "A_DEX_ISSUER")).To(Equal("https://example.com/dex"))
+			Expect(envValue(a.Env, "A_DEX_CLIENT_ID")).To(Equal(render.DexClientId))
+			Expect(envValue(a.Env, "A_DEX_USERNAME_CLAIM")).To(Equal("email"))
+			Expect(envValue(a.Env, "A_DEX_GROUPS_CLAIM")).To(Equal(render.DefaultGroupsClaim))
+			Expect(envValue(a.Env, "A_DEX_CA_FILE")).To(Equal("/etc/dex-verifier/a-tigera-dex-tls-crt/tls-dex.crt"))
+			Expect(envValue(b.Env, "B_DEX_CA_FILE")).To(Equal("/etc/dex-verifier/b-tigera-dex-tls-crt/tls-dex.crt"))
+			for _, e := range a.Env {
+				Expect(e.Name).To(HavePrefix("A_"))
+			}
+
+			Expect(a.Volume.Name).To(Equal("a-tigera-dex-tls-crt"))
+			Expect(a.Volume.Secret.SecretName).To(Equal(render.DexCertSecretName))
+			Expect(a.VolumeMount).To(Equal(corev1.VolumeMount{Name: a.Volume.Name, MountPath: "/etc/dex-verifier/a-tigera-dex-tls-crt", ReadOnly: true}))
+			Expect(b.Volume.Name).NotTo(Equal(a.Volume.Name))
+			Expect(b.VolumeMount.MountPath).NotTo(Equal(a.VolumeMount.MountPath))
+
+			// The verifiers of the same Dex share the annotations, so that they can be merged into the pod.
+			Expect(a.Annotations).To(Equal(b.Annotations))
+			Expect(a.Annotations).To(HaveLen(2))
+			Expect(a.Annotations).To(HaveKeyWithValue("hash.operator.tigera.io/tigera-dex-cert-secret",
+				cfg.RequiredAnnotations()["hash.operator.tigera.io/tigera-dex-cert-secret"]))
+		})
+
+		It("should derive the same verifier from the config of Dex and the configs of its clients", func() {
+			certSecret := render.NewDexConfig(nil, authentication, tlsSecret, dexSecret, idpSecret, nil, dns.DefaultClusterDomain).CreateCertSecret()
+			verifier := render.NewDexConfig(nil, authentication, tlsSecret, dexSecret, idpSecret, nil, dns.DefaultClusterDomain).Verifier("")
+			Expect(render.NewDexKeyValidatorConfig(authentication, certSecret, dns.DefaultClusterDomain).Verifier("")).To(Equal(verifier))
+			Expect(render.NewDexRelyingPartyConfig(authentication, certSecret, dexSecret, dns.DefaultClusterDomain).Verifier("")).To(Equal(verifier))
+		})
+
+		It("should roll out the verifiers when the settings of Dex change", func() {
+			certSecret := render.CreateCertificateSecret(tlsSecret.Data[corev1.TLSCertKey], render.DexCertSecretName, rmeta.OperatorNamespace())
+			verifier := render.NewDexKeyValidatorConfig(authentication, certSecret, dns.DefaultClusterDomain).Verifier("A_")
+
+			changedClaim := authentication.DeepCopy()
+			changedClaim.Spec.OIDC.UsernameClaim = "sub"
+			changed := render.NewDexKeyValidatorConfig(changedClaim, certSecret, dns.DefaultClusterDomain).Verifier("A_")
+			Expect(envValue(changed.Env, "A_DEX_USERNAME_CLAIM")).To(Equal("sub"))
+			Expect(changed.Annotations).NotTo(Equal(verifier.Annotations))
+
+			changed = render.NewDexKeyValidatorConfig(authenticationDiff, certSecret, dns.DefaultClusterDomain).Verifier("A_")
+			Expect(envValue(changed.Env, "A_DEX_ISSUER")).To(Equal("https://example.org/dex"))
+			Expect(changed.Annotations).NotTo(Equal(verifier.Annotations))
+
+			newCert := render.CreateCertificateSecret([]byte("another cert"), render.DexCertSecretName, rmeta.OperatorNamespace())
+			changed = render.NewDexKeyValidatorConfig(authentication, newCert, dns.DefaultClusterDomain).Verifier("A_")
+			Expect(changed.Env).To(Equal(verifier.Env))
+			Expect(changed.Annotations).NotTo(Equal(verifier.Annotations))
+		})
+	})
+
+	Context("client secret rotation", func() {
+		It("should generate random client secrets", func() {
+			secret1, secret2 := render.CreateDexClientSecret(), render.CreateDexClientSecret()
+			Expect(secret1.Data[render.ClientSecretSecretField]).To(MatchRegexp("^[A-Za-z0-9]{24}$"))
+			Expect(secret1.Data[render.ClientSecretSecretField]).NotTo(Equal(secret2.Data[render.ClientSecretSecretField]))
+		})
+
+		It("should only rotate the client secret when a new rotation is requested", func() {
+			Expect(render.RotateDexClientSecret(authentication, dexSecret)).To(BeIdenticalTo(dexSecret))
+
+			rotating := authentication.DeepCopy()
+			rotating.Annotations = map[string]string{render.DexClientSecretRotationAnnotation: "2021-06-01T00:00:00Z"}
+			rotated := render.RotateDexClientSecret(rotating, dexSecret)
+			Expect(rotated.Name).To(Equal(dexSecret.Name))
+			Expect(rotated.Namespace).To(Equal(dexSecret.Namespace))
+			Expect(rotated.Data[render.ClientSecretSecretField]).NotTo(Equal(dexSecret.Data[render.ClientSecretSecretField]))
+			Expect(render.RotateDexClientSecret(rotating, rotated)).To(BeIdenticalTo(rotated))
+		})
+
+		It("should roll out the rotated client secret to dex and its clients together", func() {
+			rotating := authentication.DeepCopy()
+			rotating.Annotations = map[string]string{render.DexClientSecretRotationAnnotation: "1"}
+			rotated := render.RotateDexClientSecret(rotating, dexSecret)
+
+			dexHashes := render.NewDexConfig(nil, authentication, tlsSecret, dexSecret, idpSecret, nil, dns.DefaultClusterDomain).RequiredAnnotations()
+			rotatedDexHashes := render.NewDexConfig(nil, rotating, tlsSecret, rotated, idpSecret, nil, dns.DefaultClusterDomain).RequiredAnnotations()
+			rpHashes := render.NewDexRelyingPartyConfig(authentication, tlsSecret, dexSecret, dns.DefaultClusterDomain).RequiredAnnotations()
+			rotatedRPHashes := render.NewDexRelyingPartyConfig(rotating, tlsSecret, rotated, dns.DefaultClusterDomain).RequiredAnnotations()
+			for _, hashes := range []map[string]string{dexHashes, rotatedDexHashes, rpHashes, rotatedRPHashes} {
+				Expect(hashes).To(HaveKey("hash.operator.tigera.io/tigera-dex-secret"))
+			}
+			Expect(rotatedDexHashes["hash.operator.tigera.io/tigera-dex-secret"]).NotTo(Equal(dexHashes["hash.operator.tigera.io/tigera-dex-secret"]))
+			Expect(rotatedRPHashes["hash.operator.tigera.io/tigera-dex-secret"]).NotTo(Equal(rpHashes["hash.operator.tigera.io/tigera-dex-secret"]))
+			Expect(rotatedDexHashes["hash.operator.tigera.io/tigera-dex-secret"]).To(Equal(rotatedRPHashes["hash.operator.tigera.io/tigera-dex-secret"]))
+
+			// Dex reads the client secret from its environment, so that it never appears in the ConfigMap.
+			dexCfg := render.NewDexConfig(nil, rotating, tlsSecret, rotated, idpSecret, nil, dns.DefaultClusterDomain)
+			Expect(dexCfg.RequiredEnv("")).To(ContainElement(corev1.EnvVar{Name: "DEX_SECRET", ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{Key: render.ClientSecretSecretField, LocalObjectReference: corev1.LocalObjectReference{Name: rotated.Name}},
+			}}))
+			objs, _ := render.Dex(render.DexConfiguration{Installation: &operatorv1.InstallationSpec{}, DexConfig: dexCfg}).Objects()
+			var configMaps int
+			for _, obj := range objs {
+				if cm, ok := obj.(*corev1.ConfigMap); ok {
+					configMaps++
+					for _, v := range cm.Data {
+						Expect(v).NotTo(ContainSubstring(string(rotated.Data[render.ClientSecretSecretField])))
+					}
+				}
+			}
+			Expect(configMaps).NotTo(BeZero())
+			Expect(render.NewDexRelyingPartyConfig(rotating, tlsSecret, rotated, dns.DefaultClusterDomain).ClientSecret()).To(Equal(rotated.Data[render.ClientSecretSecretField]))
+		})
+	})
+
 	var (
 		domain      = "https://example.com"
 		iss         = "https://issuer.com"
@@ -134,7 +282,7 @@ var _ = Describe("dex config tests", func() {
 	)
 
 	DescribeTable("Test DexConfig methods for various connectors ", func(auth *operatorv1.Authentication, expectedConnector map[string]interface{}, expectedVolumes []corev1.Volume, expectedEnv []corev1.EnvVar, secret *corev1.Secret) {
-		dexConfig := render.NewDexConfig(nil, auth, tlsSecret, dexSecret, secret, dns.DefaultClusterDomain)
+		dexConfig := render.NewDexConfig(nil, auth, tlsSecret, dexSecret, secret, nil, dns.DefaultClusterDomain)
 		Expect(dexConfig.Connector()).To(BeEquivalentTo(expectedConnector))
 		annotations := dexConfig.RequiredAnnotations()
 		Expect(annotations["hash.operator.tigera.io/tigera-dex-config"]).NotTo(BeEmpty())
@@ -303,6 +451,165 @@ var _ = Describe("dex config tests", func() {
 		Entry("Compare actual and expected Openshift config", ocp),
 	)
 
+	It("should point relying parties to dex in the configured namespace", func() {
+		auth := oidc.DeepCopy()
+		auth.Spec.DexDeployment = &operatorv1.DexDeployment{Namespace: "team-dex"}
+		dexConfig := render.NewDexRelyingPartyConfig(auth, tlsSecret, dexSecret, dns.DefaultClusterDomain)
+
+		Expect(dexConfig.TokenURI()).To(Equal("https://tigera-dex.team-dex.svc.cluster.local:5556/dex/token"))
+		Expect(dexConfig.UserInfoURI()).To(Equal("https://tigera-dex.team-dex.svc.cluster.local:5556/dex/userinfo"))
+		Expect(dexConfig.JWKSURI()).To(Equal("https://tigera-dex.team-dex.svc.cluster.local:5556/dex/keys"))
+
+		kvConfig := render.NewDexKeyValidatorConfig(auth, tlsSecret, dns.DefaultClusterDomain)
+		Expect(kvConfig.RequiredEnv("")).To(ContainElements(
+			corev1.EnvVar{Name: "DEX_URL", Value: "https://tigera-dex.team-dex.svc.cluster.local:5556/"},
+			corev1.EnvVar{Name: "DEX_JWKS_URL", Value: "https://tigera-dex.team-dex.svc.cluster.local:5556/dex/keys"},
+		))
+	})
+
+	It("should point relying parties to the http listener of dex when TLS is terminated upstream", func() {
+		auth := oidc.DeepCopy()
+		upstream := operatorv1.DexTLSTerminationUpstream
+		auth.Spec.DexDeployment = &operatorv1.DexDeployment{TLSTermination: &upstream}
+		dexConfig := render.NewDexRelyingPartyConfig(auth, tlsSecret, dexSecret, dns.DefaultClusterDomain)
+
+		Expect(dexConfig.TokenURI()).To(Equal("http://tigera-dex.tigera-dex.svc.cluster.local:5556/dex/token"))
+		Expect(dexConfig.JWKSURI()).To(Equal("http://tigera-dex.tigera-dex.svc.cluster.local:5556/dex/keys"))
+
+		kvConfig := render.NewDexKeyValidatorConfig(auth, tlsSecret, dns.DefaultClusterDomain)
+		Expect(kvConfig.RequiredEnv("")).To(ContainElements(
+			corev1.EnvVar{Name: "DEX_URL", Value: "http://tigera-dex.tigera-dex.svc.cluster.local:5556/"},
+			// The issuer is served through the manager, which is not affected.
+			corev1.EnvVar{Name: "DEX_ISSUER", Value: domain + "/dex"},
+		))
+	})
+
+	DescribeTable("should reference the secrets that dex consumes", func(auth *operatorv1.Authentication, secretName string) {
+		auth = auth.DeepCopy()
+		auth.Spec.StaticClients = []operatorv1.StaticClient{{ID: "tigera-cli", SecretName: "tigera-cli-secret"}, {ID: "tigera-spa", Public: true}}
+		idp := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: secretName, Namespace: rmeta.OperatorNamespace()}, Data: map[string][]byte{}}
+		for _, field := range render.RequiredIdpSecretFields(auth) {
+			idp.Data[field] = []byte("value")
+		}
+		cli := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "tigera-cli-secret", Namespace: rmeta.OperatorNamespace()},
+			Data:       map[string][]byte{render.ClientSecretSecretField: []byte("value")},
+		}
+		dexConfig := render.NewDexConfig(nil, auth, tlsSecret, dexSecret, idp, []*corev1.Secret{cli}, dns.DefaultClusterDomain)
+
+		// The keys that the env and the volumes of dex read, besides the client secret of dex itself.
+		var consumed []string
+		for _, env := range dexConfig.RequiredEnv("") {
+			if ref := env.ValueFrom.SecretKeyRef; ref.Name != dexSecret.Name {
+				consumed = append(consumed, fmt.Sprintf("%s/%s[%s]", rmeta.OperatorNamespace(), ref.Name, ref.Key))
+			}
+		}
+		for _, v := range dexConfig.RequiredVolumes() {
+			if v.Secret != nil && v.Secret.SecretName == secretName {
+				for _, item := range v.Secret.Items {
+					consumed = append(consumed, fmt.Sprintf("%s/%s[%s]", rmeta.OperatorNamespace(), secretName, item.Key))
+				}
+			}
+		}
+		var copied []string
+		for _, s := range dexConfig.RequiredSecrets(rmeta.OperatorNamespace()) {
+			copied = append(copied, s.Name)
+		}
+
+		var refs []string
+		for _, ref := range dexConfig.SecretReferences() {
+			refs = append(refs, ref.String())
+			Expect(copied).To(ContainElement(ref.Name))
+			if ref.Name == secretName {
+				Expect(ref.Field).To(Equal(render.IdpFieldPath(auth)))
+			} else {
+				Expect(ref.Field).To(Equal("spec.staticClients[0].secretName"))
+			}
+		}
+		Expect(refs).To(ConsistOf(consumed))
+	},
+		Entry("oidc", oidc, render.OIDCSecretName),
+		Entry("openshift", ocp, render.OpenshiftSecretName),
+		Entry("ldap", ldap, render.LDAPSecretName),
+	)
+
+	It("keeps the labels and annotations of the copied secrets with CopySecretMetadata", func() {
+		auth := oidc.DeepCopy()
+		auth.Spec.DexDeployment = &operatorv1.DexDeployment{CopySecretMetadata: true}
+		idp := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        render.OIDCSecretName,
+				Namespace:   rmeta.OperatorNamespace(),
+				Labels:      map[string]string{"team": "identity"},
+				Annotations: map[string]string{"vault.example.com/path": "oidc"},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: map[string][]byte{render.ClientIDSecretField: []byte("id"), render.ClientSecretSecretField: []byte("secret"), "unused": []byte("value")},
+		}
+
+		copies := render.NewDexConfig(nil, auth, nil, nil, idp, nil, dns.DefaultClusterDomain).RequiredSecrets("tigera-dex")
+		Expect(copies).To(HaveLen(1))
+		Expect(copies[0].Namespace).To(Equal("tigera-dex"))
+		Expect(copies[0].Labels).To(Equal(idp.Labels))
+		Expect(copies[0].Annotations).To(Equal(idp.Annotations))
+		Expect(copies[0].Data).NotTo(HaveKey("unused"))
+
+		auth.Spec.DexDeployment.CopySecretMetadata = false
+		copies = render.NewDexConfig(nil, auth, nil, nil, idp, nil, dns.DefaultClusterDomain).RequiredSecrets("tigera-dex")
+		Expect(copies).To(HaveLen(1))
+		Expect(copies[0].Labels).To(BeEmpty())
+		Expect(copies[0].Annotations).To(BeEmpty())
+	})
+
+	It("mounts the CA bundle of the identity provider instead of the root CA of its secret", func() {
+		bundle := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: render.DexIdpCABundleName(render.DexObjectName), Namespace: rmeta.OperatorNamespace()},
+			Data:       map[string][]byte{rsecret.CABundleKey: []byte("bundle")},
+		}
+		dexConfig := render.NewDexConfig(nil, ldap, tlsSecret, dexSecret, ldapSecret, nil, dns.DefaultClusterDomain, render.WithIdentityProviderCABundle(bundle))
+
+		var mounted []string
+		for _, v := range dexConfig.RequiredVolumes() {
+			if v.Secret != nil && v.Secret.SecretName != tlsSecret.Name {
+				mounted = append(mounted, v.Secret.SecretName)
+				Expect(v.Secret.Items).To(Equal([]corev1.KeyToPath{{Key: rsecret.CABundleKey, Path: "idp.pem"}}))
+			}
+		}
+		Expect(mounted).To(Equal([]string{"tigera-dex-idp-ca-bundle"}))
+
+		var copied []string
+		for _, s := range dexConfig.RequiredSecrets(render.DexNamespace) {
+			copied = append(copied, s.Name)
+		}
+		Expect(copied).To(ContainElement("tigera-dex-idp-ca-bundle"))
+		Expect(dexConfig.RequiredAnnotations()).To(HaveKey("hash.operator.tigera.io/tigera-idp-ca-bundle"))
+	})
+
+	It("mounts the CA bundle of the identity provider next to the Google service account", func() {
+		bundle := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: render.DexIdpCABundleName(render.DexObjectName), Namespace: rmeta.OperatorNamespace()},
+			Data:       map[string][]byte{rsecret.CABundleKey: []byte("bundle")},
+		}
+		dexConfig := render.NewDexConfig(nil, google, tlsSecret, dexSecret, idpSecret, nil, dns.DefaultClusterDomain, render.WithIdentityProviderCABundle(bundle))
+
+		volumes := map[string]string{}
+		for _, v := range dexConfig.RequiredVolumes() {
+			Expect(volumes).NotTo(HaveKey(v.Name))
+			if v.Secret != nil {
+				volumes[v.Name] = v.Secret.SecretName
+			}
+		}
+		Expect(volumes).To(HaveKeyWithValue("secrets", idpSecret.Name))
+		Expect(volumes).To(HaveKeyWithValue("idp-ca", "tigera-dex-idp-ca-bundle"))
+
+		mounts := map[string]string{}
+		for _, m := range dexConfig.RequiredVolumeMounts() {
+			mounts[m.MountPath] = m.Name
+		}
+		Expect(mounts).To(HaveKeyWithValue("/etc/dex/secrets", "secrets"))
+		Expect(mounts).To(HaveKeyWithValue("/etc/ssl/certs/", "idp-ca"))
+	})
+
 	DescribeTable("Test DexKVConfig methods for various connectors ", func(auth *operatorv1.Authentication) {
 		dexConfig := render.NewDexKeyValidatorConfig(auth, tlsSecret, dns.DefaultClusterDomain)
 
@@ -336,7 +643,7 @@ var _ = Describe("dex config tests", func() {
 			TypeMeta: metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
 			Data:     secretData,
 		}
-		dexConfig := render.NewDexConfig(nil, google, tlsSecret, dexSecret, secret, dns.DefaultClusterDomain)
+		dexConfig := render.NewDexConfig(nil, google, tlsSecret, dexSecret, secret, nil, dns.DefaultClusterDomain)
 		connector := dexConfig.Connector()["config"].(map[string]interface{})
 
 		email, emailFound := connector["adminEmail"]
@@ -375,7 +682,7 @@ var _ = Describe("dex config tests", func() {
 	DescribeTable("Test values for promptTypes ", func(in []operatorv1.PromptType, result string) {
 		auth := oidc.DeepCopy()
 		auth.Spec.OIDC.PromptTypes = in
-		dexConfig := render.NewDexConfig(nil, auth, tlsSecret, dexSecret, idpSecret, dns.DefaultClusterDomain)
+		dexConfig := render.NewDexConfig(nil, auth, tlsSecret, dexSecret, idpSecret, nil, dns.DefaultClusterDomain)
 		config, ok := dexConfig.Connector()["config"].(map[string]interface{})
 		Expect(ok).To(BeTrue())
 		if result == "" {
@@ -394,4 +701,184 @@ var _ = Describe("dex config tests", func() {
 		Entry("Compare actual and expected promptType", []operatorv1.PromptType{operatorv1.PromptTypeConsent, operatorv1.PromptTypeSelectAccount}, "consent select_account"),
 		Entry("Compare actual and expected promptType", []operatorv1.PromptType{operatorv1.PromptTypeConsent, operatorv1.PromptTypeSelectAccount, operatorv1.PromptTypeLogin}, "consent select_account login"),
 	)
+
+	DescribeTable("Test issuer aliasing", func(allow bool, alias string, expected string) {
+		auth := oidc.DeepCopy()
+		auth.Spec.AllowIssuerAliasing = allow
+		auth.Spec.OIDC.IssuerAlias = alias
+		dexConfig := render.NewDexConfig(nil, auth, tlsSecret, dexSecret, idpSecret, nil, dns.DefaultClusterDomain)
+		config, ok := dexConfig.Connector()["config"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(config["issuer"]).To(Equal(iss))
+		if expected == "" {
+			Expect(config).NotTo(HaveKey("insecureIssuer"))
+		} else {
+			Expect(config["insecureIssuer"]).To(Equal(expected))
+		}
+	},
+		Entry("No alias", true, "", ""),
+		Entry("Alias when aliasing is allowed", true, "https://broker.com", "https://broker.com"),
+		Entry("Alias when aliasing is not allowed", false, "https://broker.com", ""),
+	)
+
+	DescribeTable("Test issuer validation", func(auth *operatorv1.Authentication, expectedProblem string) {
+		err := render.NewDexConfig(nil, auth, tlsSecret, dexSecret, idpSecret, nil, dns.DefaultClusterDomain).Validate()
+		if expectedProblem == "" {
+			Expect(err).NotTo(HaveOccurred())
+		} else {
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*render.ValidationError).Problems).To(ContainElement(expectedProblem))
+		}
+	},
+		Entry("https issuer", oidc, ""),
+		Entry("http issuer",
+			&operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{ManagerDomain: domain, OIDC: &operatorv1.AuthenticationOIDC{IssuerURL: "http://issuer.com", UsernameClaim: "email"}}},
+			`spec.oidc.issuerURL: Invalid value: "http://issuer.com": "http://issuer.com" is not an https URL`),
+		Entry("alias when aliasing is allowed",
+			&operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{ManagerDomain: domain, AllowIssuerAliasing: true, OIDC: &operatorv1.AuthenticationOIDC{IssuerURL: iss, IssuerAlias: "https://broker.com", UsernameClaim: "email"}}},
+			""),
+		Entry("alias when aliasing is not allowed",
+			&operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{ManagerDomain: domain, OIDC: &operatorv1.AuthenticationOIDC{IssuerURL: iss, IssuerAlias: "https://broker.com", UsernameClaim: "email"}}},
+			"spec.oidc.issuerAlias: Forbidden: issuer aliasing is not allowed, please set spec.allowIssuerAliasing"),
+		Entry("http alias",
+			&operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{ManagerDomain: domain, AllowIssuerAliasing: true, OIDC: &operatorv1.AuthenticationOIDC{IssuerURL: iss, IssuerAlias: "http://broker.com", UsernameClaim: "email"}}},
+			`spec.oidc.issuerAlias: Invalid value: "http://broker.com": "http://broker.com" is not an https URL`),
+		Entry("http Openshift issuer",
+			&operatorv1.Authentication{Spec: operatorv1.AuthenticationSpec{ManagerDomain: domain, Openshift: &operatorv1.AuthenticationOpenshift{IssuerURL: "http://issuer.com"}}},
+			`spec.openshift.issuerURL: Invalid value: "http://issuer.com": "http://issuer.com" is not an https URL`),
+	)
+
+	DescribeTable("Test values for getUserInfo", func(in *bool) {
+		auth := oidc.DeepCopy()
+		auth.Spec.OIDC.GetUserInfo = in
+		dexConfig := render.NewDexConfig(nil, auth, tlsSecret, dexSecret, idpSecret, nil, dns.DefaultClusterDomain)
+		config, ok := dexConfig.Connector()["config"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		if in == nil {
+			Expect(config).NotTo(HaveKey("getUserInfo"))
+		} else {
+			Expect(config["getUserInfo"]).To(Equal(*in))
+		}
+	},
+		Entry("Omit getUserInfo by default", nil),
+		Entry("Render getUserInfo when enabled", ptr.BoolToPtr(true)),
+		Entry("Render getUserInfo when disabled", ptr.BoolToPtr(false)),
+	)
+
+	DescribeTable("Test values for basicAuthUnsupported", func(in *bool) {
+		auth := oidc.DeepCopy()
+		auth.Spec.OIDC.BasicAuthUnsupported = in
+		dexConfig := render.NewDexConfig(nil, auth, tlsSecret, dexSecret, idpSecret, nil, dns.DefaultClusterDomain)
+		config, ok := dexConfig.Connector()["config"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		if in == nil {
+			Expect(config).NotTo(HaveKey("basicAuthUnsupported"))
+		} else {
+			Expect(config["basicAuthUnsupported"]).To(Equal(*in))
+		}
+	},
+		Entry("Omit basicAuthUnsupported by default", nil),
+		Entry("Render basicAuthUnsupported when enabled", ptr.BoolToPtr(true)),
+	)
+
+	DescribeTable("Test values for overrideClaimMapping", func(in *bool) {
+		auth := oidc.DeepCopy()
+		auth.Spec.OIDC.GroupsClaim = "group"
+		auth.Spec.OIDC.OverrideClaimMapping = in
+		dexConfig := render.NewDexConfig(nil, auth, tlsSecret, dexSecret, idpSecret, nil, dns.DefaultClusterDomain)
+		Expect(dexConfig.Validate()).NotTo(HaveOccurred())
+		config, ok := dexConfig.Connector()["config"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(config["claimMapping"]).To(Equal(map[string]string{"groups": "group"}))
+		if in == nil {
+			Expect(config).NotTo(HaveKey("overrideClaimMapping"))
+		} else {
+			Expect(config["overrideClaimMapping"]).To(Equal(*in))
+		}
+	},
+		Entry("Omit overrideClaimMapping by default", nil),
+		Entry("Render overrideClaimMapping when enabled", ptr.BoolToPtr(true)),
+		Entry("Render overrideClaimMapping when disabled", ptr.BoolToPtr(false)),
+	)
+
+	DescribeTable("Test overrideClaimMapping requires a claim mapping", func(groupsClaim string) {
+		auth := oidc.DeepCopy()
+		auth.Spec.OIDC.GroupsClaim = groupsClaim
+		auth.Spec.OIDC.OverrideClaimMapping = ptr.BoolToPtr(true)
+		dexConfig := render.NewDexConfig(nil, auth, tlsSecret, dexSecret, idpSecret, nil, dns.DefaultClusterDomain)
+		err := dexConfig.Validate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*render.ValidationError).Problems).To(ContainElement("OIDC override claim mapping is set, but no claim mapping is configured"))
+		config, ok := dexConfig.Connector()["config"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(config).NotTo(HaveKey("overrideClaimMapping"))
+	},
+		Entry("Without a groups claim", ""),
+		Entry("With the default groups claim", render.DefaultGroupsClaim),
+	)
+
+	DescribeTable("Test values for connectorScopes", func(requested, connector, expected []string) {
+		auth := oidc.DeepCopy()
+		auth.Spec.OIDC.RequestedScopes = requested
+		auth.Spec.OIDC.ConnectorScopes = connector
+		dexConfig := render.NewDexConfig(nil, auth, tlsSecret, dexSecret, idpSecret, nil, dns.DefaultClusterDomain)
+		Expect(dexConfig.Validate()).NotTo(HaveOccurred())
+		config, ok := dexConfig.Connector()["config"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(config["scopes"]).To(Equal(expected))
+
+		// The scopes that the Manager requests from Dex are not affected.
+		rpConfig := render.NewDexRelyingPartyConfig(auth, tlsSecret, dexSecret, dns.DefaultClusterDomain)
+		if requested == nil {
+			Expect(rpConfig.RequestedScopes()).To(Equal([]string{"openid", "email", "profile"}))
+		} else {
+			Expect(rpConfig.RequestedScopes()).To(Equal(requested))
+		}
+	},
+		Entry("Default", nil, nil, []string{"openid", "email", "profile"}),
+		Entry("Requested scopes for backwards compatibility", []string{"openid", "email"}, nil, []string{"openid", "email"}),
+		Entry("Connector scopes", nil, []string{"openid", "profile", "email", "groups"}, []string{"openid", "profile", "email", "groups"}),
+		Entry("Connector scopes take precedence", []string{"openid", "email"}, []string{"openid", "custom"}, []string{"openid", "custom"}),
+	)
+
+	It("should require openid in the connector scopes", func() {
+		auth := oidc.DeepCopy()
+		auth.Spec.OIDC.ConnectorScopes = []string{"profile", "email"}
+		dexConfig := render.NewDexConfig(nil, auth, tlsSecret, dexSecret, idpSecret, nil, dns.DefaultClusterDomain)
+		err := dexConfig.Validate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*render.ValidationError).Problems).To(ContainElement(`spec.oidc.connectorScopes: Invalid value: []string{"profile", "email"}: must include "openid"`))
+	})
+
+	Context("cluster domain detection", func() {
+		var detector *dns.ClusterDomainDetector
+
+		BeforeEach(func() {
+			dir, err := ioutil.TempDir("", "dex")
+			Expect(err).NotTo(HaveOccurred())
+			resolvConfPath := filepath.Join(dir, "resolv.conf")
+			Expect(ioutil.WriteFile(resolvConfPath, []byte("search tigera-dex.svc.corp.local svc.corp.local corp.local\n"), 0644)).To(Succeed())
+			detector = dns.NewClusterDomainDetector(resolvConfPath)
+			Expect(detector.Detect()).To(Equal("corp.local"))
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		It("should use the detected cluster domain when none is given", func() {
+			dexConfig := render.NewDexConfig(nil, authentication, tlsSecret, dexSecret, idpSecret, nil, "", render.WithClusterDomainDetector(detector))
+			Expect(dexConfig.ClusterDomain()).To(Equal("corp.local"))
+			Expect(render.Dex(render.DexConfiguration{Installation: &operatorv1.InstallationSpec{}, DexConfig: dexConfig}).Validate()).NotTo(HaveOccurred())
+
+			keyValidator := render.NewDexKeyValidatorConfig(authentication, nil, "", render.WithClusterDomainDetector(detector))
+			Expect(keyValidator.ClusterDomain()).To(Equal("corp.local"))
+			Expect(keyValidator.RequiredEnv("")).To(ContainElement(corev1.EnvVar{Name: "DEX_URL", Value: "https://tigera-dex.tigera-dex.svc.corp.local:5556/"}))
+		})
+
+		It("should reject a cluster domain that does not match the detected one", func() {
+			dexConfig := render.NewDexConfig(nil, authentication, tlsSecret, dexSecret, idpSecret, nil, dns.DefaultClusterDomain, render.WithClusterDomainDetector(detector))
+			Expect(dexConfig.ClusterDomain()).To(Equal(dns.DefaultClusterDomain))
+			err := dexConfig.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*render.ValidationError).Problems).To(ContainElement(ContainSubstring("the cluster domain cluster.local does not match the cluster domain corp.local that was detected")))
+		})
+	})
 })
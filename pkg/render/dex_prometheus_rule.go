@@ -0,0 +1,192 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tigera/operator/pkg/common"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DexPrometheusRuleLabel marks the PrometheusRules with the alerts for Dex, with the name of the Dex instance that
+	// they alert for as its value.
+	DexPrometheusRuleLabel = "operator.tigera.io/dex-prometheus-rule"
+
+	defaultCertificateExpiryDays = 30
+	// The share of the logins that must fail for the failure rate of the logins to be alerted.
+	loginFailureRatio = 0.25
+)
+
+// PrometheusRuleGVK is the kind of the rules that the Prometheus operator loads into the Prometheus that selects them.
+var PrometheusRuleGVK = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "PrometheusRule"}
+
+// ServiceMonitorGVK is the kind of the scrape targets that the Prometheus operator adds to the Prometheus that selects
+// them.
+var ServiceMonitorGVK = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "ServiceMonitor"}
+
+// defaultPrometheusRuleLabels are the labels with which the Prometheus of Calico Enterprise selects its rules.
+var defaultPrometheusRuleLabels = map[string]string{
+	"prometheus": "calico-node-prometheus",
+	"role":       "tigera-prometheus-rules",
+}
+
+// defaultServiceMonitorLabels are the labels with which the Prometheus of Calico Enterprise selects its service
+// monitors.
+var defaultServiceMonitorLabels = map[string]string{
+	"team": "network-operators",
+}
+
+// prometheusRule returns the PrometheusRule with the alerts for Dex, or nil if it is not configured. The rule needs
+// the telemetry of Dex, so it is only rendered for a Dex that the operator deploys with its metrics service.
+func (c *dexComponent) prometheusRule() *unstructured.Unstructured {
+	cfg := c.dexConfig.DexDeployment().PrometheusRule
+	if cfg == nil || c.external() || !c.metricsEnabled() {
+		return nil
+	}
+	rule := &unstructured.Unstructured{}
+	rule.SetGroupVersionKind(PrometheusRuleGVK)
+	rule.SetName(c.name())
+	rule.SetNamespace(c.prometheusNamespace())
+	rule.SetLabels(c.prometheusLabels(defaultPrometheusRuleLabels))
+	rule.Object["spec"] = map[string]interface{}{
+		"groups": []interface{}{
+			map[string]interface{}{
+				"name":  c.name(),
+				"rules": c.alerts(),
+			},
+		},
+	}
+	return rule
+}
+
+// serviceMonitor returns the ServiceMonitor with which the Prometheus that loads the PrometheusRule scrapes the metrics
+// service of Dex, next to the rule. The alerts are based on the metrics that it scrapes.
+func (c *dexComponent) serviceMonitor(rule *unstructured.Unstructured) *unstructured.Unstructured {
+	matchLabels := map[string]interface{}{}
+	for k, v := range c.metricsServiceLabels() {
+		matchLabels[k] = v
+	}
+	monitor := &unstructured.Unstructured{}
+	monitor.SetGroupVersionKind(ServiceMonitorGVK)
+	monitor.SetName(rule.GetName())
+	monitor.SetNamespace(rule.GetNamespace())
+	monitor.SetLabels(c.prometheusLabels(defaultServiceMonitorLabels))
+	monitor.Object["spec"] = map[string]interface{}{
+		"namespaceSelector": map[string]interface{}{"matchNames": []interface{}{c.namespace()}},
+		"selector":          map[string]interface{}{"matchLabels": matchLabels},
+		"endpoints": []interface{}{
+			map[string]interface{}{"port": dexMetricsPortName, "scheme": "http", "interval": "30s"},
+		},
+	}
+	return monitor
+}
+
+// prometheusNamespace returns the namespace of the PrometheusRule and the ServiceMonitor of Dex.
+func (c *dexComponent) prometheusNamespace() string {
+	if ns := c.dexConfig.DexDeployment().PrometheusRule.Namespace; ns != "" {
+		return ns
+	}
+	return common.TigeraPrometheusNamespace
+}
+
+// prometheusLabels returns the configured labels, or the given default labels, with which the Prometheus selects the
+// PrometheusRule and the ServiceMonitor of Dex.
+func (c *dexComponent) prometheusLabels(defaults map[string]string) map[string]string {
+	selector := c.dexConfig.DexDeployment().PrometheusRule.Labels
+	if len(selector) == 0 {
+		selector = defaults
+	}
+	labels := map[string]string{}
+	for k, v := range selector {
+		labels[k] = v
+	}
+	labels[DexPrometheusRuleLabel] = c.name()
+	return labels
+}
+
+// alerts returns the alerting rules for Dex, based on the metrics that the ServiceMonitor scrapes. Prometheus records
+// whether it reached each pod in the up metric, and labels the scraped metrics with the namespace and the service of
+// the target. The telemetry of Dex counts its HTTP requests by the route that handled them: the logins go through the
+// auth and callback routes, and the identity provider returns to the callback routes, which fail with a server error
+// when the connector to the identity provider fails.
+func (c *dexComponent) alerts() []interface{} {
+	requests := fmt.Sprintf(`namespace="%s",service="%s"`, c.namespace(), c.metricsServiceName())
+	logins := requests + `,handler=~"/auth.*|/callback.*"`
+	callbacks := requests + `,handler=~"/callback.*"`
+
+	alerts := []interface{}{
+		alert("DexUnavailable", "critical", "10m",
+			fmt.Sprintf("absent(up{%s} == 1)", requests),
+			fmt.Sprintf("No pod of Dex %s/%s is available, so users cannot log in.", c.namespace(), c.name())),
+		alert("DexLoginFailureRateHigh", "warning", "15m",
+			fmt.Sprintf(`sum(rate(http_requests_total{%s,code=~"4..|5.."}[5m])) / sum(rate(http_requests_total{%s}[5m])) > %g`, logins, logins, loginFailureRatio),
+			fmt.Sprintf("More than %g%% of the logins to Dex %s/%s fail.", loginFailureRatio*100, c.namespace(), c.name())),
+		alert("DexConnectorErrors", "warning", "10m",
+			fmt.Sprintf(`sum(rate(http_requests_total{%s,code=~"5.."}[5m])) > 0`, callbacks),
+			fmt.Sprintf("Dex %s/%s fails to complete logins with its identity provider.", c.namespace(), c.name())),
+	}
+
+	// The certificate is only known when the operator created it or was given it.
+	if notAfter, ok := c.dexConfig.CertificateExpiry(); ok && !c.servingCertificate() {
+		days := int32(defaultCertificateExpiryDays)
+		if d := c.dexConfig.DexDeployment().PrometheusRule.CertificateExpiryDays; d != nil {
+			days = *d
+		}
+		alertAt := notAfter.Add(-time.Duration(days) * 24 * time.Hour)
+		alerts = append(alerts, alert("DexCertificateExpiring", "warning", "0s",
+			fmt.Sprintf("vector(time()) > %d", alertAt.Unix()),
+			fmt.Sprintf("The certificate of Dex %s/%s expires at %s.", c.namespace(), c.name(), notAfter.UTC().Format(time.RFC3339))))
+	}
+	return alerts
+}
+
+func alert(name, severity, duration, expr, description string) interface{} {
+	return map[string]interface{}{
+		"alert":       name,
+		"expr":        expr,
+		"for":         duration,
+		"labels":      map[string]interface{}{"severity": severity},
+		"annotations": map[string]interface{}{"summary": name, "description": description},
+	}
+}
+
+// stalePrometheusRules returns the PrometheusRules of this instance that are not rendered anymore, for example since
+// they were moved to another namespace or the metrics service of Dex was disabled, and the ServiceMonitors next to
+// them.
+func (c *dexComponent) stalePrometheusRules(rendered *unstructured.Unstructured) []client.Object {
+	var stale []client.Object
+	for _, existing := range c.dexConfig.PrometheusRules() {
+		if existing.GetLabels()[DexPrometheusRuleLabel] != c.name() {
+			continue
+		}
+		if rendered != nil && existing.GetName() == rendered.GetName() && existing.GetNamespace() == rendered.GetNamespace() {
+			continue
+		}
+		for _, gvk := range []schema.GroupVersionKind{PrometheusRuleGVK, ServiceMonitorGVK} {
+			obj := &unstructured.Unstructured{}
+			obj.SetGroupVersionKind(gvk)
+			obj.SetName(existing.GetName())
+			obj.SetNamespace(existing.GetNamespace())
+			stale = append(stale, obj)
+		}
+	}
+	return stale
+}
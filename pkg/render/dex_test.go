@@ -1,7 +1,13 @@
 package render_test
 
 import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	rtest "github.com/tigera/operator/pkg/render/common/test"
 
@@ -10,13 +16,27 @@ import (
 	. "github.com/onsi/gomega"
 
 	operatorv1 "github.com/tigera/operator/api/v1"
+	"github.com/tigera/operator/pkg/components"
 	"github.com/tigera/operator/pkg/dns"
+	"github.com/tigera/operator/pkg/ptr"
 	"github.com/tigera/operator/pkg/render"
+	"github.com/tigera/operator/pkg/render/common/configmap"
 	rmeta "github.com/tigera/operator/pkg/render/common/meta"
+	"github.com/tigera/operator/pkg/render/common/podsecuritycontext"
+	"github.com/tigera/operator/pkg/render/common/secret"
+
+	"gopkg.in/yaml.v2"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 var _ = Describe("dex rendering tests", func() {
@@ -57,7 +77,7 @@ var _ = Describe("dex rendering tests", func() {
 				},
 			}
 
-			tlsSecret = render.CreateDexTLSSecret("tigera-dex.tigera-dex.svc.cluster.local")
+			tlsSecret = render.CreateDexTLSSecret("tigera-dex.tigera-dex.svc.cluster.local", nil)
 			certSecret = render.CreateCertificateSecret(tlsSecret.Data[corev1.TLSCertKey], render.DexCertSecretName, rmeta.OperatorNamespace())
 			dexSecret = render.CreateDexClientSecret()
 			idpSecret = &corev1.Secret{
@@ -84,9 +104,9 @@ var _ = Describe("dex rendering tests", func() {
 
 		It("should render all resources for a OIDC setup", func() {
 
-			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, clusterName)
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
 
-			component := render.Dex(pullSecrets, false, installation, dexCfg, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
 			resources, _ := component.Objects()
 
 			expectedResources := []struct {
@@ -96,20 +116,20 @@ var _ = Describe("dex rendering tests", func() {
 				version string
 				kind    string
 			}{
-				{render.DexObjectName, render.DexNamespace, "", "v1", "ServiceAccount"},
-				{render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment"},
-				{render.DexObjectName, render.DexNamespace, "", "v1", "Service"},
 				{render.DexObjectName, "", rbac, "v1", "ClusterRole"},
 				{render.DexObjectName, "", rbac, "v1", "ClusterRoleBinding"},
+				{render.DexObjectName, render.DexNamespace, "", "v1", "ServiceAccount"},
 				{render.DexObjectName, render.DexNamespace, "", "v1", "ConfigMap"},
-				{render.DexTLSSecretName, rmeta.OperatorNamespace(), "", "v1", "Secret"},
-				{render.DexObjectName, rmeta.OperatorNamespace(), "", "v1", "Secret"},
-				{render.OIDCSecretName, rmeta.OperatorNamespace(), "", "v1", "Secret"},
-				{render.DexCertSecretName, rmeta.OperatorNamespace(), "", "v1", "Secret"},
-				{render.DexTLSSecretName, render.DexNamespace, "", "v1", "Secret"},
 				{render.DexObjectName, render.DexNamespace, "", "v1", "Secret"},
+				{render.DexTLSSecretName, render.DexNamespace, "", "v1", "Secret"},
 				{render.OIDCSecretName, render.DexNamespace, "", "v1", "Secret"},
 				{pullSecretName, render.DexNamespace, "", "v1", "Secret"},
+				{render.DexObjectName, rmeta.OperatorNamespace(), "", "v1", "Secret"},
+				{render.DexTLSSecretName, rmeta.OperatorNamespace(), "", "v1", "Secret"},
+				{render.DexCertSecretName, rmeta.OperatorNamespace(), "", "v1", "Secret"},
+				{render.OIDCSecretName, rmeta.OperatorNamespace(), "", "v1", "Secret"},
+				{render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment"},
+				{render.DexObjectName, render.DexNamespace, "", "v1", "Service"},
 			}
 
 			for i, expectedRes := range expectedResources {
@@ -142,50 +162,2511 @@ var _ = Describe("dex rendering tests", func() {
 				Effect:   corev1.TaintEffectNoExecute,
 			}
 
-			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, clusterName)
-			component := render.Dex(pullSecrets, false, &operatorv1.InstallationSpec{
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: &operatorv1.InstallationSpec{
 				ControlPlaneTolerations: []corev1.Toleration{t},
-			}, dexCfg, clusterName)
+			}, DexConfig: dexCfg})
 			resources, _ := component.Objects()
 			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
 			Expect(d.Spec.Template.Spec.Tolerations).To(ContainElements(t, rmeta.TolerateMaster))
 		})
 
-		It("should render all resources for a certificate management", func() {
+		DescribeTable("should render the grant types of the manager client", func(grantTypes []operatorv1.GrantType, expected []interface{}) {
+			if grantTypes != nil {
+				authentication.Spec.ManagerClient = &operatorv1.ManagerClient{GrantTypes: grantTypes}
+			}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			resources, _ := component.Objects()
+
+			staticClients := dexConfigYAML(resources)["staticClients"].([]interface{})
+			Expect(staticClients).To(HaveLen(1))
+			Expect(staticClients[0].(map[interface{}]interface{})["grantTypes"]).To(Equal(expected))
+		},
+			Entry("default grant types", nil, []interface{}{"authorization_code", "refresh_token"}),
+			Entry("custom grant types",
+				[]operatorv1.GrantType{operatorv1.GrantTypeAuthorizationCode, operatorv1.GrantTypeDeviceCode},
+				[]interface{}{"authorization_code", "urn:ietf:params:oauth:grant-type:device_code"}),
+			Entry("unknown grant types are skipped",
+				[]operatorv1.GrantType{operatorv1.GrantTypeRefreshToken, "Password"},
+				[]interface{}{"refresh_token"}),
+		)
+
+		DescribeTable("should render the name of the manager client", func(client *operatorv1.ManagerClient, expected string) {
+			authentication.Spec.ManagerClient = client
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			resources, _ := component.Objects()
+
+			staticClients := dexConfigYAML(resources)["staticClients"].([]interface{})
+			Expect(staticClients[0].(map[interface{}]interface{})["id"]).To(Equal(render.DexClientId))
+			Expect(staticClients[0].(map[interface{}]interface{})["name"]).To(Equal(expected))
+		},
+			Entry("default", nil, "Calico Enterprise Manager"),
+			Entry("default for an empty name", &operatorv1.ManagerClient{}, "Calico Enterprise Manager"),
+			Entry("custom", &operatorv1.ManagerClient{Name: "Example Corp Security Console"}, "Example Corp Security Console"),
+		)
+
+		It("should render a public static client without a secret", func() {
+			authentication.Spec.StaticClients = []operatorv1.StaticClient{
+				{ID: "tigera-cli", Name: "Calico CLI", RedirectURIs: []string{"http://localhost:8000"}, Public: true},
+			}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Validate()).NotTo(HaveOccurred())
+			resources, _ := component.Objects()
+
+			staticClients := dexConfigYAML(resources)["staticClients"].([]interface{})
+			Expect(staticClients).To(HaveLen(2))
+			Expect(staticClients[1]).To(Equal(map[interface{}]interface{}{
+				"id":           "tigera-cli",
+				"name":         "Calico CLI",
+				"redirectURIs": []interface{}{"http://localhost:8000"},
+				"public":       true,
+			}))
+
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			for _, env := range d.Spec.Template.Spec.Containers[0].Env {
+				Expect(env.Name).NotTo(HavePrefix("STATIC_CLIENT_SECRET"))
+			}
+		})
+
+		DescribeTable("should reference the secrets in the config as env that dex expands", func(ldap bool) {
+			if ldap {
+				authentication.Spec.OIDC = nil
+				authentication.Spec.LDAP = &operatorv1.AuthenticationLDAP{
+					Host:       "ldap.example.com:636",
+					UserSearch: &operatorv1.UserSearch{BaseDN: "dc=example,dc=com", NameAttribute: "uid"},
+				}
+				idpSecret = &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: render.LDAPSecretName, Namespace: rmeta.OperatorNamespace()},
+					TypeMeta:   metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
+					Data: map[string][]byte{
+						render.BindDNSecretField: []byte("cn=secret-bind-dn,dc=example,dc=com"),
+						render.BindPWSecretField: []byte("secret-bind-pw"),
+						render.RootCASecretField: []byte("secret-root-ca"),
+					},
+				}
+			}
+			authentication.Spec.StaticClients = []operatorv1.StaticClient{{ID: "tigera-cli", SecretName: "tigera-cli-secret"}}
+			clientSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "tigera-cli-secret", Namespace: rmeta.OperatorNamespace()},
+				TypeMeta:   metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
+				Data:       map[string][]byte{render.ClientSecretSecretField: []byte("secret-cli-secret")},
+			}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, []*corev1.Secret{clientSecret}, clusterName)
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			env := map[string]corev1.EnvVar{}
+			for _, e := range d.Spec.Template.Spec.Containers[0].Env {
+				env[e.Name] = e
+			}
+			Expect(env).To(HaveKeyWithValue("DEX_EXPAND_ENV", corev1.EnvVar{Name: "DEX_EXPAND_ENV", Value: "true"}))
+
+			// Every reference in the config is provided by the env, from a secret.
+			var references []string
+			var walk func(key string, v interface{})
+			walk = func(key string, v interface{}) {
+				switch v := v.(type) {
+				case map[interface{}]interface{}:
+					for k, e := range v {
+						walk(k.(string), e)
+					}
+				case []interface{}:
+					for _, e := range v {
+						walk(key, e)
+					}
+				case string:
+					if key == "secretEnv" {
+						references = append(references, v)
+					} else if strings.HasPrefix(v, "$") {
+						references = append(references, strings.TrimPrefix(v, "$"))
+					}
+				}
+			}
+			for k, v := range dexConfigYAML(resources) {
+				walk(k, v)
+			}
+			Expect(references).NotTo(BeEmpty())
+			for _, ref := range references {
+				Expect(env).To(HaveKey(ref))
+				Expect(env[ref].ValueFrom.SecretKeyRef).NotTo(BeNil())
+			}
+
+			// None of the secret values is inlined into the config.
+			cm := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "", "v1", "ConfigMap").(*corev1.ConfigMap)
+			for _, s := range []*corev1.Secret{dexSecret, idpSecret, clientSecret} {
+				for key, value := range s.Data {
+					Expect(cm.Data["config.yaml"]).NotTo(ContainSubstring(string(value)), "%s/%s[%s]", s.Namespace, s.Name, key)
+				}
+			}
+		},
+			Entry("oidc", false),
+			Entry("ldap", true),
+		)
+
+		It("should read the credentials of the identity provider from the files of a secrets store", func() {
+			authentication.Spec.OIDC = nil
+			authentication.Spec.LDAP = &operatorv1.AuthenticationLDAP{
+				Host:       "ldap.example.com:636",
+				UserSearch: &operatorv1.UserSearch{BaseDN: "dc=example,dc=com", NameAttribute: "uid"},
+			}
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{
+				IdentityProviderSecretsStore: &operatorv1.DexSecretsStore{SecretProviderClass: "ldap-credentials", NodePublishSecretName: "vault-credentials"},
+			}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, nil, nil, clusterName)
+			Expect(dexCfg.Validate()).NotTo(HaveOccurred())
+			Expect(dexCfg.SecretReferences()).To(BeEmpty())
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Ready()).To(BeTrue())
+			resources, _ := component.Objects()
+
+			// The secret of the identity provider is neither copied nor referenced.
+			Expect(rtest.GetResource(resources, render.LDAPSecretName, render.DexNamespace, "", "v1", "Secret")).To(BeNil())
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			for _, e := range d.Spec.Template.Spec.Containers[0].Env {
+				Expect(e.Name).NotTo(BeElementOf("BIND_DN", "BIND_PW"))
+			}
+
+			Expect(d.Spec.Template.Spec.Volumes).To(ContainElement(corev1.Volume{
+				Name: "idp-secrets-store",
+				VolumeSource: corev1.VolumeSource{CSI: &corev1.CSIVolumeSource{
+					Driver:               "secrets-store.csi.k8s.io",
+					ReadOnly:             ptr.BoolToPtr(true),
+					VolumeAttributes:     map[string]string{"secretProviderClass": "ldap-credentials"},
+					NodePublishSecretRef: &corev1.LocalObjectReference{Name: "vault-credentials"},
+				}},
+			}))
+			Expect(d.Spec.Template.Spec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "idp-secrets-store", MountPath: "/etc/dex/idp", ReadOnly: true}))
+			Expect(d.Spec.Template.Spec.Containers[0].Command).To(Equal([]string{"/bin/sh", "-c", strings.Join([]string{
+				"set -e",
+				`[ -s /etc/dex/idp/bindDN ] || { echo "the secrets store has no file /etc/dex/idp/bindDN" >&2; exit 1; }`,
+				`export BIND_DN="$(cat /etc/dex/idp/bindDN)"`,
+				`[ -s /etc/dex/idp/bindPW ] || { echo "the secrets store has no file /etc/dex/idp/bindPW" >&2; exit 1; }`,
+				`export BIND_PW="$(cat /etc/dex/idp/bindPW)"`,
+				"exec /usr/local/bin/dex serve /etc/dex/baseCfg/config.yaml",
+			}, "\n")}))
+
+			connector := dexConfigYAML(resources)["connectors"].([]interface{})[0].(map[interface{}]interface{})["config"].(map[interface{}]interface{})
+			Expect(connector["bindDN"]).To(Equal("$BIND_DN"))
+			Expect(connector["rootCA"]).To(Equal("/etc/dex/idp/rootCA"))
+		})
+
+		It("should not allow a secrets store for a Dex that runs outside of the cluster", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{
+				ExternalHost:                 "dex.example.com",
+				IdentityProviderSecretsStore: &operatorv1.DexSecretsStore{SecretProviderClass: "oidc-credentials"},
+			}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, nil, nil, clusterName)
+			Expect(dexCfg.Validate()).To(MatchError(ContainSubstring("cannot be mounted from a secrets store when Dex runs outside of this cluster")))
+		})
+
+		It("should render a static client that reads its secret from the environment", func() {
+			clientSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "tigera-cli-secret", Namespace: rmeta.OperatorNamespace()},
+				TypeMeta:   metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
+				Data:       map[string][]byte{render.ClientSecretSecretField: []byte("cli-secret")},
+			}
+			authentication.Spec.StaticClients = []operatorv1.StaticClient{{ID: "tigera-cli", SecretName: clientSecret.Name}}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, []*corev1.Secret{clientSecret}, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Validate()).NotTo(HaveOccurred())
+			resources, _ := component.Objects()
+
+			staticClients := dexConfigYAML(resources)["staticClients"].([]interface{})
+			Expect(staticClients).To(HaveLen(2))
+			Expect(staticClients[1]).To(Equal(map[interface{}]interface{}{
+				"id":        "tigera-cli",
+				"secretEnv": "STATIC_CLIENT_SECRET_0",
+			}))
+
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name: "STATIC_CLIENT_SECRET_0",
+				ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+					Key:                  render.ClientSecretSecretField,
+					LocalObjectReference: corev1.LocalObjectReference{Name: clientSecret.Name},
+				}},
+			}))
+			Expect(rtest.GetResource(resources, clientSecret.Name, render.DexNamespace, "", "v1", "Secret")).NotTo(BeNil())
+		})
+
+		It("should render a generated static client secret in the operator namespace and the namespace of dex", func() {
+			generated := render.CreateStaticClientSecret("tigera-cli-secret")
+			Expect(generated.Data[render.ClientSecretSecretField]).To(HaveLen(24))
+			Expect(render.CreateStaticClientSecret("tigera-cli-secret").Data).NotTo(Equal(generated.Data))
+
+			authentication.Spec.StaticClients = []operatorv1.StaticClient{{ID: "tigera-cli", SecretName: generated.Name, GenerateSecret: true}}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, []*corev1.Secret{generated}, clusterName)
+			// The operator creates the generated secret, so it is not a reference that must exist before dex is deployed.
+			for _, ref := range dexCfg.SecretReferences() {
+				Expect(ref.Name).NotTo(Equal(generated.Name))
+			}
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Validate()).NotTo(HaveOccurred())
+			resources, _ := component.Objects()
+
+			Expect(dexConfigYAML(resources)["staticClients"].([]interface{})[1]).To(HaveKeyWithValue("secretEnv", "STATIC_CLIENT_SECRET_0"))
+			for _, ns := range []string{rmeta.OperatorNamespace(), render.DexNamespace} {
+				s := rtest.GetResource(resources, generated.Name, ns, "", "v1", "Secret")
+				Expect(s).NotTo(BeNil(), ns)
+				Expect(s.(*corev1.Secret).Data).To(Equal(generated.Data), ns)
+			}
+		})
+
+		It("should not allow a public static client with a secret", func() {
+			authentication.Spec.StaticClients = []operatorv1.StaticClient{{ID: "tigera-cli", Public: true, SecretName: "tigera-cli-secret"}}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			err := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*render.ValidationError).Problems).To(ConsistOf("spec.staticClients[0].secretName: Forbidden: a public static client must not have a secretName"))
+		})
+
+		It("should render the trusted peers of a static client", func() {
+			authentication.Spec.StaticClients = []operatorv1.StaticClient{
+				{ID: "tigera-cli", Public: true, TrustedPeers: []string{render.DexClientId, "tigera-dashboard"}},
+				{ID: "tigera-dashboard", Public: true},
+			}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Validate()).NotTo(HaveOccurred())
+			resources, _ := component.Objects()
+
+			staticClients := dexConfigYAML(resources)["staticClients"].([]interface{})
+			Expect(staticClients).To(HaveLen(3))
+			Expect(staticClients[1]).To(HaveKeyWithValue("trustedPeers", []interface{}{"tigera-manager", "tigera-dashboard"}))
+			Expect(staticClients[2]).NotTo(HaveKey("trustedPeers"))
+		})
+
+		DescribeTable("should require the IDs of the static clients to be unique", func(ids []string, expected []string) {
+			for _, id := range ids {
+				authentication.Spec.StaticClients = append(authentication.Spec.StaticClients, operatorv1.StaticClient{ID: id, Public: true})
+			}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			err := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Validate()
+			if len(expected) == 0 {
+				Expect(err).NotTo(HaveOccurred())
+				return
+			}
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*render.ValidationError).Problems).To(ConsistOf(expected))
+		},
+			Entry("unique IDs", []string{"tigera-cli", "tigera-dashboard"}, nil),
+			Entry("a duplicate ID", []string{"tigera-cli", "tigera-dashboard", "tigera-cli", "tigera-cli"}, []string{"static client ID tigera-cli is not unique"}),
+			Entry("the ID of the Manager", []string{"tigera-cli", render.DexClientId}, []string{"static client ID tigera-manager is reserved for the Manager"}),
+		)
+
+		It("should not allow a static client to trust an unknown peer", func() {
+			authentication.Spec.StaticClients = []operatorv1.StaticClient{
+				{ID: "tigera-cli", Public: true, TrustedPeers: []string{"tigera-dashboard", "tigera-manager"}},
+			}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			err := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*render.ValidationError).Problems).To(ConsistOf(`spec.staticClients[0].trustedPeers[0]: Not found: "tigera-dashboard"`))
+		})
+
+		It("should pass validation when all inputs are present", func() {
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Validate()).NotTo(HaveOccurred())
+		})
+
+		It("should render the same objects in the same order regardless of the order of the inputs", func() {
+			otherPullSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "other-pull-secret",
+					Namespace: rmeta.OperatorNamespace(),
+				},
+				TypeMeta: metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
+			}
+
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			first, _ := render.Dex(render.DexConfiguration{PullSecrets: []*corev1.Secret{pullSecrets[0], otherPullSecret}, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			// Build the second config from copies of the secrets, so that none of their data is shared with the first.
+			dexCfg = render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret.DeepCopy(), dexSecret.DeepCopy(), idpSecret.DeepCopy(), nil, clusterName)
+			second, _ := render.Dex(render.DexConfiguration{PullSecrets: []*corev1.Secret{otherPullSecret, pullSecrets[0]}, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			Expect(second).To(Equal(first))
+		})
+
+		It("should render byte-identical objects on repeated calls", func() {
+			var clientSecrets []*corev1.Secret
+			for _, name := range []string{"cli-b-secret", "cli-a-secret"} {
+				clientSecrets = append(clientSecrets, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: rmeta.OperatorNamespace()},
+					TypeMeta:   metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
+					Data:       map[string][]byte{render.ClientSecretSecretField: []byte(name)},
+				})
+			}
+			authentication.Spec.StaticClients = []operatorv1.StaticClient{
+				{ID: "cli-b", SecretName: "cli-b-secret"},
+				{ID: "cli-a", SecretName: "cli-a-secret"},
+			}
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{PodLabels: map[string]string{"team": "auth", "tier": "identity"}}
+
+			renderYAML := func(secrets []*corev1.Secret) []byte {
+				dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, secrets, clusterName)
+				objs, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+				var out []byte
+				for _, obj := range objs {
+					b, err := yaml.Marshal(obj)
+					Expect(err).NotTo(HaveOccurred())
+					out = append(out, b...)
+				}
+				return out
+			}
+
+			first := renderYAML(clientSecrets)
+			for i := 0; i < 10; i++ {
+				Expect(renderYAML(clientSecrets)).To(Equal(first))
+			}
+			Expect(renderYAML([]*corev1.Secret{clientSecrets[1], clientSecrets[0]})).To(Equal(first))
+		})
+
+		It("should sort the monitoring objects in with the other objects", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{MetricsService: true, PrometheusRule: &operatorv1.DexPrometheusRule{}}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			objs, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			var keys []string
+			for _, obj := range objs {
+				keys = append(keys, fmt.Sprintf("%s %s/%s", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetNamespace(), obj.GetName()))
+			}
+			// The workloads and the monitoring objects come last, sorted by kind and name.
+			Expect(keys[len(keys)-5:]).To(Equal([]string{
+				"Deployment tigera-dex/tigera-dex",
+				"PrometheusRule tigera-prometheus/tigera-dex",
+				"Service tigera-dex/tigera-dex",
+				"Service tigera-dex/tigera-dex-metrics",
+				"ServiceMonitor tigera-prometheus/tigera-dex",
+			}))
+		})
+
+		It("should report every problem with the inputs", func() {
+			authentication.Spec.ManagerDomain = ""
+			delete(idpSecret.Data, render.ClientSecretSecretField)
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, nil, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+
+			err := component.Validate()
+			Expect(err).To(HaveOccurred())
+			validationErr, ok := err.(*render.ValidationError)
+			Expect(ok).To(BeTrue())
+			Expect(validationErr.Component).To(Equal(render.DexObjectName))
+			Expect(validationErr.Problems).To(ConsistOf(
+				"manager domain is not set",
+				"tigera-dex secret is missing",
+				"secret tigera-operator/tigera-oidc-credentials is missing field clientSecret",
+			))
+		})
+
+		It("should be ready when all prerequisites are present", func() {
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Ready()).To(BeTrue())
+			Expect(component.(render.ReadinessReporter).NotReadyReason()).To(BeEmpty())
+		})
+
+		It("should not be ready while a secret is missing or incomplete", func() {
+			delete(tlsSecret.Data, corev1.TLSPrivateKeyKey)
+			authentication.Spec.StaticClients = []operatorv1.StaticClient{{ID: "tigera-cli", SecretName: "tigera-cli-secret"}}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, nil, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+
+			Expect(component.Ready()).To(BeFalse())
+			Expect(render.NotReadyReason(component)).To(Equal(
+				"secret tigera-operator/tigera-dex-tls is missing field tls.key; " +
+					"identity provider secret is missing; " +
+					"tigera-cli-secret secret is missing"))
+		})
+
+		It("should not be ready without a manager domain", func() {
+			authentication.Spec.ManagerDomain = ""
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+
+			Expect(component.Ready()).To(BeFalse())
+			Expect(render.NotReadyReason(component)).To(Equal("manager domain is not set"))
+		})
+
+		It("should add the recommended labels to every object without changing the selector", func() {
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			expectedLabels := map[string]string{
+				"app.kubernetes.io/name":       render.DexObjectName,
+				"app.kubernetes.io/instance":   render.DexObjectName,
+				"app.kubernetes.io/component":  "identity-provider",
+				"app.kubernetes.io/part-of":    "calico",
+				"app.kubernetes.io/managed-by": "tigera-operator",
+			}
+			for _, resource := range resources {
+				for k, v := range expectedLabels {
+					Expect(resource.GetLabels()).To(HaveKeyWithValue(k, v), "%s %s", resource.GetObjectKind().GroupVersionKind().Kind, resource.GetName())
+				}
+			}
+
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Selector.MatchLabels).To(Equal(map[string]string{"k8s-app": render.DexObjectName}))
+			Expect(d.Spec.Template.Labels).To(HaveKeyWithValue("k8s-app", render.DexObjectName))
+			Expect(d.Spec.Template.Labels).To(HaveKeyWithValue("app.kubernetes.io/name", render.DexObjectName))
+		})
+
+		DescribeTable("should select the endpoints of the probes", func(livenessProbe *operatorv1.DexProbeEndpoint, expectedLiveness, expectedReadiness string) {
+			if livenessProbe != nil {
+				authentication.Spec.DexDeployment = &operatorv1.DexDeployment{LivenessProbe: livenessProbe}
+			}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			container := d.Spec.Template.Spec.Containers[0]
+			Expect(container.LivenessProbe.HTTPGet.Path).To(Equal(expectedLiveness))
+			if expectedReadiness == "" {
+				Expect(container.ReadinessProbe).To(BeNil())
+			} else {
+				Expect(container.ReadinessProbe.HTTPGet.Path).To(Equal(expectedReadiness))
+			}
+		},
+			Entry("default", nil, "/dex/.well-known/openid-configuration", ""),
+			Entry("discovery", probeEndpoint(operatorv1.DexProbeEndpointDiscovery), "/dex/.well-known/openid-configuration", ""),
+			Entry("healthz", probeEndpoint(operatorv1.DexProbeEndpointHealthz), "/dex/healthz", "/dex/.well-known/openid-configuration"),
+		)
+
+		DescribeTable("should follow the well-known path prefix in the probes", func(prefix string, livenessProbe *operatorv1.DexProbeEndpoint, expectedLiveness, expectedReadiness string) {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{LivenessProbe: livenessProbe, WellKnownPathPrefix: prefix}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			resources, _ := component.Objects()
+
+			d := rtest.GetResource(resources, dexCfg.Name(), dexCfg.Namespace(), "apps", "v1", "Deployment").(*appsv1.Deployment)
+			container := d.Spec.Template.Spec.Containers[0]
+			Expect(container.LivenessProbe.HTTPGet.Path).To(Equal(expectedLiveness))
+			if expectedReadiness == "" {
+				Expect(container.ReadinessProbe).To(BeNil())
+			} else {
+				Expect(container.ReadinessProbe.HTTPGet.Path).To(Equal(expectedReadiness))
+			}
+		},
+			Entry("prefix", "/proxy/dex", nil, "/proxy/dex/.well-known/openid-configuration", ""),
+			Entry("prefix with a trailing slash", "/proxy/", nil, "/proxy/.well-known/openid-configuration", ""),
+			Entry("prefix with healthz", "/proxy/dex", probeEndpoint(operatorv1.DexProbeEndpointHealthz), "/dex/healthz", "/proxy/dex/.well-known/openid-configuration"),
+		)
+
+		DescribeTable("should reject a well-known path prefix that is not a path", func(prefix string) {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{WellKnownPathPrefix: prefix}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			err := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*render.ValidationError).Problems).To(ConsistOf(fmt.Sprintf("the well-known path prefix %q must be a path that starts with a slash", prefix)))
+		},
+			Entry("relative", "proxy/dex"),
+			Entry("URL", "https://example.com/dex"),
+			Entry("query", "/proxy?dex"),
+		)
+
+		DescribeTable("should delay the shutdown of dex with a preStop hook", func(sleep *int32, grace *int64, expectedCommand []string, expectedGrace int64) {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{PreStopSleepSeconds: sleep, TerminationGracePeriodSeconds: grace}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Validate()).NotTo(HaveOccurred())
+			resources, _ := component.Objects()
+
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(*d.Spec.Template.Spec.TerminationGracePeriodSeconds).To(Equal(expectedGrace))
+			lifecycle := d.Spec.Template.Spec.Containers[0].Lifecycle
+			if expectedCommand == nil {
+				Expect(lifecycle).To(BeNil())
+			} else {
+				Expect(lifecycle.PreStop.Exec.Command).To(Equal(expectedCommand))
+			}
+		},
+			Entry("default", nil, nil, []string{"sleep", "5"}, int64(30)),
+			Entry("configured", ptr.Int32ToPtr(20), ptr.Int64ToPtr(60), []string{"sleep", "20"}, int64(60)),
+			Entry("disabled", ptr.Int32ToPtr(0), ptr.Int64ToPtr(1), nil, int64(1)),
+		)
+
+		DescribeTable("should require the preStop sleep to be less than the termination grace period", func(sleep *int32, grace *int64, expected string) {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{PreStopSleepSeconds: sleep, TerminationGracePeriodSeconds: grace}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			err := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*render.ValidationError).Problems).To(ConsistOf(expected))
+		},
+			Entry("equal", ptr.Int32ToPtr(30), nil, "the preStop sleep of 30s must be less than the termination grace period of 30s"),
+			Entry("longer", nil, ptr.Int64ToPtr(3), "the preStop sleep of 5s must be less than the termination grace period of 3s"),
+		)
+
+		DescribeTable("should match the scheme of the probes to the web listener", func(termination *operatorv1.DexTLSTermination, listener string, scheme corev1.URIScheme) {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{
+				LivenessProbe:  probeEndpoint(operatorv1.DexProbeEndpointHealthz),
+				TLSTermination: termination,
+			}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			web := dexConfigYAML(resources)["web"].(map[interface{}]interface{})
+			Expect(web).To(HaveKeyWithValue(listener, "0.0.0.0:5556"))
+			Expect(web).To(HaveLen(map[string]int{"https": 5, "http": 3}[listener]))
+
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			container := d.Spec.Template.Spec.Containers[0]
+			Expect(container.Ports).To(Equal([]corev1.ContainerPort{{Name: listener, ContainerPort: render.DexPort}}))
+			Expect(container.LivenessProbe.HTTPGet.Scheme).To(Equal(scheme))
+			Expect(container.ReadinessProbe.HTTPGet.Scheme).To(Equal(scheme))
+		},
+			Entry("default", nil, "https", corev1.URISchemeHTTPS),
+			Entry("terminated by dex", tlsTermination(operatorv1.DexTLSTerminationDex), "https", corev1.URISchemeHTTPS),
+			Entry("terminated upstream", tlsTermination(operatorv1.DexTLSTerminationUpstream), "http", corev1.URISchemeHTTP),
+		)
+
+		It("should pin the images to the digests of the ImageSet", func() {
+			digest := "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
 			installation.CertificateManagement = &operatorv1.CertificateManagement{}
-			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, clusterName)
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{
+				PullSecrets:  pullSecrets,
+				Installation: installation,
+				DexConfig:    dexCfg,
+			})
+			Expect(component.ResolveImages(&operatorv1.ImageSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "enterprise-" + components.EnterpriseRelease},
+				Spec: operatorv1.ImageSetSpec{Images: []operatorv1.Image{
+					{Image: components.ComponentDex.Image, Digest: digest},
+					{Image: components.ComponentCSRInitContainer.Image, Digest: digest},
+				}},
+			})).To(Succeed())
 
-			component := render.Dex(pullSecrets, false, installation, dexCfg, clusterName)
 			resources, _ := component.Objects()
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.Containers[0].Image).To(Equal("testregistry.com/" + components.ComponentDex.Image + "@" + digest))
+			Expect(d.Spec.Template.Spec.InitContainers[0].Image).To(Equal("testregistry.com/" + components.ComponentCSRInitContainer.Image + "@" + digest))
+		})
 
-			expectedResources := []struct {
-				name    string
-				ns      string
-				group   string
-				version string
-				kind    string
-			}{
-				{render.DexObjectName, render.DexNamespace, "", "v1", "ServiceAccount"},
-				{render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment"},
-				{render.DexObjectName, render.DexNamespace, "", "v1", "Service"},
-				{render.DexObjectName, "", rbac, "v1", "ClusterRole"},
-				{render.DexObjectName, "", rbac, "v1", "ClusterRoleBinding"},
-				{render.DexObjectName, render.DexNamespace, "", "v1", "ConfigMap"},
-				{render.DexTLSSecretName, rmeta.OperatorNamespace(), "", "v1", "Secret"},
-				{render.DexObjectName, rmeta.OperatorNamespace(), "", "v1", "Secret"},
-				{render.OIDCSecretName, rmeta.OperatorNamespace(), "", "v1", "Secret"},
-				{render.DexCertSecretName, rmeta.OperatorNamespace(), "", "v1", "Secret"},
-				{render.DexTLSSecretName, render.DexNamespace, "", "v1", "Secret"},
-				{render.DexObjectName, render.DexNamespace, "", "v1", "Secret"},
-				{render.OIDCSecretName, render.DexNamespace, "", "v1", "Secret"},
-				{pullSecretName, render.DexNamespace, "", "v1", "Secret"},
-				{"tigera-dex:csr-creator", "", "rbac.authorization.k8s.io", "v1", "ClusterRoleBinding"},
+		DescribeTable("should pass how long the CSR init container waits for the certificate only when it is configured", func(timeoutSeconds *int32, expected string) {
+			installation.CertificateManagement = &operatorv1.CertificateManagement{}
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{CSRTimeoutSeconds: timeoutSeconds}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+
+			resources, _ := component.Objects()
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.InitContainers).To(HaveLen(1))
+			if expected == "" {
+				for _, env := range d.Spec.Template.Spec.InitContainers[0].Env {
+					Expect(env.Name).NotTo(Equal("TIMEOUT"))
+				}
+				return
 			}
+			rtest.ExpectEnv(d.Spec.Template.Spec.InitContainers[0].Env, "TIMEOUT", expected)
+		},
+			Entry("default", nil, ""),
+			Entry("configured", ptr.Int32ToPtr(600), "10m0s"),
+		)
 
-			for i, expectedRes := range expectedResources {
-				rtest.ExpectResource(resources[i], expectedRes.name, expectedRes.ns, expectedRes.group, expectedRes.version, expectedRes.kind)
+		DescribeTable("should reject digests that cannot pin the images", func(image, digest, msg string) {
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{
+				PullSecrets:  pullSecrets,
+				Installation: installation,
+				DexConfig:    dexCfg,
+			})
+			err := component.ResolveImages(&operatorv1.ImageSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "enterprise-test"},
+				Spec:       operatorv1.ImageSetSpec{Images: []operatorv1.Image{{Image: image, Digest: digest}}},
+			})
+			Expect(err).To(MatchError(ContainSubstring(msg)))
+		},
+			Entry("invalid digest", "tigera/dex", "sha256:dexhash",
+				`ImageSet enterprise-test: the digest of image tigera/dex is invalid: "sha256:dexhash" is not a sha256 digest`),
+			Entry("wrong image path", "calico/dex", "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+				"ImageSet enterprise-test: image calico/dex has a digest, but the image of the component is tigera/dex"),
+		)
+
+		DescribeTable("should pull the images from the locations of the image overrides", func(overrides map[string]operatorv1.DexImageOverride, pinned bool, expectedImage, expectedInitImage string) {
+			digest := "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+			installation.CertificateManagement = &operatorv1.CertificateManagement{}
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{ImageOverrides: overrides}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Validate()).To(Succeed())
+
+			var is *operatorv1.ImageSet
+			if pinned {
+				is = &operatorv1.ImageSet{
+					ObjectMeta: metav1.ObjectMeta{Name: "enterprise-" + components.EnterpriseRelease},
+					Spec: operatorv1.ImageSetSpec{Images: []operatorv1.Image{
+						{Image: components.ComponentDex.Image, Digest: digest},
+						{Image: components.ComponentCSRInitContainer.Image, Digest: digest},
+					}},
+				}
+				expectedImage += "@" + digest
+				expectedInitImage += "@" + digest
+			} else {
+				expectedImage += ":" + components.ComponentDex.Version
+				expectedInitImage += ":" + components.ComponentCSRInitContainer.Version
 			}
-			Expect(len(resources)).To(Equal(len(expectedResources)))
+			Expect(component.ResolveImages(is)).To(Succeed())
+
+			resources, _ := component.Objects()
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.Containers[0].Image).To(Equal(expectedImage))
+			Expect(d.Spec.Template.Spec.InitContainers[0].Image).To(Equal(expectedInitImage))
+		},
+			Entry("no overrides", nil, false,
+				"testregistry.com/tigera/dex", "testregistry.com/tigera/key-cert-provisioner"),
+			Entry("dex registry", map[string]operatorv1.DexImageOverride{"dex": {Registry: "scanned.example.com"}}, false,
+				"scanned.example.com/tigera/dex", "testregistry.com/tigera/key-cert-provisioner"),
+			Entry("registry and image paths", map[string]operatorv1.DexImageOverride{
+				"dex":                  {Registry: "scanned.example.com/", ImagePath: "security"},
+				"key-cert-provisioner": {ImagePath: "mirror"},
+			}, false,
+				"scanned.example.com/security/dex", "testregistry.com/mirror/key-cert-provisioner"),
+			Entry("dex registry with digests", map[string]operatorv1.DexImageOverride{"dex": {Registry: "scanned.example.com/"}}, true,
+				"scanned.example.com/tigera/dex", "testregistry.com/tigera/key-cert-provisioner"),
+		)
+
+		DescribeTable("should require digests for the images when configured", func(images []string, expectedErr string) {
+			digest := "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+			required := operatorv1.DexImageDigestsRequired
+			installation.CertificateManagement = &operatorv1.CertificateManagement{}
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{ImageDigests: &required}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+
+			var is *operatorv1.ImageSet
+			if images != nil {
+				is = &operatorv1.ImageSet{ObjectMeta: metav1.ObjectMeta{Name: "enterprise-" + components.EnterpriseRelease}}
+				for _, image := range images {
+					is.Spec.Images = append(is.Spec.Images, operatorv1.Image{Image: image, Digest: digest})
+				}
+			}
+			err := component.ResolveImages(is)
+			if expectedErr != "" {
+				Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+
+			resources, _ := component.Objects()
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.Containers[0].Image).To(Equal("testregistry.com/" + components.ComponentDex.Image + "@" + digest))
+			Expect(d.Spec.Template.Spec.InitContainers[0].Image).To(Equal("testregistry.com/" + components.ComponentCSRInitContainer.Image + "@" + digest))
+		},
+			Entry("complete ImageSet", []string{components.ComponentDex.Image, components.ComponentCSRInitContainer.Image}, ""),
+			Entry("no ImageSet", nil,
+				"the image tigera/dex must be pinned by digest, but there is no ImageSet for the release "+components.EnterpriseRelease),
+			Entry("no digest for dex", []string{components.ComponentCSRInitContainer.Image},
+				"ImageSet did not contain image "+components.ComponentDex.Image),
+			Entry("no digest for the CSR init container", []string{components.ComponentDex.Image},
+				"ImageSet did not contain image "+components.ComponentCSRInitContainer.Image),
+		)
+
+		It("should report the images that cannot be resolved as image errors", func() {
+			required := operatorv1.DexImageDigestsRequired
+			installation.CertificateManagement = &operatorv1.CertificateManagement{}
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{
+				ImageDigests:   &required,
+				ImageOverrides: map[string]operatorv1.DexImageOverride{"dex": {Registry: "scanned.example.com/"}},
+			}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+
+			var imageErrs components.ImageErrors
+			Expect(errors.As(component.ResolveImages(nil), &imageErrs)).To(BeTrue())
+			Expect(imageErrs).To(HaveLen(2))
+			Expect(imageErrs[0].Component).To(Equal(components.ComponentDex.Image))
+			Expect(imageErrs[0].Reference).To(Equal("scanned.example.com/tigera/dex"))
+			Expect(imageErrs[1].Component).To(Equal(components.ComponentCSRInitContainer.Image))
+			Expect(imageErrs[1].Reference).To(Equal("testregistry.com/tigera/key-cert-provisioner"))
+
+			err := component.ResolveImages(&operatorv1.ImageSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "enterprise-" + components.EnterpriseRelease},
+				Spec: operatorv1.ImageSetSpec{Images: []operatorv1.Image{{
+					Image:  components.ComponentDex.Image,
+					Digest: "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+				}}},
+			})
+			Expect(err).To(MatchError("ImageSet did not contain image " + components.ComponentCSRInitContainer.Image))
+			Expect(errors.As(err, &imageErrs)).To(BeTrue())
+			Expect(imageErrs).To(HaveLen(1))
+			Expect(imageErrs[0].Component).To(Equal(components.ComponentCSRInitContainer.Image))
 		})
-	})
-})
+
+		It("should flatten the image paths when configured", func() {
+			installation.CertificateManagement = &operatorv1.CertificateManagement{}
+			installation.ImagePath = "mirror"
+			installation.ImagePathFormat = operatorv1.ImagePathFormatFlatten
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.ResolveImages(nil)).To(Succeed())
+
+			resources, _ := component.Objects()
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.Containers[0].Image).To(Equal("testregistry.com/mirror/tigera-dex:" + components.ComponentDex.Version))
+			Expect(d.Spec.Template.Spec.InitContainers[0].Image).To(Equal("testregistry.com/mirror/tigera-key-cert-provisioner:" + components.ComponentCSRInitContainer.Version))
+		})
+
+		It("should pull the images in the configured variant", func() {
+			installation.ImageVariant = operatorv1.ImageVariantFIPS
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.ResolveImages(nil)).To(Succeed())
+
+			resources, _ := component.Objects()
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.Containers[0].Image).To(Equal("testregistry.com/tigera/dex:" + components.ComponentDex.Version + "-fips"))
+
+			installation.CertificateManagement = &operatorv1.CertificateManagement{}
+			dexCfg = render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component = render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.ResolveImages(nil)).To(Succeed())
+			resources, _ = component.Objects()
+			d = rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.InitContainers[0].Image).To(Equal("testregistry.com/tigera/key-cert-provisioner:" + components.ComponentCSRInitContainer.Version + "-fips"))
+		})
+
+		It("should reject image overrides for images that dex does not use", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{ImageOverrides: map[string]operatorv1.DexImageOverride{
+				"tigera/dex": {Registry: "scanned.example.com/"},
+			}}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			err := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*render.ValidationError).Problems).To(ConsistOf(`image override "tigera/dex" does not name an image of Dex, expected dex or key-cert-provisioner`))
+		})
+
+		DescribeTable("should render the same objects with the legacy constructor", func(openshift, certificateManagement bool) {
+			if certificateManagement {
+				installation.CertificateManagement = &operatorv1.CertificateManagement{}
+			}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+
+			objs, objsToDelete := render.Dex(render.DexConfiguration{
+				PullSecrets:  pullSecrets,
+				Openshift:    openshift,
+				Installation: installation,
+				DexConfig:    dexCfg,
+			}).Objects()
+			legacyObjs, legacyObjsToDelete := render.DexWithArgs(pullSecrets, openshift, installation, dexCfg, clusterName).Objects()
+			Expect(legacyObjs).To(Equal(objs))
+			Expect(legacyObjsToDelete).To(Equal(objsToDelete))
+		},
+			Entry("default", false, false),
+			Entry("openshift", true, false),
+			Entry("certificate management", false, true),
+		)
+
+		It("should render the optional deployment settings", func() {
+			resources := corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+			}
+			affinity := &corev1.Affinity{
+				PodAntiAffinity: &corev1.PodAntiAffinity{
+					PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{{
+						Weight: 100,
+						PodAffinityTerm: corev1.PodAffinityTerm{
+							LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"k8s-app": render.DexObjectName}},
+							TopologyKey:   "kubernetes.io/hostname",
+						},
+					}},
+				},
+			}
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{CoLocateWithManager: true}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			objs, _ := render.Dex(render.DexConfiguration{
+				PullSecrets:  pullSecrets,
+				Installation: installation,
+				DexConfig:    dexCfg,
+				Replicas:     ptr.Int32ToPtr(3),
+				Resources:    &resources,
+				Affinity:     affinity,
+			}).Objects()
+
+			d := rtest.GetResource(objs, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(*d.Spec.Replicas).To(Equal(int32(3)))
+			Expect(d.Spec.Template.Spec.Containers[0].Resources).To(Equal(resources))
+			Expect(d.Spec.Template.Spec.Affinity).To(Equal(affinity))
+		})
+
+		It("should default the optional deployment settings", func() {
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			objs, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			d := rtest.GetResource(objs, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(*d.Spec.Replicas).To(Equal(int32(1)))
+			Expect(d.Spec.Template.Spec.Containers[0].Resources).To(Equal(corev1.ResourceRequirements{}))
+			Expect(d.Spec.Template.Spec.Affinity).To(BeNil())
+		})
+
+		DescribeTable("should require an ordered apply when the dex pod needs objects of the component to start", func(certificateManagement bool, crds *operatorv1.DexStorageCRDs, externalHost string, expected bool) {
+			if certificateManagement {
+				installation.CertificateManagement = &operatorv1.CertificateManagement{}
+			}
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{StorageCRDs: crds, ExternalHost: externalHost}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.(render.ComponentWithOrderedApply).RequiresOrderedApply()).To(Equal(expected))
+		},
+			Entry("without certificate management", false, nil, "", false),
+			Entry("with certificate management", true, nil, "", true),
+			Entry("with the storage CRDs applied by the operator", false, storageCRDs(operatorv1.DexStorageCRDsOperator), "", true),
+			Entry("with certificate management for an external dex", true, nil, "dex.example.com", false),
+		)
+
+		It("should render a kubeconfig user for kubectl that follows the issuer and the certificate of dex", func() {
+			authentication.Spec.StaticClients = []operatorv1.StaticClient{{ID: "kubectl", Public: true, RedirectURIs: []string{"http://localhost:8000"}}}
+			authentication.Spec.KubectlConfig = &operatorv1.KubectlConfig{ClientID: "kubectl", Namespace: "kube-public", Scopes: []string{"openid", "email", "groups"}}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Validate()).NotTo(HaveOccurred())
+			resources, _ := component.Objects()
+
+			cm := rtest.GetResource(resources, "tigera-dex-kubectl", "kube-public", "", "v1", "ConfigMap").(*corev1.ConfigMap)
+			Expect(cm.Labels).To(HaveKeyWithValue(render.DexKubectlConfigLabel, "tigera-dex"))
+			Expect(cm.Data).To(HaveKeyWithValue("issuer", "https://example.com/dex"))
+			Expect(cm.Data).To(HaveKeyWithValue("client-id", "kubectl"))
+			Expect(cm.Data).To(HaveKeyWithValue("ca.crt", string(tlsSecret.Data[corev1.TLSCertKey])))
+
+			var kubeconfig struct {
+				Users []struct {
+					Name string
+					User struct {
+						Exec struct {
+							APIVersion string `yaml:"apiVersion"`
+							Command    string
+							Args       []string
+						}
+					}
+				}
+			}
+			Expect(yaml.Unmarshal([]byte(cm.Data["user.yaml"]), &kubeconfig)).To(Succeed())
+			Expect(kubeconfig.Users).To(HaveLen(1))
+			Expect(kubeconfig.Users[0].User.Exec.Command).To(Equal("kubectl"))
+			Expect(kubeconfig.Users[0].User.Exec.Args).To(Equal([]string{
+				"oidc-login",
+				"get-token",
+				"--oidc-issuer-url=https://example.com/dex",
+				"--oidc-client-id=kubectl",
+				"--oidc-extra-scope=email",
+				"--oidc-extra-scope=groups",
+				"--certificate-authority-data=" + base64.StdEncoding.EncodeToString(tlsSecret.Data[corev1.TLSCertKey]),
+			}))
+
+			// A new certificate and manager domain are picked up by the next render.
+			authentication.Spec.ManagerDomain = "https://other.example.com"
+			renewed := render.CreateDexTLSSecret("tigera-dex.tigera-dex.svc.cluster.local", nil)
+			dexCfg = render.NewDexConfig(installation.CertificateManagement, authentication, renewed, dexSecret, idpSecret, nil, clusterName)
+			resources, _ = render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+			cm = rtest.GetResource(resources, "tigera-dex-kubectl", "kube-public", "", "v1", "ConfigMap").(*corev1.ConfigMap)
+			Expect(cm.Data).To(HaveKeyWithValue("issuer", "https://other.example.com/dex"))
+			Expect(cm.Data).To(HaveKeyWithValue("ca.crt", string(renewed.Data[corev1.TLSCertKey])))
+		})
+
+		It("should remove the kubeconfig users for kubectl that are no longer configured", func() {
+			existing := func(namespace, instance string) corev1.ConfigMap {
+				return corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+					Name:      instance + "-kubectl",
+					Namespace: namespace,
+					Labels:    map[string]string{render.DexKubectlConfigLabel: instance},
+				}}
+			}
+			configMaps := []corev1.ConfigMap{existing("kube-public", "tigera-dex"), existing("tigera-dex", "tigera-dex"), existing("kube-public", "tigera-dex-us-east")}
+
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName, render.WithKubectlConfigMaps(configMaps))
+			resources, objsToDelete := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+			Expect(rtest.GetResource(resources, "tigera-dex-kubectl", "tigera-dex", "", "v1", "ConfigMap")).To(BeNil())
+			Expect(rtest.GetResource(objsToDelete, "tigera-dex-kubectl", "kube-public", "", "v1", "ConfigMap")).NotTo(BeNil())
+			Expect(rtest.GetResource(objsToDelete, "tigera-dex-kubectl", "tigera-dex", "", "v1", "ConfigMap")).NotTo(BeNil())
+			Expect(rtest.GetResource(objsToDelete, "tigera-dex-us-east-kubectl", "kube-public", "", "v1", "ConfigMap")).To(BeNil())
+
+			// The ConfigMap in the configured namespace is kept.
+			authentication.Spec.StaticClients = []operatorv1.StaticClient{{ID: "kubectl", Public: true}}
+			authentication.Spec.KubectlConfig = &operatorv1.KubectlConfig{ClientID: "kubectl"}
+			dexCfg = render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName, render.WithKubectlConfigMaps(configMaps))
+			resources, objsToDelete = render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+			Expect(rtest.GetResource(resources, "tigera-dex-kubectl", "tigera-dex", "", "v1", "ConfigMap")).NotTo(BeNil())
+			Expect(rtest.GetResource(objsToDelete, "tigera-dex-kubectl", "tigera-dex", "", "v1", "ConfigMap")).To(BeNil())
+			Expect(rtest.GetResource(objsToDelete, "tigera-dex-kubectl", "kube-public", "", "v1", "ConfigMap")).NotTo(BeNil())
+		})
+
+		It("should let the service CA of openshift issue the certificate of dex", func() {
+			installation.CertificateManagement = &operatorv1.CertificateManagement{CACert: []byte("csr-ca")}
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{ServiceServingCertificate: true}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName, render.WithServiceCA([]byte("service-ca")))
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Openshift: true, Installation: installation, DexConfig: dexCfg})
+			Expect(component.(render.ComponentWithOrderedApply).RequiresOrderedApply()).To(BeFalse())
+			resources, objsToDelete := component.Objects()
+
+			svc := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "", "v1", "Service").(*corev1.Service)
+			Expect(svc.Annotations).To(HaveKeyWithValue("service.beta.openshift.io/serving-cert-secret-name", "tigera-dex-serving-cert"))
+			cm := rtest.GetResource(resources, "tigera-dex-service-ca", render.DexNamespace, "", "v1", "ConfigMap").(*corev1.ConfigMap)
+			Expect(cm.Annotations).To(HaveKeyWithValue("service.beta.openshift.io/inject-cabundle", "true"))
+			Expect(cm.Data).To(BeEmpty())
+			Expect(rtest.GetResource(objsToDelete, "tigera-dex-service-ca", render.DexNamespace, "", "v1", "ConfigMap")).To(BeNil())
+			Expect(rtest.GetResource(resources, "tigera-dex:csr-creator", "", "rbac.authorization.k8s.io", "v1", "ClusterRoleBinding")).To(BeNil())
+
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.InitContainers).To(BeEmpty())
+			var tls *corev1.Volume
+			for i := range d.Spec.Template.Spec.Volumes {
+				if d.Spec.Template.Spec.Volumes[i].Name == "tls" {
+					tls = &d.Spec.Template.Spec.Volumes[i]
+				}
+			}
+			Expect(tls).NotTo(BeNil())
+			Expect(tls.Secret).NotTo(BeNil())
+			Expect(tls.Secret.SecretName).To(Equal("tigera-dex-serving-cert"))
+
+			// The clients of dex trust the service CA.
+			certSecret := rtest.GetResource(resources, render.DexCertSecretName, rmeta.OperatorNamespace(), "", "v1", "Secret").(*corev1.Secret)
+			Expect(certSecret.Data).To(HaveKeyWithValue(corev1.TLSCertKey, []byte("service-ca")))
+		})
+
+		It("should only use the serving certificates of openshift on openshift", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{ServiceServingCertificate: true}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, objsToDelete := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+			svc := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "", "v1", "Service").(*corev1.Service)
+			Expect(svc.Annotations).NotTo(HaveKey("service.beta.openshift.io/serving-cert-secret-name"))
+			Expect(rtest.GetResource(resources, "tigera-dex-service-ca", render.DexNamespace, "", "v1", "ConfigMap")).To(BeNil())
+			Expect(rtest.GetResource(objsToDelete, "tigera-dex-service-ca", render.DexNamespace, "", "v1", "ConfigMap")).To(BeNil())
+
+			// On openshift, the ConfigMap of the service CA is removed when the serving certificate is turned off.
+			authentication.Spec.DexDeployment = nil
+			dexCfg = render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, objsToDelete = render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Openshift: true, Installation: installation, DexConfig: dexCfg}).Objects()
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			for _, v := range d.Spec.Template.Spec.Volumes {
+				if v.Name == "tls" {
+					Expect(v.Secret.SecretName).To(Equal(render.DexTLSSecretName))
+				}
+			}
+			Expect(rtest.GetResource(objsToDelete, "tigera-dex-service-ca", render.DexNamespace, "", "v1", "ConfigMap")).NotTo(BeNil())
+		})
+
+		It("should publish the discovery details of dex with the values that the key validators use", func() {
+			authentication.Spec.UsernamePrefix = "oidc:"
+			authentication.Spec.DiscoveryNamespaces = []string{"tigera-compliance", "monitoring"}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Validate()).NotTo(HaveOccurred())
+			resources, _ := component.Objects()
+
+			env := map[string]string{}
+			for _, e := range render.NewDexKeyValidatorConfig(authentication, tlsSecret, clusterName).RequiredEnv("") {
+				env[e.Name] = e.Value
+			}
+			var published int
+			for _, obj := range resources {
+				if cm, ok := obj.(*corev1.ConfigMap); ok && cm.Name == "tigera-dex-oidc-info" {
+					published++
+				}
+			}
+			Expect(published).To(Equal(2))
+			for _, namespace := range []string{"tigera-compliance", "monitoring"} {
+				cm := rtest.GetResource(resources, "tigera-dex-oidc-info", namespace, "", "v1", "ConfigMap").(*corev1.ConfigMap)
+				Expect(cm.Labels).To(HaveKeyWithValue(render.DexDiscoveryLabel, "tigera-dex"))
+				Expect(cm.Data).To(Equal(map[string]string{
+					"issuer":          env["DEX_ISSUER"],
+					"url":             env["DEX_URL"],
+					"jwks-uri":        env["DEX_JWKS_URL"],
+					"client-id":       env["DEX_CLIENT_ID"],
+					"username-claim":  env["DEX_USERNAME_CLAIM"],
+					"groups-claim":    env["DEX_GROUPS_CLAIM"],
+					"username-prefix": "oidc:",
+					"groups-prefix":   "",
+					"ca.crt":          string(tlsSecret.Data[corev1.TLSCertKey]),
+				}))
+				Expect(cm.Data).To(HaveKeyWithValue("issuer", "https://example.com/dex"))
+			}
+
+			// The copies are removed from the namespaces that are no longer configured.
+			authentication.Spec.DiscoveryNamespaces = []string{"monitoring"}
+			copies := []corev1.ConfigMap{}
+			for _, namespace := range []string{"tigera-compliance", "monitoring"} {
+				copies = append(copies, corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+					Name:      "tigera-dex-oidc-info",
+					Namespace: namespace,
+					Labels:    map[string]string{render.DexDiscoveryLabel: "tigera-dex"},
+				}})
+			}
+			dexCfg = render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName, render.WithDiscoveryConfigMapCopies(copies))
+			resources, objsToDelete := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+			Expect(rtest.GetResource(resources, "tigera-dex-oidc-info", "monitoring", "", "v1", "ConfigMap")).NotTo(BeNil())
+			Expect(rtest.GetResource(objsToDelete, "tigera-dex-oidc-info", "monitoring", "", "v1", "ConfigMap")).To(BeNil())
+			Expect(rtest.GetResource(objsToDelete, "tigera-dex-oidc-info", "tigera-compliance", "", "v1", "ConfigMap")).NotTo(BeNil())
+		})
+
+		It("should only let kubectl log in as a public static client", func() {
+			authentication.Spec.StaticClients = []operatorv1.StaticClient{{ID: "kubectl", SecretName: "kubectl-secret"}}
+			authentication.Spec.KubectlConfig = &operatorv1.KubectlConfig{ClientID: "kubectl"}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			Expect(dexCfg.Validate()).To(MatchError(ContainSubstring(`spec.kubectlConfig.clientID: Invalid value: "kubectl": must be the ID of a public static client`)))
+		})
+
+		It("should render the response headers into the web config", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{ResponseHeaders: map[string]string{
+				"Content-Security-Policy": "default-src 'self'",
+				"X-Frame-Options":         "DENY",
+			}}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Validate()).NotTo(HaveOccurred())
+			resources, _ := component.Objects()
+
+			web := dexConfigYAML(resources)["web"].(map[interface{}]interface{})
+			Expect(web).To(HaveKeyWithValue("headers", map[interface{}]interface{}{
+				"Content-Security-Policy": "default-src 'self'",
+				"X-Frame-Options":         "DENY",
+			}))
+		})
+
+		It("should not render headers into the web config without response headers", func() {
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			Expect(dexConfigYAML(resources)["web"]).NotTo(HaveKey("headers"))
+		})
+
+		It("should reject the response headers that Dex does not support", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{ResponseHeaders: map[string]string{
+				"X-Frame-Options": "DENY",
+				"X-Custom-Header": "value",
+				"x-frame-options": "DENY",
+				"X Frame":         "DENY",
+			}}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			err := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Validate()
+			Expect(err).To(HaveOccurred())
+			expected := "Content-Security-Policy, Strict-Transport-Security, X-Content-Type-Options, X-Frame-Options, X-XSS-Protection"
+			Expect(err.(*render.ValidationError).Problems).To(Equal([]string{
+				`response header "X Frame" is not supported by Dex, expected one of ` + expected,
+				`response header "X-Custom-Header" is not supported by Dex, expected one of ` + expected,
+				`response header "x-frame-options" is not supported by Dex, expected one of ` + expected,
+			}))
+		})
+
+		DescribeTable("should set the termination message policy on each container", func(policy *corev1.TerminationMessagePolicy, expected corev1.TerminationMessagePolicy) {
+			installation.CertificateManagement = &operatorv1.CertificateManagement{}
+			if policy != nil {
+				authentication.Spec.DexDeployment = &operatorv1.DexDeployment{TerminationMessagePolicy: policy}
+			}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.InitContainers).To(HaveLen(1))
+			Expect(d.Spec.Template.Spec.InitContainers[0].TerminationMessagePolicy).To(Equal(expected))
+			Expect(d.Spec.Template.Spec.Containers).To(HaveLen(1))
+			Expect(d.Spec.Template.Spec.Containers[0].TerminationMessagePolicy).To(Equal(expected))
+		},
+			Entry("default", nil, corev1.TerminationMessageFallbackToLogsOnError),
+			Entry("file", terminationMessagePolicy(corev1.TerminationMessageReadFile), corev1.TerminationMessageReadFile),
+		)
+
+		It("should require the CSR init image only with certificate management", func() {
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.(render.ComponentWithImages).RequiredImages()).To(Equal([]string{components.ComponentDex.Image}))
+
+			installation.CertificateManagement = &operatorv1.CertificateManagement{}
+			component = render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.(render.ComponentWithImages).RequiredImages()).To(Equal([]string{
+				components.ComponentDex.Image,
+				components.ComponentCSRInitContainer.Image,
+			}))
+		})
+
+		It("should depend on the secrets that the Dex pod uses", func() {
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+
+			var deps []string
+			for _, dep := range component.(render.ComponentWithDependencies).Dependencies() {
+				Expect(dep).To(BeAssignableToTypeOf(&corev1.Secret{}))
+				Expect(dep.GetNamespace()).To(Equal(render.DexNamespace))
+				deps = append(deps, dep.GetName())
+			}
+			Expect(deps).To(ContainElements(render.DexTLSSecretName, render.OIDCSecretName))
+		})
+
+		It("should add the pod labels to the pod template but not to the selector", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{PodLabels: map[string]string{"sidecar.istio.io/inject": "true"}}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Validate()).NotTo(HaveOccurred())
+			resources, _ := component.Objects()
+
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Labels).To(HaveKeyWithValue("sidecar.istio.io/inject", "true"))
+			Expect(d.Spec.Template.Labels).To(HaveKeyWithValue("k8s-app", render.DexObjectName))
+			Expect(d.Spec.Selector.MatchLabels).To(Equal(map[string]string{"k8s-app": render.DexObjectName}))
+			Expect(d.Labels).NotTo(HaveKey("sidecar.istio.io/inject"))
+		})
+
+		It("should not share any of the host namespaces", func() {
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Validate()).NotTo(HaveOccurred())
+			resources, _ := component.Objects()
+
+			spec := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment).Spec.Template.Spec
+			Expect(spec.HostNetwork).To(BeFalse())
+			Expect(spec.HostPID).To(BeFalse())
+			Expect(spec.HostIPC).To(BeFalse())
+		})
+
+		It("should merge the pod annotations with the required annotations", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{PodAnnotations: map[string]string{
+				"sidecar.istio.io/inject":                   "false",
+				"hash.operator.tigera.io/tigera-dex-config": "user-provided",
+			}}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Annotations).To(HaveKeyWithValue("sidecar.istio.io/inject", "false"))
+			for k, v := range dexCfg.RequiredAnnotations() {
+				Expect(d.Spec.Template.Annotations).To(HaveKeyWithValue(k, v))
+			}
+			Expect(d.Spec.Template.Annotations["hash.operator.tigera.io/tigera-dex-config"]).NotTo(Equal("user-provided"))
+			Expect(d.Spec.Template.Annotations).To(HaveKey("hash.operator.tigera.io/tigera-dex-configmaps"))
+			Expect(d.Spec.Template.Annotations).To(HaveLen(len(dexCfg.RequiredAnnotations()) + 2))
+		})
+
+		It("should restart dex when one of the ConfigMaps with its config changes", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{SplitConnectorConfig: true}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			base := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "", "v1", "ConfigMap").(*corev1.ConfigMap)
+			connectors := rtest.GetResource(resources, render.DexObjectName+"-connectors", render.DexNamespace, "", "v1", "ConfigMap").(*corev1.ConfigMap)
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Annotations).To(HaveKeyWithValue("hash.operator.tigera.io/tigera-dex-configmaps", configmap.AnnotationHash(base, connectors)))
+
+			authentication.Spec.DexDeployment.ResponseHeaders = map[string]string{"X-Frame-Options": "DENY"}
+			dexCfg = render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, _ = render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+			changed := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(changed.Spec.Template.Annotations["hash.operator.tigera.io/tigera-dex-configmaps"]).NotTo(Equal(d.Spec.Template.Annotations["hash.operator.tigera.io/tigera-dex-configmaps"]))
+		})
+
+		DescribeTable("should disable service mesh injection when configured", func(disable bool, podAnnotations, expected map[string]string) {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{DisableServiceMeshInjection: disable, PodAnnotations: podAnnotations}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			for k, v := range dexCfg.RequiredAnnotations() {
+				expected[k] = v
+			}
+			expected["hash.operator.tigera.io/tigera-dex-configmaps"] = d.Spec.Template.Annotations["hash.operator.tigera.io/tigera-dex-configmaps"]
+			Expect(d.Spec.Template.Annotations).To(Equal(expected))
+		},
+			Entry("default", false, nil, map[string]string{}),
+			Entry("disabled", true, nil, map[string]string{
+				"sidecar.istio.io/inject": "false",
+				"linkerd.io/inject":       "disabled",
+			}),
+			Entry("disabled with pod annotations", true, map[string]string{"linkerd.io/inject": "enabled"}, map[string]string{
+				"sidecar.istio.io/inject": "false",
+				"linkerd.io/inject":       "enabled",
+			}),
+		)
+
+		DescribeTable("should share the process namespace of the pod when configured", func(share *bool) {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{ShareProcessNamespace: share}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Validate()).To(Succeed())
+
+			resources, _ := component.Objects()
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.ShareProcessNamespace).To(Equal(share))
+			Expect(d.Spec.Template.Spec.HostPID).To(BeFalse())
+		},
+			Entry("default", nil),
+			Entry("enabled", ptr.BoolToPtr(true)),
+			Entry("disabled", ptr.BoolToPtr(false)),
+		)
+
+		DescribeTable("should render the progress deadline of the deployment", func(deadline *int32, expected int32, problem string) {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{ProgressDeadlineSeconds: deadline}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+
+			err := component.Validate()
+			if problem == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*render.ValidationError).Problems).To(ConsistOf(problem))
+			}
+			resources, _ := component.Objects()
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.ProgressDeadlineSeconds).To(Equal(ptr.Int32ToPtr(expected)))
+		},
+			Entry("default", nil, int32(600), ""),
+			Entry("configured", ptr.Int32ToPtr(1800), int32(1800), ""),
+			Entry("just beyond the startup of Dex", ptr.Int32ToPtr(121), int32(121), ""),
+			Entry("within the startup of Dex", ptr.Int32ToPtr(120), int32(120),
+				"the progress deadline of 120s must exceed the 120s that the probes of Dex allow for its startup"),
+		)
+
+		DescribeTable("should render the minimum ready seconds of the deployment", func(minReady, deadline *int32, expected int32, problem string) {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{MinReadySeconds: minReady, ProgressDeadlineSeconds: deadline}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+
+			err := component.Validate()
+			if problem == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*render.ValidationError).Problems).To(ConsistOf(problem))
+			}
+			resources, _ := component.Objects()
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.MinReadySeconds).To(Equal(expected))
+			Expect(d.Spec.Strategy.Type).To(Equal(appsv1.RecreateDeploymentStrategyType))
+		},
+			Entry("default", nil, nil, int32(0), ""),
+			Entry("configured", ptr.Int32ToPtr(10), nil, int32(10), ""),
+			Entry("negative", ptr.Int32ToPtr(-1), nil, int32(-1), "the minimum ready seconds of -1 may not be negative"),
+			Entry("within the progress deadline", ptr.Int32ToPtr(599), nil, int32(599), ""),
+			Entry("at the progress deadline", ptr.Int32ToPtr(600), nil, int32(600),
+				"the minimum ready seconds of 600 must be less than the progress deadline of 600s"),
+			Entry("beyond a configured progress deadline", ptr.Int32ToPtr(300), ptr.Int32ToPtr(180), int32(300),
+				"the minimum ready seconds of 300 must be less than the progress deadline of 180s"),
+		)
+
+		It("should render the connectors in a ConfigMap of their own when the connector config is split", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{SplitConnectorConfig: true}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, objsToDelete := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			base := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "", "v1", "ConfigMap").(*corev1.ConfigMap)
+			connectors := rtest.GetResource(resources, render.DexObjectName+"-connectors", render.DexNamespace, "", "v1", "ConfigMap").(*corev1.ConfigMap)
+			Expect(rtest.GetResource(objsToDelete, render.DexObjectName+"-connectors", render.DexNamespace, "", "v1", "ConfigMap")).To(BeNil())
+
+			var baseData, connectorsData map[string]interface{}
+			Expect(yaml.Unmarshal([]byte(base.Data["config.yaml"]), &baseData)).To(Succeed())
+			Expect(yaml.Unmarshal([]byte(connectors.Data["connectors.yaml"]), &connectorsData)).To(Succeed())
+			Expect(baseData).NotTo(HaveKey("connectors"))
+			Expect(baseData).To(HaveKey("staticClients"))
+			Expect(connectorsData).To(HaveLen(1))
+			Expect(connectorsData["connectors"]).To(HaveLen(1))
+
+			// The concatenation that the init container writes is the config that Dex serves.
+			var merged map[string]interface{}
+			Expect(yaml.Unmarshal([]byte(base.Data["config.yaml"]+connectors.Data["connectors.yaml"]), &merged)).To(Succeed())
+			authentication.Spec.DexDeployment = nil
+			dexCfg = render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			unsplit, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+			Expect(merged).To(Equal(dexConfigYAML(unsplit)))
+
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.Volumes).To(ContainElements(
+				corev1.Volume{
+					Name: "config",
+					VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+						LocalObjectReference: corev1.LocalObjectReference{Name: render.DexObjectName},
+						Items:                []corev1.KeyToPath{{Key: "config.yaml", Path: "config.yaml"}},
+					}},
+				},
+				corev1.Volume{
+					Name: "connectors",
+					VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+						LocalObjectReference: corev1.LocalObjectReference{Name: render.DexObjectName + "-connectors"},
+						Items:                []corev1.KeyToPath{{Key: "connectors.yaml", Path: "connectors.yaml"}},
+					}},
+				},
+				corev1.Volume{Name: "merged-config", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+			))
+
+			Expect(d.Spec.Template.Spec.InitContainers).To(HaveLen(1))
+			merge := d.Spec.Template.Spec.InitContainers[0]
+			Expect(merge.Name).To(Equal("merge-config"))
+			Expect(merge.Image).To(Equal(d.Spec.Template.Spec.Containers[0].Image))
+			Expect(merge.Command).To(Equal([]string{"/bin/sh", "-c", "cat /etc/dex/baseCfg/config.yaml /etc/dex/connectors/connectors.yaml > /etc/dex/cfg/config.yaml"}))
+			Expect(merge.VolumeMounts).To(ConsistOf(
+				corev1.VolumeMount{Name: "config", MountPath: "/etc/dex/baseCfg", ReadOnly: true},
+				corev1.VolumeMount{Name: "connectors", MountPath: "/etc/dex/connectors", ReadOnly: true},
+				corev1.VolumeMount{Name: "merged-config", MountPath: "/etc/dex/cfg"},
+			))
+			Expect(merge.SecurityContext).NotTo(BeNil())
+
+			dex := d.Spec.Template.Spec.Containers[0]
+			Expect(dex.Command).To(Equal([]string{"/usr/local/bin/dex", "serve", "/etc/dex/cfg/config.yaml"}))
+			Expect(dex.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "merged-config", MountPath: "/etc/dex/cfg", ReadOnly: true}))
+		})
+
+		It("should delete the connectors ConfigMap when the connector config is not split", func() {
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, objsToDelete := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			Expect(rtest.GetResource(resources, render.DexObjectName+"-connectors", render.DexNamespace, "", "v1", "ConfigMap")).To(BeNil())
+			Expect(rtest.GetResource(objsToDelete, render.DexObjectName+"-connectors", render.DexNamespace, "", "v1", "ConfigMap")).NotTo(BeNil())
+
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.InitContainers).To(BeEmpty())
+			Expect(d.Spec.Template.Spec.Containers[0].Command).To(Equal([]string{"/usr/local/bin/dex", "serve", "/etc/dex/baseCfg/config.yaml"}))
+			Expect(dexConfigYAML(resources)).To(HaveKey("connectors"))
+		})
+
+		It("should not allow the pod labels to replace the selector label", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{PodLabels: map[string]string{"k8s-app": "other"}}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+
+			err := component.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*render.ValidationError).Problems).To(ConsistOf("pod labels may not replace the k8s-app label of the deployment selector"))
+			resources, _ := component.Objects()
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Labels).To(HaveKeyWithValue("k8s-app", render.DexObjectName))
+		})
+
+		It("should not set an affinity by default", func() {
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.Affinity).To(BeNil())
+		})
+
+		It("should prefer the nodes of the manager when co-located with it", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{CoLocateWithManager: true}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.Affinity).To(Equal(&corev1.Affinity{
+				PodAffinity: &corev1.PodAffinity{
+					PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{{
+						Weight: 100,
+						PodAffinityTerm: corev1.PodAffinityTerm{
+							LabelSelector: &metav1.LabelSelector{
+								MatchLabels: map[string]string{"k8s-app": "tigera-manager"},
+							},
+							Namespaces:  []string{"tigera-manager"},
+							TopologyKey: "kubernetes.io/hostname",
+						},
+					}},
+				},
+			}))
+		})
+
+		DescribeTable("should prefer the nodes that are not spot instances", func(spot *operatorv1.DexSpotNodes, expected corev1.PreferredSchedulingTerm) {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{AvoidSpotNodes: spot}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.Affinity).To(Equal(&corev1.Affinity{
+				NodeAffinity: &corev1.NodeAffinity{
+					PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{expected},
+				},
+			}))
+		},
+			Entry("with the value of the label", &operatorv1.DexSpotNodes{Key: "eks.amazonaws.com/capacityType", Value: "SPOT"}, corev1.PreferredSchedulingTerm{
+				Weight: 100,
+				Preference: corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{
+					{Key: "eks.amazonaws.com/capacityType", Operator: corev1.NodeSelectorOpNotIn, Values: []string{"SPOT"}},
+				}},
+			}),
+			Entry("without a value of the label", &operatorv1.DexSpotNodes{Key: "cloud.google.com/gke-spot", Weight: ptr.Int32ToPtr(50)}, corev1.PreferredSchedulingTerm{
+				Weight: 50,
+				Preference: corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{
+					{Key: "cloud.google.com/gke-spot", Operator: corev1.NodeSelectorOpDoesNotExist},
+				}},
+			}),
+		)
+
+		It("should avoid spot instances and prefer the nodes of the manager together", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{
+				CoLocateWithManager: true,
+				AvoidSpotNodes:      &operatorv1.DexSpotNodes{Key: "cloud.google.com/gke-spot", Value: "true"},
+			}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.Affinity.PodAffinity).NotTo(BeNil())
+			Expect(d.Spec.Template.Spec.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution).To(HaveLen(1))
+		})
+
+		It("should render the affinity of the Authentication instead of the generated one", func() {
+			affinity := &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+					MatchExpressions: []corev1.NodeSelectorRequirement{
+						{Key: "node.kubernetes.io/instance-type", Operator: corev1.NodeSelectorOpIn, Values: []string{"m5.large", "m5.xlarge"}},
+						{Key: "topology.kubernetes.io/zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"us-east-1a"}},
+					},
+				}}},
+			}}
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{
+				CoLocateWithManager: true,
+				AvoidSpotNodes:      &operatorv1.DexSpotNodes{Key: "cloud.google.com/gke-spot", Value: "true"},
+				Affinity:            affinity,
+			}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Validate()).NotTo(HaveOccurred())
+			resources, _ := component.Objects()
+
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.Affinity).To(Equal(affinity))
+		})
+
+		DescribeTable("should reject a required node affinity that excludes the nodes of the control plane node selector", func(terms []corev1.NodeSelectorTerm, valid bool) {
+			installation.ControlPlaneNodeSelector = map[string]string{"node-role.kubernetes.io/control-plane": "true", "pool": "infra"}
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{Affinity: &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: terms},
+			}}}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			err := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Validate()
+			if valid {
+				Expect(err).NotTo(HaveOccurred())
+				return
+			}
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*render.ValidationError).Problems).To(ConsistOf("the required node affinity of Dex excludes the nodes that the control plane node selector of the Installation selects"))
+		},
+			Entry("a term that requires another value", []corev1.NodeSelectorTerm{
+				{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "pool", Operator: corev1.NodeSelectorOpIn, Values: []string{"dex"}}}},
+			}, false),
+			Entry("a term that excludes the value", []corev1.NodeSelectorTerm{
+				{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "pool", Operator: corev1.NodeSelectorOpNotIn, Values: []string{"infra"}}}},
+			}, false),
+			Entry("a term that requires the label not to exist", []corev1.NodeSelectorTerm{
+				{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "node-role.kubernetes.io/control-plane", Operator: corev1.NodeSelectorOpDoesNotExist}}},
+			}, false),
+			Entry("a term that requires the value", []corev1.NodeSelectorTerm{
+				{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "pool", Operator: corev1.NodeSelectorOpIn, Values: []string{"infra", "dex"}}}},
+			}, true),
+			Entry("a term on other labels", []corev1.NodeSelectorTerm{
+				{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "topology.kubernetes.io/zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"us-east-1a"}}}},
+			}, true),
+			Entry("one of the terms satisfiable", []corev1.NodeSelectorTerm{
+				{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "pool", Operator: corev1.NodeSelectorOpIn, Values: []string{"dex"}}}},
+				{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "pool", Operator: corev1.NodeSelectorOpExists}}},
+			}, true),
+		)
+
+		It("should reject an invalid label of spot instances", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{AvoidSpotNodes: &operatorv1.DexSpotNodes{Key: "cloud.google.com/gke spot", Value: "true"}}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			err := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*render.ValidationError).Problems).To(ConsistOf(HavePrefix(`spec.dexDeployment.avoidSpotNodes.key: Invalid value: "cloud.google.com/gke spot"`)))
+		})
+
+		It("should use the ClusterFirst DNS policy by default", func() {
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.DNSPolicy).To(Equal(corev1.DNSClusterFirst))
+			Expect(d.Spec.Template.Spec.DNSConfig).To(BeNil())
+		})
+
+		It("should render the configured DNS policy and config", func() {
+			dnsConfig := &corev1.PodDNSConfig{Nameservers: []string{"10.0.0.10"}, Searches: []string{"corp.example.com"}}
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{DNSPolicy: dnsPolicy(corev1.DNSNone), DNSConfig: dnsConfig}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Validate()).NotTo(HaveOccurred())
+			resources, _ := component.Objects()
+
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.DNSPolicy).To(Equal(corev1.DNSNone))
+			Expect(d.Spec.Template.Spec.DNSConfig).To(Equal(dnsConfig))
+		})
+
+		It("should not allow the None DNS policy without a DNS config", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{DNSPolicy: dnsPolicy(corev1.DNSNone)}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			err := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*render.ValidationError).Problems).To(ConsistOf("DNS policy None requires a DNS config"))
+		})
+
+		It("should harden the init container by default", func() {
+			installation.CertificateManagement = &operatorv1.CertificateManagement{}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.InitContainers[0].SecurityContext).To(Equal(&corev1.SecurityContext{
+				RunAsNonRoot:             ptr.BoolToPtr(true),
+				AllowPrivilegeEscalation: ptr.BoolToPtr(false),
+				Privileged:               ptr.BoolToPtr(false),
+			}))
+			Expect(d.Spec.Template.Spec.InitContainers[0].Resources).To(Equal(corev1.ResourceRequirements{}))
+		})
+
+		It("should apply the init container overrides to the init container only", func() {
+			installation.CertificateManagement = &operatorv1.CertificateManagement{}
+			resources := corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("64Mi")},
+			}
+			securityContext := &corev1.SecurityContext{RunAsUser: ptr.Int64ToPtr(1000), ReadOnlyRootFilesystem: ptr.BoolToPtr(true)}
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{
+				InitContainerResources:       &resources,
+				InitContainerSecurityContext: securityContext,
+			}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			objs, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			d := rtest.GetResource(objs, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.InitContainers[0].Resources).To(Equal(resources))
+			Expect(d.Spec.Template.Spec.InitContainers[0].SecurityContext).To(Equal(securityContext))
+			Expect(d.Spec.Template.Spec.Containers[0].Resources).To(Equal(corev1.ResourceRequirements{}))
+			Expect(d.Spec.Template.Spec.Containers[0].SecurityContext).To(Equal(podsecuritycontext.NewBaseContext()))
+		})
+
+		DescribeTable("should satisfy the configured pod security standard", func(pss *operatorv1.DexPodSecurityStandard, restricted bool) {
+			installation.CertificateManagement = &operatorv1.CertificateManagement{}
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{PodSecurityStandard: pss}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			if restricted {
+				Expect(d.Spec.Template.Spec.SecurityContext).To(Equal(podsecuritycontext.NewRestrictedPodContext()))
+				Expect(d.Spec.Template.Spec.Containers[0].SecurityContext).To(Equal(podsecuritycontext.NewRestrictedContext()))
+				Expect(d.Spec.Template.Spec.InitContainers[0].SecurityContext).To(Equal(podsecuritycontext.NewRestrictedContext()))
+			} else {
+				Expect(d.Spec.Template.Spec.SecurityContext).To(BeNil())
+				Expect(d.Spec.Template.Spec.Containers[0].SecurityContext).To(Equal(podsecuritycontext.NewBaseContext()))
+				Expect(d.Spec.Template.Spec.InitContainers[0].SecurityContext.SeccompProfile).To(BeNil())
+			}
+		},
+			Entry("by default", nil, false),
+			Entry("baseline", podSecurityStandard(operatorv1.DexPodSecurityStandardBaseline), false),
+			Entry("restricted", podSecurityStandard(operatorv1.DexPodSecurityStandardRestricted), true),
+		)
+
+		DescribeTable("should render the session affinity of the service", func(affinity *corev1.ServiceAffinity, timeout *int32, expected corev1.ServiceAffinity, expectedConfig *corev1.SessionAffinityConfig, problem string) {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{SessionAffinity: affinity, SessionAffinityTimeoutSeconds: timeout}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+
+			err := component.Validate()
+			if problem == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*render.ValidationError).Problems).To(ConsistOf(problem))
+			}
+			resources, _ := component.Objects()
+			svc := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "", "v1", "Service").(*corev1.Service)
+			Expect(svc.Spec.SessionAffinity).To(Equal(expected))
+			Expect(svc.Spec.SessionAffinityConfig).To(Equal(expectedConfig))
+		},
+			Entry("default", nil, nil, corev1.ServiceAffinityNone, nil, ""),
+			Entry("none", serviceAffinity(corev1.ServiceAffinityNone), nil, corev1.ServiceAffinityNone, nil, ""),
+			Entry("client IP", serviceAffinity(corev1.ServiceAffinityClientIP), nil, corev1.ServiceAffinityClientIP, nil, ""),
+			Entry("client IP with a timeout", serviceAffinity(corev1.ServiceAffinityClientIP), ptr.Int32ToPtr(600), corev1.ServiceAffinityClientIP,
+				&corev1.SessionAffinityConfig{ClientIP: &corev1.ClientIPConfig{TimeoutSeconds: ptr.Int32ToPtr(600)}}, ""),
+			Entry("a timeout without client IP", nil, ptr.Int32ToPtr(600), corev1.ServiceAffinityNone, nil,
+				"the session affinity timeout can only be set for the ClientIP session affinity"),
+		)
+
+		It("should render the configured labels and annotations of the service account", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{
+				ServiceAccountLabels:      map[string]string{"team": "identity"},
+				ServiceAccountAnnotations: map[string]string{"eks.amazonaws.com/role-arn": "arn:aws:iam::123456789012:role/dex", "vault.hashicorp.com/role": "dex"},
+			}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Validate()).To(Succeed())
+
+			resources, _ := component.Objects()
+			sa := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "", "v1", "ServiceAccount").(*corev1.ServiceAccount)
+			Expect(sa.Labels).To(HaveKeyWithValue("team", "identity"))
+			Expect(sa.Labels).To(HaveKeyWithValue(rmeta.AppNameLabel, render.DexObjectName))
+			Expect(sa.Annotations).To(Equal(map[string]string{
+				"eks.amazonaws.com/role-arn":       "arn:aws:iam::123456789012:role/dex",
+				"vault.hashicorp.com/role":         "dex",
+				rmeta.ManagedAnnotationsAnnotation: "eks.amazonaws.com/role-arn,vault.hashicorp.com/role",
+			}))
+			Expect(authentication.Spec.DexDeployment.ServiceAccountLabels).To(Equal(map[string]string{"team": "identity"}))
+		})
+
+		It("should reject service account labels and annotations that the operator sets", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{
+				ServiceAccountLabels:      map[string]string{rmeta.AppManagedByLabel: "helm"},
+				ServiceAccountAnnotations: map[string]string{rmeta.ManagedAnnotationsAnnotation: "a"},
+			}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			err := dexCfg.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*render.ValidationError).Problems).To(ConsistOf(
+				"service account labels may not replace the app.kubernetes.io/managed-by label that the operator sets",
+				"service account annotations may not set the operator.tigera.io/managed-annotations annotation",
+			))
+		})
+
+		DescribeTable("should publish the not ready addresses of the service", func(publish, expected bool) {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{PublishNotReadyAddresses: publish}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Validate()).To(Succeed())
+
+			resources, _ := component.Objects()
+			svc := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "", "v1", "Service").(*corev1.Service)
+			Expect(svc.Spec.PublishNotReadyAddresses).To(Equal(expected))
+		},
+			Entry("default", false, false),
+			Entry("enabled", true, true),
+		)
+
+		DescribeTable("should run Dex as the configured user and groups", func(pss *operatorv1.DexPodSecurityStandard, runAs *operatorv1.DexRunAs, expectedContainer *corev1.SecurityContext, expectedPod *corev1.PodSecurityContext, problem string) {
+			installation.CertificateManagement = &operatorv1.CertificateManagement{}
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{PodSecurityStandard: pss, RunAs: runAs}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+
+			err := component.Validate()
+			if problem == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*render.ValidationError).Problems).To(ConsistOf(problem))
+				return
+			}
+			resources, _ := component.Objects()
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.SecurityContext).To(Equal(expectedPod))
+			Expect(d.Spec.Template.Spec.Containers[0].SecurityContext).To(Equal(expectedContainer))
+			Expect(d.Spec.Template.Spec.InitContainers[0].SecurityContext.RunAsUser).To(Equal(expectedContainer.RunAsUser))
+			Expect(d.Spec.Template.Spec.InitContainers[0].SecurityContext.RunAsGroup).To(Equal(expectedContainer.RunAsGroup))
+		},
+			Entry("by default", nil, nil, podsecuritycontext.NewBaseContext(), nil, ""),
+			Entry("a user and groups", nil,
+				&operatorv1.DexRunAs{RunAsUser: ptr.Int64ToPtr(1000), RunAsGroup: ptr.Int64ToPtr(2000), FSGroup: ptr.Int64ToPtr(3000)},
+				&corev1.SecurityContext{
+					RunAsUser:                ptr.Int64ToPtr(1000),
+					RunAsGroup:               ptr.Int64ToPtr(2000),
+					RunAsNonRoot:             ptr.BoolToPtr(true),
+					AllowPrivilegeEscalation: ptr.BoolToPtr(false),
+				},
+				&corev1.PodSecurityContext{RunAsUser: ptr.Int64ToPtr(1000), RunAsGroup: ptr.Int64ToPtr(2000), FSGroup: ptr.Int64ToPtr(3000)}, ""),
+			Entry("a user with the restricted pod security standard", podSecurityStandard(operatorv1.DexPodSecurityStandardRestricted),
+				&operatorv1.DexRunAs{RunAsUser: ptr.Int64ToPtr(1000)},
+				podsecuritycontext.NewRestrictedContext(podsecuritycontext.WithRunAsUser(1000)),
+				podsecuritycontext.NewRestrictedPodContext(podsecuritycontext.WithRunAsUser(1000)), ""),
+			Entry("platform assigned IDs with the restricted pod security standard", podSecurityStandard(operatorv1.DexPodSecurityStandardRestricted),
+				&operatorv1.DexRunAs{PlatformAssigned: true},
+				podsecuritycontext.NewRestrictedContext(podsecuritycontext.WithAssignedIDs()),
+				podsecuritycontext.NewRestrictedPodContext(podsecuritycontext.WithAssignedIDs()), ""),
+			Entry("platform assigned IDs with a user", nil,
+				&operatorv1.DexRunAs{PlatformAssigned: true, RunAsUser: ptr.Int64ToPtr(1000)}, nil, nil,
+				"the user and groups of Dex cannot be set when the platform assigns them"),
+		)
+
+		DescribeTable("should render the readiness gates of the pod", func(gates []corev1.PodReadinessGate, problem string) {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{ReadinessGates: gates}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+
+			err := component.Validate()
+			if problem == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*render.ValidationError).Problems).To(HaveLen(1))
+				Expect(err.(*render.ValidationError).Problems[0]).To(HavePrefix(problem))
+			}
+			resources, _ := component.Objects()
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.ReadinessGates).To(Equal(gates))
+		},
+			Entry("none", nil, ""),
+			Entry("a load balancer target group", []corev1.PodReadinessGate{
+				{ConditionType: "target-health.elbv2.k8s.aws/k8s-tigerade-tigerade-0123456789"},
+				{ConditionType: "cloud.google.com/load-balancer-neg-ready"},
+			}, ""),
+			Entry("an invalid condition type", []corev1.PodReadinessGate{{ConditionType: "lb ready"}},
+				`the condition type "lb ready" of a readiness gate is invalid`),
+		)
+
+		It("should keep the init container security context override with the restricted pod security standard", func() {
+			installation.CertificateManagement = &operatorv1.CertificateManagement{}
+			securityContext := &corev1.SecurityContext{RunAsUser: ptr.Int64ToPtr(1000)}
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{
+				InitContainerSecurityContext: securityContext,
+				PodSecurityStandard:          podSecurityStandard(operatorv1.DexPodSecurityStandardRestricted),
+			}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.InitContainers[0].SecurityContext).To(Equal(securityContext))
+			Expect(d.Spec.Template.Spec.Containers[0].SecurityContext).To(Equal(podsecuritycontext.NewRestrictedContext()))
+		})
+
+		It("should compute the SANs of the Dex certificate", func() {
+			Expect(render.DexCertSANs(render.DexNamespace, clusterName, []string{"dex.example.com", "tigera-dex", "tigera-dex.svc", "Not_A_Host"})).To(Equal([]string{
+				"tigera-dex",
+				"tigera-dex.tigera-dex",
+				"tigera-dex.tigera-dex.svc",
+				"tigera-dex.tigera-dex.svc." + clusterName,
+				"tigera-dex.svc",
+				"dex.example.com",
+			}))
+		})
+
+		DescribeTable("should normalize the cluster domain of the SANs and common name of the Dex certificate", func(clusterDomain, expected string) {
+			Expect(render.DexCommonName(render.DexNamespace, clusterDomain)).To(Equal("tigera-dex.tigera-dex.svc." + expected))
+			Expect(render.DexCertSANs(render.DexNamespace, clusterDomain, nil)).To(Equal([]string{
+				"tigera-dex",
+				"tigera-dex.tigera-dex",
+				"tigera-dex.tigera-dex.svc",
+				"tigera-dex.tigera-dex.svc." + expected,
+				"tigera-dex.svc",
+			}))
+
+			// The cluster domain is not detected, so that an empty cluster domain falls back to the default.
+			installation.CertificateManagement = &operatorv1.CertificateManagement{}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterDomain,
+				render.WithClusterDomainDetector(dns.NewClusterDomainDetector("does-not.exist")))
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Validate()).To(Succeed())
+			resources, _ := component.Objects()
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.InitContainers[0].Env).To(ContainElement(
+				corev1.EnvVar{Name: "DNS_NAMES", Value: "tigera-dex,tigera-dex.tigera-dex,tigera-dex.tigera-dex.svc,tigera-dex.tigera-dex.svc." + expected + ",tigera-dex.svc"},
+			))
+		},
+			Entry("empty", "", dns.DefaultClusterDomain),
+			Entry("fully qualified", "cluster.local.", dns.DefaultClusterDomain),
+			Entry("dotted custom", ".custom.internal.", "custom.internal"),
+			Entry("custom", "custom.internal", "custom.internal"),
+		)
+
+		It("should reject an invalid cluster domain", func() {
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, "Custom_Internal")
+			err := dexCfg.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*render.ValidationError).Problems).To(ContainElement(HavePrefix(`the cluster domain "Custom_Internal" is invalid: `)))
+		})
+
+		DescribeTable("should compute the IP SANs of the Dex certificate", func(clusterIP string, extra []string, expected []string) {
+			var ips []string
+			for _, ip := range render.DexCertIPAddresses(clusterIP, extra) {
+				ips = append(ips, ip.String())
+			}
+			Expect(ips).To(Equal(expected))
+		},
+			Entry("not allocated", "", nil, nil),
+			Entry("headless", "None", []string{"192.168.0.10"}, []string{"192.168.0.10"}),
+			Entry("IPv4", "10.96.0.10", []string{"192.168.0.10"}, []string{"10.96.0.10", "192.168.0.10"}),
+			Entry("IPv6", "fd00::10", []string{"FD00:0:0::20", "192.168.0.10"}, []string{"fd00::10", "fd00::20", "192.168.0.10"}),
+			Entry("duplicates", "fd00::10", []string{"fd00:0::10", "10.96.0.10", "10.96.0.10"}, []string{"fd00::10", "10.96.0.10"}),
+			Entry("invalid", "10.96.0.10", []string{"dex.example.com"}, []string{"10.96.0.10"}),
+		)
+
+		It("should include the IP SANs in the self-signed certificate and the certificate signing request", func() {
+			ips := render.DexCertIPAddresses("10.96.0.10", []string{"fd00::10"})
+			selfSigned := render.CreateDexTLSSecret(fmt.Sprintf(render.DexCNPattern, clusterName), nil, ips...)
+			block, _ := pem.Decode(selfSigned.Data[corev1.TLSCertKey])
+			Expect(block).NotTo(BeNil())
+			cert, err := x509.ParseCertificate(block.Bytes)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cert.IPAddresses).To(HaveLen(2))
+			Expect(cert.IPAddresses[0].Equal(ips[0])).To(BeTrue())
+			Expect(cert.IPAddresses[1].Equal(ips[1])).To(BeTrue())
+
+			installation.CertificateManagement = &operatorv1.CertificateManagement{}
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{CertificateIPAddresses: []string{"fd00::10"}}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName,
+				render.WithServiceClusterIP("10.96.0.10"))
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.InitContainers[0].Env).To(ContainElement(corev1.EnvVar{Name: "IP_ADDRESSES", Value: "10.96.0.10,fd00::10"}))
+		})
+
+		It("should not request IP SANs without IP addresses", func() {
+			installation.CertificateManagement = &operatorv1.CertificateManagement{}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			for _, env := range d.Spec.Template.Spec.InitContainers[0].Env {
+				Expect(env.Name).NotTo(Equal("IP_ADDRESSES"))
+			}
+		})
+
+		It("should reject invalid certificate IP addresses", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{CertificateIPAddresses: []string{"10.96.0.10", "10.96.0.300", "fd00::10"}}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			err := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*render.ValidationError).Problems).To(ConsistOf(`certificate IP address "10.96.0.300" is not a valid IP address`))
+		})
+
+		It("should use the same SANs for the self-signed certificate and the certificate signing request", func() {
+			sans := render.DexCertSANs(render.DexNamespace, clusterName, nil)
+
+			selfSigned := render.CreateDexTLSSecret(fmt.Sprintf(render.DexCNPattern, clusterName), sans)
+			block, _ := pem.Decode(selfSigned.Data[corev1.TLSCertKey])
+			Expect(block).NotTo(BeNil())
+			cert, err := x509.ParseCertificate(block.Bytes)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cert.DNSNames).To(Equal(sans))
+
+			installation.CertificateManagement = &operatorv1.CertificateManagement{}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.InitContainers).To(HaveLen(1))
+			Expect(d.Spec.Template.Spec.InitContainers[0].Env).To(ContainElement(corev1.EnvVar{Name: "DNS_NAMES", Value: strings.Join(sans, ",")}))
+		})
+
+		It("should install dex in the configured namespace", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{Namespace: "team-dex"}
+			authentication.Status.DexNamespace = "team-dex"
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Validate()).NotTo(HaveOccurred())
+			toCreate, toDelete := component.Objects()
+			Expect(toDelete).To(HaveLen(4))
+			rtest.ExpectResource(toDelete[0], render.DexObjectName+"-connectors", "team-dex", "", "v1", "ConfigMap")
+			rtest.ExpectResource(toDelete[1], render.DexObjectName+"-metrics", "team-dex", "", "v1", "Service")
+			rtest.ExpectResource(toDelete[2], render.DexObjectName+"-storage", "team-dex", rbac, "v1", "Role")
+			rtest.ExpectResource(toDelete[3], render.DexObjectName+"-storage", "team-dex", rbac, "v1", "RoleBinding")
+
+			for _, obj := range toCreate {
+				if obj.GetNamespace() != "" && obj.GetNamespace() != rmeta.OperatorNamespace() {
+					Expect(obj.GetNamespace()).To(Equal("team-dex"), "%s %s", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName())
+				}
+			}
+			crb := rtest.GetResource(toCreate, render.DexObjectName, "", rbac, "v1", "ClusterRoleBinding").(*rbacv1.ClusterRoleBinding)
+			Expect(crb.Subjects[0].Namespace).To(Equal("team-dex"))
+			Expect(rtest.GetResource(toCreate, render.DexTLSSecretName, "team-dex", "", "v1", "Secret")).NotTo(BeNil())
+		})
+
+		It("should remove the objects from the namespace that dex was moved from", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{Namespace: "team-dex", SplitConnectorConfig: true, MetricsService: true,
+				StorageRBACScope: storageRBACScope(operatorv1.DexStorageRBACScopeNamespace)}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			toCreate, toDelete := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			var moved int
+			for _, obj := range toCreate {
+				if obj.GetNamespace() == "team-dex" {
+					moved++
+				}
+			}
+			Expect(toDelete).To(HaveLen(moved))
+			for _, obj := range toDelete {
+				Expect(obj.GetNamespace()).To(Equal(render.DexNamespace))
+			}
+			Expect(rtest.GetResource(toDelete, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment")).NotTo(BeNil())
+			Expect(rtest.GetResource(toCreate, render.DexObjectName, "team-dex", "apps", "v1", "Deployment")).NotTo(BeNil())
+			Expect(rtest.GetResource(toDelete, render.DexObjectName+"-connectors", render.DexNamespace, "", "v1", "ConfigMap")).NotTo(BeNil())
+			Expect(rtest.GetResource(toDelete, render.DexObjectName+"-storage", render.DexNamespace, rbac, "v1", "Role")).NotTo(BeNil())
+		})
+
+		It("should not install dex in the operator namespace", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{Namespace: rmeta.OperatorNamespace()}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			err := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*render.ValidationError).Problems).To(ConsistOf("Dex may not be installed in the operator namespace tigera-operator"))
+		})
+
+		DescribeTable("should only allow several replicas with a storage that they can share", func(storageType render.DexStorageType, replicas *int32, valid bool) {
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName,
+				render.WithStorage(storageType, nil))
+			err := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg, Replicas: replicas}).Validate()
+			if valid {
+				Expect(err).NotTo(HaveOccurred())
+				return
+			}
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*render.ValidationError).Problems).To(ConsistOf(fmt.Sprintf(
+				"the %s storage of Dex cannot be shared by %d replicas, use 1 replica or the kubernetes or postgres storage", storageType, *replicas)))
+		},
+			Entry("kubernetes with the default replicas", render.DexStorageKubernetes, nil, true),
+			Entry("kubernetes with 3 replicas", render.DexStorageKubernetes, ptr.Int32ToPtr(3), true),
+			Entry("postgres with 3 replicas", render.DexStoragePostgres, ptr.Int32ToPtr(3), true),
+			Entry("sqlite3 with the default replicas", render.DexStorageSQLite3, nil, true),
+			Entry("sqlite3 with 1 replica", render.DexStorageSQLite3, ptr.Int32ToPtr(1), true),
+			Entry("sqlite3 with 2 replicas", render.DexStorageSQLite3, ptr.Int32ToPtr(2), false),
+			Entry("memory with 1 replica", render.DexStorageMemory, ptr.Int32ToPtr(1), true),
+			Entry("memory with 3 replicas", render.DexStorageMemory, ptr.Int32ToPtr(3), false),
+		)
+
+		It("should render the configured storage into the config", func() {
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+			Expect(dexConfigYAML(resources)["storage"]).To(Equal(map[interface{}]interface{}{
+				"type":   "kubernetes",
+				"config": map[interface{}]interface{}{"inCluster": true},
+			}))
+
+			dexCfg = render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName,
+				render.WithStorage(render.DexStorageSQLite3, map[string]interface{}{"file": "/var/dex/dex.db"}))
+			resources, _ = render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+			Expect(dexConfigYAML(resources)["storage"]).To(Equal(map[interface{}]interface{}{
+				"type":   "sqlite3",
+				"config": map[interface{}]interface{}{"file": "/var/dex/dex.db"},
+			}))
+
+			dexCfg = render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName,
+				render.WithStorage(render.DexStorageMemory, nil))
+			resources, _ = render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+			Expect(dexConfigYAML(resources)["storage"]).To(Equal(map[interface{}]interface{}{"type": "memory"}))
+		})
+
+		It("should reject an unsupported storage", func() {
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName,
+				render.WithStorage("etcd", nil))
+			err := dexCfg.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*render.ValidationError).Problems).To(ConsistOf(`the storage type "etcd" of Dex is not supported`))
+		})
+
+		It("should render no cluster-scoped objects when a storage ClusterRole is provided", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{StorageClusterRole: "dex-storage"}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Validate()).NotTo(HaveOccurred())
+			resources, _ := component.Objects()
+
+			for _, obj := range resources {
+				Expect(obj.GetNamespace()).NotTo(BeEmpty(), "%s %s is cluster-scoped", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName())
+			}
+			rb := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, rbac, "v1", "RoleBinding").(*rbacv1.RoleBinding)
+			Expect(rb.RoleRef).To(Equal(rbacv1.RoleRef{APIGroup: rbac, Kind: "ClusterRole", Name: "dex-storage"}))
+			Expect(rb.Subjects).To(Equal([]rbacv1.Subject{{Kind: "ServiceAccount", Name: render.DexObjectName, Namespace: render.DexNamespace}}))
+		})
+
+		It("should label the copied secrets and remove the copies that are no longer needed", func() {
+			copied := map[string]string{secret.CopiedFromLabel: rmeta.OperatorNamespace()}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName,
+				render.WithSecretCopies([]corev1.Secret{
+					{ObjectMeta: metav1.ObjectMeta{Name: pullSecretName, Namespace: render.DexNamespace, Labels: copied}},
+					{ObjectMeta: metav1.ObjectMeta{Name: render.LDAPSecretName, Namespace: render.DexNamespace, Labels: copied}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "user-secret", Namespace: render.DexNamespace}},
+				}))
+			toCreate, toDelete := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			for _, obj := range toCreate {
+				if s, ok := obj.(*corev1.Secret); ok && s.Namespace == render.DexNamespace {
+					Expect(s.Labels).To(HaveKeyWithValue(secret.CopiedFromLabel, rmeta.OperatorNamespace()))
+				}
+			}
+			Expect(toDelete).To(HaveLen(5))
+			rtest.ExpectResource(toDelete[0], render.LDAPSecretName, render.DexNamespace, "", "v1", "Secret")
+			rtest.ExpectResource(toDelete[1], render.DexObjectName+"-connectors", render.DexNamespace, "", "v1", "ConfigMap")
+			rtest.ExpectResource(toDelete[2], render.DexObjectName+"-metrics", render.DexNamespace, "", "v1", "Service")
+			rtest.ExpectResource(toDelete[3], render.DexObjectName+"-storage", render.DexNamespace, rbac, "v1", "Role")
+			rtest.ExpectResource(toDelete[4], render.DexObjectName+"-storage", render.DexNamespace, rbac, "v1", "RoleBinding")
+		})
+
+		It("should only copy the keys of the identity provider secret that dex reads into its namespace", func() {
+			idpSecret.Data["kubeconfig"] = []byte("apiVersion: v1")
+			idpSecret.Data["notes"] = []byte("internal")
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			copied := rtest.GetResource(resources, render.OIDCSecretName, render.DexNamespace, "", "v1", "Secret").(*corev1.Secret)
+			Expect(copied.Data).To(HaveLen(4))
+			for _, key := range []string{"adminEmail", "clientID", "clientSecret", "serviceAccountSecret"} {
+				Expect(copied.Data).To(HaveKey(key))
+			}
+			// The original in the operator namespace keeps all of its keys.
+			original := rtest.GetResource(resources, render.OIDCSecretName, rmeta.OperatorNamespace(), "", "v1", "Secret").(*corev1.Secret)
+			Expect(original.Data).To(HaveKey("kubeconfig"))
+			Expect(original.Data).To(HaveKey("notes"))
+		})
+
+		It("should reject a TLS secret whose key does not belong to its certificate", func() {
+			other := render.CreateDexTLSSecret("other", nil)
+			tlsSecret.Data[corev1.TLSPrivateKeyKey] = other.Data[corev1.TLSPrivateKeyKey]
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			err := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*render.ValidationError).Problems).To(ConsistOf(HavePrefix("the TLS secret tigera-dex-tls is invalid: KeyMismatch")))
+		})
+
+		It("should render a pull secret that is listed twice once", func() {
+			pullSecrets = append(pullSecrets, pullSecrets[0].DeepCopy())
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.ImagePullSecrets).To(Equal([]corev1.LocalObjectReference{{Name: pullSecretName}}))
+			var copies int
+			for _, obj := range resources {
+				if obj.GetName() == pullSecretName && obj.GetNamespace() == render.DexNamespace {
+					copies++
+				}
+			}
+			Expect(copies).To(Equal(1))
+		})
+
+		It("should not render the ClusterRole and ClusterRoleBinding when cluster RBAC is managed externally", func() {
+			installation.CertificateManagement = &operatorv1.CertificateManagement{}
+			disabled := operatorv1.DexRBACDisabled
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{ClusterRBAC: &disabled}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Validate()).NotTo(HaveOccurred())
+			resources, _ := component.Objects()
+
+			Expect(rtest.GetResource(resources, render.DexObjectName, "", rbac, "v1", "ClusterRole")).To(BeNil())
+			Expect(rtest.GetResource(resources, render.DexObjectName, "", rbac, "v1", "ClusterRoleBinding")).To(BeNil())
+			Expect(rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, rbac, "v1", "RoleBinding")).To(BeNil())
+			Expect(rtest.GetResource(resources, "tigera-dex:csr-creator", "", rbac, "v1", "ClusterRoleBinding")).NotTo(BeNil())
+		})
+
+		It("should not render the CSR ClusterRoleBinding when it is managed externally", func() {
+			installation.CertificateManagement = &operatorv1.CertificateManagement{}
+			disabled := operatorv1.DexRBACDisabled
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{CSRClusterRoleBinding: &disabled}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			Expect(rtest.GetResource(resources, "tigera-dex:csr-creator", "", rbac, "v1", "ClusterRoleBinding")).To(BeNil())
+			Expect(rtest.GetResource(resources, render.DexObjectName, "", rbac, "v1", "ClusterRole")).NotTo(BeNil())
+			Expect(rtest.GetResource(resources, render.DexObjectName, "", rbac, "v1", "ClusterRoleBinding")).NotTo(BeNil())
+		})
+
+		It("should render no cluster-scoped objects for certificate management when all cluster RBAC is external", func() {
+			installation.CertificateManagement = &operatorv1.CertificateManagement{}
+			disabled := operatorv1.DexRBACDisabled
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{StorageClusterRole: "dex-storage", CSRClusterRoleBinding: &disabled}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Validate()).NotTo(HaveOccurred())
+			resources, _ := component.Objects()
+
+			for _, obj := range resources {
+				Expect(obj.GetNamespace()).NotTo(BeEmpty(), "%s %s is cluster-scoped", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName())
+			}
+		})
+
+		It("should list the cluster-scoped objects that a namespace-scoped install still requires", func() {
+			installation.CertificateManagement = &operatorv1.CertificateManagement{}
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{StorageClusterRole: "dex-storage"}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+
+			err := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*render.ValidationError).Problems).To(ConsistOf(
+				"the namespace-scoped install still requires the cluster-scoped objects: ClusterRoleBinding tigera-dex:csr-creator"))
+		})
+
+		DescribeTable("should grant dex the rules of its storage", func(rules *operatorv1.DexStorageRules, expected rbacv1.PolicyRule) {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{StorageRules: rules}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			cr := rtest.GetResource(resources, render.DexObjectName, "", rbac, "v1", "ClusterRole").(*rbacv1.ClusterRole)
+			Expect(cr.Rules).To(Equal([]rbacv1.PolicyRule{
+				expected,
+				{APIGroups: []string{"apiextensions.k8s.io"}, Resources: []string{"customresourcedefinitions"}, Verbs: []string{"create"}},
+			}))
+		},
+			Entry("by default", nil, rbacv1.PolicyRule{
+				APIGroups: []string{"dex.coreos.com"},
+				Resources: []string{
+					"authcodes", "authrequests", "connectors", "devicerequests", "devicetokens",
+					"oauth2clients", "offlinesessionses", "passwords", "refreshtokens", "signingkeies",
+				},
+				Verbs: []string{"create", "delete", "get", "list", "update"},
+			}),
+			Entry("for the resources", storageRules(operatorv1.DexStorageRulesResources), rbacv1.PolicyRule{
+				APIGroups: []string{"dex.coreos.com"},
+				Resources: []string{
+					"authcodes", "authrequests", "connectors", "devicerequests", "devicetokens",
+					"oauth2clients", "offlinesessionses", "passwords", "refreshtokens", "signingkeies",
+				},
+				Verbs: []string{"create", "delete", "get", "list", "update"},
+			}),
+			Entry("for all resources", storageRules(operatorv1.DexStorageRulesWildcard), rbacv1.PolicyRule{
+				APIGroups: []string{"dex.coreos.com"},
+				Resources: []string{"*"},
+				Verbs:     []string{"*"},
+			}),
+		)
+
+		It("should render a PrometheusRule with the alerts for dex", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{MetricsService: true, PrometheusRule: &operatorv1.DexPrometheusRule{}}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Validate()).NotTo(HaveOccurred())
+			resources, _ := component.Objects()
+
+			rule := rtest.GetResource(resources, render.DexObjectName, "tigera-prometheus", "monitoring.coreos.com", "v1", "PrometheusRule").(*unstructured.Unstructured)
+			Expect(rule.GetLabels()).To(HaveKeyWithValue("prometheus", "calico-node-prometheus"))
+			Expect(rule.GetLabels()).To(HaveKeyWithValue("role", "tigera-prometheus-rules"))
+			Expect(rule.GetLabels()).To(HaveKeyWithValue(render.DexPrometheusRuleLabel, "tigera-dex"))
+			groups, _, err := unstructured.NestedSlice(rule.Object, "spec", "groups")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(groups).To(HaveLen(1))
+			alerts := map[string]string{}
+			for _, r := range groups[0].(map[string]interface{})["rules"].([]interface{}) {
+				alerts[r.(map[string]interface{})["alert"].(string)] = r.(map[string]interface{})["expr"].(string)
+			}
+			Expect(alerts).To(HaveLen(4))
+			Expect(alerts).To(HaveKeyWithValue("DexUnavailable", `absent(up{namespace="tigera-dex",service="tigera-dex-metrics"} == 1)`))
+			Expect(alerts["DexLoginFailureRateHigh"]).To(ContainSubstring(`service="tigera-dex-metrics"`))
+			Expect(alerts["DexConnectorErrors"]).To(ContainSubstring(`handler=~"/callback.*",code=~"5.."`))
+
+			// The certificate alert fires 30 days before the certificate of the operator expires.
+			notAfter, ok := dexCfg.CertificateExpiry()
+			Expect(ok).To(BeTrue())
+			Expect(alerts).To(HaveKeyWithValue("DexCertificateExpiring", fmt.Sprintf("vector(time()) > %d", notAfter.Add(-30*24*time.Hour).Unix())))
+		})
+
+		It("should render a ServiceMonitor that scrapes the metrics service of dex", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{MetricsService: true, PrometheusRule: &operatorv1.DexPrometheusRule{}}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			monitor := rtest.GetResource(resources, render.DexObjectName, "tigera-prometheus", "monitoring.coreos.com", "v1", "ServiceMonitor").(*unstructured.Unstructured)
+			Expect(monitor.GetLabels()).To(HaveKeyWithValue("team", "network-operators"))
+			svc := rtest.GetResource(resources, "tigera-dex-metrics", render.DexNamespace, "", "v1", "Service").(*corev1.Service)
+
+			namespaces, _, err := unstructured.NestedStringSlice(monitor.Object, "spec", "namespaceSelector", "matchNames")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(namespaces).To(ConsistOf(svc.Namespace))
+			matchLabels, _, err := unstructured.NestedStringMap(monitor.Object, "spec", "selector", "matchLabels")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(matchLabels).NotTo(BeEmpty())
+			Expect(labels.SelectorFromSet(matchLabels).Matches(labels.Set(svc.Labels))).To(BeTrue())
+			endpoints, _, err := unstructured.NestedSlice(monitor.Object, "spec", "endpoints")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(endpoints).To(HaveLen(1))
+			Expect(endpoints[0].(map[string]interface{})["port"]).To(Equal(svc.Spec.Ports[0].Name))
+
+			// The Dex service has the same pods behind it, but the ServiceMonitor must not scrape its TLS port.
+			dexSvc := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "", "v1", "Service").(*corev1.Service)
+			Expect(labels.SelectorFromSet(matchLabels).Matches(labels.Set(dexSvc.Labels))).To(BeFalse())
+		})
+
+		It("should place the PrometheusRule of dex where it is configured and remove it elsewhere", func() {
+			days := int32(7)
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{MetricsService: true, PrometheusRule: &operatorv1.DexPrometheusRule{
+				Namespace:             "monitoring",
+				Labels:                map[string]string{"release": "kube-prometheus"},
+				CertificateExpiryDays: &days,
+			}}
+			existing := func(namespace, instance string) unstructured.Unstructured {
+				rule := unstructured.Unstructured{}
+				rule.SetGroupVersionKind(render.PrometheusRuleGVK)
+				rule.SetName(instance)
+				rule.SetNamespace(namespace)
+				rule.SetLabels(map[string]string{render.DexPrometheusRuleLabel: instance})
+				return rule
+			}
+			rules := []unstructured.Unstructured{existing("tigera-prometheus", "tigera-dex"), existing("monitoring", "tigera-dex"), existing("tigera-prometheus", "tigera-dex-us-east")}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName, render.WithPrometheusRules(rules))
+			resources, objsToDelete := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			rule := rtest.GetResource(resources, render.DexObjectName, "monitoring", "monitoring.coreos.com", "v1", "PrometheusRule").(*unstructured.Unstructured)
+			Expect(rule.GetLabels()).To(HaveKeyWithValue("release", "kube-prometheus"))
+			Expect(rule.GetLabels()).NotTo(HaveKey("prometheus"))
+			Expect(rtest.GetResource(objsToDelete, render.DexObjectName, "tigera-prometheus", "monitoring.coreos.com", "v1", "PrometheusRule")).NotTo(BeNil())
+			Expect(rtest.GetResource(objsToDelete, render.DexObjectName, "tigera-prometheus", "monitoring.coreos.com", "v1", "ServiceMonitor")).NotTo(BeNil())
+			Expect(rtest.GetResource(objsToDelete, render.DexObjectName, "monitoring", "monitoring.coreos.com", "v1", "PrometheusRule")).To(BeNil())
+			monitor := rtest.GetResource(resources, render.DexObjectName, "monitoring", "monitoring.coreos.com", "v1", "ServiceMonitor").(*unstructured.Unstructured)
+			Expect(monitor.GetLabels()).To(HaveKeyWithValue("release", "kube-prometheus"))
+			Expect(rtest.GetResource(objsToDelete, "tigera-dex-us-east", "tigera-prometheus", "monitoring.coreos.com", "v1", "PrometheusRule")).To(BeNil())
+
+			// Without the metrics service, there is no telemetry to alert on.
+			authentication.Spec.DexDeployment.MetricsService = false
+			dexCfg = render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName, render.WithPrometheusRules(rules))
+			Expect(dexCfg.Validate()).To(MatchError(ContainSubstring("spec.dexDeployment.prometheusRule: Invalid value: \"\": requires dexDeployment.metricsService")))
+			resources, objsToDelete = render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+			Expect(rtest.GetResource(resources, render.DexObjectName, "monitoring", "monitoring.coreos.com", "v1", "PrometheusRule")).To(BeNil())
+			Expect(rtest.GetResource(objsToDelete, render.DexObjectName, "monitoring", "monitoring.coreos.com", "v1", "PrometheusRule")).NotTo(BeNil())
+			Expect(rtest.GetResource(objsToDelete, render.DexObjectName, "monitoring", "monitoring.coreos.com", "v1", "ServiceMonitor")).NotTo(BeNil())
+		})
+
+		It("should not alert on the expiry of a certificate that the operator does not know", func() {
+			installation.CertificateManagement = &operatorv1.CertificateManagement{CACert: []byte("ca")}
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{MetricsService: true, PrometheusRule: &operatorv1.DexPrometheusRule{}}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			_, ok := dexCfg.CertificateExpiry()
+			Expect(ok).To(BeFalse())
+			resources, _ := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+			rule := rtest.GetResource(resources, render.DexObjectName, "tigera-prometheus", "monitoring.coreos.com", "v1", "PrometheusRule").(*unstructured.Unstructured)
+			Expect(fmt.Sprint(rule.Object["spec"])).NotTo(ContainSubstring("DexCertificateExpiring"))
+		})
+
+		It("should grant dex access to its storage with a role in its namespace", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{
+				StorageRBACScope: storageRBACScope(operatorv1.DexStorageRBACScopeNamespace),
+				StorageRules:     storageRules(operatorv1.DexStorageRulesWildcard),
+			}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, objsToDelete := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			role := rtest.GetResource(resources, "tigera-dex-storage", render.DexNamespace, rbac, "v1", "Role").(*rbacv1.Role)
+			Expect(role.Rules).To(Equal([]rbacv1.PolicyRule{{APIGroups: []string{"dex.coreos.com"}, Resources: []string{"*"}, Verbs: []string{"*"}}}))
+			rb := rtest.GetResource(resources, "tigera-dex-storage", render.DexNamespace, rbac, "v1", "RoleBinding").(*rbacv1.RoleBinding)
+			Expect(rb.RoleRef).To(Equal(rbacv1.RoleRef{APIGroup: rbac, Kind: "Role", Name: "tigera-dex-storage"}))
+			Expect(rb.Subjects).To(Equal([]rbacv1.Subject{{Kind: "ServiceAccount", Name: render.DexObjectName, Namespace: render.DexNamespace}}))
+			Expect(rtest.GetResource(objsToDelete, "tigera-dex-storage", render.DexNamespace, rbac, "v1", "Role")).To(BeNil())
+
+			// The ClusterRole only allows dex to create the CRDs of the storage.
+			cr := rtest.GetResource(resources, render.DexObjectName, "", rbac, "v1", "ClusterRole").(*rbacv1.ClusterRole)
+			Expect(cr.Rules).To(Equal([]rbacv1.PolicyRule{
+				{APIGroups: []string{"apiextensions.k8s.io"}, Resources: []string{"customresourcedefinitions"}, Verbs: []string{"create"}},
+			}))
+			Expect(rtest.GetResource(resources, render.DexObjectName, "", rbac, "v1", "ClusterRoleBinding")).NotTo(BeNil())
+		})
+
+		It("should not grant dex cluster-wide permissions when its storage is in its namespace and the operator applies the CRDs", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{
+				StorageRBACScope: storageRBACScope(operatorv1.DexStorageRBACScopeNamespace),
+				StorageCRDs:      storageCRDs(operatorv1.DexStorageCRDsOperator),
+			}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, objsToDelete := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+			Expect(rtest.GetResource(resources, "tigera-dex-storage", render.DexNamespace, rbac, "v1", "Role")).NotTo(BeNil())
+			Expect(rtest.GetResource(resources, render.DexObjectName, "", rbac, "v1", "ClusterRole")).To(BeNil())
+			Expect(rtest.GetResource(resources, render.DexObjectName, "", rbac, "v1", "ClusterRoleBinding")).To(BeNil())
+			Expect(rtest.GetResource(objsToDelete, render.DexObjectName, "", rbac, "v1", "ClusterRole")).NotTo(BeNil())
+			Expect(rtest.GetResource(objsToDelete, render.DexObjectName, "", rbac, "v1", "ClusterRoleBinding")).NotTo(BeNil())
+
+			// Without the option, the role is removed again.
+			authentication.Spec.DexDeployment.StorageRBACScope = nil
+			dexCfg = render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, objsToDelete = render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+			Expect(rtest.GetResource(resources, render.DexObjectName, "", rbac, "v1", "ClusterRole")).NotTo(BeNil())
+			Expect(rtest.GetResource(objsToDelete, "tigera-dex-storage", render.DexNamespace, rbac, "v1", "Role")).NotTo(BeNil())
+			Expect(rtest.GetResource(objsToDelete, "tigera-dex-storage", render.DexNamespace, rbac, "v1", "RoleBinding")).NotTo(BeNil())
+		})
+
+		It("should apply the CRDs of the storage instead of dex when the operator manages them", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{StorageCRDs: storageCRDs(operatorv1.DexStorageCRDsOperator)}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Validate()).To(Succeed())
+			resources, _ := component.Objects()
+
+			var crds []string
+			for _, obj := range resources {
+				if crd, ok := obj.(*apiextensionsv1.CustomResourceDefinition); ok {
+					crds = append(crds, crd.Name)
+					Expect(crd.Spec.Group).To(Equal("dex.coreos.com"))
+					Expect(crd.Spec.Scope).To(Equal(apiextensionsv1.NamespaceScoped))
+					Expect(crd.Spec.Names.Plural + ".dex.coreos.com").To(Equal(crd.Name))
+					Expect(crd.Spec.Versions).To(HaveLen(1))
+					Expect(crd.Spec.Versions[0].Name).To(Equal("v1"))
+					Expect(crd.Spec.Versions[0].Storage).To(BeTrue())
+					Expect(*crd.Spec.Versions[0].Schema.OpenAPIV3Schema.XPreserveUnknownFields).To(BeTrue())
+					Expect(crd.Annotations).To(HaveKeyWithValue("operator.tigera.io/dex-version", components.ComponentDex.Version))
+				}
+			}
+			Expect(crds).To(Equal([]string{
+				"authcodes.dex.coreos.com", "authrequests.dex.coreos.com", "connectors.dex.coreos.com",
+				"devicerequests.dex.coreos.com", "devicetokens.dex.coreos.com", "oauth2clients.dex.coreos.com",
+				"offlinesessionses.dex.coreos.com", "passwords.dex.coreos.com", "refreshtokens.dex.coreos.com",
+				"signingkeies.dex.coreos.com",
+			}))
+			rtest.ExpectResource(resources[0], "authcodes.dex.coreos.com", "", "apiextensions.k8s.io", "v1", "CustomResourceDefinition")
+			offlineSessions := rtest.GetResource(resources, "offlinesessionses.dex.coreos.com", "", "apiextensions.k8s.io", "v1", "CustomResourceDefinition").(*apiextensionsv1.CustomResourceDefinition)
+			Expect(offlineSessions.Spec.Names).To(Equal(apiextensionsv1.CustomResourceDefinitionNames{
+				Plural: "offlinesessionses", Singular: "offlinesessions", Kind: "OfflineSessions", ListKind: "OfflineSessionsList",
+			}))
+
+			cr := rtest.GetResource(resources, render.DexObjectName, "", rbac, "v1", "ClusterRole").(*rbacv1.ClusterRole)
+			Expect(cr.Rules).To(HaveLen(1))
+			Expect(cr.Rules[0].APIGroups).To(Equal([]string{"dex.coreos.com"}))
+
+			// The CRDs are kept when the Authentication is deleted.
+			Expect(component.(render.ComponentWithRetainedObjects).RetainedObjects()).To(HaveLen(10))
+		})
+
+		It("should delete the CRDs of the storage with the Authentication when requested", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{StorageCRDs: storageCRDs(operatorv1.DexStorageCRDsOperator), DeleteStorageCRDs: true}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			resources, _ := component.Objects()
+
+			Expect(rtest.GetResource(resources, "authcodes.dex.coreos.com", "", "apiextensions.k8s.io", "v1", "CustomResourceDefinition")).NotTo(BeNil())
+			Expect(component.(render.ComponentWithRetainedObjects).RetainedObjects()).To(BeEmpty())
+		})
+
+		It("should render a separate metrics service for the telemetry endpoint of dex", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{MetricsService: true}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			resources, toDelete := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Objects()
+
+			metrics := rtest.GetResource(resources, render.DexObjectName+"-metrics", render.DexNamespace, "", "v1", "Service").(*corev1.Service)
+			Expect(metrics.Spec.Type).To(Equal(corev1.ServiceTypeClusterIP))
+			Expect(metrics.Spec.Selector).To(Equal(map[string]string{"k8s-app": render.DexObjectName}))
+			Expect(metrics.Spec.Ports).To(Equal([]corev1.ServicePort{
+				{Name: "metrics", Port: 5558, TargetPort: intstr.FromString("metrics"), Protocol: corev1.ProtocolTCP},
+			}))
+			Expect(rtest.GetResource(toDelete, render.DexObjectName+"-metrics", render.DexNamespace, "", "v1", "Service")).To(BeNil())
+
+			// The main service only exposes the web listener of Dex.
+			svc := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "", "v1", "Service").(*corev1.Service)
+			Expect(svc.Spec.Ports).To(HaveLen(1))
+			Expect(svc.Spec.Ports[0].Port).To(BeEquivalentTo(render.DexPort))
+
+			d := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment").(*appsv1.Deployment)
+			Expect(d.Spec.Template.Spec.Containers[0].Ports).To(ContainElement(corev1.ContainerPort{Name: "metrics", ContainerPort: 5558}))
+			Expect(metrics.Spec.Selector).To(Equal(map[string]string{"k8s-app": d.Spec.Template.Labels["k8s-app"]}))
+			cm := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "", "v1", "ConfigMap").(*corev1.ConfigMap)
+			Expect(cm.Data["config.yaml"]).To(ContainSubstring("telemetry:\n  http: 0.0.0.0:5558\n"))
+		})
+
+		It("should only point the service to an external Dex when configured", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{ExternalHost: "dex.example.com", StorageCRDs: storageCRDs(operatorv1.DexStorageCRDsOperator)}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			Expect(component.Validate()).To(Succeed())
+			resources, toDelete := component.Objects()
+
+			Expect(resources).To(HaveLen(2))
+			rtest.ExpectResource(resources[0], render.DexCertSecretName, rmeta.OperatorNamespace(), "", "v1", "Secret")
+			Expect(resources[0].(*corev1.Secret).Data).To(HaveKeyWithValue(corev1.TLSCertKey, tlsSecret.Data[corev1.TLSCertKey]))
+			rtest.ExpectResource(resources[1], render.DexObjectName, render.DexNamespace, "", "v1", "Service")
+			svc := resources[1].(*corev1.Service)
+			Expect(svc.Spec.Type).To(Equal(corev1.ServiceTypeExternalName))
+			Expect(svc.Spec.ExternalName).To(Equal("dex.example.com"))
+			Expect(svc.Spec.Selector).To(BeNil())
+			Expect(component.(render.ComponentWithDependencies).Dependencies()).To(BeEmpty())
+
+			// The objects of a deployed Dex are removed, but not the secrets it was configured with or the CRDs.
+			Expect(rtest.GetResource(toDelete, render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment")).NotTo(BeNil())
+			Expect(rtest.GetResource(toDelete, render.DexObjectName, render.DexNamespace, "", "v1", "ConfigMap")).NotTo(BeNil())
+			Expect(rtest.GetResource(toDelete, render.DexObjectName, "", rbac, "v1", "ClusterRole")).NotTo(BeNil())
+			Expect(rtest.GetResource(toDelete, render.DexObjectName, render.DexNamespace, "", "v1", "Service")).To(BeNil())
+			for _, obj := range toDelete {
+				Expect(obj.GetNamespace()).NotTo(Equal(rmeta.OperatorNamespace()))
+				Expect(obj).NotTo(BeAssignableToTypeOf(&apiextensionsv1.CustomResourceDefinition{}))
+			}
+		})
+
+		It("should reject an external Dex host that is not a host name", func() {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{ExternalHost: "https://dex.example.com"}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+			err := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg}).Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*render.ValidationError).Problems).To(HaveLen(1))
+			Expect(err.(*render.ValidationError).Problems[0]).To(HavePrefix(`the external host "https://dex.example.com" of Dex is not a valid host name`))
+		})
+
+		DescribeTable("should let dex create the CRDs of its storage", func(crds *operatorv1.DexStorageCRDs, opts ...render.DexOption) {
+			authentication.Spec.DexDeployment = &operatorv1.DexDeployment{StorageCRDs: crds}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName, opts...)
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			resources, _ := component.Objects()
+
+			for _, obj := range resources {
+				Expect(obj).NotTo(BeAssignableToTypeOf(&apiextensionsv1.CustomResourceDefinition{}))
+			}
+			Expect(component.(render.ComponentWithRetainedObjects).RetainedObjects()).To(BeEmpty())
+			cr := rtest.GetResource(resources, render.DexObjectName, "", rbac, "v1", "ClusterRole").(*rbacv1.ClusterRole)
+			if crds == nil || *crds == operatorv1.DexStorageCRDsDex {
+				Expect(cr.Rules).To(ContainElement(rbacv1.PolicyRule{
+					APIGroups: []string{"apiextensions.k8s.io"}, Resources: []string{"customresourcedefinitions"}, Verbs: []string{"create"},
+				}))
+			} else {
+				Expect(cr.Rules).To(HaveLen(1))
+			}
+		},
+			Entry("by default", nil),
+			Entry("when dex creates them", storageCRDs(operatorv1.DexStorageCRDsDex)),
+			Entry("unless dex uses the kubernetes storage", storageCRDs(operatorv1.DexStorageCRDsOperator),
+				render.WithStorage(render.DexStoragePostgres, map[string]interface{}{"host": "postgres"})),
+		)
+
+		It("should render all resources for a certificate management", func() {
+			installation.CertificateManagement = &operatorv1.CertificateManagement{}
+			dexCfg := render.NewDexConfig(installation.CertificateManagement, authentication, tlsSecret, dexSecret, idpSecret, nil, clusterName)
+
+			component := render.Dex(render.DexConfiguration{PullSecrets: pullSecrets, Installation: installation, DexConfig: dexCfg})
+			resources, _ := component.Objects()
+
+			expectedResources := []struct {
+				name    string
+				ns      string
+				group   string
+				version string
+				kind    string
+			}{
+				{render.DexObjectName, "", rbac, "v1", "ClusterRole"},
+				{render.DexObjectName, "", rbac, "v1", "ClusterRoleBinding"},
+				{"tigera-dex:csr-creator", "", rbac, "v1", "ClusterRoleBinding"},
+				{render.DexObjectName, render.DexNamespace, "", "v1", "ServiceAccount"},
+				{render.DexObjectName, render.DexNamespace, "", "v1", "ConfigMap"},
+				{render.DexObjectName, render.DexNamespace, "", "v1", "Secret"},
+				{render.DexTLSSecretName, render.DexNamespace, "", "v1", "Secret"},
+				{render.OIDCSecretName, render.DexNamespace, "", "v1", "Secret"},
+				{pullSecretName, render.DexNamespace, "", "v1", "Secret"},
+				{render.DexObjectName, rmeta.OperatorNamespace(), "", "v1", "Secret"},
+				{render.DexTLSSecretName, rmeta.OperatorNamespace(), "", "v1", "Secret"},
+				{render.DexCertSecretName, rmeta.OperatorNamespace(), "", "v1", "Secret"},
+				{render.OIDCSecretName, rmeta.OperatorNamespace(), "", "v1", "Secret"},
+				{render.DexObjectName, render.DexNamespace, "apps", "v1", "Deployment"},
+				{render.DexObjectName, render.DexNamespace, "", "v1", "Service"},
+			}
+
+			for i, expectedRes := range expectedResources {
+				rtest.ExpectResource(resources[i], expectedRes.name, expectedRes.ns, expectedRes.group, expectedRes.version, expectedRes.kind)
+			}
+			Expect(len(resources)).To(Equal(len(expectedResources)))
+		})
+	})
+})
+
+// dexConfigYAML returns the unmarshalled config.yaml of the rendered Dex ConfigMap.
+func dexConfigYAML(resources []client.Object) map[string]interface{} {
+	cm := rtest.GetResource(resources, render.DexObjectName, render.DexNamespace, "", "v1", "ConfigMap").(*corev1.ConfigMap)
+	data := map[string]interface{}{}
+	Expect(yaml.Unmarshal([]byte(cm.Data["config.yaml"]), &data)).To(Succeed())
+	return data
+}
+
+func probeEndpoint(e operatorv1.DexProbeEndpoint) *operatorv1.DexProbeEndpoint {
+	return &e
+}
+
+func storageRules(r operatorv1.DexStorageRules) *operatorv1.DexStorageRules {
+	return &r
+}
+
+func storageCRDs(c operatorv1.DexStorageCRDs) *operatorv1.DexStorageCRDs {
+	return &c
+}
+
+func storageRBACScope(s operatorv1.DexStorageRBACScope) *operatorv1.DexStorageRBACScope {
+	return &s
+}
+
+func podSecurityStandard(s operatorv1.DexPodSecurityStandard) *operatorv1.DexPodSecurityStandard {
+	return &s
+}
+
+func serviceAffinity(a corev1.ServiceAffinity) *corev1.ServiceAffinity {
+	return &a
+}
+
+func terminationMessagePolicy(p corev1.TerminationMessagePolicy) *corev1.TerminationMessagePolicy {
+	return &p
+}
+
+func tlsTermination(t operatorv1.DexTLSTermination) *operatorv1.DexTLSTermination {
+	return &t
+}
+
+func dnsPolicy(p corev1.DNSPolicy) *corev1.DNSPolicy {
+	return &p
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"bytes"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// RedactedSecretValue replaces the data of secrets in exported manifests.
+const RedactedSecretValue = "REDACTED"
+
+// ToYAML returns the objects that the component creates as a multi-document YAML stream that can be applied with
+// kubectl. The data of secrets is replaced with RedactedSecretValue, so that the output can be shared for review.
+func ToYAML(component Component) ([]byte, error) {
+	objsToCreate, _ := component.Objects()
+
+	var buf bytes.Buffer
+	for _, obj := range objsToCreate {
+		if obj.GetObjectKind().GroupVersionKind().Kind == "" {
+			return nil, fmt.Errorf("object %s/%s has no kind", obj.GetNamespace(), obj.GetName())
+		}
+		if s, ok := obj.(*corev1.Secret); ok {
+			obj = redactSecret(s)
+		}
+		b, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s %s/%s: %w", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetNamespace(), obj.GetName(), err)
+		}
+		buf.WriteString("---\n")
+		buf.Write(b)
+	}
+	return buf.Bytes(), nil
+}
+
+func redactSecret(s *corev1.Secret) client.Object {
+	redacted := s.DeepCopy()
+	for k := range redacted.Data {
+		redacted.Data[k] = []byte(RedactedSecretValue)
+	}
+	for k := range redacted.StringData {
+		redacted.StringData[k] = RedactedSecretValue
+	}
+	return redacted
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	operatorv1 "github.com/tigera/operator/api/v1"
+	"github.com/tigera/operator/pkg/render"
+	rmeta "github.com/tigera/operator/pkg/render/common/meta"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+var _ = Describe("Component export tests", func() {
+	var component render.Component
+
+	BeforeEach(func() {
+		installation := &operatorv1.InstallationSpec{KubernetesProvider: operatorv1.ProviderNone}
+		authentication := &operatorv1.Authentication{
+			Spec: operatorv1.AuthenticationSpec{
+				ManagerDomain: "https://example.com",
+				OIDC: &operatorv1.AuthenticationOIDC{
+					IssuerURL:     "https://example.com",
+					UsernameClaim: "email",
+				},
+			},
+		}
+		idpSecret := &corev1.Secret{
+			TypeMeta:   metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: render.OIDCSecretName, Namespace: rmeta.OperatorNamespace()},
+			Data: map[string][]byte{
+				render.ClientIDSecretField:     []byte("a.b.com"),
+				render.ClientSecretSecretField: []byte("my-secret"),
+			},
+		}
+		dexCfg := render.NewDexConfig(nil, authentication, render.CreateDexTLSSecret("tigera-dex", nil), render.CreateDexClientSecret(), idpSecret, nil, "cluster.local")
+		component = render.Dex(render.DexConfiguration{Installation: installation, DexConfig: dexCfg})
+	})
+
+	It("should export every object as a separate YAML document", func() {
+		objs, _ := component.Objects()
+		out, err := render.ToYAML(component)
+		Expect(err).NotTo(HaveOccurred())
+
+		docs := strings.Split(strings.TrimPrefix(string(out), "---\n"), "---\n")
+		Expect(docs).To(HaveLen(len(objs)))
+		for i, doc := range docs {
+			var m map[string]interface{}
+			Expect(yaml.Unmarshal([]byte(doc), &m)).To(Succeed())
+			Expect(m["kind"]).To(Equal(objs[i].GetObjectKind().GroupVersionKind().Kind))
+			Expect(m["apiVersion"]).NotTo(BeEmpty())
+		}
+	})
+
+	It("should export the same YAML every time", func() {
+		first, err := render.ToYAML(component)
+		Expect(err).NotTo(HaveOccurred())
+		second, err := render.ToYAML(component)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(second)).To(Equal(string(first)))
+	})
+
+	It("should redact the data of secrets", func() {
+		out, err := render.ToYAML(component)
+		Expect(err).NotTo(HaveOccurred())
+
+		var secrets int
+		for _, doc := range strings.Split(string(out), "---\n") {
+			var tm metav1.TypeMeta
+			Expect(yaml.Unmarshal([]byte(doc), &tm)).To(Succeed())
+			if tm.Kind != "Secret" {
+				continue
+			}
+			var s corev1.Secret
+			Expect(yaml.Unmarshal([]byte(doc), &s)).To(Succeed())
+			secrets++
+			for k, v := range s.Data {
+				Expect(string(v)).To(Equal(render.RedactedSecretValue), "%s/%s %s", s.Namespace, s.Name, k)
+			}
+		}
+		Expect(secrets).NotTo(BeZero())
+
+		// The component itself is not affected.
+		objs, _ := component.Objects()
+		for _, obj := range objs {
+			if s, ok := obj.(*corev1.Secret); ok && s.Name == render.OIDCSecretName {
+				Expect(string(s.Data[render.ClientSecretSecretField])).To(Equal("my-secret"))
+			}
+		}
+	})
+})
@@ -145,15 +145,16 @@ type fluentdComponent struct {
 func (c *fluentdComponent) ResolveImages(is *operatorv1.ImageSet) error {
 	reg := c.installation.Registry
 	path := c.installation.ImagePath
+	opts := components.ReferenceOptionsFor(c.installation)
 
 	if c.osType == rmeta.OSTypeWindows {
 		var err error
-		c.image, err = components.GetReference(components.ComponentFluentdWindows, reg, path, is)
+		c.image, err = components.GetReference(components.ComponentFluentdWindows, reg, path, is, opts...)
 		return err
 	}
 
 	var err error
-	c.image, err = components.GetReference(components.ComponentFluentd, reg, path, is)
+	c.image, err = components.GetReference(components.ComponentFluentd, reg, path, is, opts...)
 	return err
 }
 
@@ -264,6 +265,10 @@ func (c *fluentdComponent) Ready() bool {
 	return true
 }
 
+func (c *fluentdComponent) Validate() error {
+	return nil
+}
+
 func (c *fluentdComponent) s3CredentialSecret() *corev1.Secret {
 	if c.s3Credential == nil {
 		return nil
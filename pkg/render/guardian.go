@@ -73,8 +73,9 @@ type GuardianComponent struct {
 func (c *GuardianComponent) ResolveImages(is *operatorv1.ImageSet) error {
 	reg := c.installation.Registry
 	path := c.installation.ImagePath
+	opts := components.ReferenceOptionsFor(c.installation)
 	var err error
-	c.image, err = components.GetReference(components.ComponentGuardian, reg, path, is)
+	c.image, err = components.GetReference(components.ComponentGuardian, reg, path, is, opts...)
 	return err
 }
 
@@ -108,6 +109,10 @@ func (c *GuardianComponent) Ready() bool {
 	return true
 }
 
+func (c *GuardianComponent) Validate() error {
+	return nil
+}
+
 func (c *GuardianComponent) service() *corev1.Service {
 	return &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
@@ -99,16 +99,17 @@ type intrusionDetectionComponent struct {
 func (c *intrusionDetectionComponent) ResolveImages(is *operator.ImageSet) error {
 	reg := c.installation.Registry
 	path := c.installation.ImagePath
+	opts := components.ReferenceOptionsFor(c.installation)
 	errMsgs := []string{}
 	var err error
 	if !c.managedCluster {
-		c.jobInstallerImage, err = components.GetReference(components.ComponentElasticTseeInstaller, reg, path, is)
+		c.jobInstallerImage, err = components.GetReference(components.ComponentElasticTseeInstaller, reg, path, is, opts...)
 		if err != nil {
 			errMsgs = append(errMsgs, err.Error())
 		}
 	}
 
-	c.controllerImage, err = components.GetReference(components.ComponentIntrusionDetectionController, reg, path, is)
+	c.controllerImage, err = components.GetReference(components.ComponentIntrusionDetectionController, reg, path, is, opts...)
 	if err != nil {
 		errMsgs = append(errMsgs, err.Error())
 	}
@@ -165,6 +166,10 @@ func (c *intrusionDetectionComponent) Ready() bool {
 	return true
 }
 
+func (c *intrusionDetectionComponent) Validate() error {
+	return nil
+}
+
 func (c *intrusionDetectionComponent) intrusionDetectionElasticsearchJob() *batchv1.Job {
 	podTemplate := relasticsearch.DecorateAnnotations(&v1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
@@ -108,11 +108,12 @@ type kubeControllersComponent struct {
 func (c *kubeControllersComponent) ResolveImages(is *operator.ImageSet) error {
 	reg := c.cr.Registry
 	path := c.cr.ImagePath
+	opts := components.ReferenceOptionsFor(c.cr)
 	var err error
 	if c.cr.Variant == operator.TigeraSecureEnterprise {
-		c.image, err = components.GetReference(components.ComponentTigeraKubeControllers, reg, path, is)
+		c.image, err = components.GetReference(components.ComponentTigeraKubeControllers, reg, path, is, opts...)
 	} else {
-		c.image, err = components.GetReference(components.ComponentCalicoKubeControllers, reg, path, is)
+		c.image, err = components.GetReference(components.ComponentCalicoKubeControllers, reg, path, is, opts...)
 	}
 	return err
 }
@@ -160,6 +161,10 @@ func (c *kubeControllersComponent) Ready() bool {
 	return true
 }
 
+func (c *kubeControllersComponent) Validate() error {
+	return nil
+}
+
 func (c *kubeControllersComponent) controllersServiceAccount() *v1.ServiceAccount {
 	return &v1.ServiceAccount{
 		TypeMeta: metav1.TypeMeta{Kind: "ServiceAccount", APIVersion: "v1"},
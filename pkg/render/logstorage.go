@@ -233,24 +233,25 @@ type elasticsearchComponent struct {
 func (es *elasticsearchComponent) ResolveImages(is *operatorv1.ImageSet) error {
 	reg := es.installation.Registry
 	path := es.installation.ImagePath
+	opts := components.ReferenceOptionsFor(es.installation)
 	var err error
-	es.esImage, err = components.GetReference(components.ComponentElasticsearch, reg, path, is)
+	es.esImage, err = components.GetReference(components.ComponentElasticsearch, reg, path, is, opts...)
 	errMsgs := []string{}
 	if err != nil {
 		errMsgs = append(errMsgs, err.Error())
 	}
 
-	es.esOperatorImage, err = components.GetReference(components.ComponentElasticsearchOperator, reg, path, is)
+	es.esOperatorImage, err = components.GetReference(components.ComponentElasticsearchOperator, reg, path, is, opts...)
 	if err != nil {
 		errMsgs = append(errMsgs, err.Error())
 	}
 
-	es.kibanaImage, err = components.GetReference(components.ComponentKibana, reg, path, is)
+	es.kibanaImage, err = components.GetReference(components.ComponentKibana, reg, path, is, opts...)
 	if err != nil {
 		errMsgs = append(errMsgs, err.Error())
 	}
 
-	es.curatorImage, err = components.GetReference(components.ComponentEsCurator, reg, path, is)
+	es.curatorImage, err = components.GetReference(components.ComponentEsCurator, reg, path, is, opts...)
 	if err != nil {
 		errMsgs = append(errMsgs, err.Error())
 	}
@@ -432,6 +433,10 @@ func (es *elasticsearchComponent) Ready() bool {
 	return true
 }
 
+func (es *elasticsearchComponent) Validate() error {
+	return nil
+}
+
 func (es elasticsearchComponent) elasticsearchExternalService() *corev1.Service {
 	return &corev1.Service{
 		TypeMeta: metav1.TypeMeta{Kind: "Service", APIVersion: "v1"},
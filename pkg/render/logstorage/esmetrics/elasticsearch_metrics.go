@@ -67,8 +67,9 @@ func (e *elasticsearchMetrics) ResolveImages(is *operatorv1.ImageSet) error {
 
 	reg := e.installation.Registry
 	path := e.installation.ImagePath
+	opts := components.ReferenceOptionsFor(e.installation)
 
-	e.esMetricsImage, err = components.GetReference(components.ComponentElasticsearchMetrics, reg, path, is)
+	e.esMetricsImage, err = components.GetReference(components.ComponentElasticsearchMetrics, reg, path, is, opts...)
 
 	return err
 }
@@ -86,6 +87,10 @@ func (e *elasticsearchMetrics) Ready() bool {
 	return true
 }
 
+func (e *elasticsearchMetrics) Validate() error {
+	return nil
+}
+
 func (e *elasticsearchMetrics) SupportedOSType() rmeta.OSType {
 	return rmeta.OSTypeLinux
 }
@@ -584,7 +584,7 @@ var _ = Describe("Elasticsearch rendering tests", func() {
 						RequestedScopes: []string{"scope"},
 					},
 				},
-			}, render.CreateDexTLSSecret("cn"), render.CreateDexClientSecret(), "cluster.local")
+			}, render.CreateDexTLSSecret("cn", nil), render.CreateDexClientSecret(), "cluster.local")
 
 			component := render.LogStorage(
 				logStorage,
@@ -651,7 +651,7 @@ var _ = Describe("Elasticsearch rendering tests", func() {
 						RequestedScopes: []string{"scope"},
 					},
 				},
-			}, render.CreateDexTLSSecret("cn"), render.CreateDexClientSecret(), "svc.cluster.local")
+			}, render.CreateDexTLSSecret("cn", nil), render.CreateDexClientSecret(), "svc.cluster.local")
 
 			component := render.LogStorage(
 				logStorage,
@@ -160,19 +160,20 @@ type managerComponent struct {
 func (c *managerComponent) ResolveImages(is *operator.ImageSet) error {
 	reg := c.installation.Registry
 	path := c.installation.ImagePath
+	opts := components.ReferenceOptionsFor(c.installation)
 	var err error
-	c.managerImage, err = components.GetReference(components.ComponentManager, reg, path, is)
+	c.managerImage, err = components.GetReference(components.ComponentManager, reg, path, is, opts...)
 	errMsgs := []string{}
 	if err != nil {
 		errMsgs = append(errMsgs, err.Error())
 	}
 
-	c.proxyImage, err = components.GetReference(components.ComponentManagerProxy, reg, path, is)
+	c.proxyImage, err = components.GetReference(components.ComponentManagerProxy, reg, path, is, opts...)
 	if err != nil {
 		errMsgs = append(errMsgs, err.Error())
 	}
 
-	c.esProxyImage, err = components.GetReference(components.ComponentEsProxy, reg, path, is)
+	c.esProxyImage, err = components.GetReference(components.ComponentEsProxy, reg, path, is, opts...)
 	if err != nil {
 		errMsgs = append(errMsgs, err.Error())
 	}
@@ -253,6 +254,10 @@ func (c *managerComponent) Ready() bool {
 	return true
 }
 
+func (c *managerComponent) Validate() error {
+	return nil
+}
+
 // managerDeployment creates a deployment for the Tigera Secure manager component.
 func (c *managerComponent) managerDeployment() *appsv1.Deployment {
 	var replicas int32 = 1
@@ -65,6 +65,10 @@ func (c *namespaceComponent) Ready() bool {
 	return true
 }
 
+func (c *namespaceComponent) Validate() error {
+	return nil
+}
+
 func createNamespace(name string, provider operatorv1.Provider) *corev1.Namespace {
 	ns := &corev1.Namespace{
 		TypeMeta: metav1.TypeMeta{Kind: "Namespace", APIVersion: "v1"},
@@ -109,26 +109,27 @@ type nodeComponent struct {
 func (c *nodeComponent) ResolveImages(is *operator.ImageSet) error {
 	reg := c.cr.Registry
 	path := c.cr.ImagePath
+	opts := components.ReferenceOptionsFor(c.cr)
 	var err error
 	if c.cr.Variant == operator.TigeraSecureEnterprise {
-		c.cniImage, err = components.GetReference(components.ComponentTigeraCNI, reg, path, is)
+		c.cniImage, err = components.GetReference(components.ComponentTigeraCNI, reg, path, is, opts...)
 	} else {
-		c.cniImage, err = components.GetReference(components.ComponentCalicoCNI, reg, path, is)
+		c.cniImage, err = components.GetReference(components.ComponentCalicoCNI, reg, path, is, opts...)
 	}
 	errMsgs := []string{}
 	if err != nil {
 		errMsgs = append(errMsgs, err.Error())
 	}
 
-	c.flexvolImage, err = components.GetReference(components.ComponentFlexVolume, reg, path, is)
+	c.flexvolImage, err = components.GetReference(components.ComponentFlexVolume, reg, path, is, opts...)
 	if err != nil {
 		errMsgs = append(errMsgs, err.Error())
 	}
 
 	if c.cr.Variant == operator.TigeraSecureEnterprise {
-		c.nodeImage, err = components.GetReference(components.ComponentTigeraNode, reg, path, is)
+		c.nodeImage, err = components.GetReference(components.ComponentTigeraNode, reg, path, is, opts...)
 	} else {
-		c.nodeImage, err = components.GetReference(components.ComponentCalicoNode, reg, path, is)
+		c.nodeImage, err = components.GetReference(components.ComponentCalicoNode, reg, path, is, opts...)
 	}
 	if err != nil {
 		errMsgs = append(errMsgs, err.Error())
@@ -196,6 +197,10 @@ func (c *nodeComponent) Ready() bool {
 	return true
 }
 
+func (c *nodeComponent) Validate() error {
+	return nil
+}
+
 // nodeServiceAccount creates the node's service account.
 func (c *nodeComponent) nodeServiceAccount() *v1.ServiceAccount {
 	return &v1.ServiceAccount{
@@ -0,0 +1,106 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	rmeta "github.com/tigera/operator/pkg/render/common/meta"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type osFilterComponent struct {
+	componentDecorator
+	available []rmeta.OSType
+}
+
+// WithOSFilter returns the component without the workloads that target an operating system that none of the nodes of
+// the cluster run. The workloads are skipped rather than deleted, so that existing workloads are left alone. A component
+// that supports none of the available operating systems is not ready, so that it is not applied at all, and reports
+// why. Without available operating systems, for example without an Installation, nothing is filtered.
+func WithOSFilter(c Component, available []rmeta.OSType) Component {
+	return &osFilterComponent{componentDecorator: componentDecorator{c}, available: available}
+}
+
+func (c *osFilterComponent) Ready() bool {
+	return c.unavailableReason() == "" && c.Component.Ready()
+}
+
+func (c *osFilterComponent) NotReadyReason() string {
+	if reason := c.unavailableReason(); reason != "" {
+		return reason
+	}
+	return c.componentDecorator.NotReadyReason()
+}
+
+func (c *osFilterComponent) Objects() ([]client.Object, []client.Object) {
+	objsToCreate, objsToDelete := c.Component.Objects()
+	var filtered []client.Object
+	for _, obj := range objsToCreate {
+		if os := c.targetOS(obj); os != "" && !c.isAvailable(os) {
+			continue
+		}
+		filtered = append(filtered, obj)
+	}
+	return filtered, objsToDelete
+}
+
+// unavailableReason explains why the component cannot run on the nodes of the cluster, or returns "" if it can.
+func (c *osFilterComponent) unavailableReason() string {
+	supported := SupportedOSTypes(c.Component)
+	var names []string
+	for _, os := range supported {
+		if os == rmeta.OSTypeAny || c.isAvailable(os) {
+			return ""
+		}
+		names = append(names, string(os))
+	}
+	var available []string
+	for _, os := range c.available {
+		available = append(available, string(os))
+	}
+	return fmt.Sprintf("the component runs on %s nodes, but the cluster only has %s nodes",
+		strings.Join(names, " or "), strings.Join(available, " and "))
+}
+
+// targetOS returns the operating system that the pods of the object run on, or "" if the object creates no pods or its
+// pods may run on any operating system.
+func (c *osFilterComponent) targetOS(obj client.Object) rmeta.OSType {
+	template := podTemplate(obj)
+	if template == nil {
+		return ""
+	}
+	if os, ok := template.Spec.NodeSelector["kubernetes.io/os"]; ok {
+		return rmeta.OSType(os)
+	}
+	if supported := SupportedOSTypes(c.Component); len(supported) == 1 && supported[0] != rmeta.OSTypeAny {
+		return supported[0]
+	}
+	return ""
+}
+
+func (c *osFilterComponent) isAvailable(os rmeta.OSType) bool {
+	if len(c.available) == 0 {
+		return true
+	}
+	for _, a := range c.available {
+		if a == os {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tigera/operator/pkg/render"
+	rmeta "github.com/tigera/operator/pkg/render/common/meta"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("WithOSFilter", func() {
+	linuxOnly := []rmeta.OSType{rmeta.OSTypeLinux}
+	windowsOnly := []rmeta.OSType{rmeta.OSTypeWindows}
+
+	It("keeps the workloads of a component that runs on the nodes of the cluster", func() {
+		c := render.WithOSFilter(&decoratedComponent{os: rmeta.OSTypeLinux}, linuxOnly)
+		Expect(c.Ready()).To(BeTrue())
+		objsToCreate, objsToDelete := c.Objects()
+		Expect(objsToCreate).To(HaveLen(2))
+		Expect(objsToDelete).To(BeEmpty())
+	})
+
+	It("is not ready and skips the workloads when the component runs on none of the operating systems of the cluster", func() {
+		c := render.WithOSFilter(&decoratedComponent{os: rmeta.OSTypeLinux}, windowsOnly)
+		Expect(c.Ready()).To(BeFalse())
+		Expect(render.NotReadyReason(c)).To(Equal("the component runs on linux nodes, but the cluster only has windows nodes"))
+
+		objsToCreate, objsToDelete := c.Objects()
+		Expect(objsToCreate).To(HaveLen(1))
+		Expect(objsToCreate[0]).To(BeAssignableToTypeOf(&corev1.ConfigMap{}))
+		Expect(objsToDelete).To(BeEmpty())
+	})
+
+	It("filters the workloads of a component that runs on any operating system by their node selector", func() {
+		c := render.WithOSFilter(&decoratedComponent{os: rmeta.OSTypeAny}, windowsOnly)
+		Expect(c.Ready()).To(BeTrue())
+		objsToCreate, objsToDelete := c.Objects()
+		Expect(objsToCreate).To(HaveLen(2))
+		Expect(objsToDelete).To(BeEmpty())
+
+		c = render.WithOSFilter(&decoratedComponent{os: rmeta.OSTypeAny, nodeSelector: map[string]string{"kubernetes.io/os": "linux"}}, windowsOnly)
+		Expect(c.Ready()).To(BeTrue())
+		objsToCreate, objsToDelete = c.Objects()
+		Expect(objsToCreate).To(HaveLen(1))
+		Expect(objsToCreate[0]).To(BeAssignableToTypeOf(&corev1.ConfigMap{}))
+		Expect(objsToDelete).To(BeEmpty())
+	})
+
+	It("filters nothing when the operating systems of the cluster are not known", func() {
+		c := render.WithOSFilter(&decoratedComponent{os: rmeta.OSTypeWindows}, nil)
+		Expect(c.Ready()).To(BeTrue())
+		objsToCreate, objsToDelete := c.Objects()
+		Expect(objsToCreate).To(HaveLen(2))
+		Expect(objsToDelete).To(BeEmpty())
+	})
+})
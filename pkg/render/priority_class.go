@@ -56,6 +56,10 @@ func (c *priorityClassComponent) Ready() bool {
 	return true
 }
 
+func (c *priorityClassComponent) Validate() error {
+	return nil
+}
+
 func (c *priorityClassComponent) calicoPriority() *schedv1.PriorityClass {
 	return &schedv1.PriorityClass{
 		TypeMeta: metav1.TypeMeta{Kind: "PriorityClass", APIVersion: "scheduling.k8s.io/v1"},
@@ -21,6 +21,8 @@ import (
 	. "github.com/onsi/gomega"
 
 	"github.com/onsi/ginkgo/reporters"
+
+	"github.com/tigera/operator/pkg/dns"
 )
 
 func TestRender(t *testing.T) {
@@ -28,3 +30,8 @@ func TestRender(t *testing.T) {
 	junitReporter := reporters.NewJUnitReporter("../../report/render_suite.xml")
 	RunSpecsWithDefaultAndCustomReporters(t, "pkg/render Suite", []Reporter{junitReporter})
 }
+
+var _ = BeforeSuite(func() {
+	// Keep the cluster domains in the tests independent of the resolv.conf of the host that runs them.
+	dns.DefaultClusterDomainDetector = dns.NewClusterDomainDetector("does-not.exist")
+})
@@ -83,11 +83,12 @@ type typhaComponent struct {
 func (c *typhaComponent) ResolveImages(is *operator.ImageSet) error {
 	reg := c.installation.Registry
 	path := c.installation.ImagePath
+	opts := components.ReferenceOptionsFor(c.installation)
 	var err error
 	if c.installation.Variant == operator.TigeraSecureEnterprise {
-		c.typhaImage, err = components.GetReference(components.ComponentTigeraTypha, reg, path, is)
+		c.typhaImage, err = components.GetReference(components.ComponentTigeraTypha, reg, path, is, opts...)
 	} else {
-		c.typhaImage, err = components.GetReference(components.ComponentCalicoTypha, reg, path, is)
+		c.typhaImage, err = components.GetReference(components.ComponentCalicoTypha, reg, path, is, opts...)
 	}
 	errMsgs := []string{}
 	if err != nil {
@@ -155,6 +156,10 @@ func (c *typhaComponent) Ready() bool {
 	return true
 }
 
+func (c *typhaComponent) Validate() error {
+	return nil
+}
+
 // typhaServiceAccount creates the typha's service account.
 func (c *typhaComponent) typhaServiceAccount() *v1.ServiceAccount {
 	return &v1.ServiceAccount{